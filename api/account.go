@@ -6,7 +6,10 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/chainbing/node/api/parsers"
+	"github.com/chainbing/node/common"
+	"github.com/chainbing/node/common/apitypes"
 	"github.com/chainbing/node/db/historydb"
+	"github.com/iden3/go-merkletree"
 )
 
 func (a *API) getAccount(c *gin.Context) {
@@ -40,7 +43,7 @@ func (a *API) getAccount(c *gin.Context) {
 func (a *API) getAccounts(c *gin.Context) {
 	for id := range c.Request.URL.Query() {
 		if id != "tokenIds" && id != "cbEthereumAddress" && id != "BJJ" &&
-			id != "fromItem" && id != "order" && id != "limit" {
+			id != "fromItem" && id != "order" && id != "limit" && id != fiatConversionParam {
 			retBadReq(&apiError{
 				Err:  fmt.Errorf("invalid Param: %s", id),
 				Code: ErrParamValidationFailedCode,
@@ -77,3 +80,146 @@ func (a *API) getAccounts(c *gin.Context) {
 		PendingItems: pendingItems,
 	})
 }
+
+func (a *API) getAccountProof(c *gin.Context) {
+	account, batchNum, err := parsers.ParseAccountProofFilters(c)
+	if err != nil {
+		retBadReq(&apiError{
+			Err:  err,
+			Code: ErrParamValidationFailedCode,
+			Type: ErrParamValidationFailedType,
+		}, c)
+		return
+	}
+	apiAccount, err := a.h.GetAccountAPI(*account.AccountIndex)
+	if err != nil {
+		retSQLErr(err, c)
+		return
+	}
+	//Check if symbol is correct
+	if apiAccount.TokenSymbol != account.Symbol {
+		retBadReq(&apiError{
+			Err:  fmt.Errorf("invalid token symbol"),
+			Code: ErrParamValidationFailedCode,
+			Type: ErrParamValidationFailedType,
+		}, c)
+		return
+	}
+	// If no batch is requested, use the last one processed by the StateDB
+	if batchNum == nil {
+		lastBatchNum, err := a.stateDB.LastGetCurrentBatch()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorMsg{Message: err.Error()})
+			return
+		}
+		batchNum = &lastBatchNum
+	}
+	if apiAccount.BatchNum > *batchNum {
+		c.JSON(http.StatusNotFound, apiErrorResponse{
+			Message: ErrAccountNotFoundAtBatch,
+			Code:    ErrAccountNotFoundAtBatchCode,
+			Type:    ErrAccountNotFoundAtBatchType,
+		})
+		return
+	}
+	batch, err := a.h.GetBatchAPI(*batchNum)
+	if err != nil {
+		retSQLErr(err, c)
+		return
+	}
+	exists, err := a.stateDB.CheckpointExists(*batchNum)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorMsg{Message: err.Error()})
+		return
+	} else if !exists {
+		c.JSON(http.StatusNotFound, apiErrorResponse{
+			Message: ErrCheckpointNotFound,
+			Code:    ErrCheckpointNotFoundCode,
+			Type:    ErrCheckpointNotFoundType,
+		})
+		return
+	}
+	leafAccount, proof, err := a.stateDB.CheckpointMTGetProof(*batchNum, *account.AccountIndex)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorMsg{Message: err.Error()})
+		return
+	}
+	// The proof is only useful if it can be verified against the state
+	// root published on chain for the batch
+	if proof.Root.BigInt().String() != string(batch.StateRoot) {
+		c.JSON(http.StatusInternalServerError, apiErrorResponse{
+			Message: ErrStateRootMismatch,
+			Code:    ErrStateRootMismatchCode,
+			Type:    ErrStateRootMismatchType,
+		})
+		return
+	}
+	leafBigInts, err := leafAccount.BigInts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorMsg{Message: err.Error()})
+		return
+	}
+	leaf := make([]apitypes.BigIntStr, len(leafBigInts))
+	for i, v := range leafBigInts {
+		leaf[i] = *apitypes.NewBigIntStr(v)
+	}
+
+	// Build successful response
+	type accountProofResponse struct {
+		AccountIndex apitypes.CbIdx                  `json:"accountIndex"`
+		BatchNum     common.BatchNum                 `json:"batchNum"`
+		StateRoot    apitypes.BigIntStr              `json:"stateRoot"`
+		Leaf         []apitypes.BigIntStr            `json:"leaf"`
+		MerkleProof  *merkletree.CircomVerifierProof `json:"merkleProof"`
+	}
+	c.JSON(http.StatusOK, &accountProofResponse{
+		AccountIndex: apiAccount.Idx,
+		BatchNum:     *batchNum,
+		StateRoot:    batch.StateRoot,
+		Leaf:         leaf,
+		MerkleProof:  proof,
+	})
+}
+
+func (a *API) getAccountHistory(c *gin.Context) {
+	account, request, err := parsers.ParseAccountHistoryFilters(c)
+	if err != nil {
+		retBadReq(&apiError{
+			Err:  err,
+			Code: ErrParamValidationFailedCode,
+			Type: ErrParamValidationFailedType,
+		}, c)
+		return
+	}
+	apiAccount, err := a.h.GetAccountAPI(*account.AccountIndex)
+	if err != nil {
+		retSQLErr(err, c)
+		return
+	}
+	//Check if symbol is correct
+	if apiAccount.TokenSymbol != account.Symbol {
+		retBadReq(&apiError{
+			Err:  fmt.Errorf("invalid token symbol"),
+			Code: ErrParamValidationFailedCode,
+			Type: ErrParamValidationFailedType,
+		}, c)
+		return
+	}
+
+	// Fetch the updates of the account from historyDB
+	updates, pendingItems, err := a.h.GetAccountHistoryAPI(request)
+	if err != nil {
+		retSQLErr(err, c)
+		return
+	}
+
+	// Build successful response
+	type accountHistoryResponse struct {
+		History      []historydb.AccountUpdateAPI `json:"history"`
+		PendingItems uint64                       `json:"pendingItems"`
+	}
+	c.JSON(http.StatusOK, &accountHistoryResponse{
+		History:      updates,
+		PendingItems: pendingItems,
+	})
+}
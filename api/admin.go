@@ -0,0 +1,78 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/chainbing/node/api/parsers"
+	"github.com/chainbing/tracerr"
+)
+
+// adminMiddleware only lets through the requests with the admin token in their
+// Authorization header
+func (a *API) adminMiddleware(c *gin.Context) {
+	const prefix = "Bearer "
+	auth := c.GetHeader("Authorization")
+	if !strings.HasPrefix(auth, prefix) ||
+		subtle.ConstantTimeCompare([]byte(auth[len(prefix):]), []byte(a.adminToken)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, apiErrorResponse{
+			Message: ErrInvalidAdminToken,
+			Code:    ErrInvalidAdminTokenCode,
+			Type:    ErrInvalidAdminTokenType,
+		})
+		return
+	}
+	c.Next()
+}
+
+type serverProofsResponse struct {
+	ServerProofs []string `json:"serverProofs"`
+}
+
+func (a *API) getServerProofs(c *gin.Context) {
+	c.JSON(http.StatusOK, &serverProofsResponse{ServerProofs: a.provers.ServerProofs()})
+}
+
+func (a *API) postServerProof(c *gin.Context) {
+	url, err := parsers.ParseServerProof(c)
+	if err != nil {
+		retBadReq(&apiError{
+			Err:  err,
+			Code: ErrParamValidationFailedCode,
+			Type: ErrParamValidationFailedType,
+		}, c)
+		return
+	}
+	if err := a.provers.AddServerProof(url); err != nil {
+		retBadReq(&apiError{
+			Err:  tracerr.Wrap(err),
+			Code: ErrParamValidationFailedCode,
+			Type: ErrParamValidationFailedType,
+		}, c)
+		return
+	}
+	c.JSON(http.StatusOK, &serverProofsResponse{ServerProofs: a.provers.ServerProofs()})
+}
+
+func (a *API) deleteServerProof(c *gin.Context) {
+	url, err := parsers.ParseServerProofFilter(c)
+	if err != nil {
+		retBadReq(&apiError{
+			Err:  err,
+			Code: ErrParamValidationFailedCode,
+			Type: ErrParamValidationFailedType,
+		}, c)
+		return
+	}
+	if err := a.provers.RemoveServerProof(url); err != nil {
+		retBadReq(&apiError{
+			Err:  tracerr.Wrap(err),
+			Code: ErrParamValidationFailedCode,
+			Type: ErrParamValidationFailedType,
+		}, c)
+		return
+	}
+	c.JSON(http.StatusOK, &serverProofsResponse{ServerProofs: a.provers.ServerProofs()})
+}
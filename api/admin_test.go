@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockProversAdmin is a ProversAdmin that keeps the URLs of the server proofs
+type mockProversAdmin struct {
+	urls []string
+}
+
+func (m *mockProversAdmin) ServerProofs() []string {
+	return append([]string{}, m.urls...)
+}
+
+func (m *mockProversAdmin) AddServerProof(url string) error {
+	for _, u := range m.urls {
+		if u == url {
+			return fmt.Errorf("prover already in the pool")
+		}
+	}
+	m.urls = append(m.urls, url)
+	return nil
+}
+
+func (m *mockProversAdmin) RemoveServerProof(url string) error {
+	for i, u := range m.urls {
+		if u == url {
+			m.urls = append(m.urls[:i], m.urls[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("prover not in the pool")
+}
+
+func TestAdminServerProofs(t *testing.T) {
+	provers := &mockProversAdmin{urls: []string{"http://localhost:3000/api"}}
+	a := &API{provers: provers, adminToken: "secret"}
+	server := gin.New()
+	admin := server.Group("/admin", a.adminMiddleware)
+	admin.GET("/server-proofs", a.getServerProofs)
+	admin.POST("/server-proofs", a.postServerProof)
+	admin.DELETE("/server-proofs", a.deleteServerProof)
+	doReq := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res := httptest.NewRecorder()
+		server.ServeHTTP(res, req)
+		return res
+	}
+	serverProofs := func(res *httptest.ResponseRecorder) []string {
+		var response serverProofsResponse
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &response))
+		return response.ServerProofs
+	}
+
+	// The requests without the admin token are rejected
+	for _, token := range []string{"", "wrong"} {
+		res := doReq(http.MethodGet, "/admin/server-proofs", token, "")
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+		var apiErr apiErrorResponse
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &apiErr))
+		assert.Equal(t, ErrInvalidAdminTokenCode, apiErr.Code)
+	}
+	res := doReq(http.MethodPost, "/admin/server-proofs", "wrong",
+		`{"url": "http://localhost:3001/api"}`)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+	assert.Equal(t, []string{"http://localhost:3000/api"}, provers.urls)
+
+	res = doReq(http.MethodGet, "/admin/server-proofs", "secret", "")
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, []string{"http://localhost:3000/api"}, serverProofs(res))
+
+	// Add
+	res = doReq(http.MethodPost, "/admin/server-proofs", "secret",
+		`{"url": "http://localhost:3001/api"}`)
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, []string{"http://localhost:3000/api", "http://localhost:3001/api"},
+		serverProofs(res))
+	res = doReq(http.MethodPost, "/admin/server-proofs", "secret",
+		`{"url": "http://localhost:3001/api"}`)
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	res = doReq(http.MethodPost, "/admin/server-proofs", "secret", `{"url": "localhost:3002"}`)
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+
+	// Remove
+	res = doReq(http.MethodDelete,
+		"/admin/server-proofs?url=http%3A%2F%2Flocalhost%3A3000%2Fapi", "secret", "")
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, []string{"http://localhost:3001/api"}, serverProofs(res))
+	res = doReq(http.MethodDelete,
+		"/admin/server-proofs?url=http%3A%2F%2Flocalhost%3A3000%2Fapi", "secret", "")
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+}
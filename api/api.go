@@ -28,16 +28,20 @@ import (
 	"errors"
 	"reflect"
 	"strings"
+	"time"
 
 	ethCommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/gin-gonic/gin"
 	"github.com/chainbing/node/api/parsers"
+	"github.com/chainbing/node/common"
 	"github.com/chainbing/node/db/historydb"
 	"github.com/chainbing/node/db/l2db"
 	"github.com/chainbing/node/db/statedb"
+	"github.com/chainbing/node/eth"
 	"github.com/chainbing/node/metric"
 	"github.com/chainbing/tracerr"
+	"github.com/lib/pq"
 	"gopkg.in/go-playground/validator.v9"
 )
 
@@ -49,101 +53,289 @@ type API struct {
 	stateDB       *statedb.StateDB
 	chainbingAddress ethCommon.Address
 	validate      *validator.Validate
+	stream        *streamHub
+	txSimulator   TxSimulator
+	provers       ProversAdmin
+	adminToken    string
+	apiKeys       *apiKeys
+	cache         *httpCache
+	ethClient     *ethclient.Client
+	rollupClient  RollupClient
+	writeTimeout  time.Duration
+}
+
+// TxSimulator simulates the selection of a PoolL2Tx for the next batch,
+// returning nil if it would be selected, or the reason why it would not
+type TxSimulator interface {
+	SimulateL2Tx(tx common.PoolL2Tx) (*common.TxSelectorError, error)
+}
+
+// ProversAdmin lists, adds and removes the server proofs used by the
+// coordinator while it's running
+type ProversAdmin interface {
+	ServerProofs() []string
+	AddServerProof(url string) error
+	RemoveServerProof(url string) error
+}
+
+// RollupClient gets the arguments of the forgeBatch calls made to the Rollup
+// smart contract
+type RollupClient interface {
+	RollupForgeBatchArgs(ethTxHash ethCommon.Hash,
+		l1UserTxsLen uint16) (*eth.RollupForgeBatchArgs, *ethCommon.Address, error)
+}
+
+// Config contains the parameters used to build the API
+type Config struct {
+	Version              string
+	CoordinatorEndpoints bool
+	ExplorerEndpoints    bool
+	Server               *gin.Engine
+	HistoryDB            *historydb.HistoryDB
+	L2DB                 *l2db.L2DB
+	StateDB              *statedb.StateDB
+	EthClient            *ethclient.Client
+	ForgerAddress        *ethCommon.Address
+	// RollupClient is used to get the data availability of the batches.
+	// If set, the /batches/{batchNum}/data-availability endpoint is
+	// enabled.
+	RollupClient RollupClient
+	// Stream enables the /stream endpoint, which pushes the events
+	// received by the EventsListener.  It's served with the explorer
+	// endpoints, under the same API keys and rate limits.
+	Stream bool
+	// EventsListener is the listener of the events notified by the SQL
+	// DB.  It's required when Stream is enabled, and it's not used
+	// otherwise.
+	EventsListener *pq.Listener
+	// WriteTimeout is the WriteTimeout of the HTTP server, which limits
+	// the time a tx can be waited for in /transactions/{id}.  0 means that
+	// the server has no WriteTimeout.
+	WriteTimeout time.Duration
+	// TxSimulator is used to simulate the selection of pool txs.  If set,
+	// the /transactions-pool/simulate endpoint is enabled.  Each
+	// simulation copies the state of the TxSelector, so it requires an
+	// API key and APIKeys must be enabled.
+	TxSimulator TxSimulator
+	// ProversAdmin is used to manage the server proofs.  If set, the
+	// /admin/server-proofs endpoints are enabled, which require
+	// AdminToken.
+	ProversAdmin ProversAdmin
+	// AdminToken is the bearer token that the requests to the admin
+	// endpoints must have in their Authorization header
+	AdminToken string
+	// Webhooks enables the coordinator endpoints to register webhooks and
+	// get their delivery log.  The webhooks are registered with an API
+	// key, so APIKeys must be enabled.
+	Webhooks bool
+	// APIKeys configures the API keys and the rate limits on the
+	// coordinator and explorer endpoints
+	APIKeys APIKeysConfig
+	// OpenAPIValidation enables the validation of the requests to the
+	// coordinator and explorer endpoints against the OpenAPI spec
+	OpenAPIValidation *OpenAPIValidationConfig
+	// HTTPCache enables the HTTP caching of the explorer endpoints whose
+	// data only changes when a new block is synchronized
+	HTTPCache *HTTPCacheConfig
 }
 
 // NewAPI sets the endpoints and the appropriate handlers, but doesn't start the server
-func NewAPI(
-	version string,
-	coordinatorEndpoints, explorerEndpoints bool,
-	server *gin.Engine,
-	hdb *historydb.HistoryDB,
-	l2db *l2db.L2DB,
-	stateDB *statedb.StateDB,
-	ethClient *ethclient.Client,
-	forgerAddress *ethCommon.Address,
-) (*API, error) {
+func NewAPI(setup Config) (*API, error) {
 	// Check input
-	if coordinatorEndpoints && l2db == nil {
+	if setup.CoordinatorEndpoints && setup.L2DB == nil {
 		return nil, tracerr.Wrap(errors.New("cannot serve Coordinator endpoints without L2DB"))
 	}
-	if explorerEndpoints && hdb == nil {
+	if setup.ExplorerEndpoints && setup.HistoryDB == nil {
 		return nil, tracerr.Wrap(errors.New("cannot serve Explorer endpoints without HistoryDB"))
 	}
-	consts, err := hdb.GetConstants()
+	if setup.Stream && setup.EventsListener == nil {
+		return nil, tracerr.Wrap(errors.New("cannot serve the stream endpoint without EventsListener"))
+	}
+	if setup.ProversAdmin != nil && setup.AdminToken == "" {
+		return nil, tracerr.Wrap(errors.New("cannot serve the admin endpoints without AdminToken"))
+	}
+	if setup.TxSimulator != nil && !setup.APIKeys.Enabled {
+		return nil, tracerr.Wrap(errors.New("cannot serve the simulate endpoint without API keys"))
+	}
+	if setup.Webhooks && !setup.APIKeys.Enabled {
+		return nil, tracerr.Wrap(errors.New("cannot serve the webhooks endpoints without API keys"))
+	}
+	if setup.APIKeys.Enabled && setup.APIKeys.IPRateLimit > 0 && setup.APIKeys.IPBurst < 1 {
+		return nil, tracerr.Wrap(errors.New("IPBurst must be at least 1 when IPRateLimit is set"))
+	}
+	if setup.HTTPCache != nil && setup.HTTPCache.Size < 1 {
+		return nil, tracerr.Wrap(errors.New("HTTPCache.Size must be at least 1"))
+	}
+	consts, err := setup.HistoryDB.GetConstants()
 	if err != nil {
 		return nil, err
 	}
 
 	a := &API{
-		h: hdb,
+		h: setup.HistoryDB,
 		cg: &configAPI{
 			RollupConstants:   *newRollupConstants(consts.Rollup),
 			AuctionConstants:  consts.Auction,
 			WDelayerConstants: consts.WDelayer,
 			ChainID:           consts.ChainID,
 		},
-		l2:            l2db,
-		stateDB:       stateDB,
+		l2:            setup.L2DB,
+		stateDB:       setup.StateDB,
 		chainbingAddress: consts.ChainbingAddress,
 		validate:      newValidate(),
+		txSimulator:   setup.TxSimulator,
+		provers:       setup.ProversAdmin,
+		adminToken:    setup.AdminToken,
+		ethClient:     setup.EthClient,
+		rollupClient:  setup.RollupClient,
+		writeTimeout:  setup.WriteTimeout,
+	}
+	if setup.APIKeys.Enabled {
+		if a.apiKeys, err = newAPIKeys(setup.HistoryDB, setup.APIKeys); err != nil {
+			return nil, tracerr.Wrap(err)
+		}
+	}
+	if setup.HTTPCache != nil {
+		a.cache = newHTTPCache(setup.HistoryDB, *setup.HTTPCache)
 	}
+	server := setup.Server
 
 	middleware, err := metric.PrometheusMiddleware()
 	if err != nil {
 		return nil, err
 	}
 	server.Use(middleware)
+	var openAPI *openAPIValidator
+	if setup.OpenAPIValidation != nil {
+		openAPI, err = newOpenAPIValidator(*setup.OpenAPIValidation)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	server.NoRoute(a.noRoute)
 
 	v1 := server.Group("/v1")
 
-	v1.GET("/health", gin.WrapH(a.healthRoute(version, ethClient, forgerAddress)))
+	v1.GET("/health", gin.WrapH(a.healthRoute(setup.Version, setup.EthClient, setup.ForgerAddress)))
+	// The stream hub also wakes the requests waiting for a tx, so it runs
+	// even without the explorer endpoints
+	if setup.Stream {
+		a.stream = newStreamHub(setup.EventsListener)
+		go a.runStream()
+	}
 	// Add coordinator endpoints
-	if coordinatorEndpoints {
+	if setup.CoordinatorEndpoints {
+		coordinator := v1.Group("")
+		if a.apiKeys != nil {
+			coordinator.Use(a.apiKeys.middleware(apiKeyScopeCoordinator))
+		}
+		if openAPI != nil {
+			coordinator.Use(openAPI.middleware)
+		}
+		txSubmission := coordinator.Group("")
+		if a.apiKeys != nil {
+			txSubmission.Use(a.apiKeys.txQuota)
+		}
 		// Account creation authorization
-		v1.POST("/account-creation-authorization", a.postAccountCreationAuth)
-		v1.GET("/account-creation-authorization/:cbEthereumAddress", a.getAccountCreationAuth)
+		coordinator.POST("/account-creation-authorization", a.postAccountCreationAuth)
+		coordinator.GET("/account-creation-authorization/:cbEthereumAddress", a.getAccountCreationAuth)
 		// Transaction
-		v1.POST("/transactions-pool", a.postPoolTx)
-		v1.PUT("/transactions-pool/:id", a.putPoolTx)
-		v1.GET("/transactions-pool/:id", a.getPoolTx)
-		v1.GET("/transactions-pool", a.getPoolTxs)
-		v1.POST("/atomic-pool", a.postAtomicPool)
-		v1.GET("/atomic-pool/:id", a.getAtomicGroup)
+		txSubmission.POST("/transactions-pool", a.postPoolTx)
+		if setup.TxSimulator != nil {
+			coordinator.POST("/transactions-pool/simulate", a.postSimulatePoolTx)
+		}
+		txSubmission.POST("/transactions-pool/replace", a.postReplacePoolTx)
+		coordinator.PUT("/transactions-pool/:id", a.putPoolTx)
+		coordinator.GET("/transactions-pool/:id", a.getPoolTx)
+		if setup.StateDB != nil {
+			coordinator.DELETE("/transactions-pool/:id", a.deletePoolTx)
+		}
+		coordinator.GET("/transactions-pool", a.getPoolTxs)
+		txSubmission.POST("/atomic-pool", a.postAtomicPool)
+		coordinator.GET("/atomic-pool/:id", a.getAtomicGroup)
+		// Webhooks
+		if setup.Webhooks {
+			coordinator.POST("/webhooks", a.postWebhook)
+			coordinator.DELETE("/webhooks/:id", a.deleteWebhook)
+			coordinator.GET("/webhooks/:id/deliveries", a.getWebhookDeliveries)
+		}
+		// Admin
+		if setup.ProversAdmin != nil {
+			admin := v1.Group("/admin", a.adminMiddleware)
+			if openAPI != nil {
+				admin.Use(openAPI.middleware)
+			}
+			admin.GET("/server-proofs", a.getServerProofs)
+			admin.POST("/server-proofs", a.postServerProof)
+			admin.DELETE("/server-proofs", a.deleteServerProof)
+		}
 	}
 
 	// Add explorer endpoints
-	if explorerEndpoints {
+	if setup.ExplorerEndpoints {
+		explorer := v1.Group("")
+		if a.apiKeys != nil {
+			explorer.Use(a.apiKeys.middleware(apiKeyScopeExplorer))
+		}
+		if openAPI != nil {
+			explorer.Use(openAPI.middleware)
+		}
+		// The fiat conversion goes before the cache, so that the
+		// cached responses are converted with the current rate
+		cached, historic := noCache, noCache
+		if a.cache != nil {
+			cached, historic = a.cache.middleware(false), a.cache.middleware(true)
+		}
 		// Account
-		v1.GET("/accounts", a.getAccounts)
-		v1.GET("/accounts/:accountIndex", a.getAccount)
-		v1.GET("/exits", a.getExits)
-		v1.GET("/exits/:batchNum/:accountIndex", a.getExit)
+		explorer.GET("/accounts", a.fiatConversionMiddleware, a.getAccounts)
+		explorer.GET("/accounts/:accountIndex", a.fiatConversionMiddleware, cached, a.getAccount)
+		explorer.GET("/accounts/:accountIndex/history", cached, a.getAccountHistory)
+		if a.stateDB != nil {
+			explorer.GET("/accounts/:accountIndex/proof", a.getAccountProof)
+		}
+		// Wallet
+		explorer.GET("/wallets/:id", a.fiatConversionMiddleware, a.getWallet)
+		explorer.GET("/exits", a.getExits)
+		explorer.GET("/exits/:batchNum/:accountIndex", a.getExit)
 		// Transaction
-		v1.GET("/transactions-history", a.getHistoryTxs)
-		v1.GET("/transactions-history/:id", a.getHistoryTx)
+		explorer.GET("/transactions-history", a.fiatConversionMiddleware, a.getHistoryTxs)
+		explorer.GET("/transactions-history/export", a.getHistoryTxsExport)
+		explorer.GET("/transactions-history/:id", a.fiatConversionMiddleware, a.getHistoryTx)
+		explorer.GET("/transactions/:id", a.fiatConversionMiddleware, a.getTransaction)
 		// Batches
-		v1.GET("/batches", a.getBatches)
-		v1.GET("/batches/:batchNum", a.getBatch)
-		v1.GET("/full-batches/:batchNum", a.getFullBatch)
+		explorer.GET("/batches", a.fiatConversionMiddleware, a.getBatches)
+		explorer.GET("/batches/:batchNum", a.fiatConversionMiddleware, cached, a.getBatch)
+		explorer.GET("/full-batches/:batchNum", a.fiatConversionMiddleware, cached, a.getFullBatch)
+		if a.rollupClient != nil {
+			explorer.GET("/batches/:batchNum/data-availability", historic, a.getBatchDataAvailability)
+		}
 		// Slots
-		v1.GET("/slots", a.getSlots)
-		v1.GET("/slots/:slotNum", a.getSlot)
+		explorer.GET("/slots", a.getSlots)
+		explorer.GET("/slots/:slotNum", a.getSlot)
 		// Bids
-		v1.GET("/bids", a.getBids)
+		explorer.GET("/bids", a.getBids)
 		// State
-		v1.GET("/state", a.getState)
+		explorer.GET("/state", a.fiatConversionMiddleware, a.getState)
+		// L1 queues
+		explorer.GET("/l1-queues", a.getL1Queues)
 		// Config
-		v1.GET("/config", a.getConfig)
+		explorer.GET("/config", a.getConfig)
 		// Tokens
-		v1.GET("/tokens", a.getTokens)
-		v1.GET("/tokens/:id", a.getToken)
+		explorer.GET("/tokens", a.fiatConversionMiddleware, cached, a.getTokens)
+		explorer.GET("/tokens/:id", a.fiatConversionMiddleware, cached, a.getToken)
+		explorer.GET("/tokens/:id/prices", a.getTokenPrices)
+		// Fees
+		explorer.GET("/fees/quote", a.getFeeQuote)
 		// Fiat Currencies
-		v1.GET("/currencies", a.getFiatCurrencies)
-		v1.GET("/currencies/:symbol", a.getFiatCurrency)
+		explorer.GET("/currencies", a.getFiatCurrencies)
+		explorer.GET("/currencies/:symbol", a.getFiatCurrency)
 		// Coordinators
-		v1.GET("/coordinators", a.getCoordinators)
+		explorer.GET("/coordinators", a.getCoordinators)
+		// Stream
+		if a.stream != nil {
+			explorer.GET("/stream", a.getStream)
+		}
 	}
 
 	return a, nil
@@ -163,8 +355,10 @@ func newValidate() *validator.Validate {
 	validate.RegisterStructValidation(parsers.BidsFiltersStructValidation, parsers.BidsFilters{})
 	validate.RegisterStructValidation(parsers.AccountsFiltersStructValidation, parsers.AccountsFilters{})
 	validate.RegisterStructValidation(parsers.HistoryTxsFiltersStructValidation, parsers.HistoryTxsFilters{})
+	validate.RegisterStructValidation(parsers.ExportTxsFiltersStructValidation, parsers.ExportTxsFilters{})
 	validate.RegisterStructValidation(parsers.PoolTxsTxsFiltersStructValidation, parsers.PoolTxsFilters{})
 	validate.RegisterStructValidation(parsers.SlotsFiltersStructValidation, parsers.SlotsFilters{})
+	validate.RegisterStructValidation(parsers.StreamFiltersStructValidation, parsers.StreamFilters{})
 
 	return validate
 }
@@ -217,7 +217,7 @@ func TestMain(m *testing.M) {
 		MinFeeUSD:  0.000000000000001,
 		MaxFeeUSD:  10000000000,
 	}
-	l2DB := l2db.NewL2DB(database, database, 10, 1000, nodeConfig.MinFeeUSD, nodeConfig.MaxFeeUSD, 24*time.Hour, apiConnCon)
+	l2DB := l2db.NewL2DB(database, database, 10, 1000, nodeConfig.MinFeeUSD, nodeConfig.MaxFeeUSD, 10.0, 24*time.Hour, apiConnCon)
 	test.WipeDB(l2DB.DB()) // this will clean HistoryDB and L2DB
 	// Config (smart contract constants)
 	chainID := uint16(0)
@@ -250,17 +250,14 @@ func TestMain(m *testing.M) {
 		panic(err)
 	}
 
-	api, err = NewAPI(
-		"test",
-		true,
-		true,
-		apiGin,
-		hdb,
-		l2DB,
-		nil,
-		nil,
-		nil,
-	)
+	api, err = NewAPI(Config{
+		Version:              "test",
+		CoordinatorEndpoints: true,
+		ExplorerEndpoints:    true,
+		Server:               apiGin,
+		HistoryDB:            hdb,
+		L2DB:                 l2DB,
+	})
 	if err != nil {
 		log.Error(err)
 		panic(err)
@@ -636,7 +633,7 @@ func TestTimeout(t *testing.T) {
 	hdbTO := historydb.NewHistoryDB(databaseTO, databaseTO, apiConnConTO)
 	require.NoError(t, err)
 	// L2DB
-	l2DBTO := l2db.NewL2DB(databaseTO, databaseTO, 10, 1000, 1.0, 1000.0, 24*time.Hour, apiConnConTO)
+	l2DBTO := l2db.NewL2DB(databaseTO, databaseTO, 10, 1000, 1.0, 1000.0, 10.0, 24*time.Hour, apiConnConTO)
 
 	// API
 	apiGinTO := gin.Default()
@@ -660,17 +657,14 @@ func TestTimeout(t *testing.T) {
 			require.NoError(t, err)
 		}
 	}()
-	_, err = NewAPI(
-		"test",
-		true,
-		true,
-		apiGinTO,
-		hdbTO,
-		l2DBTO,
-		nil,
-		nil,
-		nil,
-	)
+	_, err = NewAPI(Config{
+		Version:              "test",
+		CoordinatorEndpoints: true,
+		ExplorerEndpoints:    true,
+		Server:               apiGinTO,
+		HistoryDB:            hdbTO,
+		L2DB:                 l2DBTO,
+	})
 	require.NoError(t, err)
 
 	client := &http.Client{}
@@ -0,0 +1,402 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/chainbing/node/db/historydb"
+	"github.com/chainbing/node/log"
+	"github.com/chainbing/tracerr"
+)
+
+// APIKeyHeader is the header used by the clients to send their API key
+const APIKeyHeader = "X-API-Key"
+
+const (
+	// apiKeyCacheTTL is the time an API key is kept in memory before
+	// reading it again from the DB, which is the maximum delay for a
+	// revocation to take effect
+	apiKeyCacheTTL = time.Minute
+	// bucketIdleTTL is the time after which the unused buckets and
+	// expired API keys are removed from memory
+	bucketIdleTTL = 10 * time.Minute
+	// apiKeyContextKey is the key used to store the API key of the request
+	// in the gin context
+	apiKeyContextKey = "apiKey"
+	// txQuotaContextKey is the key used to store the daily tx quota
+	// reserved for the request in the gin context
+	txQuotaContextKey = "txQuota"
+	// acceptedTxsContextKey is the key used by the tx submission handlers
+	// to store the number of txs accepted in the gin context
+	acceptedTxsContextKey = "acceptedTxs"
+)
+
+// APIKeysConfig is the configuration of the API keys and the rate limits
+type APIKeysConfig struct {
+	// Enabled enables the authentication with API keys and the rate
+	// limits
+	Enabled bool
+	// Required rejects the requests without an API key
+	Required bool
+	// IPRateLimit is the number of requests per second allowed from each
+	// IP for the requests without an API key.  0 disables the limit.
+	IPRateLimit float64
+	// IPBurst is the number of requests allowed at once from each IP for
+	// the requests without an API key
+	IPBurst int
+	// TrustedProxies are the IPs or CIDRs of the reverse proxies in front
+	// of the API.  The IP of the requests that come from them is read from
+	// the X-Forwarded-For header, which is ignored otherwise so that the
+	// clients can't choose the bucket of their rate limit.
+	TrustedProxies []string
+}
+
+type apiKeyScope int
+
+const (
+	apiKeyScopeCoordinator apiKeyScope = iota
+	apiKeyScopeExplorer
+)
+
+// tokenBucket is a rate limiter that allows bursts of up to burst requests
+// and refills at rate requests per second
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   now,
+	}
+}
+
+// take takes a token from the bucket.  If there is none, false is returned
+// with the waiting time until the next one is available.
+func (b *tokenBucket) take(now time.Time) (bool, time.Duration) {
+	if now.After(b.last) {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+type cachedAPIKey struct {
+	apiKey  *historydb.APIKey
+	expires time.Time
+}
+
+// apiKeys authenticates the requests with API keys and applies the rate
+// limits of each key, or of each IP for the requests without key
+type apiKeys struct {
+	h              *historydb.HistoryDB
+	cfg            APIKeysConfig
+	trustedProxies []*net.IPNet
+	mu             sync.Mutex
+	keys           map[string]cachedAPIKey
+	buckets        map[string]*tokenBucket
+	lastEviction   time.Time
+}
+
+func newAPIKeys(h *historydb.HistoryDB, cfg APIKeysConfig) (*apiKeys, error) {
+	trustedProxies := make([]*net.IPNet, len(cfg.TrustedProxies))
+	for i, proxy := range cfg.TrustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, tracerr.Wrap(fmt.Errorf("invalid trusted proxy: %w", err))
+		}
+		trustedProxies[i] = ipNet
+	}
+	return &apiKeys{
+		h:              h,
+		cfg:            cfg,
+		trustedProxies: trustedProxies,
+		keys:           make(map[string]cachedAPIKey),
+		buckets:        make(map[string]*tokenBucket),
+		lastEviction:   time.Now(),
+	}, nil
+}
+
+// getCachedAPIKey returns the API key from the cache, and false if it's not
+// cached or has expired.  The unknown keys are cached as nil.
+func (k *apiKeys) getCachedAPIKey(keyHash []byte, now time.Time) (*historydb.APIKey, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	cached, ok := k.keys[string(keyHash)]
+	if !ok || !now.Before(cached.expires) {
+		return nil, false
+	}
+	return cached.apiKey, true
+}
+
+// loadAPIKey reads the API key from the DB and caches it.  If the key is
+// unknown nil is returned, and cached so that the requests with invalid keys
+// don't query the DB each time.
+func (k *apiKeys) loadAPIKey(keyHash []byte, now time.Time) (*historydb.APIKey, error) {
+	apiKey, err := k.h.GetAPIKeyAPI(keyHash)
+	if tracerr.Unwrap(err) == sql.ErrNoRows {
+		apiKey = nil
+	} else if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	k.mu.Lock()
+	k.keys[string(keyHash)] = cachedAPIKey{apiKey: apiKey, expires: now.Add(apiKeyCacheTTL)}
+	k.mu.Unlock()
+	return apiKey, nil
+}
+
+// clientIP returns the IP of the client of the request.  It's the address of
+// the connection, unless it's a trusted proxy, in which case it's the last
+// address of the X-Forwarded-For header that is not a trusted proxy.
+func (k *apiKeys) clientIP(c *gin.Context) string {
+	host, _, err := net.SplitHostPort(strings.TrimSpace(c.Request.RemoteAddr))
+	if err != nil {
+		return c.Request.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !k.isTrustedProxy(ip) {
+		return host
+	}
+	forwarded := strings.Split(c.GetHeader("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		forwardedIP := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if forwardedIP == nil {
+			break
+		}
+		ip = forwardedIP
+		if !k.isTrustedProxy(ip) {
+			break
+		}
+	}
+	return ip.String()
+}
+
+func (k *apiKeys) isTrustedProxy(ip net.IP) bool {
+	for _, proxy := range k.trustedProxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// take takes a token from the bucket with the given id, creating it if it
+// doesn't exist
+func (k *apiKeys) take(id string, rate float64, burst int, now time.Time) (bool, time.Duration) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if now.Sub(k.lastEviction) > bucketIdleTTL {
+		for bucketID, bucket := range k.buckets {
+			if now.Sub(bucket.last) > bucketIdleTTL {
+				delete(k.buckets, bucketID)
+			}
+		}
+		for keyHash, cached := range k.keys {
+			if now.After(cached.expires) {
+				delete(k.keys, keyHash)
+			}
+		}
+		k.lastEviction = now
+	}
+	bucket, ok := k.buckets[id]
+	if !ok {
+		bucket = newTokenBucket(rate, burst, now)
+		k.buckets[id] = bucket
+	}
+	// The limits of the API keys may have been updated
+	bucket.rate, bucket.burst = rate, float64(burst)
+	return bucket.take(now)
+}
+
+// middleware authenticates the requests to the endpoints of the given scope
+// and applies the rate limits
+func (k *apiKeys) middleware(scope apiKeyScope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		now := time.Now()
+		key := c.GetHeader(APIKeyHeader)
+		ipBucketID := "ip:" + k.clientIP(c)
+		if key == "" {
+			if k.cfg.Required {
+				retInvalidAPIKey(c)
+				return
+			}
+			k.limit(c, ipBucketID, k.cfg.IPRateLimit, k.cfg.IPBurst, now)
+			return
+		}
+		keyHash := historydb.APIKeyHash(key)
+		apiKey, ok := k.getCachedAPIKey(keyHash, now)
+		if !ok {
+			// The keys that are not cached are limited like the
+			// requests without an API key, so that random keys
+			// can't flood the DB
+			if !k.limit(c, ipBucketID, k.cfg.IPRateLimit, k.cfg.IPBurst, now) {
+				return
+			}
+			var err error
+			if apiKey, err = k.loadAPIKey(keyHash, now); err != nil {
+				retSQLErr(err, c)
+				c.Abort()
+				return
+			}
+		}
+		if apiKey == nil || apiKey.Revoked {
+			retInvalidAPIKey(c)
+			return
+		}
+		if (scope == apiKeyScopeCoordinator && !apiKey.CoordinatorScope) ||
+			(scope == apiKeyScopeExplorer && !apiKey.ExplorerScope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, apiErrorResponse{
+				Message: ErrAPIKeyScope,
+				Code:    ErrAPIKeyScopeCode,
+				Type:    ErrAPIKeyScopeType,
+			})
+			return
+		}
+		c.Set(apiKeyContextKey, apiKey)
+		k.limit(c, fmt.Sprintf("key:%d", apiKey.ItemID), apiKey.RateLimit, apiKey.Burst, now)
+	}
+}
+
+// limit takes a token from the bucket with the given id.  If there is none,
+// the request is rejected and false is returned.
+func (k *apiKeys) limit(c *gin.Context, bucketID string, rate float64, burst int, now time.Time) bool {
+	if rate <= 0 {
+		return true
+	}
+	if ok, wait := k.take(bucketID, rate, burst, now); !ok {
+		retTooManyRequests(c, wait, apiErrorResponse{
+			Message: ErrRateLimitExceeded,
+			Code:    ErrRateLimitExceededCode,
+			Type:    ErrRateLimitExceededType,
+		})
+		return false
+	}
+	return true
+}
+
+// txQuotaReservation is the daily tx quota of an API key reserved for the txs
+// of a request
+type txQuotaReservation struct {
+	h        *historydb.HistoryDB
+	apiKey   *historydb.APIKey
+	day      time.Time
+	reserved int
+}
+
+// reserve reserves the quota of numTxs more txs.  If they exceed the
+// remaining quota, the request is rejected and false is returned.
+func (r *txQuotaReservation) reserve(c *gin.Context, numTxs int) bool {
+	ok, err := r.h.ReserveAPIKeyTxQuotaAPI(r.apiKey.ItemID, r.day, numTxs, *r.apiKey.DailyTxQuota)
+	if err != nil {
+		retSQLErr(err, c)
+		c.Abort()
+		return false
+	}
+	if !ok {
+		retTxQuotaExceeded(c, time.Now().UTC())
+		return false
+	}
+	r.reserved += numTxs
+	return true
+}
+
+// txQuota reserves the daily tx quota of the API key of the request for one
+// tx, rejecting the submission if the quota has been reached.  The handlers
+// that submit more txs reserve the rest with checkTxQuota.  Once the handler
+// finishes, the reserved txs that have not been accepted are refunded, which
+// are all of them if the handler fails, or the ones beyond
+// acceptedTxsContextKey otherwise.  It must be used after the middleware that
+// authenticates the request.
+func (k *apiKeys) txQuota(c *gin.Context) {
+	value, ok := c.Get(apiKeyContextKey)
+	if !ok {
+		return
+	}
+	apiKey := value.(*historydb.APIKey)
+	if apiKey.DailyTxQuota == nil {
+		return
+	}
+	reservation := &txQuotaReservation{h: k.h, apiKey: apiKey, day: time.Now().UTC()}
+	if !reservation.reserve(c, 1) {
+		return
+	}
+	c.Set(txQuotaContextKey, reservation)
+	c.Next()
+
+	acceptedTxs := 0
+	if status := c.Writer.Status(); status >= 200 && status < 300 {
+		acceptedTxs = 1
+		if value, ok := c.Get(acceptedTxsContextKey); ok {
+			acceptedTxs = value.(int)
+		}
+	}
+	if refund := reservation.reserved - acceptedTxs; refund > 0 {
+		// The response has already been sent, so a failed refund
+		// can only be logged
+		if err := k.h.RefundAPIKeyTxQuotaAPI(apiKey.ItemID, reservation.day, refund); err != nil {
+			log.Errorw("RefundAPIKeyTxQuotaAPI", "err", err)
+		}
+	}
+}
+
+// checkTxQuota reserves the daily tx quota of the API key of the request for
+// numTxs txs, one of which is already reserved by txQuota.  If they exceed the
+// remaining quota, the request is rejected and false is returned.
+func checkTxQuota(c *gin.Context, numTxs int) bool {
+	value, ok := c.Get(txQuotaContextKey)
+	if !ok {
+		return true
+	}
+	reservation := value.(*txQuotaReservation)
+	if numTxs <= reservation.reserved {
+		return true
+	}
+	return reservation.reserve(c, numTxs-reservation.reserved)
+}
+
+func retTxQuotaExceeded(c *gin.Context, now time.Time) {
+	// The quota is restored at 00:00 UTC
+	nextDay := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	retTooManyRequests(c, nextDay.Sub(now), apiErrorResponse{
+		Message: ErrTxQuotaExceeded,
+		Code:    ErrTxQuotaExceededCode,
+		Type:    ErrTxQuotaExceededType,
+	})
+}
+
+func retInvalidAPIKey(c *gin.Context) {
+	c.AbortWithStatusJSON(http.StatusUnauthorized, apiErrorResponse{
+		Message: ErrInvalidAPIKey,
+		Code:    ErrInvalidAPIKeyCode,
+		Type:    ErrInvalidAPIKeyType,
+	})
+}
+
+func retTooManyRequests(c *gin.Context, retryAfter time.Duration, res apiErrorResponse) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, res)
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/chainbing/node/db/historydb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	bucket := newTokenBucket(2, 3, now)
+	// The burst is allowed at once
+	for i := 0; i < 3; i++ {
+		ok, _ := bucket.take(now)
+		assert.True(t, ok)
+	}
+	ok, wait := bucket.take(now)
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+	// The bucket is refilled at the rate
+	ok, _ = bucket.take(now.Add(500 * time.Millisecond))
+	assert.True(t, ok)
+	ok, _ = bucket.take(now.Add(500 * time.Millisecond))
+	assert.False(t, ok)
+	// But never beyond the burst
+	for i := 0; i < 3; i++ {
+		ok, _ := bucket.take(now.Add(time.Hour))
+		assert.True(t, ok)
+	}
+	ok, _ = bucket.take(now.Add(time.Hour))
+	assert.False(t, ok)
+}
+
+func TestAPIKeysIPRateLimit(t *testing.T) {
+	k, err := newAPIKeys(nil, APIKeysConfig{IPRateLimit: 0.001, IPBurst: 2})
+	require.NoError(t, err)
+	server := gin.New()
+	server.GET("/limited", k.middleware(apiKeyScopeExplorer), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	doReq := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/limited", nil)
+		req.RemoteAddr = ip + ":1234"
+		res := httptest.NewRecorder()
+		server.ServeHTTP(res, req)
+		return res
+	}
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusOK, doReq("10.0.0.1").Code)
+	}
+	res := doReq("10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, res.Code)
+	assert.NotEmpty(t, res.Header().Get("Retry-After"))
+	var apiErr apiErrorResponse
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &apiErr))
+	assert.Equal(t, ErrRateLimitExceededCode, apiErr.Code)
+	assert.Equal(t, ErrRateLimitExceededType, apiErr.Type)
+	// Each IP has its own bucket
+	assert.Equal(t, http.StatusOK, doReq("10.0.0.2").Code)
+	// The X-Forwarded-For header of untrusted proxies is ignored
+	req := httptest.NewRequest(http.MethodGet, "/limited", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "10.0.0.4")
+	res = httptest.NewRecorder()
+	server.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusTooManyRequests, res.Code)
+
+	// Requests without API key are rejected if it's required
+	k.cfg.Required = true
+	res = doReq("10.0.0.3")
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &apiErr))
+	assert.Equal(t, ErrInvalidAPIKeyCode, apiErr.Code)
+}
+
+func TestAPIKeysClientIP(t *testing.T) {
+	k, err := newAPIKeys(nil, APIKeysConfig{TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1"}})
+	require.NoError(t, err)
+	clientIP := func(remoteAddr, forwardedFor string) string {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		c.Request.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			c.Request.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		return k.clientIP(c)
+	}
+	// The header is only read from the trusted proxies
+	assert.Equal(t, "1.2.3.4", clientIP("1.2.3.4:1234", "5.6.7.8"))
+	assert.Equal(t, "5.6.7.8", clientIP("10.1.2.3:1234", "5.6.7.8"))
+	assert.Equal(t, "5.6.7.8", clientIP("192.168.1.1:1234", "5.6.7.8"))
+	assert.Equal(t, "192.168.1.2", clientIP("192.168.1.2:1234", "5.6.7.8"))
+	// The addresses added by the client before the proxies are ignored
+	assert.Equal(t, "5.6.7.8", clientIP("10.1.2.3:1234", "9.9.9.9, 5.6.7.8, 10.0.0.1"))
+	assert.Equal(t, "10.1.2.3", clientIP("10.1.2.3:1234", ""))
+
+	_, err = newAPIKeys(nil, APIKeysConfig{TrustedProxies: []string{"proxy"}})
+	assert.Error(t, err)
+}
+
+func TestAPIKeysUnknownKey(t *testing.T) {
+	k, err := newAPIKeys(api.h, APIKeysConfig{Enabled: true})
+	require.NoError(t, err)
+	server := gin.New()
+	server.GET("/keyed", k.middleware(apiKeyScopeExplorer), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	doReq := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/keyed", nil)
+		req.Header.Set(APIKeyHeader, "unknown key")
+		res := httptest.NewRecorder()
+		server.ServeHTTP(res, req)
+		return res.Code
+	}
+	assert.Equal(t, http.StatusUnauthorized, doReq())
+	// The unknown keys are cached, so they are not read again from the DB
+	// until the cache expires
+	require.NoError(t, api.h.AddAPIKey(&historydb.APIKey{
+		KeyHash:       historydb.APIKeyHash("unknown key"),
+		Name:          "unknown",
+		ExplorerScope: true,
+		RateLimit:     100,
+		Burst:         100,
+	}))
+	assert.Equal(t, http.StatusUnauthorized, doReq())
+	k.keys = make(map[string]cachedAPIKey)
+	assert.Equal(t, http.StatusOK, doReq())
+}
+
+func TestAPIKeysTxQuota(t *testing.T) {
+	quota := 3
+	apiKey := &historydb.APIKey{
+		KeyHash:          historydb.APIKeyHash("tx quota key"),
+		Name:             "quota",
+		CoordinatorScope: true,
+		RateLimit:        100,
+		Burst:            100,
+		DailyTxQuota:     &quota,
+	}
+	require.NoError(t, api.h.AddAPIKey(apiKey))
+	k, err := newAPIKeys(api.h, APIKeysConfig{Enabled: true})
+	require.NoError(t, err)
+	authenticated := func(c *gin.Context) {
+		c.Set(apiKeyContextKey, apiKey)
+	}
+	server := gin.New()
+	server.POST("/tx", authenticated, k.txQuota, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	server.POST("/rejected", authenticated, k.txQuota, func(c *gin.Context) {
+		c.Status(http.StatusBadRequest)
+	})
+	server.POST("/atomic/:numTxs", authenticated, k.txQuota, func(c *gin.Context) {
+		numTxs, err := strconv.Atoi(c.Param("numTxs"))
+		require.NoError(t, err)
+		if !checkTxQuota(c, numTxs) {
+			return
+		}
+		c.Set(acceptedTxsContextKey, numTxs)
+		c.Status(http.StatusOK)
+	})
+	doReq := func(path string) int {
+		res := httptest.NewRecorder()
+		server.ServeHTTP(res, httptest.NewRequest(http.MethodPost, path, nil))
+		return res.Code
+	}
+	txCount := func() int {
+		txCount, err := api.h.GetAPIKeyTxCountAPI(apiKey.ItemID, time.Now())
+		require.NoError(t, err)
+		return txCount
+	}
+
+	// The rejected submissions don't count
+	assert.Equal(t, http.StatusBadRequest, doReq("/rejected"))
+	assert.Equal(t, 0, txCount())
+	assert.Equal(t, http.StatusOK, doReq("/tx"))
+	assert.Equal(t, 1, txCount())
+	// An atomic group counts all its txs, and it's rejected if they
+	// exceed the remaining quota
+	assert.Equal(t, http.StatusTooManyRequests, doReq("/atomic/3"))
+	assert.Equal(t, 1, txCount())
+	assert.Equal(t, http.StatusOK, doReq("/atomic/2"))
+	assert.Equal(t, 3, txCount())
+	// Once the quota is reached, the submissions are rejected
+	assert.Equal(t, http.StatusTooManyRequests, doReq("/tx"))
+	assert.Equal(t, 3, txCount())
+
+	// Concurrent submissions can't exceed the quota
+	apiKey.KeyHash = historydb.APIKeyHash("concurrent tx quota key")
+	require.NoError(t, api.h.AddAPIKey(apiKey))
+	var wg sync.WaitGroup
+	var accepted int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if doReq("/tx") == http.StatusOK {
+				atomic.AddInt32(&accepted, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(quota), accepted)
+	assert.Equal(t, quota, txCount())
+}
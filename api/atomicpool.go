@@ -39,6 +39,9 @@ func (a *API) postAtomicPool(c *gin.Context) {
 		}, c)
 		return
 	}
+	if !checkTxQuota(c, nTxs) {
+		return
+	}
 	// Validate txs
 	txIDStrings := make([]string, nTxs) // used for successful response
 	clientIP := c.ClientIP()
@@ -101,6 +104,7 @@ func (a *API) postAtomicPool(c *gin.Context) {
 		return
 	}
 	// Return IDs of the added txs in the pool
+	c.Set(acceptedTxsContextKey, nTxs)
 	c.JSON(http.StatusOK, txIDStrings)
 }
 
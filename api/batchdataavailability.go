@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/ethereum/go-ethereum"
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/chainbing/node/api/parsers"
+	"github.com/chainbing/node/common"
+	"github.com/chainbing/node/db/historydb"
+	"github.com/chainbing/node/eth"
+	"github.com/chainbing/tracerr"
+)
+
+func (a *API) getBatchDataAvailability(c *gin.Context) {
+	// Get batchNum
+	batchNum, err := parsers.ParseBatchFilter(c)
+	if err != nil {
+		retBadReq(&apiError{
+			Err:  err,
+			Code: ErrParamValidationFailedCode,
+			Type: ErrParamValidationFailedType,
+		}, c)
+		return
+	}
+	// Fetch batch and its txs from historyDB
+	batch, err := a.h.GetBatchAPI(common.BatchNum(*batchNum))
+	if err != nil {
+		retSQLErr(err, c)
+		return
+	}
+	if batch.EthereumTxHash == (ethCommon.Hash{}) {
+		c.JSON(http.StatusNotFound, apiErrorResponse{
+			Message: ErrBatchEthTxUnknown,
+			Code:    ErrBatchEthTxUnknownCode,
+			Type:    ErrBatchEthTxUnknownType,
+		})
+		return
+	}
+	txs, err := a.h.GetBatchForgedTxsAPI(batch.BatchNum)
+	if err != nil {
+		retSQLErr(err, c)
+		return
+	}
+	// Fetch the decoded forgeBatch call
+	args, _, err := a.rollupClient.RollupForgeBatchArgs(batch.EthereumTxHash,
+		uint16(len(txs.L1UserTxs)))
+	if tracerr.Unwrap(err) == ethereum.NotFound {
+		c.JSON(http.StatusNotFound, apiErrorResponse{
+			Message: ErrBatchEthTxUnknown,
+			Code:    ErrBatchEthTxUnknownCode,
+			Type:    ErrBatchEthTxUnknownType,
+		})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, errorMsg{Message: err.Error()})
+		return
+	}
+	dataAvailability, err := eth.NewForgeBatchDataAvailability(args, len(txs.L1UserTxs),
+		&a.cg.RollupConstants.PublicConstants)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorMsg{Message: err.Error()})
+		return
+	}
+
+	// Build successful response
+	type batchDataAvailabilityResponse struct {
+		Batch            *historydb.BatchAPI             `json:"batch"`
+		DataAvailability *eth.ForgeBatchDataAvailability `json:"dataAvailability"`
+		Mismatches       []string                        `json:"mismatches"`
+	}
+	c.JSON(http.StatusOK, &batchDataAvailabilityResponse{
+		Batch:            batch,
+		DataAvailability: dataAvailability,
+		Mismatches:       dataAvailability.Mismatches(txs.L1CoordinatorTxs, txs.L2Txs),
+	})
+}
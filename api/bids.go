@@ -19,14 +19,7 @@ func (a *API) getBids(c *gin.Context) {
 		return
 	}
 
-	bids, pendingItems, err := a.h.GetBidsAPI(historydb.GetBidsAPIRequest{
-		SlotNum:    filters.SlotNum,
-		BidderAddr: filters.BidderAddr,
-		FromItem:   filters.FromItem,
-		Limit:      filters.Limit,
-		Order:      filters.Order,
-	})
-
+	bids, pendingItems, err := a.h.GetBidsAPI(filters)
 	if err != nil {
 		retSQLErr(err, c)
 		return
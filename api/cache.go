@@ -0,0 +1,243 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"container/list"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/chainbing/node/common"
+	"github.com/chainbing/node/db/historydb"
+	"github.com/chainbing/tracerr"
+)
+
+// minGzipSize is the minimum size of a response for it to be compressed
+const minGzipSize = 1024
+
+// HTTPCacheConfig is the configuration of the HTTP caching of the explorer
+// endpoints whose data only changes when a new block is synchronized
+type HTTPCacheConfig struct {
+	// Size is the maximum number of responses kept in memory
+	Size int
+	// MaxAge is the max-age of the responses that can change with the
+	// next block.  It's also the maximum time they are kept in memory, as
+	// some of them (like the token prices) can also change between blocks.
+	MaxAge time.Duration
+	// ImmutableMaxAge is the max-age of the responses of historic batches
+	ImmutableMaxAge time.Duration
+	// ImmutableBatches is the number of batches after which a batch is
+	// considered historic, and won't be changed by a reorg
+	ImmutableBatches int
+	// LastBlockInterval is the time the last synchronized block is cached
+	// before reading it again from the DB
+	LastBlockInterval time.Duration
+}
+
+// cachedResponse is a response stored in the cache
+type cachedResponse struct {
+	key         string
+	blockNum    int64
+	immutable   bool
+	expires     time.Time
+	etag        string
+	contentType string
+	body        []byte
+	gzipped     bool
+}
+
+// httpCache caches the successful responses of the explorer endpoints,
+// which are invalidated by new blocks, in a LRU.  The responses have an ETag
+// so that the clients can revalidate them.
+type httpCache struct {
+	h   *historydb.HistoryDB
+	cfg HTTPCacheConfig
+
+	mu           sync.Mutex
+	lru          *list.List
+	entries      map[string]*list.Element
+	lastBlockNum int64
+	lastBatchNum common.BatchNum
+	lastUpdate   time.Time
+}
+
+func newHTTPCache(h *historydb.HistoryDB, cfg HTTPCacheConfig) *httpCache {
+	return &httpCache{
+		h:       h,
+		cfg:     cfg,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// lastSynced returns the last synchronized block and batch
+func (hc *httpCache) lastSynced(now time.Time) (int64, common.BatchNum, error) {
+	hc.mu.Lock()
+	if now.Sub(hc.lastUpdate) < hc.cfg.LastBlockInterval {
+		defer hc.mu.Unlock()
+		return hc.lastBlockNum, hc.lastBatchNum, nil
+	}
+	hc.mu.Unlock()
+	lastBlock, err := hc.h.GetLastBlockAPI()
+	if err != nil {
+		return 0, 0, tracerr.Wrap(err)
+	}
+	lastBatchNum, err := hc.h.GetLastBatchNumAPI()
+	if tracerr.Unwrap(err) == sql.ErrNoRows {
+		lastBatchNum = 0
+	} else if err != nil {
+		return 0, 0, tracerr.Wrap(err)
+	}
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	hc.lastBlockNum, hc.lastBatchNum, hc.lastUpdate = lastBlock.Num, lastBatchNum, now
+	return hc.lastBlockNum, hc.lastBatchNum, nil
+}
+
+func (hc *httpCache) get(key string, blockNum int64, now time.Time) *cachedResponse {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	elem, ok := hc.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := elem.Value.(*cachedResponse)
+	if now.After(entry.expires) || (!entry.immutable && entry.blockNum != blockNum) {
+		hc.lru.Remove(elem)
+		delete(hc.entries, key)
+		return nil
+	}
+	hc.lru.MoveToFront(elem)
+	return entry
+}
+
+func (hc *httpCache) add(entry *cachedResponse) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	if elem, ok := hc.entries[entry.key]; ok {
+		hc.lru.Remove(elem)
+	}
+	hc.entries[entry.key] = hc.lru.PushFront(entry)
+	for hc.lru.Len() > hc.cfg.Size {
+		oldest := hc.lru.Back()
+		hc.lru.Remove(oldest)
+		delete(hc.entries, oldest.Value.(*cachedResponse).key)
+	}
+}
+
+// noCache is used instead of the middleware of the cache when it's disabled
+func noCache(*gin.Context) {}
+
+// middleware returns the middleware that serves the responses from the
+// cache.  If historic is set, the batchNum param of the route is used to
+// identify the responses of historic batches, which are immutable, so it
+// must only be set for the routes whose responses don't change once the
+// batch is historic.
+func (hc *httpCache) middleware(historic bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		hc.handle(c, historic)
+	}
+}
+
+func (hc *httpCache) handle(c *gin.Context, historic bool) {
+	now := time.Now()
+	blockNum, lastBatchNum, err := hc.lastSynced(now)
+	if err != nil {
+		retSQLErr(err, c)
+		c.Abort()
+		return
+	}
+	key := c.Request.URL.String()
+	if entry := hc.get(key, blockNum, now); entry != nil {
+		hc.serve(c, entry)
+		c.Abort()
+		return
+	}
+
+	original := c.Writer
+	writer := newRecordedResponseWriter(original, true)
+	c.Writer = writer
+	c.Next()
+	c.Writer = original
+	if !writer.held() {
+		// The responses that aren't JSON have already been written
+		return
+	}
+	if writer.status != http.StatusOK {
+		c.Status(writer.status)
+		_, _ = c.Writer.Write(writer.body.Bytes())
+		return
+	}
+
+	entry := &cachedResponse{
+		key:         key,
+		blockNum:    blockNum,
+		expires:     now.Add(hc.cfg.MaxAge),
+		contentType: original.Header().Get("Content-Type"),
+		body:        writer.body.Bytes(),
+	}
+	if batchNum, err := strconv.ParseUint(c.Param("batchNum"), 10, 32); historic && err == nil &&
+		common.BatchNum(batchNum)+common.BatchNum(hc.cfg.ImmutableBatches) <= lastBatchNum {
+		entry.immutable = true
+		entry.expires = now.Add(hc.cfg.ImmutableMaxAge)
+	}
+	hash := sha256.Sum256(entry.body)
+	entry.etag = fmt.Sprintf(`"%s"`, hex.EncodeToString(hash[:16]))
+	if len(entry.body) >= minGzipSize {
+		var gzipped bytes.Buffer
+		gzipWriter := gzip.NewWriter(&gzipped)
+		if _, err := gzipWriter.Write(entry.body); err == nil && gzipWriter.Close() == nil {
+			entry.body, entry.gzipped = gzipped.Bytes(), true
+		}
+	}
+	hc.add(entry)
+	hc.serve(c, entry)
+}
+
+// serve writes the cached response, or a 304 response if the client already
+// has it
+func (hc *httpCache) serve(c *gin.Context, entry *cachedResponse) {
+	maxAge := time.Until(entry.expires)
+	if maxAge < 0 {
+		maxAge = 0
+	}
+	cacheControl := fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds()))
+	if entry.immutable {
+		cacheControl += ", immutable"
+	}
+	c.Header("Cache-Control", cacheControl)
+	c.Header("ETag", entry.etag)
+	c.Header("Vary", "Accept-Encoding")
+	for _, etag := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		if etag = strings.TrimPrefix(strings.TrimSpace(etag), "W/"); etag == entry.etag || etag == "*" {
+			c.Status(http.StatusNotModified)
+			c.Writer.WriteHeaderNow()
+			return
+		}
+	}
+	body := entry.body
+	if entry.gzipped {
+		if strings.Contains(c.GetHeader("Accept-Encoding"), "gzip") {
+			c.Header("Content-Encoding", "gzip")
+		} else {
+			reader, err := gzip.NewReader(bytes.NewReader(entry.body))
+			if err == nil {
+				body, err = ioutil.ReadAll(reader)
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, errorMsg{Message: err.Error()})
+				return
+			}
+		}
+	}
+	c.Data(http.StatusOK, entry.contentType, body)
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPCache(t *testing.T) {
+	hc := newHTTPCache(nil, HTTPCacheConfig{
+		Size:              2,
+		MaxAge:            time.Minute,
+		ImmutableMaxAge:   time.Hour,
+		ImmutableBatches:  10,
+		LastBlockInterval: time.Hour,
+	})
+	// Avoid reading the last block from the DB
+	hc.lastBlockNum, hc.lastBatchNum, hc.lastUpdate = 100, 50, time.Now()
+
+	calls := 0
+	bigBody := strings.Repeat("a", 2*minGzipSize)
+	server := gin.New()
+	cached := server.Group("", hc.middleware(false))
+	historic := server.Group("", hc.middleware(true))
+	historic.GET("/batches/:batchNum", func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusOK, gin.H{"batchNum": c.Param("batchNum")})
+	})
+	cached.GET("/full-batches/:batchNum", func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusOK, gin.H{"batchNum": c.Param("batchNum")})
+	})
+	cached.GET("/tokens", func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusOK, gin.H{"tokens": bigBody})
+	})
+	cached.GET("/missing", func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusNotFound, gin.H{})
+	})
+	cached.GET("/export", func(c *gin.Context) {
+		calls++
+		c.Data(http.StatusOK, "text/csv", []byte("id\n1\n"))
+	})
+	doReq := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if header != nil {
+			req.Header = header
+		}
+		res := httptest.NewRecorder()
+		server.ServeHTTP(res, req)
+		return res
+	}
+
+	// The second request is served from the cache
+	res := doReq("/batches/45", nil)
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.JSONEq(t, `{"batchNum": "45"}`, res.Body.String())
+	assert.Equal(t, "public, max-age=60", res.Header().Get("Cache-Control"))
+	etag := res.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	res = doReq("/batches/45", nil)
+	assert.JSONEq(t, `{"batchNum": "45"}`, res.Body.String())
+	assert.Equal(t, etag, res.Header().Get("ETag"))
+	assert.Equal(t, 1, calls)
+	// The client already has the response
+	res = doReq("/batches/45", http.Header{"If-None-Match": []string{etag}})
+	assert.Equal(t, http.StatusNotModified, res.Code)
+	assert.Empty(t, res.Body.String())
+	assert.Equal(t, 1, calls)
+	// Historic batches are immutable
+	res = doReq("/batches/40", nil)
+	assert.Equal(t, "public, max-age=3600, immutable", res.Header().Get("Cache-Control"))
+	assert.Equal(t, 2, calls)
+
+	// A new block invalidates the mutable responses only
+	hc.lastBlockNum = 101
+	doReq("/batches/45", nil)
+	assert.Equal(t, 3, calls)
+	doReq("/batches/40", nil)
+	assert.Equal(t, 3, calls)
+
+	// Big responses are compressed
+	res = doReq("/tokens", http.Header{"Accept-Encoding": []string{"gzip"}})
+	assert.Equal(t, "gzip", res.Header().Get("Content-Encoding"))
+	reader, err := gzip.NewReader(bytes.NewReader(res.Body.Bytes()))
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), bigBody)
+	// And decompressed for the clients that don't accept gzip
+	res = doReq("/tokens", nil)
+	assert.Empty(t, res.Header().Get("Content-Encoding"))
+	assert.Contains(t, res.Body.String(), bigBody)
+	assert.Equal(t, 4, calls)
+
+	// The least recently used response is evicted
+	doReq("/batches/45", nil)
+	assert.Equal(t, 5, calls)
+	assert.Equal(t, 2, hc.lru.Len())
+	doReq("/tokens", nil)
+	assert.Equal(t, 5, calls)
+
+	// Unsuccessful responses are not cached
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusNotFound, doReq("/missing", nil).Code)
+	}
+	assert.Equal(t, 7, calls)
+
+	// Only the responses of the historic routes are immutable
+	res = doReq("/full-batches/40", nil)
+	assert.Equal(t, "public, max-age=60", res.Header().Get("Cache-Control"))
+	assert.Equal(t, 8, calls)
+	// The responses that aren't JSON are written through without being
+	// cached
+	for i := 0; i < 2; i++ {
+		res = doReq("/export", nil)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "id\n1\n", res.Body.String())
+		assert.Empty(t, res.Header().Get("ETag"))
+	}
+	assert.Equal(t, 10, calls)
+}
@@ -152,6 +152,130 @@ const (
 	// ErrNothingToUpdateType type for nothing to update type
 	ErrNothingToUpdateType apiErrorType = "ErrNothingToUpdate"
 
+	// ErrCheckpointNotFound error message returned when the StateDB checkpoint of the requested batch is not kept
+	ErrCheckpointNotFound = "the state of the requested batch is not available in this node"
+	// ErrCheckpointNotFoundCode code for checkpoint not found error
+	ErrCheckpointNotFoundCode apiErrorCode = 26
+	// ErrCheckpointNotFoundType type for checkpoint not found error
+	ErrCheckpointNotFoundType apiErrorType = "ErrCheckpointNotFound"
+
+	// ErrAccountNotFoundAtBatch error message returned when the account was created after the requested batch
+	ErrAccountNotFoundAtBatch = "the account didn't exist at the requested batch"
+	// ErrAccountNotFoundAtBatchCode code for account not found at batch error
+	ErrAccountNotFoundAtBatchCode apiErrorCode = 27
+	// ErrAccountNotFoundAtBatchType type for account not found at batch error
+	ErrAccountNotFoundAtBatchType apiErrorType = "ErrAccountNotFoundAtBatch"
+
+	// ErrStateRootMismatch error message returned when the root of the StateDB doesn't match the state root of the batch
+	ErrStateRootMismatch = "the local state root doesn't match the state root of the batch"
+	// ErrStateRootMismatchCode code for state root mismatch error
+	ErrStateRootMismatchCode apiErrorCode = 28
+	// ErrStateRootMismatchType type for state root mismatch error
+	ErrStateRootMismatchType apiErrorType = "ErrStateRootMismatch"
+
+	// ErrReplaceAtomicTx error message returned when trying to replace a transaction that belongs to an atomic group
+	ErrReplaceAtomicTx = "transactions that belong to an atomic group can't be replaced"
+	// ErrReplaceAtomicTxCode code for replace atomic tx error
+	ErrReplaceAtomicTxCode apiErrorCode = 29
+	// ErrReplaceAtomicTxType type for replace atomic tx error
+	ErrReplaceAtomicTxType apiErrorType = "ErrReplaceAtomicTx"
+
+	// ErrReplaceFeeTooLowCode code for replacement fee too low error
+	ErrReplaceFeeTooLowCode apiErrorCode = 30
+	// ErrReplaceFeeTooLowType type for replacement fee too low error
+	ErrReplaceFeeTooLowType apiErrorType = "ErrReplaceFeeTooLow"
+
+	// ErrCancelNotPending error message returned when trying to cancel a transaction that is not pending
+	ErrCancelNotPending = "only pending transactions can be cancelled"
+	// ErrCancelNotPendingCode code for cancel not pending tx error
+	ErrCancelNotPendingCode apiErrorCode = 31
+	// ErrCancelNotPendingType type for cancel not pending tx error
+	ErrCancelNotPendingType apiErrorType = "ErrCancelNotPending"
+
+	// ErrInvalidAPIKey error message returned when the API key is missing (and required), unknown or revoked
+	ErrInvalidAPIKey = "the API key is missing, invalid or revoked"
+	// ErrInvalidAPIKeyCode code for invalid API key error
+	ErrInvalidAPIKeyCode apiErrorCode = 32
+	// ErrInvalidAPIKeyType type for invalid API key error
+	ErrInvalidAPIKeyType apiErrorType = "ErrInvalidAPIKey"
+
+	// ErrAPIKeyScope error message returned when the API key is not allowed to use the requested endpoint
+	ErrAPIKeyScope = "the API key is not allowed to use this endpoint"
+	// ErrAPIKeyScopeCode code for API key scope error
+	ErrAPIKeyScopeCode apiErrorCode = 33
+	// ErrAPIKeyScopeType type for API key scope error
+	ErrAPIKeyScopeType apiErrorType = "ErrAPIKeyScope"
+
+	// ErrRateLimitExceeded error message returned when the rate limit of the API key or IP is exceeded
+	ErrRateLimitExceeded = "too many requests, please try again later"
+	// ErrRateLimitExceededCode code for rate limit exceeded error
+	ErrRateLimitExceededCode apiErrorCode = 34
+	// ErrRateLimitExceededType type for rate limit exceeded error
+	ErrRateLimitExceededType apiErrorType = "ErrRateLimitExceeded"
+
+	// ErrTxQuotaExceeded error message returned when the daily tx submission quota of the API key is reached
+	ErrTxQuotaExceeded = "the daily transaction submission quota of the API key has been reached"
+	// ErrTxQuotaExceededCode code for tx quota exceeded error
+	ErrTxQuotaExceededCode apiErrorCode = 35
+	// ErrTxQuotaExceededType type for tx quota exceeded error
+	ErrTxQuotaExceededType apiErrorType = "ErrTxQuotaExceeded"
+
+	// ErrBatchEthTxUnknown error message returned when the ethereum tx in which a batch was forged is not known
+	ErrBatchEthTxUnknown = "the ethereum transaction in which the batch was forged is not known"
+	// ErrBatchEthTxUnknownCode code for unknown batch ethereum tx error
+	ErrBatchEthTxUnknownCode apiErrorCode = 36
+	// ErrBatchEthTxUnknownType type for unknown batch ethereum tx error
+	ErrBatchEthTxUnknownType apiErrorType = "ErrBatchEthTxUnknown"
+
+	// ErrTokenWithoutPrice error message returned when the price of a token is needed but it's not known
+	ErrTokenWithoutPrice = "the price in USD of the token is not known"
+	// ErrTokenWithoutPriceCode code for token without price error
+	ErrTokenWithoutPriceCode apiErrorCode = 37
+	// ErrTokenWithoutPriceType type for token without price error
+	ErrTokenWithoutPriceType apiErrorType = "ErrTokenWithoutPrice"
+
+	// ErrFeeUnreachable error message returned when no fee selector reaches the recommended fee for an amount
+	ErrFeeUnreachable = "no fee selector reaches the recommended fee for the amount"
+	// ErrFeeUnreachableCode code for unreachable fee error
+	ErrFeeUnreachableCode apiErrorCode = 38
+	// ErrFeeUnreachableType type for unreachable fee error
+	ErrFeeUnreachableType apiErrorType = "ErrFeeUnreachable"
+
+	// ErrForbiddenWebhookURL error message returned when the URL of a webhook doesn't resolve to public addresses only
+	ErrForbiddenWebhookURL = "the webhook URL must resolve to public addresses only"
+	// ErrForbiddenWebhookURLCode code for forbidden webhook URL error
+	ErrForbiddenWebhookURLCode apiErrorCode = 39
+	// ErrForbiddenWebhookURLType type for forbidden webhook URL error
+	ErrForbiddenWebhookURLType apiErrorType = "ErrForbiddenWebhookURL"
+
+	// ErrMaxWebhooks error message returned when the API key has already registered the maximum number of webhooks
+	ErrMaxWebhooks = "the API key has reached the maximum number of webhooks"
+	// ErrMaxWebhooksCode code for max webhooks error
+	ErrMaxWebhooksCode apiErrorCode = 40
+	// ErrMaxWebhooksType type for max webhooks error
+	ErrMaxWebhooksType apiErrorType = "ErrMaxWebhooks"
+
+	// ErrTooManyTxWaiters error message returned when the maximum number of requests waiting for a transaction is reached
+	ErrTooManyTxWaiters = "too many requests waiting for transactions, please try again later"
+	// ErrTooManyTxWaitersCode code for too many tx waiters error
+	ErrTooManyTxWaitersCode apiErrorCode = 41
+	// ErrTooManyTxWaitersType type for too many tx waiters error
+	ErrTooManyTxWaitersType apiErrorType = "ErrTooManyTxWaiters"
+
+	// ErrInvalidAdminToken error message returned when the admin token of a request to the admin endpoints is missing or invalid
+	ErrInvalidAdminToken = "the admin token is missing or invalid"
+	// ErrInvalidAdminTokenCode code for invalid admin token error
+	ErrInvalidAdminTokenCode apiErrorCode = 42
+	// ErrInvalidAdminTokenType type for invalid admin token error
+	ErrInvalidAdminTokenType apiErrorType = "ErrInvalidAdminToken"
+
+	// ErrTooManyStreamSubscribers error message returned when the maximum number of clients subscribed to the stream is reached
+	ErrTooManyStreamSubscribers = "too many clients subscribed to the stream, please try again later"
+	// ErrTooManyStreamSubscribersCode code for too many stream subscribers error
+	ErrTooManyStreamSubscribersCode apiErrorCode = 43
+	// ErrTooManyStreamSubscribersType type for too many stream subscribers error
+	ErrTooManyStreamSubscribersType apiErrorType = "ErrTooManyStreamSubscribers"
+
 	// ErrUnsupportedMaxNumBatch error message returned when tx.MaxNumBatch != 0 until the feature is fully implemented
 	ErrUnsupportedMaxNumBatch = "currently only supported value for maxNumBatch is 0, this will change soon when the feature is fully implemented"
 
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"math/big"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/chainbing/node/api/parsers"
+	"github.com/chainbing/node/common"
+	"github.com/chainbing/node/common/apitypes"
+	"github.com/chainbing/node/db/historydb"
+	"github.com/chainbing/tracerr"
+)
+
+// feeQuote is the cheapest fee that meets the recommended fee for a type of
+// receiver
+type feeQuote struct {
+	ToType            string             `json:"toType"`
+	RecommendedFeeUSD float64            `json:"recommendedFeeUSD"`
+	Fee               common.FeeSelector `json:"fee"`
+	FeeAmount         apitypes.BigIntStr `json:"feeAmount"`
+	FeeUSD            float64            `json:"feeUSD"`
+}
+
+func (a *API) getFeeQuote(c *gin.Context) {
+	tokenID, amount, toTypes, err := parsers.ParseFeeQuoteFilters(c)
+	if err != nil {
+		retBadReq(&apiError{
+			Err:  err,
+			Code: ErrParamValidationFailedCode,
+			Type: ErrParamValidationFailedType,
+		}, c)
+		return
+	}
+	token, err := a.h.GetTokenAPI(tokenID)
+	if err != nil {
+		retSQLErr(err, c)
+		return
+	}
+	if token.USD == nil || *token.USD <= 0 {
+		c.JSON(http.StatusBadRequest, apiErrorResponse{
+			Message: ErrTokenWithoutPrice,
+			Code:    ErrTokenWithoutPriceCode,
+			Type:    ErrTokenWithoutPriceType,
+		})
+		return
+	}
+	// The recommended fees are the ones published in the state, which
+	// are updated periodically from GetRecommendedFee
+	state, err := a.h.GetStateAPI()
+	if err != nil {
+		retSQLErr(err, c)
+		return
+	}
+	recommendedFees := map[string]float64{
+		parsers.FeeQuoteToExistingAccount:       state.RecommendedFee.ExistingAccount,
+		parsers.FeeQuoteToCreateAccount:         state.RecommendedFee.CreatesAccount,
+		parsers.FeeQuoteToCreateAccountInternal: state.RecommendedFee.CreatesAccountInternal,
+	}
+
+	quotes := []feeQuote{}
+	for _, toType := range toTypes {
+		quote, err := quoteFee(token, amount, recommendedFees[toType])
+		if err != nil {
+			retBadReq(&apiError{
+				Err:  err,
+				Code: ErrFeeUnreachableCode,
+				Type: ErrFeeUnreachableType,
+			}, c)
+			return
+		}
+		quote.ToType = toType
+		quotes = append(quotes, *quote)
+	}
+
+	// Build successful response
+	type feeQuoteResponse struct {
+		Token  historydb.TokenWithUSD `json:"token"`
+		Amount apitypes.BigIntStr     `json:"amount"`
+		Quotes []feeQuote             `json:"quotes"`
+	}
+	c.JSON(http.StatusOK, &feeQuoteResponse{
+		Token:  *token,
+		Amount: *apitypes.NewBigIntStr(amount),
+		Quotes: quotes,
+	})
+}
+
+// quoteFee returns the lowest FeeSelector whose fee for the amount is worth at
+// least recommendedFeeUSD at the current price of the token
+func quoteFee(token *historydb.TokenWithUSD, amount *big.Int,
+	recommendedFeeUSD float64) (*feeQuote, error) {
+	// 1 token = 10^decimals units, so the units worth recommendedFeeUSD
+	// are recommendedFeeUSD * 10^decimals / USD, rounded up
+	unit := new(big.Float).SetInt(new(big.Int).Exp(
+		big.NewInt(10), big.NewInt(int64(token.Decimals)), nil)) //nolint:gomnd
+	minFeeUnits := new(big.Float).Mul(big.NewFloat(recommendedFeeUSD), unit)
+	minFeeUnits.Quo(minFeeUnits, big.NewFloat(*token.USD))
+	minFeeAmount, accuracy := minFeeUnits.Int(nil)
+	if accuracy == big.Below {
+		minFeeAmount.Add(minFeeAmount, big.NewInt(1))
+	}
+	feeSel, feeAmount, err := common.MinFeeSelector(amount, minFeeAmount)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	feeUSD, _ := new(big.Float).Quo(
+		new(big.Float).Mul(new(big.Float).SetInt(feeAmount), big.NewFloat(*token.USD)),
+		unit,
+	).Float64()
+	return &feeQuote{
+		RecommendedFeeUSD: recommendedFeeUSD,
+		Fee:               feeSel,
+		FeeAmount:         *apitypes.NewBigIntStr(feeAmount),
+		FeeUSD:            feeUSD,
+	}, nil
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/chainbing/node/common"
+	"github.com/chainbing/node/db/historydb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuoteFee(t *testing.T) {
+	usd := 2.0
+	token := &historydb.TokenWithUSD{Decimals: 18, USD: &usd}
+	// 1 token, worth 2 USD
+	amount, _ := new(big.Int).SetString("1000000000000000000", 10)
+	quote, err := quoteFee(token, amount, 0.1)
+	require.NoError(t, err)
+	// 0.1 USD are 0.05 tokens
+	minFeeAmount, _ := new(big.Int).SetString("50000000000000000", 10)
+	feeAmount, ok := new(big.Int).SetString(string(quote.FeeAmount), 10)
+	require.True(t, ok)
+	assert.True(t, feeAmount.Cmp(minFeeAmount) >= 0)
+	assert.GreaterOrEqual(t, quote.FeeUSD, 0.1)
+	assert.Equal(t, 0.1, quote.RecommendedFeeUSD)
+	// The previous fee selector doesn't reach the recommended fee
+	prevFeeAmount, err := common.CalcFeeAmount(amount, quote.Fee-1)
+	require.NoError(t, err)
+	assert.Equal(t, -1, prevFeeAmount.Cmp(minFeeAmount))
+
+	// A fee of 0 USD is met by the fee selector 0
+	quote, err = quoteFee(token, amount, 0)
+	require.NoError(t, err)
+	assert.Equal(t, common.FeeSelector(0), quote.Fee)
+
+	// The fees of tiny amounts can't reach the recommended fee
+	_, err = quoteFee(token, big.NewInt(1), 0.1)
+	assert.Error(t, err)
+}
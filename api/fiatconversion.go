@@ -0,0 +1,175 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/chainbing/node/log"
+	"github.com/chainbing/tracerr"
+)
+
+const (
+	// fiatConversionParam is the query param with the currency of the fiat
+	// table in which the USD values of the response are also returned
+	fiatConversionParam = "currency"
+	// fiatConversionKey is the key of the conversion rate in the responses
+	fiatConversionKey = "fiatConversion"
+	// recommendedFeeKey is the key of the recommended fees of the state,
+	// which are in USD
+	recommendedFeeKey = "recommendedFee"
+)
+
+// fiatConversion is the conversion rate from USD used in a response
+type fiatConversion struct {
+	Currency   string    `json:"currency"`
+	Rate       float64   `json:"rate"`
+	LastUpdate time.Time `json:"lastUpdate"`
+}
+
+// fiatConversionMiddleware adds to the JSON responses the value in the
+// requested currency of every USD value.  Every field named xUSD gets a xFiat
+// sibling (USD gets fiat), and so does the recommendedFee of the state.  The
+// rate and its last update are returned in the fiatConversion field.  It must
+// be used before the cache, so that the cached USD values are converted with
+// the current rate.
+func (a *API) fiatConversionMiddleware(c *gin.Context) {
+	currency := c.Query(fiatConversionParam)
+	if currency == "" {
+		return
+	}
+	fiat, err := a.h.GetCurrencyAPI(currency)
+	if tracerr.Unwrap(err) == sql.ErrNoRows {
+		retBadReq(&apiError{
+			Err:  fmt.Errorf("unknown currency: %v", currency),
+			Code: ErrParamValidationFailedCode,
+			Type: ErrParamValidationFailedType,
+		}, c)
+		c.Abort()
+		return
+	} else if err != nil {
+		retSQLErr(err, c)
+		c.Abort()
+		return
+	}
+	convertResponse(c, fiatConversion{
+		Currency:   fiat.Currency,
+		Rate:       fiat.Price,
+		LastUpdate: fiat.LastUpdate,
+	})
+}
+
+// convertResponse adds the fiat values to the response of the next handlers
+func convertResponse(c *gin.Context, conversion fiatConversion) {
+	// The response is needed in full and uncompressed, even if the client
+	// already has it, as the conversion can change
+	c.Request.Header.Del("If-None-Match")
+	c.Request.Header.Del("Accept-Encoding")
+	original := c.Writer
+	writer := newRecordedResponseWriter(original, true)
+	c.Writer = writer
+	c.Next()
+	c.Writer = original
+	if !writer.held() {
+		// The responses that aren't JSON have already been written
+		return
+	}
+	body := writer.body.Bytes()
+	if writer.status == http.StatusOK && writer.recording {
+		converted, err := convertUSDValues(body, conversion)
+		if err != nil {
+			log.Warnw("fiat conversion of the response failed", "path", c.FullPath(), "err", err)
+		} else {
+			body = converted
+			// The cache headers refer to the USD values
+			original.Header().Del("ETag")
+			original.Header().Del("Cache-Control")
+		}
+	}
+	c.Status(writer.status)
+	_, _ = c.Writer.Write(body)
+}
+
+// convertUSDValues adds the fiat values of the USD values of a JSON response,
+// and the conversion used if the response is an object
+func convertUSDValues(body []byte, conversion fiatConversion) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var response interface{}
+	if err := decoder.Decode(&response); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	addFiatValues(response, conversion.Rate)
+	if object, ok := response.(map[string]interface{}); ok {
+		object[fiatConversionKey] = conversion
+	}
+	converted, err := json.Marshal(response)
+	return converted, tracerr.Wrap(err)
+}
+
+// addFiatValues adds recursively the fiat sibling of every USD field
+func addFiatValues(value interface{}, rate float64) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		fiatValues := make(map[string]interface{})
+		for key, field := range v {
+			addFiatValues(field, rate)
+			if fiatKey, ok := fiatFieldName(key); ok {
+				if fiatValue, ok := toFiat(field, rate); ok {
+					fiatValues[fiatKey] = fiatValue
+				}
+			}
+		}
+		for key, fiatValue := range fiatValues {
+			v[key] = fiatValue
+		}
+	case []interface{}:
+		for _, item := range v {
+			addFiatValues(item, rate)
+		}
+	}
+}
+
+// fiatFieldName returns the name of the fiat sibling of a USD field
+func fiatFieldName(key string) (string, bool) {
+	if key == recommendedFeeKey {
+		return key + "Fiat", true
+	}
+	if !strings.HasSuffix(key, "USD") {
+		return "", false
+	}
+	if prefix := strings.TrimSuffix(key, "USD"); prefix != "" {
+		return prefix + "Fiat", true
+	}
+	return "fiat", true
+}
+
+// toFiat converts a USD value, which can be null or an object of USD values
+func toFiat(value interface{}, rate float64) (interface{}, bool) {
+	switch v := value.(type) {
+	case nil:
+		return nil, true
+	case json.Number:
+		usd, err := v.Float64()
+		if err != nil {
+			return nil, false
+		}
+		return usd * rate, true
+	case map[string]interface{}:
+		fiatValues := make(map[string]interface{}, len(v))
+		for key, field := range v {
+			fiatValue, ok := toFiat(field, rate)
+			if !ok {
+				return nil, false
+			}
+			fiatValues[key] = fiatValue
+		}
+		return fiatValues, true
+	}
+	return nil, false
+}
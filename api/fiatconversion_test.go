@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertUSDValues(t *testing.T) {
+	conversion := fiatConversion{
+		Currency:   "EUR",
+		Rate:       0.5,
+		LastUpdate: time.Date(2021, 4, 17, 20, 21, 16, 0, time.UTC),
+	}
+	body := []byte(`{
+		"transactions": [
+			{"id": "0x02", "itemId": 12345678901234567890, "historicUSD": 10,
+				"token": {"USD": 2, "fiatUpdate": null}},
+			{"id": "0x03", "historicUSD": null}
+		],
+		"recommendedFee": {"existingAccount": 1, "createAccount": 3},
+		"pendingItems": 0
+	}`)
+	converted, err := convertUSDValues(body, conversion)
+	require.NoError(t, err)
+	var response struct {
+		Transactions []struct {
+			ItemID       json.Number `json:"itemId"`
+			HistoricUSD  *float64    `json:"historicUSD"`
+			HistoricFiat *float64    `json:"historicFiat"`
+			Token        struct {
+				USD  float64 `json:"USD"`
+				Fiat float64 `json:"fiat"`
+			} `json:"token"`
+		} `json:"transactions"`
+		RecommendedFeeFiat map[string]float64 `json:"recommendedFeeFiat"`
+		FiatConversion     fiatConversion     `json:"fiatConversion"`
+	}
+	require.NoError(t, json.Unmarshal(converted, &response))
+	require.Equal(t, 2, len(response.Transactions))
+	// Big numbers are kept as they are
+	assert.Equal(t, "12345678901234567890", response.Transactions[0].ItemID.String())
+	assert.Equal(t, 10.0, *response.Transactions[0].HistoricUSD)
+	assert.Equal(t, 5.0, *response.Transactions[0].HistoricFiat)
+	assert.Equal(t, 2.0, response.Transactions[0].Token.USD)
+	assert.Equal(t, 1.0, response.Transactions[0].Token.Fiat)
+	assert.Nil(t, response.Transactions[1].HistoricFiat)
+	assert.Equal(t, map[string]float64{"existingAccount": 0.5, "createAccount": 1.5},
+		response.RecommendedFeeFiat)
+	assert.Equal(t, conversion, response.FiatConversion)
+}
+
+func TestFiatFieldName(t *testing.T) {
+	for key, expected := range map[string]string{
+		"USD":                           "fiat",
+		"historicUSD":                   "historicFiat",
+		"historicTotalCollectedFeesUSD": "historicTotalCollectedFeesFiat",
+		"recommendedFee":                "recommendedFeeFiat",
+	} {
+		fiatKey, ok := fiatFieldName(key)
+		assert.True(t, ok)
+		assert.Equal(t, expected, fiatKey)
+	}
+	_, ok := fiatFieldName("amount")
+	assert.False(t, ok)
+}
+
+func TestConvertCachedResponse(t *testing.T) {
+	hc := newHTTPCache(nil, HTTPCacheConfig{
+		Size:              10,
+		MaxAge:            time.Minute,
+		LastBlockInterval: time.Hour,
+	})
+	// Avoid reading the last block from the DB
+	hc.lastBlockNum, hc.lastUpdate = 100, time.Now()
+	conversion := fiatConversion{Currency: "EUR"}
+	calls := 0
+	server := gin.New()
+	server.GET("/tokens/:id", func(c *gin.Context) {
+		convertResponse(c, conversion)
+	}, hc.middleware(false), func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusOK, gin.H{"USD": 2, "name": strings.Repeat("a", 2*minGzipSize)})
+	})
+
+	// The cached response is converted with the current rate, even if the
+	// client already has the cached one or accepts it compressed
+	for _, rate := range []float64{0.5, 2} {
+		conversion.Rate = rate
+		req := httptest.NewRequest(http.MethodGet, "/tokens/1", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		req.Header.Set("If-None-Match", "*")
+		res := httptest.NewRecorder()
+		server.ServeHTTP(res, req)
+		require.Equal(t, http.StatusOK, res.Code)
+		assert.Empty(t, res.Header().Get("ETag"))
+		assert.Empty(t, res.Header().Get("Cache-Control"))
+		assert.Empty(t, res.Header().Get("Content-Encoding"))
+		var response struct {
+			Fiat float64 `json:"fiat"`
+		}
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &response))
+		assert.Equal(t, 2*rate, response.Fiat)
+	}
+	assert.Equal(t, 1, calls)
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/chainbing/node/db/historydb"
 	"github.com/chainbing/node/log"
 	"github.com/chainbing/node/metric"
 	"github.com/chainbing/tracerr"
@@ -51,6 +52,12 @@ func retSQLErr(err error, c *gin.Context) {
 			Code:    ErrSQLNoRowsCode,
 			Type:    ErrSQLNoRowsType,
 		})
+	} else if tracerr.Unwrap(err) == historydb.ErrUnknownFromItem {
+		c.JSON(http.StatusBadRequest, apiErrorResponse{
+			Message: errMsg,
+			Code:    ErrParamValidationFailedCode,
+			Type:    ErrParamValidationFailedType,
+		})
 	} else {
 		c.JSON(http.StatusInternalServerError, errorMsg{
 			Message: errMsg,
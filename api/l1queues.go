@@ -0,0 +1,110 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/chainbing/node/db/historydb"
+)
+
+// ethSecondsPerBlock is the average time between ethereum blocks used to
+// estimate when a block will be mined
+const ethSecondsPerBlock = 15
+
+// l1Queue is a queue of L1 user txs, which are forged together in the same L1
+// batch.  The frozen queue is the next one to be forged, and the txs sent to
+// the smart contract are added to the ones after it.
+type l1Queue struct {
+	ToForgeL1TxsNum     int64             `json:"toForgeL1TxsNum"`
+	Frozen              bool              `json:"frozen"`
+	EstimatedForgeBlock int64             `json:"estimatedForgeBlock"`
+	EstimatedForgeTime  time.Time         `json:"estimatedForgeTime"`
+	Transactions        []historydb.TxAPI `json:"transactions"`
+}
+
+func (a *API) getL1Queues(c *gin.Context) {
+	state, err := a.h.GetStateAPI()
+	if err != nil {
+		retSQLErr(err, c)
+		return
+	}
+	queues, err := a.h.GetL1QueuesAPI()
+	if err != nil {
+		retSQLErr(err, c)
+		return
+	}
+	// Until the first L1 batch is forged the timeout counts from now
+	lastL1BatchBlock := state.Network.LastEthBlock
+	if queues.LastL1BatchBlock != nil {
+		lastL1BatchBlock = *queues.LastL1BatchBlock
+	}
+	frozenL1TxsNum := int64(0)
+	if queues.LastL1TxsNum != nil {
+		frozenL1TxsNum = *queues.LastL1TxsNum + 1
+	}
+
+	now := time.Now()
+	l1Queues := []l1Queue{}
+	for _, tx := range queues.Txs {
+		if tx.ToForgeL1TxsNum == nil {
+			continue
+		}
+		toForgeL1TxsNum := *tx.ToForgeL1TxsNum
+		if len(l1Queues) == 0 || l1Queues[len(l1Queues)-1].ToForgeL1TxsNum != toForgeL1TxsNum {
+			forgeBlock, timeToForge := estimateL1QueueForge(
+				toForgeL1TxsNum-frozenL1TxsNum, state.Network.LastEthBlock, lastL1BatchBlock,
+				state.Rollup.ForgeL1L2BatchTimeout, state.Metrics.EstimatedTimeToForgeL1,
+			)
+			l1Queues = append(l1Queues, l1Queue{
+				ToForgeL1TxsNum:     toForgeL1TxsNum,
+				Frozen:              toForgeL1TxsNum == frozenL1TxsNum,
+				EstimatedForgeBlock: forgeBlock,
+				EstimatedForgeTime:  now.Add(timeToForge),
+				Transactions:        []historydb.TxAPI{},
+			})
+		}
+		queue := &l1Queues[len(l1Queues)-1]
+		queue.Transactions = append(queue.Transactions, tx)
+	}
+
+	// Build successful response
+	type l1QueuesResponse struct {
+		LastL1BatchBlock       int64     `json:"lastL1BatchBlock"`
+		ForgeL1L2BatchTimeout  int64     `json:"forgeL1L2BatchTimeout"`
+		EstimatedTimeToForgeL1 float64   `json:"estimatedTimeToForgeL1"`
+		Queues                 []l1Queue `json:"queues"`
+	}
+	c.JSON(http.StatusOK, &l1QueuesResponse{
+		LastL1BatchBlock:       lastL1BatchBlock,
+		ForgeL1L2BatchTimeout:  state.Rollup.ForgeL1L2BatchTimeout,
+		EstimatedTimeToForgeL1: state.Metrics.EstimatedTimeToForgeL1,
+		Queues:                 l1Queues,
+	})
+}
+
+// estimateL1QueueForge estimates the block in which the queue that will be
+// forged after the given number of L1 batches is forged, and how long it will
+// take.  Each L1 batch must be forged at most forgeL1L2BatchTimeout blocks
+// after the previous one, so that is the deadline.  When the time to forge L1
+// txs has been measured, each L1 batch is expected to take that long unless
+// the deadline is reached before.
+func estimateL1QueueForge(pendingL1Batches, lastEthBlock, lastL1BatchBlock,
+	forgeL1L2BatchTimeout int64, measuredTimeToForgeL1 float64) (int64, time.Duration) {
+	if pendingL1Batches < 0 {
+		pendingL1Batches = 0
+	}
+	deadlineBlock := lastL1BatchBlock + (pendingL1Batches+1)*forgeL1L2BatchTimeout
+	if deadlineBlock < lastEthBlock {
+		deadlineBlock = lastEthBlock
+	}
+	deadline := time.Duration(deadlineBlock-lastEthBlock) * ethSecondsPerBlock * time.Second
+	if measuredTimeToForgeL1 <= 0 {
+		return deadlineBlock, deadline
+	}
+	measured := time.Duration(float64(pendingL1Batches+1) * measuredTimeToForgeL1 * float64(time.Second))
+	if measured >= deadline {
+		return deadlineBlock, deadline
+	}
+	return lastEthBlock + int64(measured/(ethSecondsPerBlock*time.Second)), measured
+}
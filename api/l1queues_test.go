@@ -0,0 +1,30 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateL1QueueForge(t *testing.T) {
+	// Without measurements the frozen queue is forged at the deadline
+	block, timeToForge := estimateL1QueueForge(0, 110, 100, 20, 0)
+	assert.Equal(t, int64(120), block)
+	assert.Equal(t, 10*ethSecondsPerBlock*time.Second, timeToForge)
+	// The next queue needs one more L1 batch
+	block, timeToForge = estimateL1QueueForge(1, 110, 100, 20, 0)
+	assert.Equal(t, int64(140), block)
+	assert.Equal(t, 30*ethSecondsPerBlock*time.Second, timeToForge)
+	// The measured time is used when it's before the deadline
+	block, timeToForge = estimateL1QueueForge(0, 110, 100, 20, 60)
+	assert.Equal(t, int64(114), block)
+	assert.Equal(t, time.Minute, timeToForge)
+	block, timeToForge = estimateL1QueueForge(1, 110, 100, 20, 60)
+	assert.Equal(t, int64(118), block)
+	assert.Equal(t, 2*time.Minute, timeToForge)
+	// An overdue L1 batch is expected as soon as possible
+	block, timeToForge = estimateL1QueueForge(0, 150, 100, 20, 600)
+	assert.Equal(t, int64(150), block)
+	assert.Equal(t, time.Duration(0), timeToForge)
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/gin-gonic/gin"
+	"github.com/chainbing/node/log"
+	"github.com/chainbing/node/metric"
+	"github.com/chainbing/tracerr"
+)
+
+// OpenAPIValidationConfig is the configuration of the validation of the API
+// traffic against the OpenAPI spec
+type OpenAPIValidationConfig struct {
+	// SpecPath is the path of the OpenAPI spec (api/swagger.yml)
+	SpecPath string
+	// ValidateResponses enables the validation of the JSON responses,
+	// logging the ones that don't match the spec, and the reporting of
+	// the routes that are not in the spec.  The responses are buffered,
+	// so it's intended for debugging.
+	ValidateResponses bool
+}
+
+// errOpenAPIResponseMismatch is collected as an error metric for every
+// response that doesn't match the OpenAPI spec
+var errOpenAPIResponseMismatch = errors.New("response doesn't match the OpenAPI spec")
+
+// errOpenAPIRouteNotFound is collected as an error metric for every request,
+// when the responses are validated, to a route that is not in the OpenAPI spec
+var errOpenAPIRouteNotFound = errors.New("route not found in the OpenAPI spec")
+
+// openAPIValidator validates the requests, and optionally the responses,
+// against the OpenAPI spec.  The requests to paths that are not in the spec
+// are not validated, but they are reported when the responses are validated,
+// so that the drift between the routes and the spec is noticed.
+type openAPIValidator struct {
+	router            *openapi3filter.Router
+	validateResponses bool
+	// unmatchedRoutes are the routes not found in the spec that have
+	// already been logged
+	unmatchedRoutes sync.Map
+}
+
+func newOpenAPIValidator(cfg OpenAPIValidationConfig) (*openAPIValidator, error) {
+	spec, err := openapi3.NewSwaggerLoader().LoadSwaggerFromFile(cfg.SpecPath)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	// The servers of the spec are absolute URLs, but the server only knows
+	// the path of the requests, which is matched after removing the /v1
+	// prefix
+	spec.Servers = nil
+	router := openapi3filter.NewRouter()
+	if err := router.AddSwagger(spec); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	return &openAPIValidator{
+		router:            router,
+		validateResponses: cfg.ValidateResponses,
+	}, nil
+}
+
+func (v *openAPIValidator) middleware(c *gin.Context) {
+	specURL := *c.Request.URL
+	specURL.Path = strings.TrimPrefix(specURL.Path, "/v1")
+	route, pathParams, err := v.router.FindRoute(c.Request.Method, &specURL)
+	if err != nil {
+		if v.validateResponses {
+			v.reportUnmatchedRoute(c)
+		}
+		return
+	}
+	input := &openapi3filter.RequestValidationInput{
+		Request:    c.Request,
+		PathParams: pathParams,
+		Route:      route,
+	}
+	// The body of the request is restored after the validation, so that
+	// it can be read by the handler
+	if err := openapi3filter.ValidateRequest(c.Request.Context(), input); err != nil {
+		retBadReq(&apiError{
+			Err:  err,
+			Code: ErrParamValidationFailedCode,
+			Type: ErrParamValidationFailedType,
+		}, c)
+		c.Abort()
+		return
+	}
+	if !v.validateResponses {
+		return
+	}
+
+	writer := newRecordedResponseWriter(c.Writer, false)
+	c.Writer = writer
+	c.Next()
+	// Only the JSON responses are validated
+	if !writer.recording {
+		return
+	}
+	resInput := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 writer.Status(),
+		Header:                 writer.Header(),
+	}
+	resInput = resInput.SetBodyBytes(writer.body.Bytes())
+	if err := openapi3filter.ValidateResponse(c.Request.Context(), resInput); err != nil {
+		log.Warnw("HTTP API response doesn't match the OpenAPI spec",
+			"method", c.Request.Method, "path", route.Path, "status", writer.Status(), "err", err)
+		metric.CollectError(errOpenAPIResponseMismatch)
+	}
+}
+
+// reportUnmatchedRoute collects the error metric of a request to a route that
+// is not in the spec, and logs the route the first time it's requested
+func (v *openAPIValidator) reportUnmatchedRoute(c *gin.Context) {
+	metric.CollectError(errOpenAPIRouteNotFound)
+	route := c.Request.Method + " " + c.FullPath()
+	if _, logged := v.unmatchedRoutes.LoadOrStore(route, struct{}{}); !logged {
+		log.Warnw("HTTP API route is not in the OpenAPI spec", "route", route)
+	}
+}
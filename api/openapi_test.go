@@ -0,0 +1,125 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testOpenAPISpec = `
+openapi: 3.0.0
+info:
+  title: test
+  version: "1"
+servers:
+  - url: http://localhost:4010/v1
+paths:
+  /tokens/{id}:
+    get:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: integer
+      responses:
+        '200':
+          description: The token
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [id]
+                properties:
+                  id:
+                    type: integer
+`
+
+func TestOpenAPIValidator(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tmpspec")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+	specPath := path.Join(dir, "swagger.yml")
+	require.NoError(t, ioutil.WriteFile(specPath, []byte(testOpenAPISpec), 0600))
+
+	validator, err := newOpenAPIValidator(OpenAPIValidationConfig{
+		SpecPath:          specPath,
+		ValidateResponses: true,
+	})
+	require.NoError(t, err)
+	server := gin.New()
+	v1 := server.Group("/v1", validator.middleware)
+	v1.GET("/tokens/:id", func(c *gin.Context) {
+		// Responses that don't match the spec are only logged
+		if c.Param("id") == "2" {
+			c.JSON(http.StatusOK, gin.H{"id": "two"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"id": 1})
+	})
+	v1.GET("/unspecified", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	doReq := func(path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		server.ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+		return res
+	}
+
+	assert.Equal(t, http.StatusOK, doReq("/v1/tokens/1").Code)
+	assert.Equal(t, http.StatusOK, doReq("/v1/tokens/2").Code)
+	assert.Equal(t, http.StatusOK, doReq("/v1/unspecified").Code)
+	// The routes that are not in the spec are reported
+	_, reported := validator.unmatchedRoutes.Load("GET /v1/unspecified")
+	assert.True(t, reported)
+	_, reported = validator.unmatchedRoutes.Load("GET /v1/tokens/:id")
+	assert.False(t, reported)
+	// Requests that don't match the spec are rejected
+	res := doReq("/v1/tokens/one")
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	var apiErr apiErrorResponse
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &apiErr))
+	assert.Equal(t, ErrParamValidationFailedCode, apiErr.Code)
+	assert.Equal(t, ErrParamValidationFailedType, apiErr.Type)
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	// The spec loads and has the routes of the server
+	validator, err := newOpenAPIValidator(OpenAPIValidationConfig{SpecPath: "./swagger.yml"})
+	require.NoError(t, err)
+	txID := "0x02" + strings.Repeat("00", 32)
+	for _, route := range []struct{ method, path string }{
+		{http.MethodGet, "/stream"},
+		{http.MethodGet, "/wallets/cb:0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf"},
+		{http.MethodGet, "/accounts/cb:ETH:256/history"},
+		{http.MethodGet, "/accounts/cb:ETH:256/proof"},
+		{http.MethodGet, "/transactions/" + txID},
+		{http.MethodGet, "/transactions-history/export"},
+		{http.MethodPost, "/transactions-pool/simulate"},
+		{http.MethodPost, "/transactions-pool/replace"},
+		{http.MethodDelete, "/transactions-pool/" + txID},
+		{http.MethodGet, "/batches/1/data-availability"},
+		{http.MethodGet, "/l1-queues"},
+		{http.MethodGet, "/tokens/1/prices"},
+		{http.MethodGet, "/fees/quote"},
+		{http.MethodPost, "/webhooks"},
+		{http.MethodDelete, "/webhooks/1"},
+		{http.MethodGet, "/webhooks/1/deliveries"},
+		{http.MethodGet, "/admin/server-proofs"},
+		{http.MethodPost, "/admin/server-proofs"},
+		{http.MethodDelete, "/admin/server-proofs"},
+	} {
+		_, _, err := validator.router.FindRoute(route.method, &url.URL{Path: route.path})
+		assert.NoError(t, err, "%s %s", route.method, route.path)
+	}
+}
@@ -87,3 +87,75 @@ func ParseAccountsFilters(c *gin.Context, v *validator.Validate) (historydb.GetA
 		Limit:    accountsFilter.Limit,
 	}, nil
 }
+
+// AccountProofFilters for parsing /accounts/{accountIndex}/proof query params to struct
+type AccountProofFilters struct {
+	BatchNum *uint `form:"batchNum"`
+}
+
+// ParseAccountProofFilters parses /accounts/{accountIndex}/proof request to
+// the account index and the optional batch num
+func ParseAccountProofFilters(c *gin.Context) (common.QueryAccount, *common.BatchNum, error) {
+	account, err := ParseAccountFilter(c)
+	if err != nil {
+		return common.QueryAccount{}, nil, tracerr.Wrap(err)
+	}
+	var accountProofFilters AccountProofFilters
+	if err := c.ShouldBindQuery(&accountProofFilters); err != nil {
+		return common.QueryAccount{}, nil, tracerr.Wrap(err)
+	}
+	var batchNum *common.BatchNum
+	if accountProofFilters.BatchNum != nil {
+		batchNum = new(common.BatchNum)
+		*batchNum = common.BatchNum(*accountProofFilters.BatchNum)
+	}
+	return account, batchNum, nil
+}
+
+// AccountHistoryFilters for parsing /accounts/{accountIndex}/history query
+// params to struct
+type AccountHistoryFilters struct {
+	MinBatchNum   *uint  `form:"minBatchNum"`
+	MaxBatchNum   *uint  `form:"maxBatchNum"`
+	FromTimestamp string `form:"fromTimestamp"`
+	ToTimestamp   string `form:"toTimestamp"`
+	Resolution    string `form:"resolution" binding:"omitempty,oneof=change day"`
+
+	Pagination
+}
+
+// ParseAccountHistoryFilters parses /accounts/{accountIndex}/history request
+// to the account index and the GetAccountHistoryAPIRequest
+func ParseAccountHistoryFilters(c *gin.Context) (common.QueryAccount, historydb.GetAccountHistoryAPIRequest, error) {
+	account, err := ParseAccountFilter(c)
+	if err != nil {
+		return common.QueryAccount{}, historydb.GetAccountHistoryAPIRequest{}, tracerr.Wrap(err)
+	}
+	var historyFilters AccountHistoryFilters
+	if err := c.ShouldBindQuery(&historyFilters); err != nil {
+		return common.QueryAccount{}, historydb.GetAccountHistoryAPIRequest{}, tracerr.Wrap(err)
+	}
+
+	request := historydb.GetAccountHistoryAPIRequest{
+		Idx:      *account.AccountIndex,
+		Daily:    historyFilters.Resolution == "day",
+		FromItem: historyFilters.FromItem,
+		Limit:    historyFilters.Limit,
+		Order:    *historyFilters.Order,
+	}
+	if historyFilters.MinBatchNum != nil {
+		minBatchNum := common.BatchNum(*historyFilters.MinBatchNum)
+		request.MinBatchNum = &minBatchNum
+	}
+	if historyFilters.MaxBatchNum != nil {
+		maxBatchNum := common.BatchNum(*historyFilters.MaxBatchNum)
+		request.MaxBatchNum = &maxBatchNum
+	}
+	if request.FromTimestamp, err = parseTimestamp(historyFilters.FromTimestamp); err != nil {
+		return common.QueryAccount{}, historydb.GetAccountHistoryAPIRequest{}, tracerr.Wrap(err)
+	}
+	if request.ToTimestamp, err = parseTimestamp(historyFilters.ToTimestamp); err != nil {
+		return common.QueryAccount{}, historydb.GetAccountHistoryAPIRequest{}, tracerr.Wrap(err)
+	}
+	return account, request, nil
+}
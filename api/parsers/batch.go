@@ -27,7 +27,9 @@ type BatchesFilters struct {
 	MaxBatchNum *uint  `form:"maxBatchNum"`
 	SlotNum     *uint  `form:"slotNum"`
 	ForgerAddr  string `form:"forgerAddr"`
+	SortBy      string `form:"sortBy" binding:"omitempty,oneof=itemId totalFeesUSD"`
 
+	RangeFilters
 	Pagination
 }
 
@@ -43,13 +45,23 @@ func ParseBatchesFilter(c *gin.Context) (historydb.GetBatchesAPIRequest, error)
 		return historydb.GetBatchesAPIRequest{}, tracerr.Wrap(err)
 	}
 
+	fromTimestamp, toTimestamp, err := batchesFilters.parseTimestamps()
+	if err != nil {
+		return historydb.GetBatchesAPIRequest{}, tracerr.Wrap(err)
+	}
+
 	return historydb.GetBatchesAPIRequest{
-		MinBatchNum: batchesFilters.MinBatchNum,
-		MaxBatchNum: batchesFilters.MaxBatchNum,
-		SlotNum:     batchesFilters.SlotNum,
-		ForgerAddr:  addr,
-		FromItem:    batchesFilters.FromItem,
-		Limit:       batchesFilters.Limit,
-		Order:       *batchesFilters.Order,
+		MinBatchNum:   batchesFilters.MinBatchNum,
+		MaxBatchNum:   batchesFilters.MaxBatchNum,
+		SlotNum:       batchesFilters.SlotNum,
+		ForgerAddr:    addr,
+		FromTimestamp: fromTimestamp,
+		ToTimestamp:   toTimestamp,
+		FromBlock:     batchesFilters.FromBlock,
+		ToBlock:       batchesFilters.ToBlock,
+		FromItem:      batchesFilters.FromItem,
+		Limit:         batchesFilters.Limit,
+		Order:         *batchesFilters.Order,
+		SortBy:        batchesFilters.SortBy,
 	}, nil
 }
@@ -12,7 +12,9 @@ import (
 type BidsFilters struct {
 	SlotNum    *int64 `form:"slotNum" binding:"omitempty,min=0"`
 	BidderAddr string `form:"bidderAddr"`
+	SortBy     string `form:"sortBy" binding:"omitempty,oneof=itemId bidValue"`
 
+	RangeFilters
 	Pagination
 }
 
@@ -42,11 +44,21 @@ func ParseBidsFilters(c *gin.Context, v *validator.Validate) (historydb.GetBidsA
 		return historydb.GetBidsAPIRequest{}, tracerr.Wrap(err)
 	}
 
+	fromTimestamp, toTimestamp, err := bidsFilters.parseTimestamps()
+	if err != nil {
+		return historydb.GetBidsAPIRequest{}, tracerr.Wrap(err)
+	}
+
 	return historydb.GetBidsAPIRequest{
-		SlotNum:    bidsFilters.SlotNum,
-		BidderAddr: bidderAddress,
-		FromItem:   bidsFilters.FromItem,
-		Order:      *bidsFilters.Order,
-		Limit:      bidsFilters.Limit,
+		SlotNum:       bidsFilters.SlotNum,
+		BidderAddr:    bidderAddress,
+		FromTimestamp: fromTimestamp,
+		ToTimestamp:   toTimestamp,
+		FromBlock:     bidsFilters.FromBlock,
+		ToBlock:       bidsFilters.ToBlock,
+		FromItem:      bidsFilters.FromItem,
+		Order:         *bidsFilters.Order,
+		Limit:         bidsFilters.Limit,
+		SortBy:        bidsFilters.SortBy,
 	}, nil
 }
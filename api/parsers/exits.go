@@ -37,7 +37,9 @@ type ExitsFilters struct {
 	AccountIndex         string `form:"accountIndex"`
 	BatchNum             *uint  `form:"batchNum"`
 	OnlyPendingWithdraws *bool  `form:"onlyPendingWithdraws"`
+	SortBy               string `form:"sortBy" binding:"omitempty,oneof=itemId balance"`
 
+	RangeFilters
 	Pagination
 }
 
@@ -90,6 +92,11 @@ func ParseExitsFilters(c *gin.Context, v *validator.Validate) (historydb.GetExit
 		return historydb.GetExitsAPIRequest{}, tracerr.Wrap(err)
 	}
 
+	fromTimestamp, toTimestamp, err := exitsFilters.parseTimestamps()
+	if err != nil {
+		return historydb.GetExitsAPIRequest{}, tracerr.Wrap(err)
+	}
+
 	return historydb.GetExitsAPIRequest{
 		EthAddr:              addr,
 		Bjj:                  bjj,
@@ -97,8 +104,13 @@ func ParseExitsFilters(c *gin.Context, v *validator.Validate) (historydb.GetExit
 		Idx:                  queryAccount.AccountIndex,
 		BatchNum:             exitsFilters.BatchNum,
 		OnlyPendingWithdraws: exitsFilters.OnlyPendingWithdraws,
+		FromTimestamp:        fromTimestamp,
+		ToTimestamp:          toTimestamp,
+		FromBlock:            exitsFilters.FromBlock,
+		ToBlock:              exitsFilters.ToBlock,
 		FromItem:             exitsFilters.FromItem,
 		Limit:                exitsFilters.Limit,
 		Order:                *exitsFilters.Order,
+		SortBy:               exitsFilters.SortBy,
 	}, nil
 }
@@ -0,0 +1,55 @@
+package parsers
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/gin-gonic/gin"
+	"github.com/chainbing/node/common"
+	"github.com/chainbing/tracerr"
+)
+
+// Types of receivers of a tx, which determine its recommended fee
+const (
+	// FeeQuoteToExistingAccount is a tx to an existing account
+	FeeQuoteToExistingAccount = "existingAccount"
+	// FeeQuoteToCreateAccount is a tx that creates an account for an
+	// Ethereum address
+	FeeQuoteToCreateAccount = "createAccount"
+	// FeeQuoteToCreateAccountInternal is a tx that creates an internal
+	// account
+	FeeQuoteToCreateAccountInternal = "createAccountInternal"
+)
+
+// FeeQuoteToTypes are all the types of receivers of a tx, in the order in
+// which they are quoted
+var FeeQuoteToTypes = []string{
+	FeeQuoteToExistingAccount,
+	FeeQuoteToCreateAccount,
+	FeeQuoteToCreateAccountInternal,
+}
+
+// FeeQuoteFilters for parsing /fees/quote query params
+type FeeQuoteFilters struct {
+	TokenID *uint  `form:"tokenId" binding:"required"`
+	Amount  string `form:"amount" binding:"required"`
+	ToType  string `form:"toType" binding:"omitempty,oneof=existingAccount createAccount createAccountInternal"`
+}
+
+// ParseFeeQuoteFilters parses the token and amount of the tx to quote, and the
+// types of receivers to quote it for (all of them if none is given)
+func ParseFeeQuoteFilters(c *gin.Context) (common.TokenID, *big.Int, []string, error) {
+	var filters FeeQuoteFilters
+	if err := c.ShouldBindQuery(&filters); err != nil {
+		return 0, nil, nil, tracerr.Wrap(err)
+	}
+	amount, ok := new(big.Int).SetString(filters.Amount, 10)
+	if !ok || amount.Sign() <= 0 {
+		return 0, nil, nil, tracerr.Wrap(fmt.Errorf("invalid amount"))
+	}
+	toTypes := FeeQuoteToTypes
+	if filters.ToType != "" {
+		toTypes = []string{filters.ToType}
+	}
+	return common.TokenID(*filters.TokenID), amount, toTypes, nil
+}
@@ -0,0 +1,42 @@
+package parsers
+
+import (
+	"time"
+
+	"github.com/chainbing/tracerr"
+)
+
+// RangeFilters struct for holding the time and block range of the lists.
+// fromTimestamp is inclusive and toTimestamp exclusive, while fromBlock and
+// toBlock are inclusive.
+type RangeFilters struct {
+	FromTimestamp string `form:"fromTimestamp"`
+	ToTimestamp   string `form:"toTimestamp"`
+	FromBlock     *int64 `form:"fromBlock" binding:"omitempty,min=0"`
+	ToBlock       *int64 `form:"toBlock" binding:"omitempty,min=0"`
+}
+
+// parseTimestamps parses the timestamp range
+func (rf *RangeFilters) parseTimestamps() (*time.Time, *time.Time, error) {
+	fromTimestamp, err := parseTimestamp(rf.FromTimestamp)
+	if err != nil {
+		return nil, nil, tracerr.Wrap(err)
+	}
+	toTimestamp, err := parseTimestamp(rf.ToTimestamp)
+	if err != nil {
+		return nil, nil, tracerr.Wrap(err)
+	}
+	return fromTimestamp, toTimestamp, nil
+}
+
+// parseTimestamp parses an optional RFC3339 timestamp
+func parseTimestamp(timestamp string) (*time.Time, error) {
+	if timestamp == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	return &t, nil
+}
@@ -0,0 +1,51 @@
+package parsers
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/chainbing/tracerr"
+)
+
+// ServerProofBody is the body of the request to add a server proof
+type ServerProofBody struct {
+	URL string `json:"url" binding:"required"`
+}
+
+// ServerProofFilter is the query of the request to remove a server proof
+type ServerProofFilter struct {
+	URL string `form:"url" binding:"required"`
+}
+
+// ParseServerProof parses the body of the request to add a server proof and
+// returns its URL
+func ParseServerProof(c *gin.Context) (string, error) {
+	var body ServerProofBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		return "", tracerr.Wrap(err)
+	}
+	return checkServerProofURL(body.URL)
+}
+
+// ParseServerProofFilter parses the query of the request to remove a server
+// proof and returns its URL
+func ParseServerProofFilter(c *gin.Context) (string, error) {
+	var filter ServerProofFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		return "", tracerr.Wrap(err)
+	}
+	return checkServerProofURL(filter.URL)
+}
+
+func checkServerProofURL(rawURL string) (string, error) {
+	serverProofURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "", tracerr.Wrap(err)
+	}
+	if (serverProofURL.Scheme != "http" && serverProofURL.Scheme != "https") ||
+		serverProofURL.Host == "" {
+		return "", tracerr.Wrap(fmt.Errorf("invalid url %q, must be an http(s) URL", rawURL))
+	}
+	return rawURL, nil
+}
@@ -0,0 +1,118 @@
+package parsers
+
+import (
+	"fmt"
+	"strings"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/chainbing/node/common"
+	"github.com/chainbing/tracerr"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+const (
+	// StreamTopicBatches is the topic of the newly synced batches
+	StreamTopicBatches = "batches"
+	// StreamTopicPoolTxs is the topic of the pool txs that are inserted or
+	// change their state
+	StreamTopicPoolTxs = "poolTxs"
+	// StreamTopicBids is the topic of the newly synced bids
+	StreamTopicBids = "bids"
+	// StreamTopicSlots is the topic of the slot changes
+	StreamTopicSlots = "slots"
+)
+
+// StreamTopics contains all the topics that can be subscribed to
+var StreamTopics = []string{StreamTopicBatches, StreamTopicPoolTxs, StreamTopicBids, StreamTopicSlots}
+
+// StreamFilters struct for holding the /stream query params
+type StreamFilters struct {
+	Topics       string `form:"topics"`
+	AccountIndex string `form:"accountIndex"`
+	Addr         string `form:"cbEthereumAddress"`
+	Bjj          string `form:"BJJ"`
+}
+
+// StreamSubscription contains the topics and the account filters of a stream
+// subscription.  The account filters only apply to the poolTxs topic.
+type StreamSubscription struct {
+	Topics  map[string]bool
+	Idx     *common.Idx
+	EthAddr *ethCommon.Address
+	Bjj     *babyjub.PublicKeyComp
+}
+
+// StreamFiltersStructValidation validates StreamFilters
+func StreamFiltersStructValidation(sl validator.StructLevel) {
+	ef := sl.Current().Interface().(StreamFilters)
+
+	if ef.Addr != "" && ef.Bjj != "" {
+		sl.ReportError(ef.Addr, "cbEthereumAddress", "Addr", "cbethaddrorbjj", "")
+		sl.ReportError(ef.Bjj, "BJJ", "Bjj", "cbethaddrorbjj", "")
+	}
+
+	if ef.AccountIndex != "" && (ef.Addr != "" || ef.Bjj != "") {
+		sl.ReportError(ef.AccountIndex, "accountIndex", "AccountIndex", "onlyaccountindex", "")
+		sl.ReportError(ef.Addr, "cbEthereumAddress", "Addr", "onlyaccountindex", "")
+		sl.ReportError(ef.Bjj, "BJJ", "Bjj", "onlyaccountindex", "")
+	}
+}
+
+// ParseStreamFilters parses the /stream query params to a StreamSubscription.
+// If no topics are specified, all of them are subscribed.
+func ParseStreamFilters(c *gin.Context, v *validator.Validate) (StreamSubscription, error) {
+	var streamFilters StreamFilters
+	if err := c.ShouldBindQuery(&streamFilters); err != nil {
+		return StreamSubscription{}, tracerr.Wrap(err)
+	}
+
+	if err := v.Struct(streamFilters); err != nil {
+		return StreamSubscription{}, tracerr.Wrap(err)
+	}
+
+	topics := make(map[string]bool)
+	if streamFilters.Topics == "" {
+		for _, topic := range StreamTopics {
+			topics[topic] = true
+		}
+	} else {
+		for _, topic := range strings.Split(streamFilters.Topics, ",") {
+			valid := false
+			for _, validTopic := range StreamTopics {
+				if topic == validTopic {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				return StreamSubscription{}, tracerr.Wrap(
+					fmt.Errorf("invalid topic %q, must be one of %v", topic, StreamTopics))
+			}
+			topics[topic] = true
+		}
+	}
+
+	queryAccount, err := common.StringToIdx(streamFilters.AccountIndex, "accountIndex")
+	if err != nil {
+		return StreamSubscription{}, tracerr.Wrap(err)
+	}
+
+	addr, err := common.CbStringToEthAddr(streamFilters.Addr, "cbEthereumAddress")
+	if err != nil {
+		return StreamSubscription{}, tracerr.Wrap(err)
+	}
+
+	bjj, err := common.CbStringToBJJ(streamFilters.Bjj, "BJJ")
+	if err != nil {
+		return StreamSubscription{}, tracerr.Wrap(err)
+	}
+
+	return StreamSubscription{
+		Topics:  topics,
+		Idx:     queryAccount.AccountIndex,
+		EthAddr: addr,
+		Bjj:     bjj,
+	}, nil
+}
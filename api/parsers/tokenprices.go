@@ -0,0 +1,91 @@
+package parsers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/chainbing/node/common"
+	"github.com/chainbing/node/db/historydb"
+	"github.com/chainbing/tracerr"
+)
+
+const (
+	// tokenPricesDefaultRange is the time range of the prices when no
+	// fromTimestamp is given
+	tokenPricesDefaultRange = 24 * time.Hour
+	// tokenPricesDefaultInterval is the interval of the prices when none
+	// is given
+	tokenPricesDefaultInterval = time.Hour
+	// tokenPricesMinInterval is the minimum interval of the prices
+	tokenPricesMinInterval = time.Minute
+	// tokenPricesMaxIntervals is the maximum number of intervals in the
+	// time range, which is the maximum number of prices returned
+	tokenPricesMaxIntervals = 1000
+)
+
+// TokenPricesFilter struct to hold the token id from the request
+// /tokens/:id/prices
+type TokenPricesFilter struct {
+	TokenID *uint `uri:"id" binding:"required"`
+}
+
+// TokenPricesFilters struct to hold the query params of /tokens/:id/prices.
+// The interval is a duration like 5m, 1h or 24h.
+type TokenPricesFilters struct {
+	FromTimestamp string `form:"fromTimestamp"`
+	ToTimestamp   string `form:"toTimestamp"`
+	Interval      string `form:"interval"`
+}
+
+// ParseTokenPricesFilters func parsing the request /tokens/:id/prices to the
+// GetTokenPricesAPIRequest.  By default the prices are the ones of the last
+// 24 hours, every hour.
+func ParseTokenPricesFilters(c *gin.Context, now time.Time) (historydb.GetTokenPricesAPIRequest, error) {
+	var tokenFilter TokenPricesFilter
+	if err := c.ShouldBindUri(&tokenFilter); err != nil {
+		return historydb.GetTokenPricesAPIRequest{}, tracerr.Wrap(err)
+	}
+	var filters TokenPricesFilters
+	if err := c.ShouldBindQuery(&filters); err != nil {
+		return historydb.GetTokenPricesAPIRequest{}, tracerr.Wrap(err)
+	}
+	request := historydb.GetTokenPricesAPIRequest{
+		TokenID:     common.TokenID(*tokenFilter.TokenID),
+		ToTimestamp: now,
+		Interval:    tokenPricesDefaultInterval,
+	}
+	toTimestamp, err := parseTimestamp(filters.ToTimestamp)
+	if err != nil {
+		return historydb.GetTokenPricesAPIRequest{}, tracerr.Wrap(err)
+	}
+	if toTimestamp != nil {
+		request.ToTimestamp = *toTimestamp
+	}
+	request.FromTimestamp = request.ToTimestamp.Add(-tokenPricesDefaultRange)
+	fromTimestamp, err := parseTimestamp(filters.FromTimestamp)
+	if err != nil {
+		return historydb.GetTokenPricesAPIRequest{}, tracerr.Wrap(err)
+	}
+	if fromTimestamp != nil {
+		request.FromTimestamp = *fromTimestamp
+	}
+	if !request.FromTimestamp.Before(request.ToTimestamp) {
+		return historydb.GetTokenPricesAPIRequest{}, tracerr.Wrap(
+			fmt.Errorf("fromTimestamp must be before toTimestamp"))
+	}
+	if filters.Interval != "" {
+		if request.Interval, err = time.ParseDuration(filters.Interval); err != nil {
+			return historydb.GetTokenPricesAPIRequest{}, tracerr.Wrap(err)
+		}
+		if request.Interval < tokenPricesMinInterval {
+			return historydb.GetTokenPricesAPIRequest{}, tracerr.Wrap(
+				fmt.Errorf("interval must be at least %v", tokenPricesMinInterval))
+		}
+	}
+	if request.ToTimestamp.Sub(request.FromTimestamp)/request.Interval > tokenPricesMaxIntervals {
+		return historydb.GetTokenPricesAPIRequest{}, tracerr.Wrap(
+			fmt.Errorf("the time range can't have more than %v intervals", tokenPricesMaxIntervals))
+	}
+	return request, nil
+}
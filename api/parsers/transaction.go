@@ -0,0 +1,54 @@
+package parsers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/chainbing/node/common"
+	"github.com/chainbing/tracerr"
+)
+
+// TxWaitForForged is the waitFor value used to wait until the tx is forged
+const TxWaitForForged = "forged"
+
+// TransactionFilter struct for filtering the tx to look up in the pool and the
+// history
+type TransactionFilter struct {
+	TxID string `uri:"id" binding:"required"`
+}
+
+// TransactionWaitFilters for parsing the long-polling params of the
+// /transactions/{id} request
+type TransactionWaitFilters struct {
+	WaitFor string `form:"waitFor" binding:"omitempty,oneof=forged"`
+	Timeout string `form:"timeout"`
+}
+
+// ParseTransaction parses the TxID of the tx to look up, the state to wait for
+// (if any) and the maximum time to wait for it
+func ParseTransaction(c *gin.Context) (common.TxID, string, time.Duration, error) {
+	var filter TransactionFilter
+	if err := c.ShouldBindUri(&filter); err != nil {
+		return common.TxID{}, "", 0, tracerr.Wrap(err)
+	}
+	txID, err := common.NewTxIDFromString(filter.TxID)
+	if err != nil {
+		return common.TxID{}, "", 0, tracerr.Wrap(fmt.Errorf("invalid id"))
+	}
+	var waitFilters TransactionWaitFilters
+	if err := c.ShouldBindQuery(&waitFilters); err != nil {
+		return common.TxID{}, "", 0, tracerr.Wrap(err)
+	}
+	var timeout time.Duration
+	if waitFilters.Timeout != "" {
+		if waitFilters.WaitFor == "" {
+			return common.TxID{}, "", 0, tracerr.Wrap(fmt.Errorf("timeout requires waitFor"))
+		}
+		timeout, err = time.ParseDuration(waitFilters.Timeout)
+		if err != nil || timeout <= 0 {
+			return common.TxID{}, "", 0, tracerr.Wrap(fmt.Errorf("invalid timeout"))
+		}
+	}
+	return txID, waitFilters.WaitFor, timeout, nil
+}
@@ -0,0 +1,120 @@
+package parsers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/chainbing/node/common"
+	"github.com/chainbing/node/db/historydb"
+	"github.com/chainbing/tracerr"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// ExportTxsFilters struct for holding the filters of the txs export.  The
+// filters are the same as the ones of the txs history, without pagination.
+type ExportTxsFilters struct {
+	TokenID           *uint  `form:"tokenId"`
+	Addr              string `form:"cbEthereumAddress"`
+	FromAddr          string `form:"fromCbEthereumAddress"`
+	ToAddr            string `form:"toCbEthereumAddress"`
+	Bjj               string `form:"BJJ"`
+	FromBjj           string `form:"fromBJJ"`
+	ToBjj             string `form:"toBJJ"`
+	AccountIndex      string `form:"accountIndex"`
+	FromIdx           string `form:"fromAccountIndex"`
+	ToIdx             string `form:"toAccountIndex"`
+	BatchNum          *uint  `form:"batchNum"`
+	TxType            string `form:"type"`
+	IncludePendingL1s *bool  `form:"includePendingL1s"`
+	Format            string `form:"format" binding:"omitempty,oneof=csv jsonl"`
+	Order             string `form:"order" binding:"omitempty,oneof=ASC DESC"`
+	SortBy            string `form:"sortBy" binding:"omitempty,oneof=itemId amount amountUSD feeUSD"`
+
+	RangeFilters
+}
+
+// ExportTxsFiltersStructValidation func validates ExportTxsFilters
+func ExportTxsFiltersStructValidation(sl validator.StructLevel) {
+	ef := sl.Current().Interface().(ExportTxsFilters)
+
+	if (ef.Addr != "" || ef.FromAddr != "" || ef.ToAddr != "") &&
+		(ef.Bjj != "" || ef.FromBjj != "" || ef.ToBjj != "") {
+		sl.ReportError(ef.Addr, "cbEthereumAddress", "Addr", "cbethaddrorbjj", "")
+		sl.ReportError(ef.Bjj, "BJJ", "Bjj", "cbethaddrorbjj", "")
+	}
+
+	if ef.AccountIndex != "" && (ef.FromIdx != "" || ef.ToIdx != "") {
+		sl.ReportError(ef.AccountIndex, "accountIndex", "AccountIndex", "accountindexorfromto", "")
+	}
+}
+
+// ParseExportTxsFilters func parsing the filters of the txs export to the
+// GetTxsAPIRequest and the format of the export
+func ParseExportTxsFilters(c *gin.Context, v *validator.Validate) (historydb.GetTxsAPIRequest,
+	historydb.TxsExportFormat, error) {
+	var exportFilters ExportTxsFilters
+	if err := c.ShouldBindQuery(&exportFilters); err != nil {
+		return historydb.GetTxsAPIRequest{}, "", tracerr.Wrap(err)
+	}
+
+	if err := v.Struct(exportFilters); err != nil {
+		return historydb.GetTxsAPIRequest{}, "", tracerr.Wrap(err)
+	}
+
+	request := historydb.GetTxsAPIRequest{
+		BatchNum:          exportFilters.BatchNum,
+		IncludePendingL1s: exportFilters.IncludePendingL1s,
+		FromBlock:         exportFilters.FromBlock,
+		ToBlock:           exportFilters.ToBlock,
+		Order:             exportFilters.Order,
+		SortBy:            exportFilters.SortBy,
+	}
+	var err error
+	if exportFilters.TokenID != nil {
+		tokenID := common.TokenID(*exportFilters.TokenID)
+		request.TokenID = &tokenID
+	}
+	if request.EthAddr, err = common.CbStringToEthAddr(exportFilters.Addr, "cbEthereumAddress"); err != nil {
+		return historydb.GetTxsAPIRequest{}, "", tracerr.Wrap(err)
+	}
+	if request.FromEthAddr, err = common.CbStringToEthAddr(exportFilters.FromAddr, "fromCbEthereumAddress"); err != nil {
+		return historydb.GetTxsAPIRequest{}, "", tracerr.Wrap(err)
+	}
+	if request.ToEthAddr, err = common.CbStringToEthAddr(exportFilters.ToAddr, "toCbEthereumAddress"); err != nil {
+		return historydb.GetTxsAPIRequest{}, "", tracerr.Wrap(err)
+	}
+	if request.Bjj, err = common.CbStringToBJJ(exportFilters.Bjj, "BJJ"); err != nil {
+		return historydb.GetTxsAPIRequest{}, "", tracerr.Wrap(err)
+	}
+	if request.FromBjj, err = common.CbStringToBJJ(exportFilters.FromBjj, "fromBJJ"); err != nil {
+		return historydb.GetTxsAPIRequest{}, "", tracerr.Wrap(err)
+	}
+	if request.ToBjj, err = common.CbStringToBJJ(exportFilters.ToBjj, "toBJJ"); err != nil {
+		return historydb.GetTxsAPIRequest{}, "", tracerr.Wrap(err)
+	}
+	queryAccount, err := common.StringToIdx(exportFilters.AccountIndex, "accountIndex")
+	if err != nil {
+		return historydb.GetTxsAPIRequest{}, "", tracerr.Wrap(err)
+	}
+	request.Idx = queryAccount.AccountIndex
+	queryAccount, err = common.StringToIdx(exportFilters.FromIdx, "fromAccountIndex")
+	if err != nil {
+		return historydb.GetTxsAPIRequest{}, "", tracerr.Wrap(err)
+	}
+	request.FromIdx = queryAccount.AccountIndex
+	queryAccount, err = common.StringToIdx(exportFilters.ToIdx, "toAccountIndex")
+	if err != nil {
+		return historydb.GetTxsAPIRequest{}, "", tracerr.Wrap(err)
+	}
+	request.ToIdx = queryAccount.AccountIndex
+	if request.TxType, err = common.StringToTxType(exportFilters.TxType); err != nil {
+		return historydb.GetTxsAPIRequest{}, "", tracerr.Wrap(err)
+	}
+	if request.FromTimestamp, request.ToTimestamp, err = exportFilters.parseTimestamps(); err != nil {
+		return historydb.GetTxsAPIRequest{}, "", tracerr.Wrap(err)
+	}
+
+	format := historydb.TxsExportFormatCSV
+	if exportFilters.Format != "" {
+		format = historydb.TxsExportFormat(exportFilters.Format)
+	}
+	return request, format, nil
+}
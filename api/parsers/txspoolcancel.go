@@ -0,0 +1,38 @@
+package parsers
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/chainbing/node/common"
+	"github.com/chainbing/tracerr"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+)
+
+// CancelPoolTxFilter struct for filtering the tx to cancel
+type CancelPoolTxFilter struct {
+	TxID string `uri:"id" binding:"required"`
+}
+
+// CancelPoolTxBody is the body of the request to cancel a pool tx
+type CancelPoolTxBody struct {
+	Signature babyjub.SignatureComp `json:"signature" binding:"required"`
+}
+
+// ParseCancelPoolTx parses the TxID of the tx to cancel and the signature of
+// the cancel message
+func ParseCancelPoolTx(c *gin.Context) (common.TxID, babyjub.SignatureComp, error) {
+	var filter CancelPoolTxFilter
+	if err := c.ShouldBindUri(&filter); err != nil {
+		return common.TxID{}, babyjub.SignatureComp{}, tracerr.Wrap(err)
+	}
+	txID, err := common.NewTxIDFromString(filter.TxID)
+	if err != nil {
+		return common.TxID{}, babyjub.SignatureComp{}, tracerr.Wrap(fmt.Errorf("invalid id"))
+	}
+	var body CancelPoolTxBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		return common.TxID{}, babyjub.SignatureComp{}, tracerr.Wrap(err)
+	}
+	return txID, body.Signature, nil
+}
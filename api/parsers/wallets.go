@@ -0,0 +1,39 @@
+package parsers
+
+import (
+	"github.com/gin-gonic/gin"
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/chainbing/node/common"
+	"github.com/chainbing/tracerr"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+)
+
+// cbEthAddrLen is the length of a cb ethereum address: cb:0x[a-fA-F0-9]{40}
+const cbEthAddrLen = 45
+
+// WalletFilter struct to hold the cb ethereum address or BJJ from the request
+// /wallets/:id
+type WalletFilter struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+// ParseWalletFilters func parsing the request /wallets/:id to the ethereum
+// address or the BJJ of the wallet
+func ParseWalletFilters(c *gin.Context) (*ethCommon.Address, *babyjub.PublicKeyComp, error) {
+	var walletFilter WalletFilter
+	if err := c.ShouldBindUri(&walletFilter); err != nil {
+		return nil, nil, tracerr.Wrap(err)
+	}
+	if len(walletFilter.ID) == cbEthAddrLen {
+		addr, err := common.CbStringToEthAddr(walletFilter.ID, "cbEthereumAddress")
+		if err != nil {
+			return nil, nil, tracerr.Wrap(err)
+		}
+		return addr, nil, nil
+	}
+	bjj, err := common.CbStringToBJJ(walletFilter.ID, "BJJ")
+	if err != nil {
+		return nil, nil, tracerr.Wrap(err)
+	}
+	return nil, bjj, nil
+}
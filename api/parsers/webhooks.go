@@ -0,0 +1,120 @@
+package parsers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/chainbing/node/common"
+	"github.com/chainbing/node/db/l2db"
+	"github.com/chainbing/tracerr"
+)
+
+// WebhookSecretHeader is the header used to send the secret of a webhook, which
+// is required to manage it
+const WebhookSecretHeader = "X-Chainbing-Webhook-Secret"
+
+// WebhookBody is the body of the request to register a webhook
+type WebhookBody struct {
+	URL          string `json:"url" binding:"required"`
+	TokenID      *uint  `json:"tokenId"`
+	AccountIndex string `json:"accountIndex"`
+	Addr         string `json:"cbEthereumAddress"`
+	Bjj          string `json:"BJJ"`
+}
+
+// ParseWebhook parses the body of the request to register a webhook.  At least
+// one filter is required.
+func ParseWebhook(c *gin.Context) (l2db.Webhook, error) {
+	var body WebhookBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		return l2db.Webhook{}, tracerr.Wrap(err)
+	}
+	hookURL, err := url.Parse(body.URL)
+	if err != nil {
+		return l2db.Webhook{}, tracerr.Wrap(err)
+	}
+	if (hookURL.Scheme != "http" && hookURL.Scheme != "https") || hookURL.Host == "" {
+		return l2db.Webhook{}, tracerr.Wrap(fmt.Errorf("invalid url %q, must be an http(s) URL", body.URL))
+	}
+	if body.TokenID == nil && body.AccountIndex == "" && body.Addr == "" && body.Bjj == "" {
+		return l2db.Webhook{}, tracerr.Wrap(errors.New(
+			"at least one of tokenId, accountIndex, cbEthereumAddress or BJJ is required"))
+	}
+	queryAccount, err := common.StringToIdx(body.AccountIndex, "accountIndex")
+	if err != nil {
+		return l2db.Webhook{}, tracerr.Wrap(err)
+	}
+	addr, err := common.CbStringToEthAddr(body.Addr, "cbEthereumAddress")
+	if err != nil {
+		return l2db.Webhook{}, tracerr.Wrap(err)
+	}
+	bjj, err := common.CbStringToBJJ(body.Bjj, "BJJ")
+	if err != nil {
+		return l2db.Webhook{}, tracerr.Wrap(err)
+	}
+	var tokenID *common.TokenID
+	if body.TokenID != nil {
+		id := common.TokenID(*body.TokenID)
+		tokenID = &id
+	}
+	return l2db.Webhook{
+		URL:     hookURL.String(),
+		EthAddr: addr,
+		BJJ:     bjj,
+		Idx:     queryAccount.AccountIndex,
+		TokenID: tokenID,
+	}, nil
+}
+
+// WebhookFilter struct for filtering the webhook of a request
+type WebhookFilter struct {
+	ID uint64 `uri:"id" binding:"required"`
+}
+
+// ParseWebhookFilter parses the id of the webhook and its secret
+func ParseWebhookFilter(c *gin.Context) (uint64, string, error) {
+	var webhookFilter WebhookFilter
+	if err := c.ShouldBindUri(&webhookFilter); err != nil {
+		return 0, "", tracerr.Wrap(err)
+	}
+	secret := c.GetHeader(WebhookSecretHeader)
+	if secret == "" {
+		return 0, "", tracerr.Wrap(fmt.Errorf("missing %s header", WebhookSecretHeader))
+	}
+	return webhookFilter.ID, secret, nil
+}
+
+// WebhookDeliveriesFilters struct for holding the delivery log query params
+type WebhookDeliveriesFilters struct {
+	State string `form:"state" binding:"omitempty,oneof=pend done fail"`
+
+	Pagination
+}
+
+// ParseWebhookDeliveriesFilters parses the delivery log request of a webhook
+// to a GetWebhookDeliveriesAPIRequest
+func ParseWebhookDeliveriesFilters(c *gin.Context) (l2db.GetWebhookDeliveriesAPIRequest, error) {
+	webhookID, secret, err := ParseWebhookFilter(c)
+	if err != nil {
+		return l2db.GetWebhookDeliveriesAPIRequest{}, tracerr.Wrap(err)
+	}
+	var deliveriesFilters WebhookDeliveriesFilters
+	if err := c.ShouldBindQuery(&deliveriesFilters); err != nil {
+		return l2db.GetWebhookDeliveriesAPIRequest{}, tracerr.Wrap(err)
+	}
+	var state *l2db.WebhookDeliveryState
+	if deliveriesFilters.State != "" {
+		s := l2db.WebhookDeliveryState(deliveriesFilters.State)
+		state = &s
+	}
+	return l2db.GetWebhookDeliveriesAPIRequest{
+		WebhookID: webhookID,
+		Secret:    secret,
+		State:     state,
+		FromItem:  deliveriesFilters.FromItem,
+		Order:     *deliveriesFilters.Order,
+		Limit:     deliveriesFilters.Limit,
+	}, nil
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordedResponseWriter keeps a copy of the status and the body of the JSON
+// responses for the middlewares that handle them once the handler is done.
+// The responses of other types, like the streams and the exports, could be
+// too big to be buffered, so they are written through without being
+// recorded.  If hold is set, the JSON responses are not written, so that the
+// middleware can still set their headers before writing them.
+type recordedResponseWriter struct {
+	gin.ResponseWriter
+	hold   bool
+	status int
+	// decided is set on the first write of the body, when the Content-Type
+	// of the response is known
+	decided   bool
+	recording bool
+	body      bytes.Buffer
+}
+
+func newRecordedResponseWriter(w gin.ResponseWriter, hold bool) *recordedResponseWriter {
+	return &recordedResponseWriter{ResponseWriter: w, hold: hold, status: http.StatusOK}
+}
+
+// record returns true if the body of the response is recorded
+func (w *recordedResponseWriter) record() bool {
+	if !w.decided {
+		w.decided = true
+		w.recording = strings.HasPrefix(w.Header().Get("Content-Type"), gin.MIMEJSON)
+		if !w.recording && w.hold {
+			w.ResponseWriter.WriteHeader(w.status)
+		}
+	}
+	return w.recording
+}
+
+// held returns true if the response hasn't been written, which is the case
+// of the JSON responses and the empty ones when hold is set
+func (w *recordedResponseWriter) held() bool {
+	return w.hold && (!w.decided || w.recording)
+}
+
+func (w *recordedResponseWriter) WriteHeader(code int) {
+	w.status = code
+	if !w.hold {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *recordedResponseWriter) WriteHeaderNow() {
+	if w.record() && w.hold {
+		return
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *recordedResponseWriter) Write(b []byte) (int, error) {
+	if !w.record() {
+		return w.ResponseWriter.Write(b)
+	}
+	w.body.Write(b)
+	if w.hold {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordedResponseWriter) WriteString(s string) (int, error) {
+	if !w.record() {
+		return w.ResponseWriter.WriteString(s)
+	}
+	w.body.WriteString(s)
+	if w.hold {
+		return len(s), nil
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *recordedResponseWriter) Flush() {
+	if w.record() && w.hold {
+		return
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *recordedResponseWriter) Status() int {
+	if w.held() {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *recordedResponseWriter) Size() int {
+	if w.held() {
+		return w.body.Len()
+	}
+	return w.ResponseWriter.Size()
+}
+
+func (w *recordedResponseWriter) Written() bool {
+	if w.held() {
+		return false
+	}
+	return w.ResponseWriter.Written()
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordedResponseWriter(t *testing.T) {
+	var writer *recordedResponseWriter
+	hold := false
+	server := gin.New()
+	server.Use(func(c *gin.Context) {
+		original := c.Writer
+		writer = newRecordedResponseWriter(original, hold)
+		c.Writer = writer
+		c.Next()
+		if writer.held() {
+			original.Header().Set("ETag", "held")
+			original.WriteHeader(writer.status)
+			_, _ = original.Write(writer.body.Bytes())
+		}
+	})
+	server.GET("/json", func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"id": 1})
+	})
+	server.GET("/csv", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/csv", []byte("id\n1\n"))
+	})
+	doReq := func(path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		server.ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+		return res
+	}
+
+	// The JSON responses are recorded while they are written
+	res := doReq("/json")
+	assert.True(t, writer.recording)
+	assert.Equal(t, `{"id":1}`, writer.body.String())
+	assert.Equal(t, http.StatusCreated, res.Code)
+	assert.Equal(t, `{"id":1}`, res.Body.String())
+	assert.Empty(t, res.Header().Get("ETag"))
+	// The rest are written through
+	res = doReq("/csv")
+	assert.False(t, writer.recording)
+	assert.Equal(t, 0, writer.body.Len())
+	assert.Equal(t, "id\n1\n", res.Body.String())
+
+	// The JSON responses are held until the middleware writes them
+	hold = true
+	res = doReq("/json")
+	assert.True(t, writer.held())
+	assert.Equal(t, http.StatusCreated, res.Code)
+	assert.Equal(t, `{"id":1}`, res.Body.String())
+	assert.Equal(t, "held", res.Header().Get("ETag"))
+	// The rest are still written through
+	res = doReq("/csv")
+	assert.False(t, writer.held())
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "id\n1\n", res.Body.String())
+	assert.Empty(t, res.Header().Get("ETag"))
+}
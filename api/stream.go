@@ -0,0 +1,368 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/gin-gonic/gin"
+	"github.com/chainbing/node/api/parsers"
+	"github.com/chainbing/node/common"
+	"github.com/chainbing/node/common/apitypes"
+	"github.com/chainbing/node/log"
+	"github.com/chainbing/tracerr"
+	"github.com/lib/pq"
+)
+
+const (
+	// streamSubscriberBufferLen is the number of events that can be queued
+	// for a subscriber before new events are dropped for it
+	streamSubscriberBufferLen = 64
+	// streamKeepAliveInterval is the interval between keep alive comments
+	// sent to the subscribers when there are no events
+	streamKeepAliveInterval = 15 * time.Second
+	// streamPingInterval is the interval between pings to the SQL
+	// listener, used to detect lost connections
+	streamPingInterval = 90 * time.Second
+	// maxTxWaiters is the maximum number of requests waiting for a tx at
+	// the same time
+	maxTxWaiters = 1000
+	// maxStreamSubscribers is the maximum number of clients subscribed to
+	// the stream at the same time
+	maxStreamSubscribers = 1000
+)
+
+// sqlEvent is the payload of the notifications sent by the SQL triggers.  The
+// poolTx events have the accounts of the tx, so that the subscribers are
+// filtered before querying the tx.
+type sqlEvent struct {
+	Type        string             `json:"type"`
+	EthBlockNum int64              `json:"ethBlockNum"`
+	BatchNum    common.BatchNum    `json:"batchNum"`
+	TxID        common.TxID        `json:"txId"`
+	ItemID      uint64             `json:"itemId"`
+	SlotNum     int64              `json:"slotNum"`
+	BidValue    string             `json:"bidValue"`
+	BidderAddr  ethCommon.Address  `json:"bidderAddr"`
+	FromIdx     common.Idx         `json:"fromIdx"`
+	ToIdx       *common.Idx        `json:"toIdx"`
+	FromEthAddr *ethCommon.Address `json:"fromEthAddr"`
+	FromBJJ     *hexutil.Bytes     `json:"fromBjj"`
+	ToEthAddr   *ethCommon.Address `json:"toEthAddr"`
+	ToBJJ       *hexutil.Bytes     `json:"toBjj"`
+}
+
+// streamBid is the data sent to the subscribers of the bids topic
+type streamBid struct {
+	ItemID      uint64             `json:"itemId"`
+	SlotNum     int64              `json:"slotNum"`
+	BidValue    apitypes.BigIntStr `json:"bidValue"`
+	EthBlockNum int64              `json:"ethereumBlockNum"`
+	BidderAddr  ethCommon.Address  `json:"bidderAddr"`
+}
+
+// streamSlot is the data sent to the subscribers of the slots topic
+type streamSlot struct {
+	SlotNum     int64 `json:"slotNum"`
+	FirstBlock  int64 `json:"firstBlock"`
+	LastBlock   int64 `json:"lastBlock"`
+	EthBlockNum int64 `json:"ethereumBlockNum"`
+}
+
+type streamMessage struct {
+	topic string
+	data  interface{}
+}
+
+type streamSubscriber struct {
+	subscription parsers.StreamSubscription
+	ch           chan streamMessage
+}
+
+// matchPoolTx returns true if the tx of the poolTx event involves the account
+// filtered by the subscriber, or if the subscriber has no account filters
+func (s *streamSubscriber) matchPoolTx(event *sqlEvent) bool {
+	sub := s.subscription
+	if sub.Idx != nil {
+		return event.FromIdx == *sub.Idx || (event.ToIdx != nil && *event.ToIdx == *sub.Idx)
+	}
+	if sub.EthAddr != nil {
+		return (event.FromEthAddr != nil && *event.FromEthAddr == *sub.EthAddr) ||
+			(event.ToEthAddr != nil && *event.ToEthAddr == *sub.EthAddr)
+	}
+	if sub.Bjj != nil {
+		return (event.FromBJJ != nil && bytes.Equal(*event.FromBJJ, sub.Bjj[:])) ||
+			(event.ToBJJ != nil && bytes.Equal(*event.ToBJJ, sub.Bjj[:]))
+	}
+	return true
+}
+
+// streamHub keeps the stream subscribers and broadcasts to them the events
+// notified by the SQL DB.  It also wakes the requests waiting for a tx when
+// the tx may have changed.
+type streamHub struct {
+	listener     *pq.Listener
+	subscribers  map[*streamSubscriber]struct{}
+	txWaiters    map[common.TxID]map[chan struct{}]struct{}
+	numTxWaiters int
+	rw           sync.RWMutex
+	lastSlotNum  int64
+}
+
+func newStreamHub(listener *pq.Listener) *streamHub {
+	return &streamHub{
+		listener:    listener,
+		subscribers: make(map[*streamSubscriber]struct{}),
+		txWaiters:   make(map[common.TxID]map[chan struct{}]struct{}),
+		lastSlotNum: -1,
+	}
+}
+
+// waitTx registers a waiter of the tx, which receives a signal each time the
+// tx may have changed: when its state in the pool changes and when a batch is
+// forged.  Returns false if there are already maxTxWaiters waiters.
+func (h *streamHub) waitTx(txID common.TxID) (chan struct{}, bool) {
+	h.rw.Lock()
+	defer h.rw.Unlock()
+	if h.numTxWaiters >= maxTxWaiters {
+		return nil, false
+	}
+	waiter := make(chan struct{}, 1)
+	if h.txWaiters[txID] == nil {
+		h.txWaiters[txID] = make(map[chan struct{}]struct{})
+	}
+	h.txWaiters[txID][waiter] = struct{}{}
+	h.numTxWaiters++
+	return waiter, true
+}
+
+func (h *streamHub) stopWaitingTx(txID common.TxID, waiter chan struct{}) {
+	h.rw.Lock()
+	defer h.rw.Unlock()
+	delete(h.txWaiters[txID], waiter)
+	if len(h.txWaiters[txID]) == 0 {
+		delete(h.txWaiters, txID)
+	}
+	h.numTxWaiters--
+}
+
+// wakeTxWaiters signals the waiters of the tx, or the waiters of all the txs
+// if txID is nil.  The signals of the waiters that are still handling the
+// previous one are merged.
+func (h *streamHub) wakeTxWaiters(txID *common.TxID) {
+	h.rw.RLock()
+	defer h.rw.RUnlock()
+	wake := func(waiters map[chan struct{}]struct{}) {
+		for waiter := range waiters {
+			select {
+			case waiter <- struct{}{}:
+			default:
+			}
+		}
+	}
+	if txID != nil {
+		wake(h.txWaiters[*txID])
+		return
+	}
+	for _, waiters := range h.txWaiters {
+		wake(waiters)
+	}
+}
+
+// subscribe registers a subscriber, which receives the events of the
+// subscribed topics.  Returns false if there are already maxStreamSubscribers
+// subscribers.
+func (h *streamHub) subscribe(subscription parsers.StreamSubscription) (*streamSubscriber, bool) {
+	h.rw.Lock()
+	defer h.rw.Unlock()
+	if len(h.subscribers) >= maxStreamSubscribers {
+		return nil, false
+	}
+	s := &streamSubscriber{
+		subscription: subscription,
+		ch:           make(chan streamMessage, streamSubscriberBufferLen),
+	}
+	h.subscribers[s] = struct{}{}
+	return s, true
+}
+
+func (h *streamHub) unsubscribe(s *streamSubscriber) {
+	h.rw.Lock()
+	defer h.rw.Unlock()
+	delete(h.subscribers, s)
+}
+
+// hasSubscribers returns true if there is some subscriber of the topic for
+// which match returns true, so that the data of the events that no one
+// receives is not queried
+func (h *streamHub) hasSubscribers(topic string, match func(s *streamSubscriber) bool) bool {
+	h.rw.RLock()
+	defer h.rw.RUnlock()
+	for s := range h.subscribers {
+		if s.subscription.Topics[topic] && (match == nil || match(s)) {
+			return true
+		}
+	}
+	return false
+}
+
+// broadcast sends the message to all the subscribers of the topic for which
+// match returns true.  Subscribers that are not consuming their events fast
+// enough miss the message.
+func (h *streamHub) broadcast(msg streamMessage, match func(s *streamSubscriber) bool) {
+	h.rw.RLock()
+	defer h.rw.RUnlock()
+	for s := range h.subscribers {
+		if !s.subscription.Topics[msg.topic] || (match != nil && !match(s)) {
+			continue
+		}
+		select {
+		case s.ch <- msg:
+		default:
+			log.Warnw("API stream: subscriber buffer full, dropping event", "topic", msg.topic)
+		}
+	}
+}
+
+// runStream receives the notifications from the SQL listener until it's
+// closed, and broadcasts the corresponding events to the subscribers
+func (a *API) runStream() {
+	for {
+		select {
+		case notification, ok := <-a.stream.listener.Notify:
+			if !ok {
+				log.Info("API stream: SQL listener closed")
+				return
+			}
+			// A nil notification is received after the listener
+			// reconnects, events sent in between are lost.
+			if notification == nil {
+				log.Warn("API stream: SQL listener reconnected")
+				continue
+			}
+			if err := a.handleSQLEvent(notification.Extra); err != nil {
+				log.Warnw("API stream: handleSQLEvent", "err", err)
+			}
+		case <-time.After(streamPingInterval):
+			go func() {
+				if err := a.stream.listener.Ping(); err != nil {
+					log.Warnw("API stream: SQL listener ping", "err", err)
+				}
+			}()
+		}
+	}
+}
+
+func (a *API) handleSQLEvent(payload string) error {
+	var event sqlEvent
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		return tracerr.Wrap(fmt.Errorf("invalid event payload %q: %w", payload, err))
+	}
+	switch event.Type {
+	case "block":
+		slotNum := a.cg.AuctionConstants.SlotNum(event.EthBlockNum)
+		if slotNum == a.stream.lastSlotNum {
+			return nil
+		}
+		prevSlotNum := a.stream.lastSlotNum
+		a.stream.lastSlotNum = slotNum
+		if prevSlotNum == -1 {
+			// Don't notify the first slot seen after starting
+			return nil
+		}
+		firstBlock, lastBlock := a.cg.AuctionConstants.SlotBlocks(slotNum)
+		a.stream.broadcast(streamMessage{
+			topic: parsers.StreamTopicSlots,
+			data: streamSlot{
+				SlotNum:     slotNum,
+				FirstBlock:  firstBlock,
+				LastBlock:   lastBlock,
+				EthBlockNum: event.EthBlockNum,
+			},
+		}, nil)
+	case "batch":
+		// The forged txs, including the L1 txs that are not in the
+		// pool, are found in the history
+		a.stream.wakeTxWaiters(nil)
+		if a.h == nil || !a.stream.hasSubscribers(parsers.StreamTopicBatches, nil) {
+			return nil
+		}
+		batch, err := a.h.GetBatchAPI(event.BatchNum)
+		if err != nil {
+			return err
+		}
+		a.stream.broadcast(streamMessage{topic: parsers.StreamTopicBatches, data: batch}, nil)
+	case "bid":
+		a.stream.broadcast(streamMessage{
+			topic: parsers.StreamTopicBids,
+			data: streamBid{
+				ItemID:      event.ItemID,
+				SlotNum:     event.SlotNum,
+				BidValue:    apitypes.BigIntStr(event.BidValue),
+				EthBlockNum: event.EthBlockNum,
+				BidderAddr:  event.BidderAddr,
+			},
+		}, nil)
+	case "poolTx":
+		a.stream.wakeTxWaiters(&event.TxID)
+		match := func(s *streamSubscriber) bool { return s.matchPoolTx(&event) }
+		if a.l2 == nil || !a.stream.hasSubscribers(parsers.StreamTopicPoolTxs, match) {
+			return nil
+		}
+		tx, err := a.l2.GetTxAPI(event.TxID)
+		if err != nil {
+			return err
+		}
+		a.stream.broadcast(streamMessage{topic: parsers.StreamTopicPoolTxs, data: tx}, match)
+	default:
+		return tracerr.Wrap(fmt.Errorf("unknown event type %q", event.Type))
+	}
+	return nil
+}
+
+// getStream subscribes the client to the requested topics and pushes the
+// events using Server-Sent Events until the client disconnects
+func (a *API) getStream(c *gin.Context) {
+	subscription, err := parsers.ParseStreamFilters(c, a.validate)
+	if err != nil {
+		retBadReq(&apiError{
+			Err:  err,
+			Code: ErrParamValidationFailedCode,
+			Type: ErrParamValidationFailedType,
+		}, c)
+		return
+	}
+	subscriber, ok := a.stream.subscribe(subscription)
+	if !ok {
+		c.JSON(http.StatusServiceUnavailable, apiErrorResponse{
+			Message: ErrTooManyStreamSubscribers,
+			Code:    ErrTooManyStreamSubscribersCode,
+			Type:    ErrTooManyStreamSubscribersType,
+		})
+		return
+	}
+	defer a.stream.unsubscribe(subscriber)
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case msg := <-subscriber.ch:
+			c.SSEvent(msg.topic, msg.data)
+			return true
+		case <-time.After(streamKeepAliveInterval):
+			_, err := w.Write([]byte(": keep-alive\n\n"))
+			return err == nil
+		}
+	})
+}
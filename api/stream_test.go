@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/chainbing/node/api/parsers"
+	"github.com/chainbing/node/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamHubBroadcast(t *testing.T) {
+	hub := newStreamHub(nil)
+	fromAddr := ethCommon.HexToAddress("0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf")
+	otherAddr := ethCommon.HexToAddress("0x2B5AD5c4795c026514f8317c7a215E218DcCD6cF")
+	fromIdx := common.Idx(256)
+	otherIdx := common.Idx(257)
+
+	subscribe := func(subscription parsers.StreamSubscription) *streamSubscriber {
+		s, ok := hub.subscribe(subscription)
+		require.True(t, ok)
+		return s
+	}
+	all := subscribe(parsers.StreamSubscription{
+		Topics: map[string]bool{parsers.StreamTopicBatches: true, parsers.StreamTopicPoolTxs: true},
+	})
+	onlyBatches := subscribe(parsers.StreamSubscription{
+		Topics: map[string]bool{parsers.StreamTopicBatches: true},
+	})
+	byIdx := subscribe(parsers.StreamSubscription{
+		Topics: map[string]bool{parsers.StreamTopicPoolTxs: true},
+		Idx:    &fromIdx,
+	})
+	byOtherIdx := subscribe(parsers.StreamSubscription{
+		Topics: map[string]bool{parsers.StreamTopicPoolTxs: true},
+		Idx:    &otherIdx,
+	})
+	byAddr := subscribe(parsers.StreamSubscription{
+		Topics:  map[string]bool{parsers.StreamTopicPoolTxs: true},
+		EthAddr: &fromAddr,
+	})
+	byOtherAddr := subscribe(parsers.StreamSubscription{
+		Topics:  map[string]bool{parsers.StreamTopicPoolTxs: true},
+		EthAddr: &otherAddr,
+	})
+
+	// The poolTx events are matched with the accounts of their payload
+	txID := common.TxID{0x02, 0x01}
+	payload := fmt.Sprintf(`{"type":"poolTx","txId":"0x%x","state":"pend","fromIdx":256,"toIdx":null,`+
+		`"fromEthAddr":"0x7e5f4552091a69125d5dfcb7b8c2659029395bdf","fromBjj":null,`+
+		`"toEthAddr":null,"toBjj":null}`, txID[:])
+	var event sqlEvent
+	require.NoError(t, json.Unmarshal([]byte(payload), &event))
+	match := func(s *streamSubscriber) bool { return s.matchPoolTx(&event) }
+	assert.True(t, hub.hasSubscribers(parsers.StreamTopicPoolTxs, match))
+	assert.False(t, hub.hasSubscribers(parsers.StreamTopicBids, nil))
+	hub.broadcast(streamMessage{topic: parsers.StreamTopicPoolTxs, data: nil}, match)
+	hub.broadcast(streamMessage{topic: parsers.StreamTopicBatches, data: nil}, nil)
+
+	assert.Equal(t, 2, len(all.ch))
+	assert.Equal(t, 1, len(onlyBatches.ch))
+	assert.Equal(t, 1, len(byIdx.ch))
+	assert.Equal(t, 0, len(byOtherIdx.ch))
+	assert.Equal(t, 1, len(byAddr.ch))
+	assert.Equal(t, 0, len(byOtherAddr.ch))
+
+	// Unsubscribed clients don't receive more events
+	hub.unsubscribe(all)
+	hub.broadcast(streamMessage{topic: parsers.StreamTopicBatches, data: nil}, nil)
+	assert.Equal(t, 2, len(all.ch))
+	assert.Equal(t, 2, len(onlyBatches.ch))
+}
+
+func TestStreamHubMaxSubscribers(t *testing.T) {
+	hub := newStreamHub(nil)
+	subscription := parsers.StreamSubscription{
+		Topics: map[string]bool{parsers.StreamTopicBatches: true},
+	}
+	first, ok := hub.subscribe(subscription)
+	require.True(t, ok)
+	for i := 1; i < maxStreamSubscribers; i++ {
+		_, ok := hub.subscribe(subscription)
+		require.True(t, ok)
+	}
+	_, ok = hub.subscribe(subscription)
+	assert.False(t, ok)
+	hub.unsubscribe(first)
+	_, ok = hub.subscribe(subscription)
+	assert.True(t, ok)
+}
+
+func TestStreamHubTxWaiters(t *testing.T) {
+	hub := newStreamHub(nil)
+	txID := common.TxID{0x02, 0x01}
+	otherTxID := common.TxID{0x02, 0x02}
+	waiter, ok := hub.waitTx(txID)
+	require.True(t, ok)
+	otherWaiter, ok := hub.waitTx(otherTxID)
+	require.True(t, ok)
+
+	// The waiters are only woken by the events of their tx, and the
+	// signals are merged while they are not handled
+	hub.wakeTxWaiters(&txID)
+	hub.wakeTxWaiters(&txID)
+	assert.Equal(t, 1, len(waiter))
+	assert.Equal(t, 0, len(otherWaiter))
+	<-waiter
+	// The batches wake all the waiters
+	hub.wakeTxWaiters(nil)
+	assert.Equal(t, 1, len(waiter))
+	assert.Equal(t, 1, len(otherWaiter))
+
+	// The number of waiters is limited
+	hub.stopWaitingTx(otherTxID, otherWaiter)
+	for i := 1; i < maxTxWaiters; i++ {
+		_, ok := hub.waitTx(txID)
+		require.True(t, ok)
+	}
+	_, ok = hub.waitTx(otherTxID)
+	assert.False(t, ok)
+	hub.stopWaitingTx(txID, waiter)
+	_, ok = hub.waitTx(otherTxID)
+	assert.True(t, ok)
+}
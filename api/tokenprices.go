@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/chainbing/node/api/parsers"
+	"github.com/chainbing/node/common"
+	"github.com/chainbing/node/db/historydb"
+)
+
+func (a *API) getTokenPrices(c *gin.Context) {
+	request, err := parsers.ParseTokenPricesFilters(c, time.Now())
+	if err != nil {
+		retBadReq(&apiError{
+			Err:  err,
+			Code: ErrParamValidationFailedCode,
+			Type: ErrParamValidationFailedType,
+		}, c)
+		return
+	}
+	// 404 if the token doesn't exist
+	if _, err := a.h.GetTokenAPI(request.TokenID); err != nil {
+		retSQLErr(err, c)
+		return
+	}
+	prices, err := a.h.GetTokenPricesAPI(request)
+	if err != nil {
+		retSQLErr(err, c)
+		return
+	}
+
+	// Build successful response
+	type tokenPricesResponse struct {
+		TokenID       common.TokenID            `json:"tokenId"`
+		FromTimestamp time.Time                 `json:"fromTimestamp"`
+		ToTimestamp   time.Time                 `json:"toTimestamp"`
+		Interval      string                    `json:"interval"`
+		Prices        []historydb.TokenPriceAPI `json:"prices"`
+	}
+	c.JSON(http.StatusOK, &tokenPricesResponse{
+		TokenID:       request.TokenID,
+		FromTimestamp: request.FromTimestamp,
+		ToTimestamp:   request.ToTimestamp,
+		Interval:      request.Interval.String(),
+		Prices:        prices,
+	})
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/chainbing/node/api/parsers"
+	"github.com/chainbing/node/common"
+	"github.com/chainbing/node/common/apitypes"
+	"github.com/chainbing/node/db/historydb"
+	"github.com/chainbing/node/db/l2db"
+	"github.com/chainbing/tracerr"
+)
+
+const (
+	// txWaitDefaultTimeout is the time a tx is waited for when no timeout
+	// is requested
+	txWaitDefaultTimeout = 30 * time.Second
+	// txWaitMaxTimeout is the maximum time a tx can be waited for
+	txWaitMaxTimeout = 60 * time.Second
+	// txWaitWriteMargin is the time left between the end of the wait and
+	// the WriteTimeout of the server to send the response
+	txWaitWriteMargin = 5 * time.Second
+)
+
+// txLifecycle is the lifecycle of a tx, from its submission to the pool to
+// its forging
+type txLifecycle struct {
+	TxID                common.TxID                 `json:"id"`
+	SubmissionTimestamp *time.Time                  `json:"submissionTimestamp"`
+	PoolState           *common.PoolL2TxState       `json:"poolState"`
+	PoolStateChanges    []l2db.PoolTxStateChangeAPI `json:"poolStateChanges"`
+	BatchNum            *common.BatchNum            `json:"batchNum"`
+	EthBlockNum         *int64                      `json:"ethereumBlockNum"`
+	ForgeTimestamp      *time.Time                  `json:"forgeTimestamp"`
+	Confirmations       *int64                      `json:"confirmations"`
+	PoolTx              *apitypes.TxL2              `json:"poolTransaction"`
+	HistoryTx           *historydb.TxAPI            `json:"historyTransaction"`
+}
+
+// getTxLifecycle looks up the tx in the pool and the history, returning
+// sql.ErrNoRows if it's in none of them
+func (a *API) getTxLifecycle(txID common.TxID) (*txLifecycle, error) {
+	lifecycle := &txLifecycle{
+		TxID:             txID,
+		PoolStateChanges: []l2db.PoolTxStateChangeAPI{},
+	}
+	if a.l2 != nil {
+		poolTx, err := a.l2.GetTxAPI(txID)
+		if err == nil {
+			lifecycle.PoolTx = &poolTx
+			lifecycle.PoolState = &poolTx.State
+			lifecycle.SubmissionTimestamp = &poolTx.Timestamp
+			if lifecycle.PoolStateChanges, err = a.l2.GetTxStateChangesAPI(txID); err != nil {
+				return nil, tracerr.Wrap(err)
+			}
+		} else if tracerr.Unwrap(err) != sql.ErrNoRows {
+			return nil, tracerr.Wrap(err)
+		}
+	}
+	historyTx, err := a.h.GetTxAPI(txID)
+	if tracerr.Unwrap(err) == sql.ErrNoRows {
+		if lifecycle.PoolTx == nil {
+			return nil, tracerr.Wrap(sql.ErrNoRows)
+		}
+		return lifecycle, nil
+	} else if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	lifecycle.HistoryTx = historyTx
+	// The L1 user txs are submitted in the block in which they are added
+	// to the queue
+	if lifecycle.SubmissionTimestamp == nil && historyTx.IsL1 &&
+		historyTx.UserOrigin != nil && *historyTx.UserOrigin {
+		lifecycle.SubmissionTimestamp = &historyTx.Timestamp
+	}
+	if historyTx.BatchNum == nil {
+		return lifecycle, nil
+	}
+	batch, err := a.h.GetBatchAPI(*historyTx.BatchNum)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	lastBlock, err := a.h.GetLastBlockAPI()
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	confirmations := lastBlock.Num - batch.EthBlockNum + 1
+	lifecycle.BatchNum = &batch.BatchNum
+	lifecycle.EthBlockNum = &batch.EthBlockNum
+	lifecycle.ForgeTimestamp = &batch.Timestamp
+	lifecycle.Confirmations = &confirmations
+	return lifecycle, nil
+}
+
+func (a *API) getTransaction(c *gin.Context) {
+	txID, waitFor, timeout, err := parsers.ParseTransaction(c)
+	if err != nil {
+		retBadReq(&apiError{
+			Err:  err,
+			Code: ErrParamValidationFailedCode,
+			Type: ErrParamValidationFailedType,
+		}, c)
+		return
+	}
+	// The waiters are woken by the events of the stream, instead of
+	// looking up the tx periodically
+	var wake chan struct{}
+	if waitFor != "" {
+		if a.stream == nil {
+			retBadReq(&apiError{
+				Err:  errors.New("waitFor requires the stream to be enabled"),
+				Code: ErrParamValidationFailedCode,
+				Type: ErrParamValidationFailedType,
+			}, c)
+			return
+		}
+		var ok bool
+		if wake, ok = a.stream.waitTx(txID); !ok {
+			c.JSON(http.StatusServiceUnavailable, apiErrorResponse{
+				Message: ErrTooManyTxWaiters,
+				Code:    ErrTooManyTxWaitersCode,
+				Type:    ErrTooManyTxWaitersType,
+			})
+			return
+		}
+		defer a.stream.stopWaitingTx(txID, wake)
+	}
+	if timeout == 0 {
+		timeout = txWaitDefaultTimeout
+	}
+	if timeout > txWaitMaxTimeout {
+		timeout = txWaitMaxTimeout
+	}
+	// The response must be sent before the WriteTimeout of the server
+	if a.writeTimeout > 0 && timeout > a.writeTimeout-txWaitWriteMargin {
+		timeout = a.writeTimeout - txWaitWriteMargin
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	timedOut := timeout <= 0
+	for {
+		lifecycle, err := a.getTxLifecycle(txID)
+		if err != nil && tracerr.Unwrap(err) != sql.ErrNoRows {
+			retSQLErr(err, c)
+			return
+		}
+		// When waiting, the tx is looked up again until it reaches the
+		// state or the timeout expires, even if it's not known yet
+		done := waitFor == "" || timedOut ||
+			(waitFor == parsers.TxWaitForForged && lifecycle != nil && lifecycle.BatchNum != nil)
+		if done {
+			if err != nil {
+				retSQLErr(err, c)
+				return
+			}
+			c.JSON(http.StatusOK, lifecycle)
+			return
+		}
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-wake:
+		case <-deadline.C:
+			timedOut = true
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/chainbing/node/api/parsers"
+	"github.com/chainbing/node/db/historydb"
+	"github.com/chainbing/node/log"
+)
+
+func (a *API) getHistoryTxsExport(c *gin.Context) {
+	// Get query parameters
+	request, format, err := parsers.ParseExportTxsFilters(c, a.validate)
+	if err != nil {
+		retBadReq(&apiError{
+			Err:  err,
+			Code: ErrParamValidationFailedCode,
+			Type: ErrParamValidationFailedType,
+		}, c)
+		return
+	}
+
+	// Stream the txs from historyDB
+	contentType := "text/csv"
+	if format == historydb.TxsExportFormatJSONL {
+		contentType = "application/x-ndjson"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="transactions.%s"`, format))
+	if err := a.h.ExportTxsAPI(c.Writer, format, request); err != nil {
+		if c.Writer.Written() {
+			// The response can't be changed once the export has
+			// started, so the client gets a truncated export
+			log.Warnw("HTTP API txs export error", "err", err)
+			return
+		}
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+		retSQLErr(err, c)
+	}
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/chainbing/node/api/parsers"
+	"github.com/chainbing/node/common"
+	"github.com/chainbing/node/db/l2db"
+	"github.com/chainbing/tracerr"
+)
+
+func (a *API) deletePoolTx(c *gin.Context) {
+	txID, signature, err := parsers.ParseCancelPoolTx(c)
+	if err != nil {
+		retBadReq(&apiError{
+			Err:  err,
+			Code: ErrParamValidationFailedCode,
+			Type: ErrParamValidationFailedType,
+		}, c)
+		return
+	}
+	// Get the sender of the tx
+	tx, err := a.l2.GetTxAPI(txID)
+	if err != nil {
+		retSQLErr(err, c)
+		return
+	}
+	var fromIdx common.StrCbIdx
+	if err := fromIdx.UnmarshalText([]byte(tx.FromIdx)); err != nil {
+		c.JSON(http.StatusInternalServerError, errorMsg{Message: err.Error()})
+		return
+	}
+	account, err := a.stateDB.LastGetAccount(fromIdx.Idx)
+	if err != nil {
+		retBadReq(&apiError{
+			Err:  err,
+			Code: ErrGettingSenderAccountCode,
+			Type: ErrGettingSenderAccountType,
+		}, c)
+		return
+	}
+	// Only the owner of the sender account can cancel the tx
+	ok, err := common.VerifyCancelTxSignature(a.cg.ChainID, txID, account.BJJ, signature)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorMsg{Message: err.Error()})
+		return
+	} else if !ok {
+		retBadReq(&apiError{
+			Err:  errors.New("wrong signature"),
+			Code: ErrInvalidSignatureCode,
+			Type: ErrInvalidSignatureType,
+		}, c)
+		return
+	}
+	cancelledTxIDs, err := a.l2.CancelTxAPI(txID)
+	if err != nil {
+		if errors.Is(tracerr.Unwrap(err), l2db.ErrCancelNotPending) {
+			retBadReq(&apiError{
+				Err:  errors.New(ErrCancelNotPending),
+				Code: ErrCancelNotPendingCode,
+				Type: ErrCancelNotPendingType,
+			}, c)
+			return
+		}
+		retSQLErr(err, c)
+		return
+	}
+
+	// Build successful response
+	type cancelPoolTxResponse struct {
+		CancelledTxIDs []common.TxID `json:"cancelledIds"`
+	}
+	c.JSON(http.StatusOK, &cancelPoolTxResponse{
+		CancelledTxIDs: cancelledTxIDs,
+	})
+}
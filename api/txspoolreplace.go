@@ -0,0 +1,71 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/chainbing/node/common"
+	"github.com/chainbing/node/db/l2db"
+	"github.com/chainbing/tracerr"
+)
+
+func (a *API) postReplacePoolTx(c *gin.Context) {
+	// Parse body
+	var receivedTx common.PoolL2Tx
+	if err := c.ShouldBindJSON(&receivedTx); err != nil {
+		retBadReq(&apiError{
+			Err:  err,
+			Code: ErrParamValidationFailedCode,
+			Type: ErrParamValidationFailedType,
+		}, c)
+		return
+	}
+	// Atomic txs can't replace nor be replaced
+	if receivedTx.RqOffset != 0 || receivedTx.AtomicGroupID != common.EmptyAtomicGroupID {
+		retBadReq(&apiError{
+			Err:  errors.New(ErrNotAtomicTxsInPostPoolTx),
+			Code: ErrNotAtomicTxsInPostPoolTxCode,
+			Type: ErrNotAtomicTxsInPostPoolTxType,
+		}, c)
+		return
+	}
+	receivedTx.ClientIP = c.ClientIP()
+	receivedTx.Info = ""
+	// Validate transaction the same way it's done for new txs
+	if err := a.verifyPoolL2Tx(receivedTx); err != nil {
+		retBadReq(err, c)
+		return
+	}
+	// Replace the pending tx with the same sender and nonce
+	replacedTxID, err := a.l2.ReplaceTxAPI(&receivedTx)
+	if err != nil {
+		switch {
+		case errors.Is(tracerr.Unwrap(err), l2db.ErrReplaceAtomicTx):
+			retBadReq(&apiError{
+				Err:  errors.New(ErrReplaceAtomicTx),
+				Code: ErrReplaceAtomicTxCode,
+				Type: ErrReplaceAtomicTxType,
+			}, c)
+		case errors.Is(tracerr.Unwrap(err), l2db.ErrReplaceFeeTooLow):
+			retBadReq(&apiError{
+				Err:  tracerr.Unwrap(err),
+				Code: ErrReplaceFeeTooLowCode,
+				Type: ErrReplaceFeeTooLowType,
+			}, c)
+		default:
+			retSQLErr(err, c)
+		}
+		return
+	}
+
+	// Build successful response
+	type replacePoolTxResponse struct {
+		TxID         common.TxID `json:"id"`
+		ReplacedTxID common.TxID `json:"replacedId"`
+	}
+	c.JSON(http.StatusOK, &replacePoolTxResponse{
+		TxID:         receivedTx.TxID,
+		ReplacedTxID: replacedTxID,
+	})
+}
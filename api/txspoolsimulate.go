@@ -0,0 +1,71 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/chainbing/node/common"
+)
+
+func (a *API) postSimulatePoolTx(c *gin.Context) {
+	// Each simulation copies the state of the TxSelector, so it's only
+	// allowed with an API key, which is rate limited
+	if _, ok := c.Get(apiKeyContextKey); !ok {
+		retInvalidAPIKey(c)
+		return
+	}
+	// Parse body
+	var receivedTx common.PoolL2Tx
+	if err := c.ShouldBindJSON(&receivedTx); err != nil {
+		retBadReq(&apiError{
+			Err:  err,
+			Code: ErrParamValidationFailedCode,
+			Type: ErrParamValidationFailedType,
+		}, c)
+		return
+	}
+	// Atomic txs depend on the rest of txs of the group, so they can't be
+	// simulated on their own
+	if receivedTx.RqOffset != 0 || receivedTx.AtomicGroupID != common.EmptyAtomicGroupID {
+		retBadReq(&apiError{
+			Err:  errors.New(ErrNotAtomicTxsInPostPoolTx),
+			Code: ErrNotAtomicTxsInPostPoolTxCode,
+			Type: ErrNotAtomicTxsInPostPoolTxType,
+		}, c)
+		return
+	}
+	receivedTx.Info = ""
+	// Validate transaction the same way it's done before inserting it
+	if err := a.verifyPoolL2Tx(receivedTx); err != nil {
+		retBadReq(err, c)
+		return
+	}
+	// Simulate the selection, nothing is inserted to the pool
+	reason, err := a.txSimulator.SimulateL2Tx(receivedTx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorMsg{Message: err.Error()})
+		return
+	}
+
+	// Build successful response
+	type simulatePoolTxResponse struct {
+		TxID         common.TxID                    `json:"id"`
+		Selected     bool                           `json:"selected"`
+		Info         string                         `json:"info,omitempty"`
+		ErrorCode    int                            `json:"errorCode,omitempty"`
+		ErrorType    string                         `json:"errorType,omitempty"`
+		ErrorDetails *common.TxSelectorErrorDetails `json:"errorDetails,omitempty"`
+	}
+	response := simulatePoolTxResponse{
+		TxID:     receivedTx.TxID,
+		Selected: reason == nil,
+	}
+	if reason != nil {
+		response.Info = reason.Message
+		response.ErrorCode = reason.Code
+		response.ErrorType = reason.Type
+		response.ErrorDetails = reason.Details
+	}
+	c.JSON(http.StatusOK, &response)
+}
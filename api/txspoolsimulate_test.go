@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/chainbing/node/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingTxSimulator is a TxSimulator that counts the simulations
+type countingTxSimulator struct {
+	simulations int
+}
+
+func (s *countingTxSimulator) SimulateL2Tx(tx common.PoolL2Tx) (*common.TxSelectorError, error) {
+	s.simulations++
+	return nil, nil
+}
+
+func TestSimulatePoolTxRequiresAPIKey(t *testing.T) {
+	simulator := &countingTxSimulator{}
+	a := &API{txSimulator: simulator}
+	server := gin.New()
+	server.POST("/transactions-pool/simulate", a.postSimulatePoolTx)
+	req := httptest.NewRequest(http.MethodPost, "/transactions-pool/simulate",
+		strings.NewReader("{}"))
+	res := httptest.NewRecorder()
+	server.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+	assert.Equal(t, 0, simulator.simulations)
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/chainbing/node/api/parsers"
+	"github.com/chainbing/node/common"
+	"github.com/chainbing/node/common/apitypes"
+	"github.com/chainbing/node/db"
+	"github.com/chainbing/node/db/historydb"
+	"github.com/chainbing/node/db/l2db"
+	"github.com/chainbing/tracerr"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+)
+
+// walletMaxItems is the maximum number of items of each list of a wallet.  It's
+// the maximum limit of the paginated endpoints, so that each list of a wallet
+// is at most a page of the endpoint that lists the same items.
+const walletMaxItems uint = 2049
+
+// walletAccount is an account of a wallet with the value of its balance
+type walletAccount struct {
+	Account    historydb.AccountAPI `json:"account"`
+	BalanceUSD *float64             `json:"balanceUSD"`
+}
+
+// walletPendingItems is the number of items of each list of a wallet that
+// didn't fit in walletMaxItems and are not included
+type walletPendingItems struct {
+	Accounts                uint64 `json:"accounts"`
+	PendingPoolTransactions uint64 `json:"pendingPoolTransactions"`
+	PendingDeposits         uint64 `json:"pendingDeposits"`
+	PendingExits            uint64 `json:"pendingExits"`
+}
+
+// wallet is the overview of all the accounts of an ethereum address or BJJ.
+// Truncated is set when some list has more than walletMaxItems items, in which
+// case TotalBalanceUSD only accounts for the included accounts
+type wallet struct {
+	Accounts                []walletAccount     `json:"accounts"`
+	TotalBalanceUSD         float64             `json:"totalBalanceUSD"`
+	PendingPoolTransactions []apitypes.TxL2     `json:"pendingPoolTransactions"`
+	PendingDeposits         []historydb.TxAPI   `json:"pendingDeposits"`
+	PendingExits            []historydb.ExitAPI `json:"pendingExits"`
+	Truncated               bool                `json:"truncated"`
+	PendingItems            walletPendingItems  `json:"pendingItems"`
+}
+
+func (a *API) getWallet(c *gin.Context) {
+	ethAddr, bjj, err := parsers.ParseWalletFilters(c)
+	if err != nil {
+		retBadReq(&apiError{
+			Err:  err,
+			Code: ErrParamValidationFailedCode,
+			Type: ErrParamValidationFailedType,
+		}, c)
+		return
+	}
+	w, err := a.getWalletOverview(ethAddr, bjj, walletMaxItems)
+	if err != nil {
+		retSQLErr(err, c)
+		return
+	}
+	c.JSON(http.StatusOK, w)
+}
+
+// getWalletOverview gathers the accounts of ethAddr or bjj with their value,
+// and the pool txs, deposits and exits of the wallet that are still pending.
+// Each list has at most limit items.
+func (a *API) getWalletOverview(ethAddr *ethCommon.Address, bjj *babyjub.PublicKeyComp,
+	limit uint) (*wallet, error) {
+	w := &wallet{
+		Accounts:                []walletAccount{},
+		PendingPoolTransactions: []apitypes.TxL2{},
+	}
+	accounts, pendingAccounts, err := a.h.GetAccountsAPI(historydb.GetAccountsAPIRequest{
+		EthAddr: ethAddr,
+		Bjj:     bjj,
+		Limit:   &limit,
+		Order:   db.OrderAsc,
+	})
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	w.PendingItems.Accounts = pendingAccounts
+	for _, account := range accounts {
+		wAccount := walletAccount{Account: account}
+		if account.Balance != nil && account.TokenUSD != nil {
+			balance, ok := new(big.Int).SetString(string(*account.Balance), 10)
+			if !ok {
+				return nil, tracerr.Wrap(fmt.Errorf("invalid balance: %v", *account.Balance))
+			}
+			balanceUSD := tokenAmountValue(balance, account.TokenDecimals, *account.TokenUSD)
+			wAccount.BalanceUSD = &balanceUSD
+			w.TotalBalanceUSD += balanceUSD
+		}
+		w.Accounts = append(w.Accounts, wAccount)
+	}
+
+	if a.l2 != nil {
+		for _, state := range []common.PoolL2TxState{
+			common.PoolL2TxStatePending, common.PoolL2TxStateForging,
+		} {
+			state := state
+			txs, pendingTxs, err := a.l2.GetPoolTxsAPI(l2db.GetPoolTxsAPIRequest{
+				FromEthAddr: ethAddr,
+				FromBjj:     bjj,
+				State:       &state,
+				Limit:       &limit,
+				Order:       db.OrderAsc,
+			})
+			if err != nil {
+				return nil, tracerr.Wrap(err)
+			}
+			w.PendingPoolTransactions = append(w.PendingPoolTransactions, txs...)
+			w.PendingItems.PendingPoolTransactions += pendingTxs
+		}
+		// The txs of both states are capped together
+		if uint(len(w.PendingPoolTransactions)) > limit {
+			w.PendingItems.PendingPoolTransactions += uint64(uint(len(w.PendingPoolTransactions)) - limit)
+			w.PendingPoolTransactions = w.PendingPoolTransactions[:limit]
+		}
+	}
+
+	if w.PendingDeposits, w.PendingItems.PendingDeposits, err = a.h.GetPendingDepositsAPI(
+		ethAddr, bjj, limit); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	onlyPendingWithdraws := true
+	if w.PendingExits, w.PendingItems.PendingExits, err = a.h.GetExitsAPI(historydb.GetExitsAPIRequest{
+		EthAddr:              ethAddr,
+		Bjj:                  bjj,
+		OnlyPendingWithdraws: &onlyPendingWithdraws,
+		Limit:                &limit,
+		Order:                db.OrderAsc,
+	}); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	w.Truncated = w.PendingItems != walletPendingItems{}
+	return w, nil
+}
+
+// tokenAmountValue returns the value of an amount of a token with the given
+// decimals and price
+func tokenAmountValue(amount *big.Int, decimals uint64, price float64) float64 {
+	value := new(big.Float).SetInt(amount)
+	divisor := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), //nolint:gomnd
+		new(big.Int).SetUint64(decimals), nil))
+	value.Quo(value, divisor)
+	value.Mul(value, big.NewFloat(price))
+	result, _ := value.Float64()
+	return result
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/chainbing/node/common"
+	"github.com/chainbing/node/common/apitypes"
+	"github.com/iden3/go-merkletree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenAmountValue(t *testing.T) {
+	// 1.5 tokens with 18 decimals, worth 2 USD each
+	amount, _ := new(big.Int).SetString("1500000000000000000", 10)
+	assert.InDelta(t, 3.0, tokenAmountValue(amount, 18, 2), 1e-9)
+	assert.Equal(t, 0.0, tokenAmountValue(big.NewInt(0), 18, 2))
+	assert.InDelta(t, 12.5, tokenAmountValue(big.NewInt(125), 1, 1), 1e-9)
+}
+
+func TestGetWalletOverview(t *testing.T) {
+	// Generate a wallet with 3 accounts
+	const nAccounts = 3
+	const usedToken = 0
+	addr, privKey := generateKeys(7654321)
+	bjj := privKey.Public().Compress()
+	accounts := make([]common.Account, nAccounts)
+	accountUpdates := make([]common.AccountUpdate, nAccounts)
+	for i := 0; i < nAccounts; i++ {
+		idx := common.Idx(i) + 6000
+		accounts[i] = common.Account{
+			Idx:      idx,
+			TokenID:  tc.tokens[usedToken].TokenID,
+			BatchNum: 1,
+			BJJ:      bjj,
+			EthAddr:  addr,
+		}
+		accountUpdates[i] = common.AccountUpdate{
+			Idx:      idx,
+			BatchNum: 1,
+			Nonce:    0,
+			Balance:  big.NewInt(1000000),
+		}
+	}
+	require.NoError(t, api.h.AddAccounts(accounts))
+	require.NoError(t, api.h.AddAccountUpdates(accountUpdates))
+
+	// A pending and a forging pool tx
+	poolTxs := []common.PoolL2Tx{}
+	for i, state := range []common.PoolL2TxState{
+		common.PoolL2TxStatePending, common.PoolL2TxStateForging,
+	} {
+		tx := common.PoolL2Tx{
+			FromIdx: accounts[0].Idx,
+			ToIdx:   accounts[1].Idx,
+			TokenID: tc.tokens[usedToken].TokenID,
+			Amount:  big.NewInt(100),
+			Fee:     100,
+			Nonce:   common.Nonce(i),
+			State:   state,
+		}
+		_, err := common.NewPoolL2Tx(&tx)
+		require.NoError(t, err)
+		require.NoError(t, api.l2.AddTxTest(&tx))
+		poolTxs = append(poolTxs, tx)
+	}
+	// A deposit that hasn't been forged yet
+	toForgeL1TxsNum := int64(1000)
+	deposit, err := common.NewL1Tx(&common.L1Tx{
+		FromIdx:         accounts[0].Idx,
+		FromEthAddr:     addr,
+		FromBJJ:         bjj,
+		Amount:          big.NewInt(0),
+		DepositAmount:   big.NewInt(500),
+		TokenID:         tc.tokens[usedToken].TokenID,
+		ToForgeL1TxsNum: &toForgeL1TxsNum,
+		Position:        0,
+		UserOrigin:      true,
+		EthBlockNum:     tc.blocks[len(tc.blocks)-1].Num,
+	})
+	require.NoError(t, err)
+	require.NoError(t, api.h.AddL1Txs([]common.L1Tx{*deposit}))
+	// An exit that hasn't been withdrawn
+	require.NoError(t, api.h.AddExitTree([]common.ExitInfo{{
+		BatchNum:   1,
+		AccountIdx: accounts[2].Idx,
+		MerkleProof: &merkletree.CircomVerifierProof{
+			Root:     &merkletree.Hash{1},
+			Siblings: []*merkletree.Hash{},
+			OldKey:   &merkletree.Hash{2},
+			OldValue: &merkletree.Hash{3},
+			IsOld0:   true,
+			Key:      &merkletree.Hash{4},
+			Value:    &merkletree.Hash{5},
+			Fnc:      1,
+		},
+		Balance: big.NewInt(700),
+	}}))
+	defer func() {
+		for _, tx := range poolTxs {
+			_, err := api.h.DB().DB.Exec("delete from tx_pool where tx_id = $1;", tx.TxID)
+			assert.NoError(t, err)
+		}
+		_, err := api.h.DB().DB.Exec("delete from tx where id = $1;", deposit.TxID)
+		assert.NoError(t, err)
+		_, err = api.h.DB().DB.Exec("delete from exit_tree where account_idx = $1;", accounts[2].Idx)
+		assert.NoError(t, err)
+	}()
+
+	// The wallet of the address and of the BJJ have all the items
+	fetchedWallets := []wallet{}
+	w, err := api.getWalletOverview(&addr, nil, walletMaxItems)
+	require.NoError(t, err)
+	fetchedWallets = append(fetchedWallets, *w)
+	w, err = api.getWalletOverview(nil, &bjj, walletMaxItems)
+	require.NoError(t, err)
+	fetchedWallets = append(fetchedWallets, *w)
+	for _, w := range fetchedWallets {
+		require.Equal(t, nAccounts, len(w.Accounts))
+		for i, account := range w.Accounts {
+			assert.Equal(t, apitypes.CbIdx(common.IdxToCb(accounts[i].Idx, tc.tokens[usedToken].Symbol)),
+				account.Account.Idx)
+		}
+		require.Equal(t, len(poolTxs), len(w.PendingPoolTransactions))
+		for i, tx := range w.PendingPoolTransactions {
+			assert.Equal(t, poolTxs[i].TxID, tx.TxID)
+		}
+		require.Equal(t, 1, len(w.PendingDeposits))
+		assert.Equal(t, deposit.TxID, w.PendingDeposits[0].TxID)
+		require.Equal(t, 1, len(w.PendingExits))
+		assert.Equal(t, apitypes.CbIdx(common.IdxToCb(accounts[2].Idx, tc.tokens[usedToken].Symbol)),
+			w.PendingExits[0].AccountIdx)
+		assert.False(t, w.Truncated)
+		assert.Equal(t, walletPendingItems{}, w.PendingItems)
+	}
+
+	// With a smaller limit the lists are truncated, and the pool txs of
+	// both states are capped together
+	w, err = api.getWalletOverview(&addr, nil, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(w.Accounts))
+	assert.Equal(t, 1, len(w.PendingPoolTransactions))
+	assert.Equal(t, poolTxs[0].TxID, w.PendingPoolTransactions[0].TxID)
+	assert.Equal(t, 1, len(w.PendingDeposits))
+	assert.Equal(t, 1, len(w.PendingExits))
+	assert.True(t, w.Truncated)
+	assert.Equal(t, walletPendingItems{
+		Accounts:                nAccounts - 1,
+		PendingPoolTransactions: uint64(len(poolTxs) - 1),
+	}, w.PendingItems)
+}
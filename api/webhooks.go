@@ -0,0 +1,139 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/chainbing/node/api/parsers"
+	"github.com/chainbing/node/db/historydb"
+	"github.com/chainbing/node/db/l2db"
+	"github.com/chainbing/node/webhook"
+	"github.com/chainbing/tracerr"
+)
+
+const (
+	// webhookSecretLen is the number of random bytes of the webhook
+	// secrets
+	webhookSecretLen = 32
+	// maxWebhooksPerAPIKey is the maximum number of webhooks registered
+	// with each API key
+	maxWebhooksPerAPIKey = 10
+)
+
+func (a *API) postWebhook(c *gin.Context) {
+	// The webhooks are registered with an API key, which limits the
+	// number of webhooks of each client
+	value, ok := c.Get(apiKeyContextKey)
+	if !ok {
+		retInvalidAPIKey(c)
+		return
+	}
+	apiKey := value.(*historydb.APIKey)
+	hook, err := parsers.ParseWebhook(c)
+	if err != nil {
+		retBadReq(&apiError{
+			Err:  err,
+			Code: ErrParamValidationFailedCode,
+			Type: ErrParamValidationFailedType,
+		}, c)
+		return
+	}
+	// The URL can't point to the internal network of the node.  The
+	// addresses are checked again at each delivery.
+	if err := webhook.CheckURL(c.Request.Context(), hook.URL); errors.Is(tracerr.Unwrap(err), webhook.ErrForbiddenAddress) {
+		retBadReq(&apiError{
+			Err:  errors.New(ErrForbiddenWebhookURL),
+			Code: ErrForbiddenWebhookURLCode,
+			Type: ErrForbiddenWebhookURLType,
+		}, c)
+		return
+	} else if err != nil {
+		retBadReq(&apiError{
+			Err:  err,
+			Code: ErrParamValidationFailedCode,
+			Type: ErrParamValidationFailedType,
+		}, c)
+		return
+	}
+	hook.APIKeyID = apiKey.ItemID
+	// The secret is used to sign the deliveries and to manage the webhook
+	secret := make([]byte, webhookSecretLen)
+	if _, err := rand.Read(secret); err != nil {
+		c.JSON(http.StatusInternalServerError, errorMsg{Message: err.Error()})
+		return
+	}
+	hook.Secret = hex.EncodeToString(secret)
+	if err := a.l2.AddWebhookAPI(&hook, maxWebhooksPerAPIKey); errors.Is(tracerr.Unwrap(err), l2db.ErrMaxWebhooks) {
+		retBadReq(&apiError{
+			Err:  errors.New(ErrMaxWebhooks),
+			Code: ErrMaxWebhooksCode,
+			Type: ErrMaxWebhooksType,
+		}, c)
+		return
+	} else if err != nil {
+		retSQLErr(err, c)
+		return
+	}
+
+	// Build successful response
+	type webhookResponse struct {
+		ID        uint64    `json:"id"`
+		URL       string    `json:"url"`
+		Secret    string    `json:"secret"`
+		Timestamp time.Time `json:"timestamp"`
+	}
+	c.JSON(http.StatusOK, &webhookResponse{
+		ID:        hook.ItemID,
+		URL:       hook.URL,
+		Secret:    hook.Secret,
+		Timestamp: hook.Timestamp,
+	})
+}
+
+func (a *API) deleteWebhook(c *gin.Context) {
+	webhookID, secret, err := parsers.ParseWebhookFilter(c)
+	if err != nil {
+		retBadReq(&apiError{
+			Err:  err,
+			Code: ErrParamValidationFailedCode,
+			Type: ErrParamValidationFailedType,
+		}, c)
+		return
+	}
+	if err := a.l2.DeleteWebhookAPI(webhookID, secret); err != nil {
+		retSQLErr(err, c)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (a *API) getWebhookDeliveries(c *gin.Context) {
+	request, err := parsers.ParseWebhookDeliveriesFilters(c)
+	if err != nil {
+		retBadReq(&apiError{
+			Err:  err,
+			Code: ErrParamValidationFailedCode,
+			Type: ErrParamValidationFailedType,
+		}, c)
+		return
+	}
+	deliveries, pendingItems, err := a.l2.GetWebhookDeliveriesAPI(request)
+	if err != nil {
+		retSQLErr(err, c)
+		return
+	}
+
+	// Build successful response
+	type webhookDeliveriesResponse struct {
+		Deliveries   []l2db.WebhookDeliveryAPI `json:"deliveries"`
+		PendingItems uint64                    `json:"pendingItems"`
+	}
+	c.JSON(http.StatusOK, &webhookDeliveriesResponse{
+		Deliveries:   deliveries,
+		PendingItems: pendingItems,
+	})
+}
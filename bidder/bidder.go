@@ -0,0 +1,360 @@
+/*
+Package bidder places the bids of the coordinator in the auction of the slots.
+
+The Bidder periodically goes over the open slots of the auction (the ones
+after the ClosedAuctionSlots next slots, up to OpenAuctionSlots slots ahead),
+and for each slot in which the coordinator isn't the best bidder, it bids the
+minimum amount that outbids the best bid.  The bid is only placed if it doesn't
+exceed the maximum bid of the slot, which is the lowest of MaxBidPerSlot and
+MaxValuePerc percent of the estimated value of the slot, and if the bids
+placed in the last 24 hours don't exceed DailyBudget.  The bids placed before
+a restart are taken from the HistoryDB, so they are still counted in the
+budget.  The value of a slot is estimated as the average of the fees collected
+in the last slots in which batches were forged before the current one,
+converted to CB tokens with the price of the token.
+
+The bids are sent with a permit, so the CB tokens don't need to be approved
+in advance, and every decision is logged.  They are sent from a bidder
+account different from the forger one, so that their nonces don't conflict
+with the ones of the forge calls, and only once the synchronizer is synced,
+since the best bids are taken from the HistoryDB.
+*/
+package bidder
+
+import (
+	"context"
+	"database/sql"
+	"math/big"
+	"time"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/chainbing/node/common"
+	"github.com/chainbing/node/config"
+	"github.com/chainbing/node/db/historydb"
+	"github.com/chainbing/node/eth"
+	"github.com/chainbing/node/log"
+	"github.com/chainbing/node/synchronizer"
+	"github.com/chainbing/tracerr"
+)
+
+const (
+	// budgetPeriod is the period of time in which the bids can't exceed
+	// the daily budget
+	budgetPeriod = 24 * time.Hour
+	// permitValidity is the time during which the permit sent with a bid is
+	// valid
+	permitValidity = time.Hour
+	// outbiddingDivisor is the divisor of the outbidding of the auction,
+	// which is expressed in 0.01% units
+	outbiddingDivisor = 10000
+	// defaultInterval is the Interval used if it's not set
+	defaultInterval = time.Minute
+	// statsChLen is the length of the channel of the synchronizer Stats
+	statsChLen = 16
+)
+
+// Config is the configuration of the Bidder
+type Config struct {
+	// Interval is the waiting time between checks of the open slots
+	Interval time.Duration
+	// MaxBidPerSlot is the maximum bid for a slot in CB token units
+	MaxBidPerSlot *big.Int
+	// DailyBudget is the maximum amount of CB token units bid in 24 hours
+	DailyBudget *big.Int
+	// MaxValuePerc is the maximum percentage of the estimated value of a
+	// slot that is bid for it
+	MaxValuePerc float64
+	// ValueSlots is the number of last slots with forged batches whose
+	// collected fees are averaged to estimate the value of a slot
+	ValueSlots int
+	// MaxSlotsAhead is the number of open slots, starting from the first
+	// one, for which bids are placed.  If set to 0, bids are placed for
+	// all the open slots.
+	MaxSlotsAhead int
+}
+
+// NewConfig returns the Config of the bidder configuration of the
+// coordinator, or nil if the automated bidding is not enabled
+func NewConfig(cfg *config.Bidder) *Config {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &Config{
+		Interval:      cfg.Interval.Duration,
+		MaxBidPerSlot: cfg.MaxBidPerSlot,
+		DailyBudget:   cfg.DailyBudget,
+		MaxValuePerc:  cfg.MaxValuePerc,
+		ValueSlots:    cfg.ValueSlots,
+		MaxSlotsAhead: cfg.MaxSlotsAhead,
+	}
+}
+
+// placedBid is a bid placed by the Bidder
+type placedBid struct {
+	slot      int64
+	amount    *big.Int
+	timestamp time.Time
+}
+
+// Bidder places the bids of the coordinator in the auction
+type Bidder struct {
+	cfg       Config
+	historyDB *historydb.HistoryDB
+	ethClient eth.ClientInterface
+	consts    common.AuctionConstants
+	addr      ethCommon.Address
+	// bids are the bids placed in the last budgetPeriod
+	bids []placedBid
+	// slotBids are the last bids placed for each slot
+	slotBids map[int64]*big.Int
+
+	statsCh chan synchronizer.Stats
+	// synced is true if the synchronizer was synced in the last Stats
+	synced bool
+}
+
+// NewBidder creates a new Bidder that bids with the account of the ethClient,
+// which must not be used to send other transactions.  The bids of the account
+// synchronized in the HistoryDB during the last 24 hours are counted in the
+// daily budget.
+func NewBidder(cfg Config, historyDB *historydb.HistoryDB, ethClient eth.ClientInterface,
+	consts *common.AuctionConstants) (*Bidder, error) {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultInterval
+	}
+	addr, err := ethClient.EthAddress()
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	syncedBids, err := historyDB.GetBidsByBidderSince(*addr, time.Now().Add(-budgetPeriod))
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	bids := make([]placedBid, len(syncedBids))
+	for i, bid := range syncedBids {
+		bids[i] = placedBid{slot: bid.SlotNum, amount: bid.BidValue, timestamp: bid.Timestamp}
+	}
+	return &Bidder{
+		cfg:       cfg,
+		historyDB: historyDB,
+		ethClient: ethClient,
+		consts:    *consts,
+		addr:      *addr,
+		bids:      bids,
+		slotBids:  make(map[int64]*big.Int),
+		statsCh:   make(chan synchronizer.Stats, statsChLen),
+	}, nil
+}
+
+// SetSyncStats is a thread safe method to set the synchronizer Stats
+func (b *Bidder) SetSyncStats(ctx context.Context, stats *synchronizer.Stats) {
+	select {
+	case b.statsCh <- *stats:
+	case <-ctx.Done():
+	}
+}
+
+// Run checks the open slots and places the bids every Interval, while the
+// synchronizer is synced
+func (b *Bidder) Run(ctx context.Context) {
+	timer := time.NewTimer(b.cfg.Interval)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("Bidder done")
+			return
+		case stats := <-b.statsCh:
+			synced := stats.Synced()
+			if synced && !b.synced {
+				// Check the slots as soon as the
+				// synchronizer is synced
+				if !timer.Stop() {
+					<-timer.C
+				}
+				timer.Reset(0)
+			}
+			b.synced = synced
+		case <-timer.C:
+			timer.Reset(b.cfg.Interval)
+			if !b.synced {
+				log.Debug("Bidder: waiting for the synchronizer to be synced")
+				continue
+			}
+			if err := b.BidSlots(time.Now()); err != nil {
+				log.Errorw("Bidder.BidSlots", "err", err)
+			}
+		}
+	}
+}
+
+// BidSlots places the bids for the open slots of the auction at time now
+func (b *Bidder) BidSlots(now time.Time) error {
+	currentSlot, err := b.ethClient.AuctionGetCurrentSlotNumber()
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	closedSlots, err := b.ethClient.AuctionGetClosedAuctionSlots()
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	openSlots, err := b.ethClient.AuctionGetOpenAuctionSlots()
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	outbidding, err := b.ethClient.AuctionGetOutbidding()
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	slotValue, err := b.slotValue(currentSlot)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	b.pruneBids(now, currentSlot+int64(closedSlots))
+
+	numSlots := int64(openSlots)
+	if b.cfg.MaxSlotsAhead > 0 && int64(b.cfg.MaxSlotsAhead) < numSlots {
+		numSlots = int64(b.cfg.MaxSlotsAhead)
+	}
+	firstSlot := currentSlot + int64(closedSlots) + 1
+	for slot := firstSlot; slot < firstSlot+numSlots; slot++ {
+		if err := b.bidSlot(slot, slotValue, outbidding, now); err != nil {
+			return tracerr.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// bidSlot places a bid for the slot if the coordinator isn't the best bidder
+// and the bid doesn't exceed the maximum bid nor the daily budget
+func (b *Bidder) bidSlot(slot int64, slotValue *big.Int, outbidding uint16,
+	now time.Time) error {
+	var bestBid *big.Int
+	bestBidCoord, err := b.historyDB.GetBestBidCoordinator(slot)
+	if err != nil && tracerr.Unwrap(err) != sql.ErrNoRows {
+		return tracerr.Wrap(err)
+	} else if err == nil {
+		if bestBidCoord.Bidder == b.addr {
+			log.Infow("Bidder: we are the best bidder", "slot", slot,
+				"bid", bestBidCoord.BidValue)
+			return nil
+		}
+		bestBid = bestBidCoord.BidValue
+	}
+	// The bids placed are synchronized some blocks later
+	if ourBid, ok := b.slotBids[slot]; ok && (bestBid == nil || ourBid.Cmp(bestBid) >= 0) {
+		log.Infow("Bidder: our bid is pending to be synchronized", "slot", slot,
+			"bid", ourBid)
+		return nil
+	}
+
+	minBid, err := b.ethClient.AuctionGetMinBidBySlot(slot)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	if bestBid != nil {
+		if outbid := outbidAmount(bestBid, outbidding); outbid.Cmp(minBid) > 0 {
+			minBid = outbid
+		}
+	}
+	maxBid := maxSlotBid(b.cfg.MaxBidPerSlot, slotValue, b.cfg.MaxValuePerc)
+	if maxBid == nil {
+		log.Infow("Bidder: skipping slot, its value can't be estimated", "slot", slot,
+			"minBid", minBid)
+		return nil
+	}
+	if minBid.Cmp(maxBid) > 0 {
+		log.Infow("Bidder: skipping slot, the min bid exceeds the max bid", "slot", slot,
+			"minBid", minBid, "maxBid", maxBid, "bestBid", bestBid)
+		return nil
+	}
+	spent := b.spent()
+	if new(big.Int).Add(spent, minBid).Cmp(b.cfg.DailyBudget) > 0 {
+		log.Infow("Bidder: skipping slot, the bid exceeds the daily budget", "slot", slot,
+			"minBid", minBid, "spent", spent, "dailyBudget", b.cfg.DailyBudget)
+		return nil
+	}
+
+	deadline := big.NewInt(now.Add(permitValidity).Unix())
+	tx, err := b.ethClient.AuctionBid(minBid, slot, minBid, deadline)
+	if err != nil {
+		log.Warnw("Bidder: bid failed", "slot", slot, "bid", minBid, "err", err)
+		return nil
+	}
+	b.bids = append(b.bids, placedBid{slot: slot, amount: minBid, timestamp: now})
+	b.slotBids[slot] = minBid
+	log.Infow("Bidder: bid placed", "slot", slot, "bid", minBid, "maxBid", maxBid,
+		"bestBid", bestBid, "ethTx", tx.Hash())
+	return nil
+}
+
+// slotValue returns the estimated value of a slot in CB token units, or nil if
+// it can't be estimated
+func (b *Bidder) slotValue(currentSlot int64) (*big.Int, error) {
+	avgFeesUSD, err := b.historyDB.GetAvgSlotFeesUSD(b.cfg.ValueSlots, currentSlot)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	} else if avgFeesUSD == nil {
+		return nil, nil
+	}
+	tokenCB, err := b.historyDB.GetTokenByEthAddr(b.consts.TokenCB)
+	if tracerr.Unwrap(err) == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	if tokenCB.USD == nil || *tokenCB.USD <= 0 {
+		return nil, nil
+	}
+	value := big.NewFloat(*avgFeesUSD / *tokenCB.USD)
+	value.Mul(value, new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), //nolint:gomnd
+		new(big.Int).SetUint64(tokenCB.Decimals), nil)))
+	valueInt, _ := value.Int(nil)
+	return valueInt, nil
+}
+
+// pruneBids forgets the bids placed before the budgetPeriod, and the bids of
+// the slots whose auction is closed
+func (b *Bidder) pruneBids(now time.Time, lastClosedSlot int64) {
+	i := 0
+	for i < len(b.bids) && now.Sub(b.bids[i].timestamp) >= budgetPeriod {
+		i++
+	}
+	b.bids = b.bids[i:]
+	for slot := range b.slotBids {
+		if slot <= lastClosedSlot {
+			delete(b.slotBids, slot)
+		}
+	}
+}
+
+// spent returns the amount bid in the last budgetPeriod
+func (b *Bidder) spent() *big.Int {
+	spent := big.NewInt(0)
+	for _, bid := range b.bids {
+		spent.Add(spent, bid.amount)
+	}
+	return spent
+}
+
+// outbidAmount returns the minimum bid that outbids bid
+func outbidAmount(bid *big.Int, outbidding uint16) *big.Int {
+	inc := new(big.Int).Mul(bid, big.NewInt(int64(outbidding)))
+	inc.Div(inc, big.NewInt(outbiddingDivisor))
+	return inc.Add(inc, bid)
+}
+
+// maxSlotBid returns the maximum bid for a slot, which is the lowest of
+// maxBidPerSlot and maxValuePerc percent of the slot value, or nil if the slot
+// value is unknown
+func maxSlotBid(maxBidPerSlot, slotValue *big.Int, maxValuePerc float64) *big.Int {
+	if slotValue == nil {
+		return nil
+	}
+	value := new(big.Float).SetInt(slotValue)
+	value.Mul(value, big.NewFloat(maxValuePerc/100)) //nolint:gomnd
+	maxBid, _ := value.Int(nil)
+	if maxBid.Cmp(maxBidPerSlot) > 0 {
+		return new(big.Int).Set(maxBidPerSlot)
+	}
+	return maxBid
+}
@@ -0,0 +1,142 @@
+package bidder
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/chainbing/node/common"
+	dbUtils "github.com/chainbing/node/db"
+	"github.com/chainbing/node/db/historydb"
+	"github.com/chainbing/node/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type timer struct {
+	time int64
+}
+
+func (t *timer) Time() int64 {
+	currentTime := t.time
+	t.time++
+	return currentTime
+}
+
+var coordAddr = ethCommon.HexToAddress("0x6b175474e89094c44da98b954eedeac495271d0f")
+var otherCoordAddr = ethCommon.HexToAddress("0xc344E203a046Da13b0B4467EB7B3629D0C99F6E6")
+
+// cb returns an amount of CB tokens in token units
+func cb(amount string) *big.Int {
+	value, ok := new(big.Rat).SetString(amount)
+	if !ok {
+		panic("bad amount")
+	}
+	value.Mul(value, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)))
+	return new(big.Int).Quo(value.Num(), value.Denom())
+}
+
+func TestBidSlots(t *testing.T) {
+	db, err := dbUtils.InitTestSQLDB()
+	require.NoError(t, err)
+	test.WipeDB(db)
+	historyDB := historydb.NewHistoryDB(db, db, nil)
+
+	setup := test.NewClientSetupExample()
+	var timer timer
+	ethClient := test.NewClient(true, &timer, &coordAddr, setup)
+	_, err = ethClient.AuctionSetCoordinator(coordAddr, "https://foo.bar")
+	require.NoError(t, err)
+	// Move to the slot 2
+	for i := 0; i < 2*int(setup.AuctionConstants.BlocksPerSlot); i++ {
+		ethClient.CtlMineBlock()
+	}
+	currentSlot, err := ethClient.AuctionGetCurrentSlotNumber()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), currentSlot)
+
+	require.NoError(t, historyDB.SetInitialSCVars(setup.RollupVariables,
+		setup.AuctionVariables, setup.WDelayerVariables))
+	require.NoError(t, historyDB.AddBlock(&common.Block{Num: 1, Timestamp: time.Now()}))
+	require.NoError(t, historyDB.AddCoordinators([]common.Coordinator{
+		{Bidder: otherCoordAddr, Forger: otherCoordAddr, EthBlockNum: 1, URL: "https://bar.foo"},
+	}))
+	// The CB token is worth 2 USD, and the fees collected in the last slots
+	// are worth 40 CB in average.  The fees of the current slot are not
+	// counted.
+	require.NoError(t, historyDB.AddToken(&common.Token{TokenID: 1, EthBlockNum: 1,
+		EthAddr: setup.AuctionConstants.TokenCB, Name: "Chainbing", Symbol: "CB", Decimals: 18}))
+	require.NoError(t, historyDB.UpdateTokenValue(setup.AuctionConstants.TokenCB, 2))
+	for i, fees := range []string{"30", "50", "1000"} {
+		require.NoError(t, historyDB.AddBatch(&common.Batch{
+			BatchNum:      common.BatchNum(i + 1),
+			EthBlockNum:   1,
+			CollectedFees: map[common.TokenID]*big.Int{1: cb(fees)},
+			StateRoot:     big.NewInt(0),
+			ExitRoot:      big.NewInt(0),
+			SlotNum:       int64(i),
+		}))
+	}
+	// The slot 7 has a bid above our max bid
+	require.NoError(t, historyDB.AddBids([]common.Bid{
+		{SlotNum: 7, BidValue: cb("19"), EthBlockNum: 1, Bidder: otherCoordAddr},
+	}))
+
+	cfg := Config{
+		MaxBidPerSlot: cb("30"),
+		DailyBudget:   cb("30"),
+		MaxValuePerc:  50,
+		ValueSlots:    10,
+		MaxSlotsAhead: 3,
+	}
+	bidder, err := NewBidder(cfg, historyDB, ethClient, setup.AuctionConstants)
+	require.NoError(t, err)
+	assert.Equal(t, defaultInterval, bidder.cfg.Interval)
+
+	// The open slots are 5, 6 and 7, and the max bid is 20 CB
+	slotValue, err := bidder.slotValue(currentSlot)
+	require.NoError(t, err)
+	assert.Equal(t, cb("40"), slotValue)
+	now := time.Now()
+	require.NoError(t, bidder.BidSlots(now))
+	assert.Equal(t, map[int64]*big.Int{5: cb("11"), 6: cb("11")}, bidder.slotBids)
+	assert.Equal(t, cb("22"), bidder.spent())
+	ethClient.CtlMineBlock()
+	minBid, err := ethClient.AuctionGetMinBidBySlot(5)
+	require.NoError(t, err)
+	assert.Equal(t, cb("12.1"), minBid)
+
+	// The pending bids are not placed again
+	require.NoError(t, bidder.BidSlots(now))
+	assert.Equal(t, 2, len(bidder.bids))
+
+	// Once outbid, the slot is bid again within the daily budget
+	require.NoError(t, historyDB.AddBids([]common.Bid{
+		{SlotNum: 5, BidValue: cb("12.1"), EthBlockNum: 1, Bidder: otherCoordAddr},
+	}))
+	require.NoError(t, bidder.BidSlots(now))
+	assert.Equal(t, 2, len(bidder.bids))
+	now = now.Add(budgetPeriod)
+	require.NoError(t, bidder.BidSlots(now))
+	require.Equal(t, 1, len(bidder.bids))
+	assert.Equal(t, cb("13.31"), bidder.slotBids[5])
+	assert.Equal(t, cb("13.31"), bidder.spent())
+
+	// The bids synchronized in the last 24 hours are counted in the budget
+	// after a restart
+	require.NoError(t, historyDB.AddBids([]common.Bid{
+		{SlotNum: 6, BidValue: cb("11"), EthBlockNum: 1, Bidder: coordAddr},
+		{SlotNum: 5, BidValue: cb("13.31"), EthBlockNum: 1, Bidder: coordAddr},
+	}))
+	bidder, err = NewBidder(cfg, historyDB, ethClient, setup.AuctionConstants)
+	require.NoError(t, err)
+	assert.Equal(t, cb("24.31"), bidder.spent())
+}
+
+func TestMaxSlotBid(t *testing.T) {
+	assert.Nil(t, maxSlotBid(cb("30"), nil, 50))
+	assert.Equal(t, cb("20"), maxSlotBid(cb("30"), cb("40"), 50))
+	assert.Equal(t, cb("30"), maxSlotBid(cb("30"), cb("100"), 50))
+	assert.Equal(t, cb("11"), outbidAmount(cb("10"), 1000))
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -10,16 +12,19 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	ethKeystore "github.com/ethereum/go-ethereum/accounts/keystore"
 	ethCommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/chainbing/node/common"
 	"github.com/chainbing/node/config"
 	dbUtils "github.com/chainbing/node/db"
 	"github.com/chainbing/node/db/historydb"
 	"github.com/chainbing/node/db/kvdb"
 	"github.com/chainbing/node/db/l2db"
+	"github.com/chainbing/node/eth"
 	"github.com/chainbing/node/log"
 	"github.com/chainbing/node/node"
 	"github.com/chainbing/tracerr"
@@ -38,6 +43,19 @@ const (
 	modeCoord   = "coord"
 	nMigrations = "nMigrations"
 	flagAccount = "account"
+	flagName    = "name"
+	flagCoord   = "coordinator"
+	flagExpl    = "explorer"
+	flagRate    = "rate"
+	flagBurst   = "burst"
+	flagQuota   = "quota"
+	flagID      = "id"
+	flagFormat  = "format"
+	flagOutput  = "output"
+	flagFrom    = "from"
+	flagTo      = "to"
+	flagToken   = "token"
+	flagTxHash  = "txhash"
 )
 
 var (
@@ -366,6 +384,186 @@ func cmdGetAccountDetails(c *cli.Context) error {
 	return nil
 }
 
+func cmdGenAPIKey(c *cli.Context) error {
+	_cfg, err := parseCli(c)
+	if err != nil {
+		return tracerr.Wrap(fmt.Errorf("error parsing flags and config: %w", err))
+	}
+	cfg := _cfg.node
+	if !c.Bool(flagCoord) && !c.Bool(flagExpl) {
+		return tracerr.Wrap(fmt.Errorf("at least one of %v and %v must be set", flagCoord, flagExpl))
+	}
+	if c.Float64(flagRate) <= 0 || c.Int(flagBurst) < 1 {
+		return tracerr.Wrap(fmt.Errorf("%v must be positive and %v at least 1", flagRate, flagBurst))
+	}
+	historyDB, err := openDBConexion(cfg)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	var keyBytes [32]byte
+	if _, err := rand.Read(keyBytes[:]); err != nil {
+		return tracerr.Wrap(err)
+	}
+	key := hex.EncodeToString(keyBytes[:])
+	apiKey := historydb.APIKey{
+		KeyHash:          historydb.APIKeyHash(key),
+		Name:             c.String(flagName),
+		CoordinatorScope: c.Bool(flagCoord),
+		ExplorerScope:    c.Bool(flagExpl),
+		RateLimit:        c.Float64(flagRate),
+		Burst:            c.Int(flagBurst),
+	}
+	if c.IsSet(flagQuota) {
+		quota := c.Int(flagQuota)
+		apiKey.DailyTxQuota = &quota
+	}
+	if err := historyDB.AddAPIKey(&apiKey); err != nil {
+		return tracerr.Wrap(fmt.Errorf("historyDB.AddAPIKey: %w", err))
+	}
+	// The key is not stored, so this is the only time it's shown
+	fmt.Printf("API key id: %v\n", apiKey.ItemID)
+	fmt.Printf("API key:    %v\n", key)
+	return nil
+}
+
+func cmdRevokeAPIKey(c *cli.Context) error {
+	_cfg, err := parseCli(c)
+	if err != nil {
+		return tracerr.Wrap(fmt.Errorf("error parsing flags and config: %w", err))
+	}
+	cfg := _cfg.node
+	historyDB, err := openDBConexion(cfg)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	if err := historyDB.RevokeAPIKey(c.Uint64(flagID)); err != nil {
+		return tracerr.Wrap(fmt.Errorf("historyDB.RevokeAPIKey: %w", err))
+	}
+	log.Infof("API key %v revoked", c.Uint64(flagID))
+	return nil
+}
+
+func cmdExportTxs(c *cli.Context) error {
+	_cfg, err := parseCli(c)
+	if err != nil {
+		return tracerr.Wrap(fmt.Errorf("error parsing flags and config: %w", err))
+	}
+	cfg := _cfg.node
+	format := historydb.TxsExportFormat(c.String(flagFormat))
+	if format != historydb.TxsExportFormatCSV && format != historydb.TxsExportFormatJSONL {
+		return tracerr.Wrap(fmt.Errorf("invalid %v \"%v\"", flagFormat, format))
+	}
+	var request historydb.GetTxsAPIRequest
+	if c.IsSet(flagAccount) {
+		addr, bjj, accountIdxs, err := checkAccountParam(c)
+		if err != nil {
+			return tracerr.Wrap(err)
+		}
+		request.EthAddr = addr
+		request.Bjj = bjj
+		if len(accountIdxs) > 0 {
+			request.Idx = &accountIdxs[0]
+		}
+	}
+	if c.IsSet(flagToken) {
+		tokenID := common.TokenID(c.Uint(flagToken))
+		request.TokenID = &tokenID
+	}
+	for _, flag := range []struct {
+		name string
+		ts   **time.Time
+	}{{flagFrom, &request.FromTimestamp}, {flagTo, &request.ToTimestamp}} {
+		if !c.IsSet(flag.name) {
+			continue
+		}
+		ts, err := time.Parse(time.RFC3339, c.String(flag.name))
+		if err != nil {
+			return tracerr.Wrap(fmt.Errorf("invalid %v: %w", flag.name, err))
+		}
+		*flag.ts = &ts
+	}
+	historyDB, err := openDBConexion(cfg)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	file, err := os.Create(c.String(flagOutput))
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer file.Close() //nolint:errcheck
+	log.Infof("Exporting txs to %v...", c.String(flagOutput))
+	if err := historyDB.ExportTxsAPI(file, format, request); err != nil {
+		return tracerr.Wrap(fmt.Errorf("historyDB.ExportTxsAPI: %w", err))
+	}
+	return tracerr.Wrap(file.Close())
+}
+
+func cmdDecodeBatch(c *cli.Context) error {
+	_cfg, err := parseCli(c)
+	if err != nil {
+		return tracerr.Wrap(fmt.Errorf("error parsing flags and config: %w", err))
+	}
+	cfg := _cfg.node
+	txHash := ethCommon.HexToHash(c.String(flagTxHash))
+	historyDB, err := openDBConexion(cfg)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	constants, err := historyDB.GetConstants()
+	if err != nil {
+		return tracerr.Wrap(fmt.Errorf("historyDB.GetConstants: %w", err))
+	}
+	batchNum, err := historyDB.GetBatchNumByEthTxHash(txHash)
+	if err != nil {
+		return tracerr.Wrap(fmt.Errorf("historyDB.GetBatchNumByEthTxHash: %w", err))
+	}
+	batch, err := historyDB.GetBatchInternalAPI(batchNum)
+	if err != nil {
+		return tracerr.Wrap(fmt.Errorf("historyDB.GetBatchInternalAPI: %w", err))
+	}
+	txs, err := historyDB.GetBatchForgedTxs(batchNum)
+	if err != nil {
+		return tracerr.Wrap(fmt.Errorf("historyDB.GetBatchForgedTxs: %w", err))
+	}
+
+	ethClient, err := ethclient.Dial(cfg.Web3.URL)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	ethereumClient, err := eth.NewEthereumClient(ethClient, nil, nil, nil)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	rollupClient, err := eth.NewRollupClient(ethereumClient, constants.ChainbingAddress)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	args, _, err := rollupClient.RollupForgeBatchArgs(txHash, uint16(len(txs.L1UserTxs)))
+	if err != nil {
+		return tracerr.Wrap(fmt.Errorf("rollupClient.RollupForgeBatchArgs: %w", err))
+	}
+	dataAvailability, err := eth.NewForgeBatchDataAvailability(args, len(txs.L1UserTxs),
+		&constants.Rollup)
+	if err != nil {
+		return tracerr.Wrap(fmt.Errorf("eth.NewForgeBatchDataAvailability: %w", err))
+	}
+	mismatches := dataAvailability.Mismatches(txs.L1CoordinatorTxs, txs.L2Txs)
+	for _, mismatch := range mismatches {
+		log.Warnw("Batch data availability doesn't match the HistoryDB",
+			"batchNum", batchNum, "mismatch", mismatch)
+	}
+	out, err := json.MarshalIndent(struct {
+		Batch            *historydb.BatchAPI             `json:"batch"`
+		DataAvailability *eth.ForgeBatchDataAvailability `json:"dataAvailability"`
+		Mismatches       []string                        `json:"mismatches"`
+	}{batch, dataAvailability, mismatches}, "", "  ")
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
 func cmdDiscard(c *cli.Context) error {
 	_cfg, err := parseCli(c)
 	if err != nil {
@@ -418,6 +616,7 @@ func cmdDiscard(c *cli.Context) error {
 		cfg.Coordinator.L2DB.MaxTxs,
 		cfg.Coordinator.L2DB.MinFeeUSD,
 		cfg.Coordinator.L2DB.MaxFeeUSD,
+		cfg.Coordinator.L2DB.MinFeeBumpPerc,
 		cfg.Coordinator.L2DB.TTL.Duration,
 		nil,
 	)
@@ -628,6 +827,105 @@ func main() {
 					Required: true,
 				}),
 		},
+		{
+			Name:    "export-txs",
+			Aliases: []string{},
+			Usage:   "Export the transaction history as CSV or JSONL for accounting",
+			Action:  cmdExportTxs,
+			Flags: append(flags,
+				&cli.StringFlag{
+					Name:     flagOutput,
+					Usage:    "output `FILE`",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:     flagFormat,
+					Usage:    "export `FORMAT` (csv or jsonl)",
+					Value:    string(historydb.TxsExportFormatCSV),
+					Required: false,
+				},
+				&cli.StringFlag{
+					Name:     flagAccount,
+					Usage:    "only export the txs of the account (address, BJJ or account index)",
+					Required: false,
+				},
+				&cli.UintFlag{
+					Name:     flagToken,
+					Usage:    "only export the txs of the token `ID`",
+					Required: false,
+				},
+				&cli.StringFlag{
+					Name:     flagFrom,
+					Usage:    "only export the txs forged from this RFC3339 `TIMESTAMP` (included)",
+					Required: false,
+				},
+				&cli.StringFlag{
+					Name:     flagTo,
+					Usage:    "only export the txs forged until this RFC3339 `TIMESTAMP` (excluded)",
+					Required: false,
+				}),
+		},
+		{
+			Name:    "decode-batch",
+			Aliases: []string{},
+			Usage:   "Decode the data availability of a forged batch and compare it with the HistoryDB",
+			Action:  cmdDecodeBatch,
+			Flags: append(flags,
+				&cli.StringFlag{
+					Name:     flagTxHash,
+					Usage:    "`HASH` of the ethereum tx that forged the batch",
+					Required: true,
+				}),
+		},
+		{
+			Name:    "genapikey",
+			Aliases: []string{},
+			Usage:   "Generate a new API key with its own rate limits and daily tx quota",
+			Action:  cmdGenAPIKey,
+			Flags: append(flags,
+				&cli.StringFlag{
+					Name:     flagName,
+					Usage:    "`NAME` of the client that uses the key",
+					Required: true,
+				},
+				&cli.BoolFlag{
+					Name:     flagCoord,
+					Usage:    "allow the key to use the coordinator endpoints",
+					Required: false,
+				},
+				&cli.BoolFlag{
+					Name:     flagExpl,
+					Usage:    "allow the key to use the explorer endpoints",
+					Required: false,
+				},
+				&cli.Float64Flag{
+					Name:     flagRate,
+					Usage:    "requests per second allowed",
+					Required: true,
+				},
+				&cli.IntFlag{
+					Name:     flagBurst,
+					Usage:    "requests allowed at once",
+					Required: true,
+				},
+				&cli.IntFlag{
+					Name:     flagQuota,
+					Usage:    "tx submissions allowed per day (unlimited if not set)",
+					Required: false,
+				}),
+		},
+		{
+			Name:    "revokeapikey",
+			Aliases: []string{},
+			Usage:   "Revoke an API key",
+			Action:  cmdRevokeAPIKey,
+			Flags: append(flags,
+				&cli.Uint64Flag{
+					Name:     flagID,
+					Usage:    "`ID` of the API key",
+					Required: true,
+				}),
+		},
 	}
 
 	err := app.Run(os.Args)
@@ -38,7 +38,8 @@ func StringToL2TxState(txState string) (*PoolL2TxState, error) {
 	}
 	txStateCasted := PoolL2TxState(txState)
 	switch txStateCasted {
-	case PoolL2TxStatePending, PoolL2TxStateForged, PoolL2TxStateForging, PoolL2TxStateInvalid:
+	case PoolL2TxStatePending, PoolL2TxStateForged, PoolL2TxStateForging, PoolL2TxStateInvalid,
+		PoolL2TxStateCancelled:
 		return &txStateCasted, nil
 	default:
 		return nil, tracerr.Wrap(fmt.Errorf(
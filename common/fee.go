@@ -56,6 +56,24 @@ func CalcFeeAmount(amount *big.Int, feeSel FeeSelector) (*big.Int, error) {
 	return feeAmount, nil
 }
 
+// MinFeeSelector returns the lowest FeeSelector whose fee for the amount is at
+// least minFeeAmount, together with the resulting fee amount
+func MinFeeSelector(amount, minFeeAmount *big.Int) (FeeSelector, *big.Int, error) {
+	for i := 0; i < MaxFeePlan; i++ {
+		feeAmount, err := CalcFeeAmount(amount, FeeSelector(i))
+		if err != nil {
+			// The fee amount only grows with the FeeSelector, so
+			// the next ones would overflow too
+			break
+		}
+		if feeAmount.Cmp(minFeeAmount) >= 0 {
+			return FeeSelector(i), feeAmount, nil
+		}
+	}
+	return 0, nil, tracerr.Wrap(fmt.Errorf(
+		"no FeeSelector reaches a fee of %v for an amount of %v", minFeeAmount, amount))
+}
+
 func init() {
 	setFeeFactorLsh60(&FeeFactorLsh60)
 }
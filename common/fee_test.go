@@ -47,6 +47,33 @@ func TestCalcFeeAmount(t *testing.T) {
 	assert.Equal(t, "1", feeAmount.String())
 }
 
+func TestMinFeeSelector(t *testing.T) {
+	v := big.NewInt(1000)
+	feeSel, feeAmount, err := MinFeeSelector(v, big.NewInt(0))
+	assert.NoError(t, err)
+	assert.Equal(t, FeeSelector(0), feeSel)
+	assert.Equal(t, "0", feeAmount.String())
+
+	feeSel, feeAmount, err = MinFeeSelector(v, big.NewInt(1))
+	assert.NoError(t, err)
+	assert.Equal(t, FeeSelector(31), feeSel)
+	assert.Equal(t, "1", feeAmount.String())
+
+	feeSel, feeAmount, err = MinFeeSelector(v, big.NewInt(500))
+	assert.NoError(t, err)
+	assert.Equal(t, FeeSelector(172), feeSel)
+	assert.Equal(t, "500", feeAmount.String())
+
+	feeSel, feeAmount, err = MinFeeSelector(v, big.NewInt(1000))
+	assert.NoError(t, err)
+	assert.Equal(t, FeeSelector(192), feeSel)
+	assert.Equal(t, "1000", feeAmount.String())
+
+	// The fee of an amount of 0 is always 0
+	_, _, err = MinFeeSelector(big.NewInt(0), big.NewInt(1))
+	assert.Error(t, err)
+}
+
 func TestFeePrintSQLSwitch(t *testing.T) {
 	debug := false
 	for i := 0; i < 256; i++ {
@@ -0,0 +1,51 @@
+package common
+
+import (
+	"math/big"
+
+	"github.com/chainbing/tracerr"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+	"github.com/iden3/go-iden3-crypto/poseidon"
+)
+
+// PoolL2TxStateCancelled is used when the tx has been cancelled by its
+// owner, cancelled txs are never selected to be forged
+const PoolL2TxStateCancelled PoolL2TxState = "cncl"
+
+// cancelTxMsgPrefix is the prefix of the message signed to cancel a
+// PoolL2Tx, used to avoid reusing the signature of other kind of messages
+var cancelTxMsgPrefix = new(big.Int).SetBytes([]byte("CANCEL_TX"))
+
+// CancelTxHashToSign returns the hash of the message that the owner of a
+// PoolL2Tx signs to cancel it
+func CancelTxHashToSign(chainID uint16, txID TxID) (*big.Int, error) {
+	// The TxID doesn't fit in a single element of the finite field, so
+	// it's split in two
+	txIDHi := new(big.Int).SetBytes(txID[:TxIDLen/2])
+	txIDLo := new(big.Int).SetBytes(txID[TxIDLen/2:])
+	return poseidon.Hash([]*big.Int{
+		cancelTxMsgPrefix,
+		big.NewInt(int64(chainID)),
+		txIDHi,
+		txIDLo,
+	})
+}
+
+// VerifyCancelTxSignature returns true if the signature of the cancel
+// message of the PoolL2Tx has been done with the private key of pk
+func VerifyCancelTxSignature(chainID uint16, txID TxID, pk babyjub.PublicKeyComp,
+	signature babyjub.SignatureComp) (bool, error) {
+	h, err := CancelTxHashToSign(chainID, txID)
+	if err != nil {
+		return false, tracerr.Wrap(err)
+	}
+	pkDecomp, err := pk.Decompress()
+	if err != nil {
+		return false, tracerr.Wrap(err)
+	}
+	sigDecomp, err := signature.Decompress()
+	if err != nil {
+		return false, nil
+	}
+	return pkDecomp.VerifyPoseidon(h, sigDecomp), nil
+}
@@ -0,0 +1,48 @@
+package common
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/iden3/go-iden3-crypto/babyjub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyCancelTxSignature(t *testing.T) {
+	var sk babyjub.PrivateKey
+	_, err := hex.Decode(sk[:],
+		[]byte("0001020304050607080900010203040506070809000102030405060708090001"))
+	require.NoError(t, err)
+	pk := sk.Public().Compress()
+	chainID := uint16(5)
+	txID, err := NewTxIDFromString("0x022669acda59b827d20ef5354a3eebd1dffb3972b0a6bf89d18bfd2efa0ab9f41e")
+	require.NoError(t, err)
+	otherTxID, err := NewTxIDFromString("0x029e7499a830f8f5eb17c07da48cf91415710f1bcbe0169d363ff91e81faf92fc2")
+	require.NoError(t, err)
+
+	h, err := CancelTxHashToSign(chainID, txID)
+	require.NoError(t, err)
+	signature := sk.SignPoseidon(h).Compress()
+
+	ok, err := VerifyCancelTxSignature(chainID, txID, pk, signature)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// The signature is only valid for the signed TxID and chainID
+	ok, err = VerifyCancelTxSignature(chainID, otherTxID, pk, signature)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = VerifyCancelTxSignature(chainID+1, txID, pk, signature)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// The signature is only valid for the key of the signer
+	var otherSk babyjub.PrivateKey
+	_, err = hex.Decode(otherSk[:],
+		[]byte("0001020304050607080900010203040506070809000102030405060708090002"))
+	require.NoError(t, err)
+	ok, err = VerifyCancelTxSignature(chainID, txID, otherSk.Public().Compress(), signature)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,50 @@
+package common
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"github.com/chainbing/tracerr"
+)
+
+// TxSelectorErrorDetails are the structured details of the reason why a pool
+// tx was not selected, so that they can be handled without parsing the
+// message.  Only the fields related to the error type are set.
+type TxSelectorErrorDetails struct {
+	// RequiredBalance is the amount + fee of the tx (ErrSenderNotEnoughBalance)
+	RequiredBalance *string `json:"requiredBalance,omitempty"`
+	// AvailableBalance is the balance of the sender (ErrSenderNotEnoughBalance)
+	AvailableBalance *string `json:"availableBalance,omitempty"`
+	// ExpectedNonce is the nonce of the sender account (ErrNoCurrentNonce)
+	ExpectedNonce *Nonce `json:"expectedNonce,omitempty"`
+	// ProvidedNonce is the nonce of the tx (ErrNoCurrentNonce)
+	ProvidedNonce *Nonce `json:"providedNonce,omitempty"`
+	// MaxNumBatch is the maximum batch of the tx (ErrUnsupportedMaxNumBatch)
+	MaxNumBatch *uint32 `json:"maxNumBatch,omitempty"`
+	// BatchNum is the batch being selected (ErrUnsupportedMaxNumBatch)
+	BatchNum *BatchNum `json:"batchNum,omitempty"`
+	// ToIdx is the receiver account that doesn't exist (ErrToIdxNotFound)
+	ToIdx *Idx `json:"toIdx,omitempty"`
+	// AtomicGroupID is the atomic group that failed, if the tx is atomic
+	AtomicGroupID *AtomicGroupID `json:"atomicGroupId,omitempty"`
+	// FailedTxID is the tx that made the atomic group fail
+	FailedTxID *TxID `json:"failedTxId,omitempty"`
+	// Cause is the error returned while processing the tx
+	// (ErrTxDiscartedInProcessTxToEthAddrBJJ, ErrTxDiscartedInProcessL2Tx)
+	Cause *string `json:"cause,omitempty"`
+}
+
+// Scan implements Scanner for database/sql.
+func (d *TxSelectorErrorDetails) Scan(src interface{}) error {
+	srcB, ok := src.([]byte)
+	if !ok {
+		return tracerr.Wrap(fmt.Errorf("can't scan %T into TxSelectorErrorDetails", src))
+	}
+	return tracerr.Wrap(json.Unmarshal(srcB, d))
+}
+
+// Value implements valuer for database/sql.
+func (d TxSelectorErrorDetails) Value() (driver.Value, error) {
+	return json.Marshal(d)
+}
@@ -51,6 +51,135 @@ type ForgeBatchGasCost struct {
 type CoordinatorAPI struct {
 	// Coordinator enables the coordinator API endpoints
 	Coordinator bool
+	// Webhooks enables the coordinator API endpoints used to register
+	// webhooks and get their delivery log.  The webhooks are registered
+	// with an API key, so the API keys must be enabled.
+	Webhooks bool
+	// AdminToken enables the admin API endpoints, used to list, add and
+	// remove the server proofs while the node is running.  The requests
+	// must have the header "Authorization: Bearer <AdminToken>".  If
+	// empty, the admin endpoints are disabled.
+	AdminToken string
+}
+
+// APIKeys specifies the configuration parameters of the API keys, which are
+// created with the genapikey command, and the rate limits of the coordinator
+// and explorer endpoints
+type APIKeys struct {
+	// Enabled enables the authentication with API keys and the rate
+	// limits
+	Enabled bool
+	// Required rejects the requests without an API key
+	Required bool
+	// IPRateLimit is the number of requests per second allowed from each
+	// IP for the requests without an API key.  0 disables the limit.
+	IPRateLimit float64 `validate:"gte=0"`
+	// IPBurst is the number of requests allowed at once from each IP for
+	// the requests without an API key
+	IPBurst int `validate:"gte=0"`
+	// TrustedProxies are the IPs or CIDRs of the reverse proxies in front
+	// of the API.  The IP of the requests that come from them is read from
+	// the X-Forwarded-For header, which is ignored otherwise.
+	TrustedProxies []string
+}
+
+// OpenAPIValidation specifies the configuration parameters of the validation
+// of the API requests against the OpenAPI spec
+type OpenAPIValidation struct {
+	// Enabled enables the validation of the requests, which are rejected
+	// if they don't match the spec
+	Enabled bool
+	// SpecPath is the path of the OpenAPI spec (api/swagger.yml)
+	SpecPath string `validate:"required_with=Enabled"`
+}
+
+// HTTPCache specifies the configuration parameters of the HTTP caching of
+// the explorer endpoints whose data only changes with new blocks
+type HTTPCache struct {
+	// Enabled enables the cache
+	Enabled bool
+	// Size is the maximum number of responses kept in memory
+	Size int `validate:"required_with=Enabled"`
+	// MaxAge is the max-age of the responses that can change with the
+	// next block, and the maximum time they are kept in memory
+	MaxAge Duration
+	// ImmutableMaxAge is the max-age of the responses of historic
+	// batches
+	ImmutableMaxAge Duration
+	// ImmutableBatches is the number of batches after which a batch is
+	// considered historic
+	ImmutableBatches int
+	// LastBlockInterval is the time the last synchronized block is
+	// cached before reading it again from the DB
+	LastBlockInterval Duration
+}
+
+// ForgePolicy specifies the policy used by the coordinator to decide if a
+// batch is forged
+type ForgePolicy struct {
+	// Type is the policy.  "default" forges according to ForgeDelay,
+	// ForgeNoTxsDelay, IgnoreSlotCommitment and ForgeOncePerSlotIfTxs,
+	// and "profit" only forges the batches whose fees pay their gas cost.
+	// If empty, the default policy is used.
+	Type string `validate:"omitempty,oneof=default profit"`
+	// ProfitMarginPerc is the percentage by which the fees in USD of a
+	// batch must exceed its estimated gas cost to be forged with the
+	// profit policy
+	ProfitMarginPerc float64 `validate:"gte=0"`
+}
+
+// ProversPool specifies the health checks and the failover of the server
+// proofs
+type ProversPool struct {
+	// HealthCheckInterval is the waiting interval between health
+	// checks of the idle server proofs.  If not set, 10s is used.
+	HealthCheckInterval Duration
+	// HealthCheckTimeout is the time a server proof has to report
+	// that it's ready in a health check.  If not set, 5s is used.
+	HealthCheckTimeout Duration
+	// ProofTimeout is the time after which a proof that hasn't
+	// been calculated is sent to another server proof.  If set to
+	// 0s, the proofs don't time out.
+	ProofTimeout Duration
+	// MaxErrors is the number of consecutive errors after which a
+	// server proof is quarantined.  If not set, 3 is used.
+	MaxErrors int `validate:"gte=0"`
+	// QuarantineTime is the time during which a quarantined
+	// server proof is not used.  If set to 0s, a failing server
+	// proof is used again once it passes a health check.
+	QuarantineTime Duration
+}
+
+// Bidder specifies the configuration parameters of the automated bidding in
+// the auction of the slots
+type Bidder struct {
+	// Enabled enables the automated bidding
+	Enabled bool
+	// Address is the account that places the bids, which must be in
+	// the keystore.  It must be different from the ForgerAddress, so
+	// that the nonces of the bids don't conflict with the ones of the
+	// forge calls.
+	Address ethCommon.Address `validate:"required_with=Enabled"`
+	// Interval is the waiting time between checks of the open
+	// slots
+	Interval Duration `validate:"required_with=Enabled"`
+	// MaxBidPerSlot is the maximum bid for a slot in CB token
+	// units
+	MaxBidPerSlot *big.Int `validate:"required_with=Enabled"`
+	// DailyBudget is the maximum amount of CB token units bid in
+	// 24 hours
+	DailyBudget *big.Int `validate:"required_with=Enabled"`
+	// MaxValuePerc is the maximum percentage of the estimated
+	// value of a slot that is bid for it
+	MaxValuePerc float64 `validate:"required_with=Enabled"`
+	// ValueSlots is the number of last slots with forged batches
+	// whose collected fees are averaged to estimate the value of a
+	// slot
+	ValueSlots int `validate:"required_with=Enabled"`
+	// MaxSlotsAhead is the number of open slots, starting from the
+	// first one, for which bids are placed.  If set to 0, bids are
+	// placed for all the open slots.
+	MaxSlotsAhead int `validate:"gte=0"`
 }
 
 // Coordinator is the coordinator specific configuration.
@@ -124,6 +253,8 @@ type Coordinator struct {
 	// overrides `ForgeDelay`, `ForgeNoTxsDelay`, `MustForgeAtSlotDeadline`
 	// and `IgnoreSlotCommitment`.
 	ForgeOncePerSlotIfTxs bool
+	// ForgePolicy is the policy used to decide if a batch is forged
+	ForgePolicy ForgePolicy
 	// SyncRetryInterval is the waiting interval between calls to the main
 	// handler of a synced block after an error
 	SyncRetryInterval Duration `validate:"required"`
@@ -149,6 +280,10 @@ type Coordinator struct {
 		// order to be accepted into the pool.  Txs with greater than
 		// maximum fee will be rejected at the API level.
 		MaxFeeUSD float64 `validate:"required,gte=0"`
+		// MinFeeBumpPerc is the minimum increase of the fee, in
+		// percentage, that a tx must pay in order to replace a
+		// pending tx with the same sender and nonce.
+		MinFeeBumpPerc float64 `validate:"gte=0"`
 		// TTL is the Time To Live for L2Txs in the pool. L2Txs older
 		// than TTL will be deleted.
 		TTL Duration `validate:"required"`
@@ -182,7 +317,11 @@ type Coordinator struct {
 		Path string `validate:"required"`
 	} `validate:"required"`
 	ServerProofs []ServerProof `validate:"required"`
-	Circuit      struct {
+	// ProversPool specifies the health checks and the failover of the
+	// server proofs.  The server proofs can also be added and removed
+	// while the node is running with the admin API endpoints.
+	ProversPool ProversPool
+	Circuit     struct {
 		// MaxTx is the maximum number of txs supported by the circuit
 		MaxTx int64 `validate:"required,gte=0"`
 		// NLevels is the maximum number of merkle tree levels
@@ -191,13 +330,17 @@ type Coordinator struct {
 	} `validate:"required"`
 	EthClient struct {
 		// MaxGasPrice is the maximum gas price allowed for ethereum
-		// transactions
+		// transactions.  In dynamic fee transactions it caps the
+		// maxFeePerGas.
 		MaxGasPrice int64 `validate:"required"`
 		// MinGasPrice is the minimum gas price in gwei allowed for ethereum
+		// transactions.  In dynamic fee transactions it is the minimum
+		// maxFeePerGas.
 		MinGasPrice int64 `validate:"required"`
 		// GasPriceIncPerc is the percentage increase of gas price set
 		// in an ethereum transaction from the suggested gas price by
-		// the ethereum node
+		// the ethereum node.  In dynamic fee transactions it increases
+		// the maxPriorityFeePerGas.
 		GasPriceIncPerc int64 `validate:"gte=0"`
 		// CheckLoopInterval is the waiting interval between receipt
 		// checks of ethereum transactions in the TxManager
@@ -226,8 +369,36 @@ type Coordinator struct {
 		// ForgeBatch transaction.
 		ForgeBatchGasCost ForgeBatchGasCost `validate:"required"`
 	} `validate:"required"`
-	API   CoordinatorAPI `validate:"required"`
-	Debug struct {
+	API CoordinatorAPI `validate:"required"`
+	// Webhooks specifies the configuration parameters of the delivery of
+	// the events to the webhooks, which is done when API.Webhooks is
+	// enabled
+	Webhooks struct {
+		// Interval is the waiting time between checks of pending
+		// deliveries
+		Interval Duration
+		// Timeout is the maximum duration of each delivery request
+		Timeout Duration
+		// BatchSize is the maximum number of deliveries attempted at
+		// each check
+		BatchSize int `validate:"gte=0"`
+		// MaxAttempts is the number of attempts after which a delivery
+		// is marked as failed
+		MaxAttempts int `validate:"gte=0"`
+		// MinBackoff is the waiting time after the first failed attempt
+		// of a delivery, which is doubled after each failed attempt
+		MinBackoff Duration
+		// MaxBackoff is the maximum waiting time between attempts of a
+		// delivery
+		MaxBackoff Duration
+		// KeepDeliveries is the time that the done and failed
+		// deliveries are kept for the delivery log
+		KeepDeliveries Duration
+	}
+	// Bidder specifies the configuration parameters of the automated
+	// bidding in the auction of the slots
+	Bidder Bidder
+	Debug  struct {
 		// BatchPath if set, specifies the path where batchInfo is stored
 		// in JSON in every step/update of the pipeline
 		BatchPath string
@@ -285,6 +456,10 @@ type NodeDebug struct {
 	// GinDebugMode sets Gin-Gonic (the web framework) to run in
 	// debug mode
 	GinDebugMode bool
+	// OpenAPIResponseValidation validates the API responses against the
+	// OpenAPI spec when API.OpenAPIValidation is enabled, logging the
+	// responses that don't match it and the routes that are not in it
+	OpenAPIResponseValidation bool
 }
 
 // Node is the chainbing node configuration.
@@ -352,6 +527,19 @@ type Node struct {
 		// SQLConnectionTimeout is the maximum amount of time that an API request
 		// can wait to establish a SQL connection
 		SQLConnectionTimeout Duration
+		// Stream enables the /stream endpoint, which pushes the events
+		// notified by the SQL DB (new batches, bids, slots and pool tx
+		// state changes) to the subscribed clients
+		Stream bool
+		// APIKeys specifies the configuration of the API keys and the
+		// rate limits
+		APIKeys APIKeys
+		// OpenAPIValidation specifies the configuration of the
+		// validation of the requests against the OpenAPI spec
+		OpenAPIValidation OpenAPIValidation
+		// HTTPCache specifies the configuration of the HTTP caching of
+		// the explorer endpoints
+		HTTPCache HTTPCache
 	} `validate:"required"`
 	RecommendedFeePolicy stateapiupdater.RecommendedFeePolicy `validate:"required"`
 	Debug                NodeDebug                            `validate:"required"`
@@ -371,12 +559,31 @@ type APIServer struct {
 		// SQLConnectionTimeout is the maximum amount of time that an API request
 		// can wait to establish a SQL connection
 		SQLConnectionTimeout Duration
+		// Stream enables the /stream endpoint, which pushes the events
+		// notified by the SQL DB (new batches, bids, slots and pool tx
+		// state changes) to the subscribed clients
+		Stream bool
+		// APIKeys specifies the configuration of the API keys and the
+		// rate limits
+		APIKeys APIKeys
+		// OpenAPIValidation specifies the configuration of the
+		// validation of the requests against the OpenAPI spec
+		OpenAPIValidation OpenAPIValidation
+		// HTTPCache specifies the configuration of the HTTP caching of
+		// the explorer endpoints
+		HTTPCache HTTPCache
 	} `validate:"required"`
 	PostgreSQL  PostgreSQL `validate:"required"`
 	Coordinator struct {
 		API struct {
 			// Coordinator enables the coordinator API endpoints
 			Coordinator bool
+			// Webhooks enables the coordinator API endpoints used to
+			// register webhooks and get their delivery log.  The
+			// events are delivered by the node in coordinator mode.
+			// The webhooks are registered with an API key, so the
+			// API keys must be enabled.
+			Webhooks bool
 		} `validate:"required"`
 		L2DB struct {
 			// MaxTxs is the maximum number of pending L2Txs that can be
@@ -392,6 +599,10 @@ type APIServer struct {
 			// order to be accepted into the pool.  Txs with greater than
 			// maximum fee will be rejected at the API level.
 			MaxFeeUSD float64 `validate:"required,gte=0"`
+			// MinFeeBumpPerc is the minimum increase of the fee, in
+			// percentage, that a tx must pay in order to replace a
+			// pending tx with the same sender and nonce.
+			MinFeeBumpPerc float64 `validate:"gte=0"`
 		} `validate:"required"`
 	}
 	Debug NodeDebug `validate:"required"`
@@ -26,6 +26,9 @@ const (
 	StatusForged Status = "forged"
 	// StatusProof marks the batch as proof calculated
 	StatusProof Status = "proof"
+	// StatusSigned marks the EthTx as signed and stored, but maybe not
+	// sent
+	StatusSigned Status = "signed"
 	// StatusSent marks the EthTx as Sent
 	StatusSent Status = "sent"
 	// StatusMined marks the EthTx as Mined
@@ -50,6 +50,7 @@ import (
 
 	ethCommon "github.com/ethereum/go-ethereum/common"
 	"github.com/chainbing/node/batchbuilder"
+	"github.com/chainbing/node/bidder"
 	"github.com/chainbing/node/common"
 	"github.com/chainbing/node/config"
 	"github.com/chainbing/node/db/historydb"
@@ -61,6 +62,7 @@ import (
 	"github.com/chainbing/node/synchronizer"
 	"github.com/chainbing/node/txprocessor"
 	"github.com/chainbing/node/txselector"
+	"github.com/chainbing/node/webhook"
 	"github.com/chainbing/tracerr"
 )
 
@@ -137,6 +139,8 @@ type Config struct {
 	// overrides `ForgeDelay`, `ForgeNoTxsDelay`, `MustForgeAtSlotDeadline`
 	// and `IgnoreSlotCommitment`.
 	ForgeOncePerSlotIfTxs bool
+	// ForgePolicy is the policy used to decide if a batch is forged
+	ForgePolicy config.ForgePolicy
 	// SyncRetryInterval is the waiting interval between calls to the main
 	// handler of a synced block after an error
 	SyncRetryInterval time.Duration
@@ -155,13 +159,17 @@ type Config struct {
 	// new replacement transactions
 	EthNoReuseNonce bool
 	// MaxGasPrice is the maximum gas price in gwei allowed for ethereum
-	// transactions
+	// transactions.  In dynamic fee transactions it caps the
+	// maxFeePerGas.
 	MaxGasPrice int64
 	// MinGasPrice is the minimum gas price in gwei allowed for ethereum
+	// transactions.  In dynamic fee transactions it is the minimum
+	// maxFeePerGas.
 	MinGasPrice int64
 	// GasPriceIncPerc is the percentage increase of gas price set in an
 	// ethereum transaction from the suggested gas price by the ehtereum
-	// node
+	// node.  In dynamic fee transactions it increases the
+	// maxPriorityFeePerGas.
 	GasPriceIncPerc int64
 	// TxManagerCheckInterval is the waiting interval between receipt
 	// checks of ethereum transactions in the TxManager
@@ -170,6 +178,10 @@ type Config struct {
 	// in JSON in every step/update of the pipeline
 	DebugBatchPath string
 	Purger         PurgerCfg
+	ProversPool    ProversPoolCfg
+	// ProofServerPollInterval is the waiting interval between polling the
+	// server proofs added with AddServerProof for a calculated proof
+	ProofServerPollInterval time.Duration
 	// VerifierIdx is the index of the verifier contract registered in the
 	// smart contract
 	VerifierIdx uint8
@@ -177,6 +189,17 @@ type Config struct {
 	// ForgeBatch transaction.
 	ForgeBatchGasCost config.ForgeBatchGasCost
 	TxProcessorConfig txprocessor.Config
+	// Webhooks is the configuration of the delivery of the events to the
+	// webhooks.  If nil, the events are not delivered.
+	Webhooks *webhook.Config
+	// Bidder is the configuration of the automated bidding in the auction
+	// of the slots.  If nil, no bids are placed.
+	Bidder *bidder.Config
+	// BidderEthClient is the ethereum client of the account that places
+	// the bids, which must be different from the forger account so that
+	// the nonces of the bids don't conflict with the ones of the forge
+	// calls.  Required if Bidder is set.
+	BidderEthClient eth.ClientInterface
 }
 
 func (c *Config) debugBatchStore(batchInfo *BatchInfo) {
@@ -199,7 +222,7 @@ type Coordinator struct {
 	// State
 	pipelineNum       int       // Pipeline sequential number.  The first pipeline is 1
 	pipelineFromBatch fromBatch // batch from which we started the pipeline
-	provers           []prover.Client
+	proversPool       *ProversPool
 	consts            common.SCConsts
 	vars              common.SCVariables
 	stats             synchronizer.Stats
@@ -229,8 +252,10 @@ type Coordinator struct {
 	pipeline              *Pipeline
 	lastNonFailedBatchNum common.BatchNum
 
-	purger    *Purger
-	txManager *TxManager
+	purger     *Purger
+	txManager  *TxManager
+	dispatcher *webhook.Dispatcher
+	bidder     *bidder.Bidder
 }
 
 // NewCoordinator creates a new Coordinator
@@ -267,9 +292,9 @@ func NewCoordinator(cfg Config,
 			ForgerAddr: ethCommon.Address{},
 			StateRoot:  big.NewInt(0),
 		},
-		provers: serverProofs,
-		consts:  *scConsts,
-		vars:    *initSCVars,
+		proversPool: NewProversPool(cfg.ProversPool, serverProofs),
+		consts:      *scConsts,
+		vars:        *initSCVars,
 
 		cfg: cfg,
 
@@ -293,6 +318,31 @@ func NewCoordinator(cfg Config,
 		return nil, tracerr.Wrap(err)
 	}
 	c.txManager = txManager
+	// The batches restored by the TxManager may still be mined, so the
+	// first pipeline forges the batches that follow them
+	c.lastNonFailedBatchNum = txManager.lastRestoredBatchNum()
+	if cfg.Webhooks != nil {
+		c.dispatcher = webhook.NewDispatcher(*cfg.Webhooks, l2DB)
+	}
+	if cfg.Bidder != nil {
+		if cfg.BidderEthClient == nil {
+			return nil, tracerr.Wrap(fmt.Errorf("the bidder requires the eth client of " +
+				"the bidder account"))
+		}
+		var bidderAddr *ethCommon.Address
+		if bidderAddr, err = cfg.BidderEthClient.EthAddress(); err != nil {
+			return nil, tracerr.Wrap(err)
+		}
+		if *bidderAddr == cfg.ForgerAddress {
+			return nil, tracerr.Wrap(fmt.Errorf("the bidder account (%v) must be "+
+				"different from the forger account", bidderAddr))
+		}
+		c.bidder, err = bidder.NewBidder(*cfg.Bidder, historyDB, cfg.BidderEthClient,
+			&scConsts.Auction)
+		if err != nil {
+			return nil, tracerr.Wrap(err)
+		}
+	}
 	// Set Eth LastBlockNum to -1 in stats so that stats.Synced() is
 	// guaranteed to return false before it's updated with a real stats
 	c.stats.Eth.LastBlock.Num = -1
@@ -304,16 +354,46 @@ func (c *Coordinator) TxSelector() *txselector.TxSelector {
 	return c.txSelector
 }
 
+// SimulateL2Tx simulates the selection of the PoolL2Tx by the TxSelector
+// for the next batch, using the TxProcessor configuration of the Coordinator
+func (c *Coordinator) SimulateL2Tx(tx common.PoolL2Tx) (*common.TxSelectorError, error) {
+	return c.txSelector.SimulateL2Tx(c.cfg.TxProcessorConfig, tx)
+}
+
 // BatchBuilder returns the inner BatchBuilder
 func (c *Coordinator) BatchBuilder() *batchbuilder.BatchBuilder {
 	return c.batchBuilder
 }
 
+// ProversPool returns the inner ProversPool, which allows adding and removing
+// provers while the coordinator is running
+func (c *Coordinator) ProversPool() *ProversPool {
+	return c.proversPool
+}
+
+// ServerProofs returns the URLs of the server proofs used by the coordinator
+func (c *Coordinator) ServerProofs() []string {
+	return c.proversPool.ServerProofURLs()
+}
+
+// AddServerProof adds the server proof at url to the pool of provers.  It's
+// used once it passes a health check.
+func (c *Coordinator) AddServerProof(url string) error {
+	return c.proversPool.AddServerProof(
+		prover.NewProofServerClient(url, c.cfg.ProofServerPollInterval))
+}
+
+// RemoveServerProof removes the server proof at url from the pool of provers.
+// If it's calculating a proof, it's removed once the proof is done.
+func (c *Coordinator) RemoveServerProof(url string) error {
+	return c.proversPool.RemoveServerProof(url)
+}
+
 func (c *Coordinator) newPipeline(ctx context.Context) (*Pipeline, error) {
 	c.pipelineNum++
 	return NewPipeline(ctx, c.cfg, c.pipelineNum, c.historyDB, c.l2DB, c.txSelector,
 		c.batchBuilder, &c.mutexL2DBUpdateDelete, c.purger, c, c.txManager,
-		c.provers, &c.consts)
+		c.proversPool, &c.consts)
 }
 
 // MsgSyncBlock indicates an update to the Synchronizer stats
@@ -484,6 +564,9 @@ func (c *Coordinator) handleMsgSyncBlock(ctx context.Context, msg *MsgSyncBlock)
 	if c.pipeline != nil {
 		c.pipeline.SetSyncStatsVars(ctx, &msg.Stats, &msg.Vars)
 	}
+	if c.bidder != nil {
+		c.bidder.SetSyncStats(ctx, &msg.Stats)
+	}
 
 	// If there's any batch not forged by us, make sure we don't keep
 	// "phantom forged l2txs" in the pool.  That is, l2txs that we
@@ -525,6 +608,9 @@ func (c *Coordinator) handleReorg(ctx context.Context, msg *MsgSyncReorg) error
 	if c.pipeline != nil {
 		c.pipeline.SetSyncStatsVars(ctx, &msg.Stats, &msg.Vars)
 	}
+	if c.bidder != nil {
+		c.bidder.SetSyncStats(ctx, &msg.Stats)
+	}
 	if c.stats.Sync.LastBatch.ForgerAddr != c.cfg.ForgerAddress &&
 		(c.stats.Sync.LastBatch.StateRoot == nil || c.pipelineFromBatch.StateRoot == nil ||
 			c.stats.Sync.LastBatch.StateRoot.Cmp(c.pipelineFromBatch.StateRoot) != 0) {
@@ -599,6 +685,28 @@ func (c *Coordinator) Start() {
 		c.wg.Done()
 	}()
 
+	c.wg.Add(1)
+	go func() {
+		c.proversPool.Run(c.ctx)
+		c.wg.Done()
+	}()
+
+	if c.dispatcher != nil {
+		c.wg.Add(1)
+		go func() {
+			c.dispatcher.Run(c.ctx)
+			c.wg.Done()
+		}()
+	}
+
+	if c.bidder != nil {
+		c.wg.Add(1)
+		go func() {
+			c.bidder.Run(c.ctx)
+			c.wg.Done()
+		}()
+	}
+
 	c.wg.Add(1)
 	go func() {
 		timer := time.NewTimer(longWaitDuration)
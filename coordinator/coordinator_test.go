@@ -105,7 +105,7 @@ func newTestModules(t *testing.T) modules {
 	db, err := dbUtils.InitTestSQLDB()
 	require.NoError(t, err)
 	test.WipeDB(db)
-	l2DB := l2db.NewL2DB(db, db, 10, 100, 0.0, 1000.0, 24*time.Hour, nil)
+	l2DB := l2db.NewL2DB(db, db, 10, 100, 0.0, 1000.0, 0.0, 24*time.Hour, nil)
 	historyDB := historydb.NewHistoryDB(db, db, nil)
 
 	txSelDBPath, err = ioutil.TempDir("", "tmpTxSelDB")
@@ -181,6 +181,13 @@ func newTestCoordinator(t *testing.T, forgerAddr ethCommon.Address, ethClient *t
 			InvalidateBatchDelay: 4,
 			InvalidateBlockDelay: 4,
 		},
+		ProversPool: ProversPoolCfg{
+			HealthCheckInterval: 1 * time.Second,
+			HealthCheckTimeout:  1 * time.Second,
+			ProofTimeout:        10 * time.Second,
+			MaxErrors:           3,
+			QuarantineTime:      10 * time.Second,
+		},
 		TxProcessorConfig: txprocessor.Config{
 			NLevels:  nLevels,
 			MaxFeeTx: maxFeeTxs,
@@ -0,0 +1,294 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/chainbing/node/common"
+	"github.com/chainbing/node/config"
+	"github.com/chainbing/node/db/historydb"
+	"github.com/chainbing/node/synchronizer"
+	"github.com/chainbing/tracerr"
+)
+
+const (
+	// ForgePolicyDefault forges according to ForgeDelay, ForgeNoTxsDelay,
+	// IgnoreSlotCommitment and ForgeOncePerSlotIfTxs
+	ForgePolicyDefault = "default"
+	// ForgePolicyProfit only forges the batches whose collected fees pay
+	// their gas cost
+	ForgePolicyProfit = "profit"
+)
+
+// etherTokenID is the token of ether, in which the gas is paid
+const etherTokenID common.TokenID = 0
+
+// ForgePolicyState is the state of the pipeline when deciding if a batch is
+// forged
+type ForgePolicyState struct {
+	Now           time.Time
+	LastForgeTime time.Time
+	// SlotCommitted is true if a batch has already been forged in the
+	// current slot
+	SlotCommitted bool
+	Stats         *synchronizer.Stats
+}
+
+// ForgePolicySelection is the selection of txs of a batch
+type ForgePolicySelection struct {
+	BatchInfo  *BatchInfo
+	L1UserTxs  []common.L1Tx
+	L1CoordTxs []common.L1Tx
+	PoolL2Txs  []common.PoolL2Tx
+	// CoordTokenIDs are the tokens of the CoordIdxs of the batch, the only
+	// ones whose fees are collected by the coordinator
+	CoordTokenIDs []common.TokenID
+	// PendingTxs is true if the batch forges txs, or if it's an L1Batch
+	// and there are L1UserTxs waiting in the queues
+	PendingTxs bool
+}
+
+// ForgePolicy decides if the pipeline forges a batch.  Both methods return
+// true and the reason when the forging of the batch must be skipped.
+type ForgePolicy interface {
+	// SkipPreSelection is called before doing the tx selection of a
+	// batch
+	SkipPreSelection(state *ForgePolicyState) (bool, string)
+	// SkipPostSelection is called after doing the tx selection of a
+	// batch
+	SkipPostSelection(ctx context.Context, state *ForgePolicyState,
+		selection *ForgePolicySelection) (bool, string, error)
+}
+
+// newForgePolicy creates the ForgePolicy selected in the configuration
+func newForgePolicy(cfg *Config, historyDB *historydb.HistoryDB,
+	txManager *TxManager) (ForgePolicy, error) {
+	switch cfg.ForgePolicy.Type {
+	case "", ForgePolicyDefault:
+		return NewDefaultForgePolicy(cfg), nil
+	case ForgePolicyProfit:
+		return NewProfitForgePolicy(cfg, historyDB, txManager), nil
+	default:
+		return nil, tracerr.Wrap(fmt.Errorf("invalid forge policy: %v", cfg.ForgePolicy.Type))
+	}
+}
+
+// DefaultForgePolicy forges a batch at the beginning of every slot, and then
+// every ForgeDelay, or every ForgeNoTxsDelay if there are no txs to forge
+type DefaultForgePolicy struct {
+	forgeDelay            time.Duration
+	forgeNoTxsDelay       time.Duration
+	ignoreSlotCommitment  bool
+	forgeOncePerSlotIfTxs bool
+}
+
+// NewDefaultForgePolicy creates a DefaultForgePolicy
+func NewDefaultForgePolicy(cfg *Config) *DefaultForgePolicy {
+	return &DefaultForgePolicy{
+		forgeDelay:            cfg.ForgeDelay,
+		forgeNoTxsDelay:       cfg.ForgeNoTxsDelay,
+		ignoreSlotCommitment:  cfg.IgnoreSlotCommitment,
+		forgeOncePerSlotIfTxs: cfg.ForgeOncePerSlotIfTxs,
+	}
+}
+
+// SkipPreSelection implements ForgePolicy
+func (f *DefaultForgePolicy) SkipPreSelection(state *ForgePolicyState) (bool, string) {
+	if f.forgeOncePerSlotIfTxs {
+		if state.SlotCommitted {
+			return true, "cfg.ForgeOncePerSlotIfTxs = true and slot already committed"
+		}
+		return false, ""
+	}
+	// Determine if we must commit the slot
+	if !f.ignoreSlotCommitment && !state.SlotCommitted {
+		return false, ""
+	}
+
+	// If we haven't reached the ForgeDelay, skip forging the batch
+	if state.Now.Sub(state.LastForgeTime) < f.forgeDelay {
+		return true, "we haven't reached the forge delay"
+	}
+	return false, ""
+}
+
+// SkipPostSelection implements ForgePolicy
+func (f *DefaultForgePolicy) SkipPostSelection(ctx context.Context, state *ForgePolicyState,
+	selection *ForgePolicySelection) (bool, string, error) {
+	if f.forgeOncePerSlotIfTxs {
+		if state.SlotCommitted {
+			return true, "cfg.ForgeOncePerSlotIfTxs = true and slot already committed",
+				nil
+		}
+		if selection.PendingTxs {
+			return false, "", nil
+		}
+		return true, "cfg.ForgeOncePerSlotIfTxs = true and no pending txs",
+			nil
+	}
+
+	// Determine if we must commit the slot
+	if !f.ignoreSlotCommitment && !state.SlotCommitted {
+		return false, "", nil
+	}
+
+	// check if there is no txs to forge, no l1UserTxs in the open queue to
+	// freeze and we haven't reached the ForgeNoTxsDelay
+	if state.Now.Sub(state.LastForgeTime) < f.forgeNoTxsDelay {
+		if !selection.PendingTxs {
+			return true, "no txs to forge and we haven't reached the forge no txs delay",
+				nil
+		}
+	}
+	return false, "", nil
+}
+
+// ProfitForgePolicy only forges a batch when the fees in USD collected in it
+// exceed the estimated gas cost of the forge call by ProfitMarginPerc, unless
+// the batch is needed to commit the slot or to forge the L1UserTxs before the
+// L1Batch timeout.
+type ProfitForgePolicy struct {
+	marginPerc           float64
+	gasCost              config.ForgeBatchGasCost
+	ignoreSlotCommitment bool
+	historyDB            *historydb.HistoryDB
+	txManager            *TxManager
+}
+
+// NewProfitForgePolicy creates a ProfitForgePolicy that estimates the gas
+// price with the fees used by the txManager to forge the batches
+func NewProfitForgePolicy(cfg *Config, historyDB *historydb.HistoryDB,
+	txManager *TxManager) *ProfitForgePolicy {
+	return &ProfitForgePolicy{
+		marginPerc:           cfg.ForgePolicy.ProfitMarginPerc,
+		gasCost:              cfg.ForgeBatchGasCost,
+		ignoreSlotCommitment: cfg.IgnoreSlotCommitment,
+		historyDB:            historyDB,
+		txManager:            txManager,
+	}
+}
+
+// SkipPreSelection implements ForgePolicy.  The profit can only be known
+// after the selection, so the batch is never skipped here.
+func (f *ProfitForgePolicy) SkipPreSelection(state *ForgePolicyState) (bool, string) {
+	return false, ""
+}
+
+// SkipPostSelection implements ForgePolicy
+func (f *ProfitForgePolicy) SkipPostSelection(ctx context.Context, state *ForgePolicyState,
+	selection *ForgePolicySelection) (bool, string, error) {
+	// Determine if we must commit the slot
+	if !f.ignoreSlotCommitment && !state.SlotCommitted {
+		return false, "", nil
+	}
+	if !selection.PendingTxs {
+		return true, "no txs to forge", nil
+	}
+	// The L1Batch is scheduled before the L1Batch timeout, after which no
+	// more batches could be forged until the L1UserTxs are forged
+	if selection.BatchInfo.L1Batch {
+		return false, "", nil
+	}
+
+	feesUSD, err := f.feesUSD(selection.PoolL2Txs, selection.CoordTokenIDs)
+	if err != nil {
+		return false, "", tracerr.Wrap(err)
+	}
+	ether, err := f.historyDB.GetToken(etherTokenID)
+	if err != nil {
+		return false, "", tracerr.Wrap(err)
+	}
+	if ether.USD == nil {
+		return true, "the gas cost can't be estimated without the price of ether", nil
+	}
+	gasPrice, err := f.txManager.effectiveGasPrice(ctx)
+	if err != nil {
+		return false, "", tracerr.Wrap(err)
+	}
+	gasLimit := forgeBatchGasLimit(f.gasCost, len(selection.L1UserTxs),
+		len(selection.L1CoordTxs), len(selection.PoolL2Txs))
+	costUSD := gasCostUSD(gasLimit, gasPrice, *ether.USD)
+	if !profitable(feesUSD, costUSD, f.marginPerc) {
+		return true, fmt.Sprintf("fees (%v USD) don't exceed the gas cost (%v USD) by %v%%",
+			feesUSD, costUSD, f.marginPerc), nil
+	}
+	return false, "", nil
+}
+
+// feesUSD returns the value in USD of the fees of the txs collected in the
+// coordTokenIDs.  The fees of the tokens without price are not counted.
+func (f *ProfitForgePolicy) feesUSD(txs []common.PoolL2Tx,
+	coordTokenIDs []common.TokenID) (float64, error) {
+	tokens := make(map[common.TokenID]*historydb.TokenWithUSD)
+	for _, tokenID := range coordTokenIDs {
+		token, err := f.historyDB.GetToken(tokenID)
+		if err != nil {
+			return 0, tracerr.Wrap(err)
+		}
+		tokens[tokenID] = token
+	}
+	return l2TxsFeesUSD(txs, tokens)
+}
+
+// l2TxsFeesUSD returns the value in USD of the fees of the txs with the
+// prices of the tokens.  The fees of the txs whose token isn't in tokens are
+// not collected, so they are not counted.
+func l2TxsFeesUSD(txs []common.PoolL2Tx,
+	tokens map[common.TokenID]*historydb.TokenWithUSD) (float64, error) {
+	feesUSD := 0.0
+	for _, tx := range txs {
+		token, ok := tokens[tx.TokenID]
+		if !ok || token.USD == nil {
+			continue
+		}
+		feeAmount, err := common.CalcFeeAmount(tx.Amount, tx.Fee)
+		if err != nil {
+			return 0, tracerr.Wrap(err)
+		}
+		fee := new(big.Float).SetInt(feeAmount)
+		fee.Quo(fee, new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), //nolint:gomnd
+			new(big.Int).SetUint64(token.Decimals), nil)))
+		feeUSD, _ := fee.Mul(fee, big.NewFloat(*token.USD)).Float64()
+		feesUSD += feeUSD
+	}
+	return feesUSD, nil
+}
+
+// forgeBatchGasLimit returns the gas limit of a forge call with the given
+// number of txs
+func forgeBatchGasLimit(gasCost config.ForgeBatchGasCost, l1UserTxs, l1CoordTxs,
+	l2Txs int) uint64 {
+	return gasCost.Fixed +
+		uint64(l1UserTxs)*gasCost.L1UserTx +
+		uint64(l1CoordTxs)*gasCost.L1CoordTx +
+		uint64(l2Txs)*gasCost.L2Tx
+}
+
+// increaseGasPrice returns the gas price increased by incPerc percent
+func increaseGasPrice(gasPrice *big.Int, incPerc int64) *big.Int {
+	gasPrice = new(big.Int).Set(gasPrice)
+	if incPerc != 0 {
+		inc := new(big.Int).Set(gasPrice)
+		inc.Mul(inc, new(big.Int).SetInt64(incPerc))
+		// nolint reason: to calculate percentages we use 100
+		inc.Div(inc, new(big.Int).SetUint64(100)) //nolint:gomnd
+		gasPrice.Add(gasPrice, inc)
+	}
+	return gasPrice
+}
+
+// gasCostUSD returns the cost in USD of gasLimit gas at gasPrice wei
+func gasCostUSD(gasLimit uint64, gasPrice *big.Int, etherUSD float64) float64 {
+	cost := new(big.Float).SetInt(new(big.Int).Mul(new(big.Int).SetUint64(gasLimit), gasPrice))
+	cost.Quo(cost, big.NewFloat(params.Ether))
+	costUSD, _ := cost.Mul(cost, big.NewFloat(etherUSD)).Float64()
+	return costUSD
+}
+
+// profitable returns true if the fees exceed the cost by marginPerc percent
+func profitable(feesUSD, costUSD, marginPerc float64) bool {
+	return feesUSD > costUSD*(1+marginPerc/100) //nolint:gomnd
+}
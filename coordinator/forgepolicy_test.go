@@ -0,0 +1,123 @@
+package coordinator
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/chainbing/node/common"
+	"github.com/chainbing/node/config"
+	"github.com/chainbing/node/db/historydb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultForgePolicy(t *testing.T) {
+	now := time.Now()
+	policy := NewDefaultForgePolicy(&Config{
+		ForgeDelay:      10 * time.Second,
+		ForgeNoTxsDelay: 20 * time.Second,
+	})
+	state := &ForgePolicyState{Now: now, LastForgeTime: now.Add(-5 * time.Second)}
+	empty := &ForgePolicySelection{BatchInfo: &BatchInfo{}}
+	withTxs := &ForgePolicySelection{BatchInfo: &BatchInfo{}, PendingTxs: true}
+
+	// The slot must be committed
+	skip, _ := policy.SkipPreSelection(state)
+	assert.False(t, skip)
+	skip, _, err := policy.SkipPostSelection(context.Background(), state, empty)
+	require.NoError(t, err)
+	assert.False(t, skip)
+
+	// Once committed, the delays are respected
+	state.SlotCommitted = true
+	skip, _ = policy.SkipPreSelection(state)
+	assert.True(t, skip)
+	state.LastForgeTime = now.Add(-15 * time.Second)
+	skip, _ = policy.SkipPreSelection(state)
+	assert.False(t, skip)
+	skip, _, err = policy.SkipPostSelection(context.Background(), state, empty)
+	require.NoError(t, err)
+	assert.True(t, skip)
+	skip, _, err = policy.SkipPostSelection(context.Background(), state, withTxs)
+	require.NoError(t, err)
+	assert.False(t, skip)
+
+	// Only one batch with txs per slot
+	policy.forgeOncePerSlotIfTxs = true
+	skip, _ = policy.SkipPreSelection(state)
+	assert.True(t, skip)
+	state.SlotCommitted = false
+	skip, _, err = policy.SkipPostSelection(context.Background(), state, empty)
+	require.NoError(t, err)
+	assert.True(t, skip)
+	skip, _, err = policy.SkipPostSelection(context.Background(), state, withTxs)
+	require.NoError(t, err)
+	assert.False(t, skip)
+}
+
+func TestProfitForgePolicyForcedBatches(t *testing.T) {
+	policy := NewProfitForgePolicy(&Config{
+		ForgePolicy: config.ForgePolicy{Type: ForgePolicyProfit, ProfitMarginPerc: 10},
+	}, nil, nil)
+	state := &ForgePolicyState{Now: time.Now()}
+	skip, _ := policy.SkipPreSelection(state)
+	assert.False(t, skip)
+
+	// The slot must be committed even if the batch is empty
+	empty := &ForgePolicySelection{BatchInfo: &BatchInfo{}}
+	skip, _, err := policy.SkipPostSelection(context.Background(), state, empty)
+	require.NoError(t, err)
+	assert.False(t, skip)
+
+	state.SlotCommitted = true
+	skip, _, err = policy.SkipPostSelection(context.Background(), state, empty)
+	require.NoError(t, err)
+	assert.True(t, skip)
+
+	// The L1Batch with pending L1UserTxs is forged before the timeout
+	l1Batch := &ForgePolicySelection{BatchInfo: &BatchInfo{L1Batch: true}, PendingTxs: true}
+	skip, _, err = policy.SkipPostSelection(context.Background(), state, l1Batch)
+	require.NoError(t, err)
+	assert.False(t, skip)
+}
+
+func TestForgeBatchCost(t *testing.T) {
+	gasCost := config.ForgeBatchGasCost{Fixed: 500000, L1UserTx: 8000, L1CoordTx: 9000, L2Tx: 1000}
+	gasLimit := forgeBatchGasLimit(gasCost, 2, 1, 100)
+	assert.Equal(t, uint64(500000+2*8000+9000+100*1000), gasLimit)
+
+	// 1M gas at 20 gwei is 0.02 ether
+	gasPrice := big.NewInt(20000000000)
+	assert.InDelta(t, 40.0, gasCostUSD(1000000, gasPrice, 2000), 1e-9)
+	assert.Equal(t, "22000000000", increaseGasPrice(gasPrice, 10).String())
+	assert.Equal(t, "20000000000", gasPrice.String())
+
+	assert.True(t, profitable(45, 40, 10))
+	assert.False(t, profitable(44, 40, 10))
+	assert.True(t, profitable(41, 40, 0))
+}
+
+func TestL2TxsFeesUSD(t *testing.T) {
+	usd := 2.0
+	tokens := map[common.TokenID]*historydb.TokenWithUSD{
+		1: {TokenID: 1, Decimals: 18, USD: &usd},
+		2: {TokenID: 2, Decimals: 18},
+	}
+	amount, ok := new(big.Int).SetString("100000000000000000000", 10)
+	require.True(t, ok)
+	// Only the fees collected in the tokens with price are counted
+	txs := []common.PoolL2Tx{
+		{TokenID: 1, Amount: amount, Fee: 126},
+		{TokenID: 1, Amount: amount, Fee: 0},
+		{TokenID: 2, Amount: amount, Fee: 126},
+		{TokenID: 3, Amount: amount, Fee: 126},
+	}
+	feesUSD, err := l2TxsFeesUSD(txs, tokens)
+	require.NoError(t, err)
+	feeAmount, err := common.CalcFeeAmount(amount, 126)
+	require.NoError(t, err)
+	expected, _ := new(big.Float).Quo(new(big.Float).SetInt(feeAmount), big.NewFloat(1e18)).Float64()
+	assert.InDelta(t, expected*usd, feesUSD, 1e-9)
+}
@@ -51,7 +51,6 @@ type Pipeline struct {
 	lastForgeTime time.Time
 
 	proversPool           *ProversPool
-	provers               []prover.Client
 	coord                 *Coordinator
 	txManager             *TxManager
 	historyDB             *historydb.HistoryDB
@@ -60,6 +59,7 @@ type Pipeline struct {
 	batchBuilder          *batchbuilder.BatchBuilder
 	mutexL2DBUpdateDelete *sync.Mutex
 	purger                *Purger
+	forgePolicy           ForgePolicy
 
 	stats       synchronizer.Stats
 	vars        common.SCVariables
@@ -94,24 +94,16 @@ func NewPipeline(ctx context.Context,
 	purger *Purger,
 	coord *Coordinator,
 	txManager *TxManager,
-	provers []prover.Client,
+	proversPool *ProversPool,
 	scConsts *common.SCConsts,
 ) (*Pipeline, error) {
-	proversPool := NewProversPool(len(provers))
-	proversPoolSize := 0
-	for _, prover := range provers {
-		ctxTimeout, ctxTimeoutCancel := context.WithTimeout(ctx, proverWaitReadyTimeout)
-		defer ctxTimeoutCancel()
-		if err := prover.WaitReady(ctxTimeout); err != nil {
-			log.Errorw("prover.WaitReady", "err", err)
-		} else {
-			proversPool.Add(ctx, prover)
-			proversPoolSize++
-		}
-	}
-	if proversPoolSize == 0 {
+	if proversPool.CheckHealth(ctx, proverWaitReadyTimeout) == 0 {
 		return nil, tracerr.Wrap(fmt.Errorf("no provers in the pool"))
 	}
+	forgePolicy, err := newForgePolicy(&cfg, historyDB, txManager)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
 	return &Pipeline{
 		num:                   num,
 		cfg:                   cfg,
@@ -119,10 +111,10 @@ func NewPipeline(ctx context.Context,
 		l2DB:                  l2DB,
 		txSelector:            txSelector,
 		batchBuilder:          batchBuilder,
-		provers:               provers,
 		proversPool:           proversPool,
 		mutexL2DBUpdateDelete: mutexL2DBUpdateDelete,
 		purger:                purger,
+		forgePolicy:           forgePolicy,
 		coord:                 coord,
 		txManager:             txManager,
 		consts:                *scConsts,
@@ -225,7 +217,7 @@ func (p *Pipeline) handleForgeBatch(ctx context.Context,
 		// of unexpected errors but also due to benign causes), add the
 		// serverProof back to the pool
 		if err != nil {
-			p.proversPool.Add(ctx, serverProof)
+			p.proversPool.Release(serverProof)
 		}
 	}()
 
@@ -233,7 +225,7 @@ func (p *Pipeline) handleForgeBatch(ctx context.Context,
 	// all the smart contract arguments)
 	var skipReason *string
 	p.mutexL2DBUpdateDelete.Lock()
-	batchInfo, skipReason, err = p.forgeBatch(batchNum)
+	batchInfo, skipReason, err = p.forgeBatch(ctx, batchNum)
 	p.mutexL2DBUpdateDelete.Unlock()
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
@@ -258,6 +250,7 @@ func (p *Pipeline) handleForgeBatch(ctx context.Context,
 		return nil, ctx.Err()
 	} else if err != nil {
 		log.Errorw("sendServerProof", "err", err)
+		p.proversPool.ProofFailed(serverProof)
 		return nil, tracerr.Wrap(err)
 	}
 	return batchInfo, nil
@@ -364,8 +357,6 @@ func (p *Pipeline) Start(batchNum common.BatchNum,
 					p.revertPoolChanges(batchNum)
 					continue
 				}
-				// We are done with this serverProof, add it back to the pool
-				p.proversPool.Add(p.ctx, batchInfo.ServerProof)
 				p.txManager.AddBatch(p.ctx, batchInfo)
 			}
 		}
@@ -392,13 +383,7 @@ func (p *Pipeline) Stop(ctx context.Context) {
 	log.Info("Stopping Pipeline...")
 	p.cancel()
 	p.wg.Wait()
-	for _, prover := range p.provers {
-		if err := prover.Cancel(ctx); ctx.Err() != nil {
-			continue
-		} else if err != nil {
-			log.Errorw("prover.Cancel", "err", err)
-		}
-	}
+	p.proversPool.CancelAll(ctx)
 }
 
 // sendServerProof sends the circuit inputs to the proof server
@@ -422,90 +407,51 @@ func (p *Pipeline) slotCommitted() bool {
 		p.stats.Sync.Auction.CurrentSlot.SlotNum == p.state.lastSlotForged
 }
 
-// forgePolicySkipPreSelection is called before doing a tx selection in a batch to
-// determine by policy if we should forge the batch or not.  Returns true and
-// the reason when the forging of the batch must be skipped.
-func (p *Pipeline) forgePolicySkipPreSelection(now time.Time) (bool, string) {
-	// Check if the slot is not yet fulfilled
-	slotCommitted := p.slotCommitted()
-	if p.cfg.ForgeOncePerSlotIfTxs {
-		if slotCommitted {
-			return true, "cfg.ForgeOncePerSlotIfTxs = true and slot already committed"
+// coordTokenIDs returns the tokens of the coordIdxs, whose accounts exist in
+// the local AccountsDB after the selection
+func (p *Pipeline) coordTokenIDs(coordIdxs []common.Idx) ([]common.TokenID, error) {
+	tokenIDs := make([]common.TokenID, len(coordIdxs))
+	for i, idx := range coordIdxs {
+		account, err := p.txSelector.LocalAccountsDB().GetAccount(idx)
+		if err != nil {
+			return nil, tracerr.Wrap(err)
 		}
-		return false, ""
-	}
-	// Determine if we must commit the slot
-	if !p.cfg.IgnoreSlotCommitment && !slotCommitted {
-		return false, ""
+		tokenIDs[i] = account.TokenID
 	}
-
-	// If we haven't reached the ForgeDelay, skip forging the batch
-	if now.Sub(p.lastForgeTime) < p.cfg.ForgeDelay {
-		return true, "we haven't reached the forge delay"
-	}
-	return false, ""
+	return tokenIDs, nil
 }
 
-// forgePolicySkipPostSelection is called after doing a tx selection in a batch to
-// determine by policy if we should forge the batch or not.  Returns true and
-// the reason when the forging of the batch must be skipped.
-func (p *Pipeline) forgePolicySkipPostSelection(now time.Time, l1UserTxsExtra, l1CoordTxs []common.L1Tx,
-	poolL2Txs []common.PoolL2Tx, batchInfo *BatchInfo) (bool, string, error) {
-	// Check if the slot is not yet fulfilled
-	slotCommitted := p.slotCommitted()
-
-	pendingTxs := true
-	if len(l1UserTxsExtra) == 0 && len(l1CoordTxs) == 0 && len(poolL2Txs) == 0 {
-		if batchInfo.L1Batch {
-			// Query the number of unforged L1UserTxs
-			// (either in a open queue or in a frozen
-			// not-yet-forged queue).
-			count, err := p.historyDB.GetUnforgedL1UserTxsCount()
-			if err != nil {
-				return false, "", err
-			}
-			// If there are future L1UserTxs, we forge a
-			// batch to advance the queues to be able to
-			// forge the L1UserTxs in the future.
-			// Otherwise, skip.
-			if count == 0 {
-				pendingTxs = false
-			}
-		} else {
-			pendingTxs = false
-		}
+// forgePolicyState returns the state of the pipeline used by the forge policy
+func (p *Pipeline) forgePolicyState(now time.Time) *ForgePolicyState {
+	return &ForgePolicyState{
+		Now:           now,
+		LastForgeTime: p.lastForgeTime,
+		SlotCommitted: p.slotCommitted(),
+		Stats:         &p.stats,
 	}
+}
 
-	if p.cfg.ForgeOncePerSlotIfTxs {
-		if slotCommitted {
-			return true, "cfg.ForgeOncePerSlotIfTxs = true and slot already committed",
-				nil
-		}
-		if pendingTxs {
-			return false, "", nil
-		}
-		return true, "cfg.ForgeOncePerSlotIfTxs = true and no pending txs",
-			nil
+// pendingTxs returns true if the selection of a batch has txs to forge, or if
+// the batch is an L1Batch and there are unforged L1UserTxs (either in a open
+// queue or in a frozen not-yet-forged queue), which are forged in the future
+// after advancing the queues
+func (p *Pipeline) pendingTxs(l1UserTxs, l1CoordTxs []common.L1Tx,
+	poolL2Txs []common.PoolL2Tx, batchInfo *BatchInfo) (bool, error) {
+	if len(l1UserTxs) != 0 || len(l1CoordTxs) != 0 || len(poolL2Txs) != 0 {
+		return true, nil
 	}
-
-	// Determine if we must commit the slot
-	if !p.cfg.IgnoreSlotCommitment && !slotCommitted {
-		return false, "", nil
+	if !batchInfo.L1Batch {
+		return false, nil
 	}
-
-	// check if there is no txs to forge, no l1UserTxs in the open queue to
-	// freeze and we haven't reached the ForgeNoTxsDelay
-	if now.Sub(p.lastForgeTime) < p.cfg.ForgeNoTxsDelay {
-		if !pendingTxs {
-			return true, "no txs to forge and we haven't reached the forge no txs delay",
-				nil
-		}
+	count, err := p.historyDB.GetUnforgedL1UserTxsCount()
+	if err != nil {
+		return false, tracerr.Wrap(err)
 	}
-	return false, "", nil
+	return count != 0, nil
 }
 
 // forgeBatch forges the batchNum batch.
-func (p *Pipeline) forgeBatch(batchNum common.BatchNum) (batchInfo *BatchInfo,
+func (p *Pipeline) forgeBatch(ctx context.Context, batchNum common.BatchNum) (batchInfo *BatchInfo,
 	skipReason *string, err error) {
 	// remove transactions from the pool that have been there for too long
 	_, err = p.purger.InvalidateMaybe(p.l2DB, p.txSelector.LocalAccountsDB(),
@@ -529,7 +475,7 @@ func (p *Pipeline) forgeBatch(batchNum common.BatchNum) (batchInfo *BatchInfo,
 	var auths [][]byte
 	var coordIdxs []common.Idx
 
-	if skip, reason := p.forgePolicySkipPreSelection(now); skip {
+	if skip, reason := p.forgePolicy.SkipPreSelection(p.forgePolicyState(now)); skip {
 		return nil, &reason, nil
 	}
 
@@ -574,8 +520,23 @@ func (p *Pipeline) forgeBatch(batchNum common.BatchNum) (batchInfo *BatchInfo,
 		l1UserTxs = nil
 	}
 
-	if skip, reason, err := p.forgePolicySkipPostSelection(now,
-		l1UserTxs, l1CoordTxs, poolL2Txs, batchInfo); err != nil {
+	pendingTxs, err := p.pendingTxs(l1UserTxs, l1CoordTxs, poolL2Txs, batchInfo)
+	if err != nil {
+		return nil, nil, tracerr.Wrap(err)
+	}
+	coordTokenIDs, err := p.coordTokenIDs(coordIdxs)
+	if err != nil {
+		return nil, nil, tracerr.Wrap(err)
+	}
+	if skip, reason, err := p.forgePolicy.SkipPostSelection(ctx, p.forgePolicyState(now),
+		&ForgePolicySelection{
+			BatchInfo:     batchInfo,
+			L1UserTxs:     l1UserTxs,
+			L1CoordTxs:    l1CoordTxs,
+			PoolL2Txs:     poolL2Txs,
+			CoordTokenIDs: coordTokenIDs,
+			PendingTxs:    pendingTxs,
+		}); err != nil {
 		return nil, nil, tracerr.Wrap(err)
 	} else if skip {
 		if err := p.txSelector.Reset(batchInfo.BatchNum-1, false); err != nil {
@@ -639,15 +600,48 @@ func (p *Pipeline) forgeBatch(batchNum common.BatchNum) (batchInfo *BatchInfo,
 	return batchInfo, nil, nil
 }
 
-// waitServerProof gets the generated zkProof & sends it to the SmartContract
+// waitServerProof gets the generated zkProof & sends it to the SmartContract.
+// If the proof fails or times out, the ZKInputs are sent to another prover of
+// the pool, trying each prover at most once.
 func (p *Pipeline) waitServerProof(ctx context.Context, batchInfo *BatchInfo) error {
 	defer metric.MeasureDuration(metric.WaitServerProof, batchInfo.ProofStart,
 		batchInfo.BatchNum.BigInt().String(), strconv.Itoa(batchInfo.PipelineNum))
 
-	proof, pubInputs, err := batchInfo.ServerProof.GetProof(ctx) // blocking call,
-	// until not resolved don't continue. Returns when the proof server has calculated the proof
-	if err != nil {
-		return tracerr.Wrap(err)
+	// tried are the provers that failed the proof, which are not used
+	// again for it
+	var tried []prover.Client
+	var proof *prover.Proof
+	var pubInputs []*big.Int
+	for attempt := 1; ; attempt++ {
+		var err error
+		proof, pubInputs, err = p.getServerProof(ctx, batchInfo)
+		if ctx.Err() != nil {
+			return tracerr.Wrap(common.ErrDone)
+		} else if err == nil {
+			// We are done with this serverProof, add it back to
+			// the pool
+			p.proversPool.ProofDone(batchInfo.ServerProof,
+				time.Since(batchInfo.ProofStart))
+			break
+		}
+		p.proversPool.ProofFailed(batchInfo.ServerProof)
+		tried = append(tried, batchInfo.ServerProof)
+		serverProof, errGet := p.proversPool.Get(ctx, tried...)
+		if tracerr.Unwrap(errGet) == errNoUntriedProvers {
+			return tracerr.Wrap(err)
+		} else if errGet != nil {
+			return tracerr.Wrap(errGet)
+		}
+		log.Warnw("Pipeline: proof failed, sending it to another prover",
+			"batch", batchInfo.BatchNum, "attempt", attempt, "err", err)
+		batchInfo.ServerProof = serverProof
+		batchInfo.ProofStart = time.Now()
+		if err := p.sendServerProof(ctx, batchInfo); ctx.Err() != nil {
+			return tracerr.Wrap(common.ErrDone)
+		} else if err != nil {
+			p.proversPool.ProofFailed(serverProof)
+			return tracerr.Wrap(err)
+		}
 	}
 	batchInfo.Proof = proof
 	batchInfo.PublicInputs = pubInputs
@@ -658,6 +652,30 @@ func (p *Pipeline) waitServerProof(ctx context.Context, batchInfo *BatchInfo) er
 	return nil
 }
 
+// getServerProof waits for the proof of the batch, which times out if it's
+// not calculated ProofTimeout after being sent to the prover
+func (p *Pipeline) getServerProof(ctx context.Context,
+	batchInfo *BatchInfo) (*prover.Proof, []*big.Int, error) {
+	proofCtx := ctx
+	if p.cfg.ProversPool.ProofTimeout > 0 {
+		var cancel context.CancelFunc
+		proofCtx, cancel = context.WithDeadline(ctx,
+			batchInfo.ProofStart.Add(p.cfg.ProversPool.ProofTimeout))
+		defer cancel()
+	}
+	proof, pubInputs, err := batchInfo.ServerProof.GetProof(proofCtx) // blocking call,
+	// until not resolved don't continue. Returns when the proof server has calculated the proof
+	if err != nil && ctx.Err() == nil && proofCtx.Err() != nil {
+		// The prover stops calculating the proof that timed out
+		if err := batchInfo.ServerProof.Cancel(ctx); err != nil {
+			log.Warnw("prover.Cancel", "err", err)
+		}
+		return nil, nil, tracerr.Wrap(fmt.Errorf("proof not calculated after %v",
+			p.cfg.ProversPool.ProofTimeout))
+	}
+	return proof, pubInputs, tracerr.Wrap(err)
+}
+
 func (p *Pipeline) shouldL1L2Batch(batchInfo *BatchInfo) bool {
 	// Take the lastL1BatchBlockNum as the biggest between the last
 	// scheduled one, and the synchronized one.
@@ -2,40 +2,489 @@ package coordinator
 
 import (
 	"context"
+	"fmt"
+	"sync"
+	"time"
 
 	"github.com/chainbing/node/common"
+	"github.com/chainbing/node/config"
 	"github.com/chainbing/node/log"
 	"github.com/chainbing/node/prover"
 	"github.com/chainbing/tracerr"
 )
 
-// ProversPool contains the multiple prover clients
+const (
+	// proofLatencySmoothing is the weight of the previous average in the
+	// moving average of the proof latency of a prover
+	proofLatencySmoothing = 4
+	// defaultHealthCheckInterval is the HealthCheckInterval used if it's
+	// not set
+	defaultHealthCheckInterval = 10 * time.Second
+	// defaultHealthCheckTimeout is the HealthCheckTimeout used if it's not
+	// set
+	defaultHealthCheckTimeout = 5 * time.Second
+	// defaultMaxErrors is the MaxErrors used if it's not set
+	defaultMaxErrors = 3
+)
+
+// errNoUntriedProvers is returned by ProversPool.Get when all the provers of
+// the pool are excluded
+var errNoUntriedProvers = fmt.Errorf("all the provers of the pool have been tried")
+
+// ProversPoolCfg is the configuration of the pool of provers
+type ProversPoolCfg struct {
+	// HealthCheckInterval is the waiting interval between health checks
+	// of the idle provers
+	HealthCheckInterval time.Duration
+	// HealthCheckTimeout is the time a prover has to report that it's
+	// ready in a health check
+	HealthCheckTimeout time.Duration
+	// ProofTimeout is the time after which a proof that hasn't been
+	// calculated is sent to another prover.  If set to 0s, the proofs
+	// don't time out.
+	ProofTimeout time.Duration
+	// MaxErrors is the number of consecutive errors after which a prover
+	// is quarantined
+	MaxErrors int
+	// QuarantineTime is the time during which a quarantined prover is not
+	// used.  After it, the prover is used again once it passes a health
+	// check.
+	QuarantineTime time.Duration
+}
+
+// NewProversPoolCfg returns the ProversPoolCfg of the coordinator
+// configuration
+func NewProversPoolCfg(cfg *config.ProversPool) ProversPoolCfg {
+	return ProversPoolCfg{
+		HealthCheckInterval: cfg.HealthCheckInterval.Duration,
+		HealthCheckTimeout:  cfg.HealthCheckTimeout.Duration,
+		ProofTimeout:        cfg.ProofTimeout.Duration,
+		MaxErrors:           cfg.MaxErrors,
+		QuarantineTime:      cfg.QuarantineTime.Duration,
+	}
+}
+
+// ProverStats are the health and the proof statistics of a prover of the pool
+type ProverStats struct {
+	// Healthy is true if the prover passed its last health check and
+	// hasn't failed a proof since then
+	Healthy bool
+	// Busy is true while the prover is calculating a proof
+	Busy bool
+	// QuarantinedUntil is the time until which the prover is not used due
+	// to its errors
+	QuarantinedUntil time.Time
+	// Proofs is the number of proofs calculated by the prover
+	Proofs int
+	// Errors is the number of failed proofs and health checks
+	Errors int
+	// ConsecutiveErrors is the number of errors since the last success
+	ConsecutiveErrors int
+	// AvgProofLatency is the moving average of the time taken by the
+	// prover to calculate a proof
+	AvgProofLatency time.Duration
+}
+
+type poolProver struct {
+	client   prover.Client
+	stats    ProverStats
+	checking bool // a health check is in progress
+	removed  bool // the prover is removed once it's idle
+}
+
+// available returns true if the prover can be used to calculate a proof
+func (pp *poolProver) available(now time.Time) bool {
+	return pp.stats.Healthy && !pp.stats.Busy && !pp.checking && !pp.removed &&
+		!now.Before(pp.stats.QuarantinedUntil)
+}
+
+// ProversPool manages the provers used to calculate the proofs of the
+// batches.  The idle provers are health checked periodically, the failing ones
+// are quarantined, and each proof is calculated by the available prover with
+// the lowest proof latency.  Provers can be added and removed while the pool
+// is running.
 type ProversPool struct {
-	pool chan prover.Client
+	cfg     ProversPoolCfg
+	rw      sync.Mutex
+	provers []*poolProver
+	// changedCh is closed and replaced every time a prover may have
+	// become available
+	changedCh chan struct{}
+}
+
+// NewProversPool creates a new pool of provers.  The provers are used once
+// they pass a health check.  The HealthCheckInterval, HealthCheckTimeout and
+// MaxErrors that are not set take their default values.
+func NewProversPool(cfg ProversPoolCfg, provers []prover.Client) *ProversPool {
+	if cfg.HealthCheckInterval <= 0 {
+		cfg.HealthCheckInterval = defaultHealthCheckInterval
+	}
+	if cfg.HealthCheckTimeout <= 0 {
+		cfg.HealthCheckTimeout = defaultHealthCheckTimeout
+	}
+	if cfg.MaxErrors <= 0 {
+		cfg.MaxErrors = defaultMaxErrors
+	}
+	p := &ProversPool{
+		cfg:       cfg,
+		changedCh: make(chan struct{}),
+	}
+	for _, client := range provers {
+		p.provers = append(p.provers, &poolProver{client: client})
+	}
+	return p
+}
+
+// find returns the prover of the pool with the given client.  It must be
+// called with the lock held.
+func (p *ProversPool) find(client prover.Client) *poolProver {
+	for _, pp := range p.provers {
+		if pp.client == client {
+			return pp
+		}
+	}
+	return nil
+}
+
+// drop deletes a removed prover from the pool once it's idle.  It must be
+// called with the lock held.
+func (p *ProversPool) drop(pp *poolProver) {
+	if !pp.removed || pp.stats.Busy || pp.checking {
+		return
+	}
+	for i := range p.provers {
+		if p.provers[i] == pp {
+			p.provers = append(p.provers[:i], p.provers[i+1:]...)
+			return
+		}
+	}
+}
+
+// notify wakes up the calls to Get waiting for a prover.  It must be called
+// with the lock held.
+func (p *ProversPool) notify() {
+	close(p.changedCh)
+	p.changedCh = make(chan struct{})
+}
+
+// recordError counts an error of the prover, and quarantines it after
+// MaxErrors consecutive errors.  It must be called with the lock held.
+func (p *ProversPool) recordError(pp *poolProver, now time.Time) {
+	pp.stats.Healthy = false
+	pp.stats.Errors++
+	pp.stats.ConsecutiveErrors++
+	if pp.stats.ConsecutiveErrors >= p.cfg.MaxErrors {
+		pp.stats.QuarantinedUntil = now.Add(p.cfg.QuarantineTime)
+		log.Warnw("ProversPool: prover quarantined",
+			"errors", pp.stats.ConsecutiveErrors, "until", pp.stats.QuarantinedUntil)
+	}
+}
+
+// serverProofURL returns the URL of the prover if it's a server proof, or an
+// empty string otherwise
+func serverProofURL(client prover.Client) string {
+	if serverProof, ok := client.(*prover.ProofServerClient); ok {
+		return serverProof.URL
+	}
+	return ""
+}
+
+// findURL returns the server proof of the pool with the given URL.  It must be
+// called with the lock held.
+func (p *ProversPool) findURL(url string) *poolProver {
+	for _, pp := range p.provers {
+		if serverProofURL(pp.client) == url {
+			return pp
+		}
+	}
+	return nil
+}
+
+// add adds a prover to the pool, or restores it if it was being removed.  It
+// must be called with the lock held.
+func (p *ProversPool) add(pp *poolProver, client prover.Client) error {
+	if pp != nil && !pp.removed {
+		return tracerr.Wrap(fmt.Errorf("prover already in the pool"))
+	} else if pp != nil {
+		pp.removed = false
+		return nil
+	}
+	p.provers = append(p.provers, &poolProver{client: client})
+	return nil
 }
 
-// NewProversPool creates a new pool of provers.
-func NewProversPool(maxServerProofs int) *ProversPool {
-	return &ProversPool{
-		pool: make(chan prover.Client, maxServerProofs),
+// remove removes a prover from the pool once it's idle.  It must be called
+// with the lock held.
+func (p *ProversPool) remove(pp *poolProver) error {
+	if pp == nil || pp.removed {
+		return tracerr.Wrap(fmt.Errorf("prover not in the pool"))
 	}
+	pp.removed = true
+	p.drop(pp)
+	return nil
+}
+
+// AddProver adds a prover to the pool.  It's used once it passes a health
+// check.
+func (p *ProversPool) AddProver(client prover.Client) error {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+	return p.add(p.find(client), client)
 }
 
-// Add a prover to the pool
-func (p *ProversPool) Add(ctx context.Context, serverProof prover.Client) {
-	select {
-	case p.pool <- serverProof:
-	case <-ctx.Done():
+// RemoveProver removes a prover from the pool.  A busy prover is removed once
+// its proof is done.
+func (p *ProversPool) RemoveProver(client prover.Client) error {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+	return p.remove(p.find(client))
+}
+
+// AddServerProof adds a server proof to the pool, unless there's already one
+// with the same URL
+func (p *ProversPool) AddServerProof(client *prover.ProofServerClient) error {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+	return p.add(p.findURL(client.URL), client)
+}
+
+// RemoveServerProof removes the server proof with the given URL from the
+// pool.  A busy server proof is removed once its proof is done.
+func (p *ProversPool) RemoveServerProof(url string) error {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+	return p.remove(p.findURL(url))
+}
+
+// ServerProofURLs returns the URLs of the server proofs in the pool
+func (p *ProversPool) ServerProofURLs() []string {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+	urls := []string{}
+	for _, pp := range p.provers {
+		if url := serverProofURL(pp.client); url != "" && !pp.removed {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+// Len returns the number of provers in the pool
+func (p *ProversPool) Len() int {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+	n := 0
+	for _, pp := range p.provers {
+		if !pp.removed {
+			n++
+		}
+	}
+	return n
+}
+
+// Stats returns the statistics of the provers in the pool
+func (p *ProversPool) Stats() map[prover.Client]ProverStats {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+	stats := make(map[prover.Client]ProverStats, len(p.provers))
+	for _, pp := range p.provers {
+		if !pp.removed {
+			stats[pp.client] = pp.stats
+		}
+	}
+	return stats
+}
+
+// Get returns the available prover with the lowest proof latency, waiting
+// until there is one.  The excluded provers are not returned, and if all the
+// provers of the pool are excluded an error is returned.  The prover is busy
+// until it's returned to the pool with Release, ProofDone or ProofFailed.
+func (p *ProversPool) Get(ctx context.Context, excluded ...prover.Client) (prover.Client, error) {
+	isExcluded := func(client prover.Client) bool {
+		for _, excludedClient := range excluded {
+			if client == excludedClient {
+				return true
+			}
+		}
+		return false
+	}
+	for {
+		p.rw.Lock()
+		var best *poolProver
+		candidates := 0
+		now := time.Now()
+		for _, pp := range p.provers {
+			if pp.removed || isExcluded(pp.client) {
+				continue
+			}
+			candidates++
+			if pp.available(now) &&
+				(best == nil || pp.stats.AvgProofLatency < best.stats.AvgProofLatency) {
+				best = pp
+			}
+		}
+		if best != nil {
+			best.stats.Busy = true
+			p.rw.Unlock()
+			return best.client, nil
+		}
+		changedCh := p.changedCh
+		p.rw.Unlock()
+		if candidates == 0 && len(excluded) > 0 {
+			return nil, tracerr.Wrap(errNoUntriedProvers)
+		}
+		select {
+		case <-ctx.Done():
+			log.Info("ProversPool.Get done")
+			return nil, tracerr.Wrap(common.ErrDone)
+		case <-changedCh:
+		}
+	}
+}
+
+// Release returns a prover that hasn't calculated a proof to the pool
+func (p *ProversPool) Release(client prover.Client) {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+	pp := p.find(client)
+	if pp == nil || !pp.stats.Busy {
+		return
+	}
+	pp.stats.Busy = false
+	p.drop(pp)
+	p.notify()
+}
+
+// ProofDone records a proof calculated by the prover in latency and returns
+// the prover to the pool
+func (p *ProversPool) ProofDone(client prover.Client, latency time.Duration) {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+	pp := p.find(client)
+	if pp == nil {
+		return
+	}
+	pp.stats.Proofs++
+	pp.stats.ConsecutiveErrors = 0
+	if pp.stats.AvgProofLatency == 0 {
+		pp.stats.AvgProofLatency = latency
+	} else {
+		pp.stats.AvgProofLatency = (pp.stats.AvgProofLatency*proofLatencySmoothing +
+			latency) / (proofLatencySmoothing + 1)
+	}
+	pp.stats.Busy = false
+	p.drop(pp)
+	p.notify()
+}
+
+// ProofFailed records a failed proof of the prover and returns the prover to
+// the pool.  The prover isn't used again until it passes a health check.
+func (p *ProversPool) ProofFailed(client prover.Client) {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+	pp := p.find(client)
+	if pp == nil {
+		return
+	}
+	p.recordError(pp, time.Now())
+	pp.stats.Busy = false
+	p.drop(pp)
+	p.notify()
+}
+
+// CheckHealth checks that the idle provers that aren't quarantined are ready
+// within timeout, and returns the number of healthy provers
+func (p *ProversPool) CheckHealth(ctx context.Context, timeout time.Duration) int {
+	p.rw.Lock()
+	now := time.Now()
+	var checks []*poolProver
+	for _, pp := range p.provers {
+		if !pp.stats.Busy && !pp.checking && !pp.removed &&
+			!now.Before(pp.stats.QuarantinedUntil) {
+			pp.checking = true
+			checks = append(checks, pp)
+		}
+	}
+	p.rw.Unlock()
+
+	errs := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, pp := range checks {
+		wg.Add(1)
+		go func(i int, client prover.Client) {
+			defer wg.Done()
+			ctxTimeout, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			errs[i] = client.WaitReady(ctxTimeout)
+		}(i, pp.client)
+	}
+	wg.Wait()
+
+	p.rw.Lock()
+	defer p.rw.Unlock()
+	now = time.Now()
+	for i, pp := range checks {
+		pp.checking = false
+		if ctx.Err() != nil {
+			// The check was interrupted, so the previous health
+			// is kept
+		} else if errs[i] != nil {
+			log.Warnw("ProversPool: prover health check failed", "err", errs[i])
+			p.recordError(pp, now)
+		} else {
+			if !pp.stats.Healthy {
+				log.Infow("ProversPool: prover healthy")
+			}
+			pp.stats.Healthy = true
+			pp.stats.ConsecutiveErrors = 0
+			pp.stats.QuarantinedUntil = time.Time{}
+		}
+		p.drop(pp)
+	}
+	p.notify()
+	healthy := 0
+	for _, pp := range p.provers {
+		if pp.stats.Healthy && !pp.removed && !now.Before(pp.stats.QuarantinedUntil) {
+			healthy++
+		}
+	}
+	return healthy
+}
+
+// CancelAll cancels the proofs of the busy provers and returns them to the
+// pool
+func (p *ProversPool) CancelAll(ctx context.Context) {
+	p.rw.Lock()
+	var busy []*poolProver
+	for _, pp := range p.provers {
+		if pp.stats.Busy {
+			busy = append(busy, pp)
+		}
+	}
+	p.rw.Unlock()
+	for _, pp := range busy {
+		if err := pp.client.Cancel(ctx); err != nil && ctx.Err() == nil {
+			log.Errorw("prover.Cancel", "err", err)
+		}
+		p.Release(pp.client)
 	}
 }
 
-// Get returns the next available prover
-func (p *ProversPool) Get(ctx context.Context) (prover.Client, error) {
-	select {
-	case <-ctx.Done():
-		log.Info("ServerProofPool.Get done")
-		return nil, tracerr.Wrap(common.ErrDone)
-	case serverProof := <-p.pool:
-		return serverProof, nil
+// Run health checks the provers every HealthCheckInterval
+func (p *ProversPool) Run(ctx context.Context) {
+	timer := time.NewTimer(zeroDuration)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("ProversPool done")
+			return
+		case <-timer.C:
+			if healthy := p.CheckHealth(ctx, p.cfg.HealthCheckTimeout); ctx.Err() != nil {
+				continue
+			} else if healthy == 0 {
+				log.Warnw("ProversPool: no healthy provers", "provers", p.Len())
+			}
+			timer.Reset(p.cfg.HealthCheckInterval)
+		}
 	}
 }
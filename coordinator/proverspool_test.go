@@ -0,0 +1,168 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/chainbing/node/common"
+	"github.com/chainbing/node/config"
+	"github.com/chainbing/node/prover"
+	"github.com/chainbing/tracerr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unreadyProver is a mock prover that fails the health checks while it's not
+// ready
+type unreadyProver struct {
+	prover.MockClient
+	ready bool
+}
+
+func (p *unreadyProver) WaitReady(ctx context.Context) error {
+	if !p.ready {
+		return tracerr.Wrap(fmt.Errorf("prover not ready"))
+	}
+	return nil
+}
+
+func getProverTimeout(pool *ProversPool) (prover.Client, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	return pool.Get(ctx)
+}
+
+func TestProversPool(t *testing.T) {
+	ctx := context.Background()
+	prover1 := &unreadyProver{ready: true}
+	prover2 := &unreadyProver{}
+	pool := NewProversPool(ProversPoolCfg{
+		MaxErrors:      2,
+		QuarantineTime: time.Hour,
+	}, []prover.Client{prover1, prover2})
+	assert.Equal(t, 2, pool.Len())
+
+	// The provers are used once they pass a health check
+	_, err := getProverTimeout(pool)
+	assert.Equal(t, common.ErrDone, tracerr.Unwrap(err))
+	assert.Equal(t, 1, pool.CheckHealth(ctx, time.Second))
+	serverProof, err := pool.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, prover1, serverProof)
+	_, err = getProverTimeout(pool)
+	assert.Equal(t, common.ErrDone, tracerr.Unwrap(err))
+
+	// The prover with the lowest latency is used
+	pool.ProofDone(prover1, 2*time.Second)
+	prover2.ready = true
+	assert.Equal(t, 2, pool.CheckHealth(ctx, time.Second))
+	serverProof, err = pool.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, prover2, serverProof)
+	pool.ProofDone(prover2, time.Second)
+	serverProof, err = pool.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, prover2, serverProof)
+	stats := pool.Stats()
+	assert.Equal(t, 1, stats[prover1].Proofs)
+	assert.Equal(t, 2*time.Second, stats[prover1].AvgProofLatency)
+	assert.True(t, stats[prover2].Busy)
+
+	// A failing prover is not used until it passes a health check, and
+	// it's quarantined after MaxErrors consecutive errors
+	pool.ProofFailed(prover2)
+	assert.False(t, pool.Stats()[prover2].Healthy)
+	prover2.ready = false
+	assert.Equal(t, 1, pool.CheckHealth(ctx, time.Second))
+	stats = pool.Stats()
+	assert.Equal(t, 2, stats[prover2].ConsecutiveErrors)
+	assert.True(t, stats[prover2].QuarantinedUntil.After(time.Now()))
+	prover2.ready = true
+	assert.Equal(t, 1, pool.CheckHealth(ctx, time.Second))
+	assert.False(t, pool.Stats()[prover2].Healthy)
+
+	// A busy prover is removed once its proof is done
+	serverProof, err = pool.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, prover1, serverProof)
+	require.NoError(t, pool.RemoveProver(prover1))
+	assert.Error(t, pool.RemoveProver(prover1))
+	assert.Equal(t, 1, pool.Len())
+	pool.ProofDone(prover1, time.Second)
+	_, ok := pool.Stats()[prover1]
+	assert.False(t, ok)
+
+	// Provers can be added back
+	require.NoError(t, pool.AddProver(prover1))
+	assert.Error(t, pool.AddProver(prover1))
+	assert.Equal(t, 1, pool.CheckHealth(ctx, time.Second))
+	serverProof, err = pool.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, prover1, serverProof)
+
+	// Cancelling the busy provers returns them to the pool
+	pool.CancelAll(ctx)
+	assert.False(t, pool.Stats()[prover1].Busy)
+}
+
+func TestProversPoolGetExcluded(t *testing.T) {
+	ctx := context.Background()
+	prover1 := &unreadyProver{ready: true}
+	prover2 := &unreadyProver{ready: true}
+	pool := NewProversPool(ProversPoolCfg{}, []prover.Client{prover1, prover2})
+	assert.Equal(t, 2, pool.CheckHealth(ctx, time.Second))
+
+	// The excluded provers are not returned even if they are available
+	serverProof, err := pool.Get(ctx, prover1)
+	require.NoError(t, err)
+	assert.Equal(t, prover2, serverProof)
+	pool.Release(prover2)
+	_, err = pool.Get(ctx, prover1, prover2)
+	assert.Equal(t, errNoUntriedProvers, tracerr.Unwrap(err))
+}
+
+func TestProversPoolCfgDefaults(t *testing.T) {
+	pool := NewProversPool(ProversPoolCfg{}, nil)
+	assert.Equal(t, defaultHealthCheckInterval, pool.cfg.HealthCheckInterval)
+	assert.Equal(t, defaultHealthCheckTimeout, pool.cfg.HealthCheckTimeout)
+	assert.Equal(t, defaultMaxErrors, pool.cfg.MaxErrors)
+
+	cfg := NewProversPoolCfg(&config.ProversPool{
+		HealthCheckInterval: config.Duration{Duration: time.Minute},
+		HealthCheckTimeout:  config.Duration{Duration: time.Second},
+		ProofTimeout:        config.Duration{Duration: time.Hour},
+		MaxErrors:           2,
+		QuarantineTime:      config.Duration{Duration: 10 * time.Minute},
+	})
+	assert.Equal(t, ProversPoolCfg{
+		HealthCheckInterval: time.Minute,
+		HealthCheckTimeout:  time.Second,
+		ProofTimeout:        time.Hour,
+		MaxErrors:           2,
+		QuarantineTime:      10 * time.Minute,
+	}, cfg)
+}
+
+func TestProversPoolServerProofs(t *testing.T) {
+	mockProver := &unreadyProver{ready: true}
+	serverProof1 := prover.NewProofServerClient("http://localhost:3000/api", time.Second)
+	pool := NewProversPool(ProversPoolCfg{}, []prover.Client{mockProver, serverProof1})
+	assert.Equal(t, []string{"http://localhost:3000/api"}, pool.ServerProofURLs())
+
+	// The server proofs are identified by their URL
+	serverProof2 := prover.NewProofServerClient("http://localhost:3001/api", time.Second)
+	require.NoError(t, pool.AddServerProof(serverProof2))
+	assert.Error(t, pool.AddServerProof(
+		prover.NewProofServerClient("http://localhost:3001/api", time.Second)))
+	assert.Equal(t, []string{"http://localhost:3000/api", "http://localhost:3001/api"},
+		pool.ServerProofURLs())
+	assert.Equal(t, 3, pool.Len())
+
+	require.NoError(t, pool.RemoveServerProof("http://localhost:3000/api"))
+	assert.Error(t, pool.RemoveServerProof("http://localhost:3000/api"))
+	assert.Error(t, pool.RemoveServerProof("http://localhost:3002/api"))
+	assert.Equal(t, []string{"http://localhost:3001/api"}, pool.ServerProofURLs())
+	assert.Equal(t, 2, pool.Len())
+}
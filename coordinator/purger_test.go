@@ -19,7 +19,7 @@ func newL2DB(t *testing.T) *l2db.L2DB {
 	db, err := dbUtils.InitTestSQLDB()
 	require.NoError(t, err)
 	test.WipeDB(db)
-	return l2db.NewL2DB(db, db, 10, 100, 0.0, 1000.0, 24*time.Hour, nil)
+	return l2db.NewL2DB(db, db, 10, 100, 0.0, 1000.0, 0.0, 24*time.Hour, nil)
 }
 
 func newStateDB(t *testing.T) (*statedb.LocalStateDB, *statedb.StateDB) {
@@ -4,12 +4,14 @@ import (
 	"context"
 	"fmt"
 	"math/big"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethCommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/params"
@@ -22,6 +24,15 @@ import (
 	"github.com/chainbing/tracerr"
 )
 
+const (
+	// feeHistoryBlocks is the number of last blocks whose fees are used
+	// to calculate the fees of the dynamic fee transactions
+	feeHistoryBlocks = 10
+	// feeHistoryRewardPercentile is the percentile of the priority fees
+	// paid in each block that is taken into account
+	feeHistoryRewardPercentile = 50
+)
+
 // TxManager handles everything related to ethereum transactions:  It makes the
 // call to forge, waits for transaction confirmation, and keeps checking them
 // until a number of confirmed blocks have passed.
@@ -29,7 +40,7 @@ type TxManager struct {
 	cfg              Config
 	ethClient        eth.ClientInterface
 	etherscanService *etherscan.Service
-	l2DB             *l2db.L2DB   // Used only to mark forged txs as forged in the L2DB
+	l2DB             *l2db.L2DB   // Used to mark forged txs as forged and to store the sent batches in the L2DB
 	coord            *Coordinator // Used only to send messages to stop the pipeline
 	batchCh          chan *BatchInfo
 	chainID          *big.Int
@@ -72,8 +83,15 @@ func NewTxManager(ctx context.Context, cfg *Config, ethClient eth.ClientInterfac
 	if err != nil {
 		return nil, tracerr.Wrap(err)
 	}
-	log.Infow("TxManager started", "nonce", accNonce)
-	return &TxManager{
+	// The batches sent before a restart are restored so that their eth
+	// txs are checked, and their nonces are not reused
+	queue, accNextNonce, err := restoreQueue(l2DB, accNonce)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	log.Infow("TxManager started", "nonce", accNonce, "nextNonce", accNextNonce,
+		"restoredBatches", queue.Len())
+	t := &TxManager{
 		cfg:               *cfg,
 		ethClient:         ethClient,
 		etherscanService:  etherscanService,
@@ -91,10 +109,133 @@ func NewTxManager(ctx context.Context, cfg *Config, ethClient eth.ClientInterfac
 		vars: *initSCVars,
 
 		minPipelineNum: 0,
-		queue:          NewQueue(),
+		queue:          queue,
 		accNonce:       accNonce,
-		accNextNonce:   accNonce,
-	}, nil
+		accNextNonce:   accNextNonce,
+	}
+	// The auth of the restored batches is rebuilt from their last eth tx,
+	// so that they can be resent with the same nonce
+	for i := 0; i < t.queue.Len(); i++ {
+		batchInfo := t.queue.At(i)
+		if batchInfo.Auth, err = t.restoredAuth(batchInfo.EthTxs[len(batchInfo.EthTxs)-1]); err != nil {
+			return nil, tracerr.Wrap(err)
+		}
+	}
+	return t, nil
+}
+
+// restoreQueue returns the queue of the batches stored in the L2DB, and the
+// nonce to use in the next tx, which is the one following the nonces used by
+// the restored batches.  The restored batches don't have the ForgeBatchArgs
+// nor the PipelineNum, so they are resent by signing again the data of their
+// last eth tx.
+func restoreQueue(l2DB *l2db.L2DB, accNonce uint64) (Queue, uint64, error) {
+	queue := NewQueue()
+	batches, err := l2DB.GetTxManagerBatches()
+	if err != nil {
+		return queue, 0, tracerr.Wrap(err)
+	}
+	accNextNonce := accNonce
+	for i := range batches {
+		batch := &batches[i]
+		if len(batch.EthTxs) == 0 {
+			continue
+		}
+		batchInfo := &BatchInfo{
+			BatchNum:      batch.BatchNum,
+			L1Batch:       batch.L1Batch,
+			EthTxs:        batch.EthTxs,
+			SendTimestamp: batch.SendTimestamp,
+		}
+		batchInfo.Debug.Status = Status(batch.Status)
+		batchInfo.Debug.ResendNum = batch.ResendNum
+		batchInfo.Debug.SendTimestamp = batch.SendTimestamp
+		batchInfo.Debug.SendBlockNum = batch.SendBlockNum
+		queue.Push(batchInfo)
+		if batch.Nonce+1 > accNextNonce {
+			accNextNonce = batch.Nonce + 1
+		}
+	}
+	return queue, accNextNonce, nil
+}
+
+// storeBatchInfo stores the sent eth txs of the batch in the L2DB, so that
+// they can be checked after a restart.  The last eth tx is enough to resend
+// the batch with the same nonce and calldata.
+func (t *TxManager) storeBatchInfo(batchInfo *BatchInfo) error {
+	if len(batchInfo.EthTxs) == 0 {
+		return nil
+	}
+	return tracerr.Wrap(t.l2DB.StoreTxManagerBatch(&l2db.TxManagerBatch{
+		BatchNum:      batchInfo.BatchNum,
+		L1Batch:       batchInfo.L1Batch,
+		Nonce:         batchInfo.EthTxs[len(batchInfo.EthTxs)-1].Nonce(),
+		Status:        string(batchInfo.Debug.Status),
+		ResendNum:     batchInfo.Debug.ResendNum,
+		EthTxs:        batchInfo.EthTxs,
+		SendTimestamp: batchInfo.SendTimestamp,
+		SendBlockNum:  batchInfo.Debug.SendBlockNum,
+	}))
+}
+
+// storeSignedEthTx stores the batch with its signed ethTx before the tx is
+// sent, so that the tx is checked after a restart even if the TxManager
+// stops or fails to store it once sent.  A restored tx that was never sent
+// is not mined, so it's resent after EthTxResendTimeout.
+func (t *TxManager) storeSignedEthTx(batchInfo *BatchInfo, ethTx *types.Transaction) error {
+	ethTxs := make([]*types.Transaction, 0, len(batchInfo.EthTxs)+1)
+	ethTxs = append(append(ethTxs, batchInfo.EthTxs...), ethTx)
+	return tracerr.Wrap(t.l2DB.StoreTxManagerBatch(&l2db.TxManagerBatch{
+		BatchNum:      batchInfo.BatchNum,
+		L1Batch:       batchInfo.L1Batch,
+		Nonce:         ethTx.Nonce(),
+		Status:        string(StatusSigned),
+		ResendNum:     batchInfo.Debug.ResendNum,
+		EthTxs:        ethTxs,
+		SendTimestamp: time.Now(),
+		SendBlockNum:  t.stats.Eth.LastBlock.Num + 1,
+	}))
+}
+
+// restoredAuth rebuilds the auth of a restored batch from its last eth tx
+func (t *TxManager) restoredAuth(ethTx *types.Transaction) (*bind.TransactOpts, error) {
+	auth, err := bind.NewKeyStoreTransactorWithChainID(t.ethClient.EthKeyStore(), t.account, t.chainID)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	auth.Value = ethTx.Value()
+	auth.GasLimit = ethTx.Gas()
+	auth.Nonce = new(big.Int).SetUint64(ethTx.Nonce())
+	if ethTx.Type() == types.DynamicFeeTxType {
+		auth.GasFeeCap = ethTx.GasFeeCap()
+		auth.GasTipCap = ethTx.GasTipCap()
+	} else {
+		auth.GasPrice = ethTx.GasPrice()
+	}
+	return auth, nil
+}
+
+// lastRestoredBatchNum returns the highest BatchNum of the batches restored
+// from the L2DB, or 0 if there are none.  It must be called before Run.
+func (t *TxManager) lastRestoredBatchNum() common.BatchNum {
+	var batchNum common.BatchNum
+	for i := 0; i < t.queue.Len(); i++ {
+		if t.queue.At(i).BatchNum > batchNum {
+			batchNum = t.queue.At(i).BatchNum
+		}
+	}
+	return batchNum
+}
+
+// removeFromQueue removes the BatchInfo at position from the queue and from
+// the L2DB
+func (t *TxManager) removeFromQueue(position int) {
+	batchInfo := t.queue.At(position)
+	t.queue.Remove(position)
+	if err := t.l2DB.RemoveTxManagerBatch(batchInfo.BatchNum); err != nil {
+		log.Errorw("TxManager: l2DB.RemoveTxManagerBatch", "err", err,
+			"batch", batchInfo.BatchNum)
+	}
 }
 
 // AddBatch is a thread safe method to pass a new batch TxManager to be sent to
@@ -130,6 +271,98 @@ func (t *TxManager) syncSCVars(vars common.SCVariablesPtr) {
 
 // NewAuth generates a new auth object for an ethereum transaction
 func (t *TxManager) NewAuth(ctx context.Context, batchInfo *BatchInfo) (*bind.TransactOpts, error) {
+	gasPrice, gasFeeCap, gasTipCap, err := t.gasFees(ctx)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	auth, err := bind.NewKeyStoreTransactorWithChainID(t.ethClient.EthKeyStore(), t.account, t.chainID)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	auth.Value = big.NewInt(0) // in wei
+
+	auth.GasLimit = forgeBatchGasLimit(t.cfg.ForgeBatchGasCost, len(batchInfo.L1UserTxs),
+		len(batchInfo.L1CoordTxs), len(batchInfo.L2Txs))
+	auth.GasPrice = gasPrice
+	auth.GasFeeCap = gasFeeCap
+	auth.GasTipCap = gasTipCap
+	auth.Nonce = nil
+	auth.Context = ctx
+
+	return auth, nil
+}
+
+// gasFees returns the fees of a new ethereum transaction.  If the chain
+// supports EIP-1559, the maxFeePerGas (gasFeeCap) and maxPriorityFeePerGas
+// (gasTipCap) of a dynamic fee transaction are returned.  Otherwise the
+// gasPrice of a legacy transaction is returned.
+func (t *TxManager) gasFees(ctx context.Context) (gasPrice, gasFeeCap, gasTipCap *big.Int,
+	err error) {
+	baseFee, gasFeeCap, gasTipCap, err := t.dynamicGasFees(ctx)
+	if err != nil {
+		return nil, nil, nil, tracerr.Wrap(err)
+	} else if baseFee != nil {
+		return nil, gasFeeCap, gasTipCap, nil
+	}
+	gasPrice, err = t.legacyGasPrice(ctx)
+	if err != nil {
+		return nil, nil, nil, tracerr.Wrap(err)
+	}
+	return gasPrice, nil, nil, nil
+}
+
+// effectiveGasPrice returns the price per gas that a new ethereum transaction
+// is expected to pay: the base fee plus the priority fee of a dynamic fee
+// transaction, bounded by its maxFeePerGas, or the gasPrice of a legacy
+// transaction if the chain doesn't support EIP-1559.
+func (t *TxManager) effectiveGasPrice(ctx context.Context) (*big.Int, error) {
+	baseFee, gasFeeCap, gasTipCap, err := t.dynamicGasFees(ctx)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	} else if baseFee == nil {
+		gasPrice, err := t.legacyGasPrice(ctx)
+		return gasPrice, tracerr.Wrap(err)
+	}
+	gasPrice := new(big.Int).Add(baseFee, gasTipCap)
+	if gasPrice.Cmp(gasFeeCap) > 0 {
+		gasPrice.Set(gasFeeCap)
+	}
+	return gasPrice, nil
+}
+
+// dynamicGasFees returns the base fee of the next block, and the maxFeePerGas
+// (gasFeeCap) and maxPriorityFeePerGas (gasTipCap) of a dynamic fee
+// transaction, calculated from the fee history of the last blocks.  The base
+// fee is nil if the chain doesn't support EIP-1559 or the fee history can't
+// be obtained.
+func (t *TxManager) dynamicGasFees(ctx context.Context) (baseFee, gasFeeCap,
+	gasTipCap *big.Int, err error) {
+	feeHistory, err := t.ethClient.EthFeeHistory(ctx, feeHistoryBlocks,
+		[]float64{feeHistoryRewardPercentile})
+	if err != nil {
+		log.Warnw("TxManager ethClient.EthFeeHistory, using legacy gas price", "err", err)
+		return nil, nil, nil, nil
+	}
+	baseFee = nextBaseFee(feeHistory)
+	if baseFee == nil {
+		return nil, nil, nil, nil
+	}
+	gasTipCap = medianReward(feeHistory)
+	if gasTipCap == nil {
+		if gasTipCap, err = t.ethClient.EthSuggestGasTipCap(ctx); err != nil {
+			return nil, nil, nil, tracerr.Wrap(err)
+		}
+	}
+	gasFeeCap, gasTipCap = dynamicFees(baseFee, gasTipCap, t.cfg.GasPriceIncPerc,
+		gweiToWei(t.cfg.MinGasPrice), gweiToWei(t.cfg.MaxGasPrice))
+	log.Debugw("TxManager: transaction metadata", "baseFee", baseFee,
+		"maxFeePerGas", gasFeeCap, "maxPriorityFeePerGas", gasTipCap)
+	return baseFee, gasFeeCap, gasTipCap, nil
+}
+
+// legacyGasPrice returns the gas price of a legacy transaction
+func (t *TxManager) legacyGasPrice(ctx context.Context) (*big.Int, error) {
 	// First we try getting the gas price from etherscan. Later we get the gas price from the ethereum node.
 	var err error
 
@@ -177,30 +410,68 @@ func (t *TxManager) NewAuth(ctx context.Context, batchInfo *BatchInfo) (*bind.Tr
 
 	log.Debugw("TxManager: transaction metadata", "gasPrice", gasPrice)
 
-	if t.cfg.GasPriceIncPerc != 0 {
-		inc := new(big.Int).Set(gasPrice)
-		inc.Mul(inc, new(big.Int).SetInt64(t.cfg.GasPriceIncPerc))
-		// nolint reason: to calculate percentages we use 100
-		inc.Div(inc, new(big.Int).SetUint64(100)) //nolint:gomnd
-		gasPrice.Add(gasPrice, inc)
+	gasPrice = increaseGasPrice(gasPrice, t.cfg.GasPriceIncPerc)
+
+	return gasPrice, nil
+}
+
+// nextBaseFee returns the base fee of the next block, or nil if the chain
+// doesn't support EIP-1559
+func nextBaseFee(feeHistory *ethereum.FeeHistory) *big.Int {
+	if len(feeHistory.BaseFee) == 0 {
+		return nil
+	}
+	baseFee := feeHistory.BaseFee[len(feeHistory.BaseFee)-1]
+	if baseFee == nil || baseFee.Sign() <= 0 {
+		return nil
 	}
+	return baseFee
+}
 
-	auth, err := bind.NewKeyStoreTransactorWithChainID(t.ethClient.EthKeyStore(), t.account, t.chainID)
-	if err != nil {
-		return nil, tracerr.Wrap(err)
+// medianReward returns the median of the priority fees paid in the blocks of
+// the fee history, or nil if they are unknown or 0
+func medianReward(feeHistory *ethereum.FeeHistory) *big.Int {
+	rewards := []*big.Int{}
+	for _, reward := range feeHistory.Reward {
+		if len(reward) > 0 && reward[0] != nil {
+			rewards = append(rewards, reward[0])
+		}
 	}
-	auth.Value = big.NewInt(0) // in wei
+	if len(rewards) == 0 {
+		return nil
+	}
+	sort.Slice(rewards, func(i, j int) bool { return rewards[i].Cmp(rewards[j]) < 0 })
+	median := rewards[len(rewards)/2]
+	if median.Sign() <= 0 {
+		return nil
+	}
+	return new(big.Int).Set(median)
+}
 
-	gasLimit := t.cfg.ForgeBatchGasCost.Fixed +
-		uint64(len(batchInfo.L1UserTxs))*t.cfg.ForgeBatchGasCost.L1UserTx +
-		uint64(len(batchInfo.L1CoordTxs))*t.cfg.ForgeBatchGasCost.L1CoordTx +
-		uint64(len(batchInfo.L2Txs))*t.cfg.ForgeBatchGasCost.L2Tx
-	auth.GasLimit = gasLimit
-	auth.GasPrice = gasPrice
-	auth.Nonce = nil
-	auth.Context = ctx
+// dynamicFees returns the maxFeePerGas and maxPriorityFeePerGas of a dynamic
+// fee transaction.  The maxFeePerGas is twice the base fee plus the priority
+// fee, so that the transaction remains valid after a few full blocks, bounded
+// by minFeeCap and maxFeeCap.
+func dynamicFees(baseFee, gasTipCap *big.Int, incPerc int64,
+	minFeeCap, maxFeeCap *big.Int) (*big.Int, *big.Int) {
+	gasTipCap = increaseGasPrice(gasTipCap, incPerc)
+	gasFeeCap := new(big.Int).Mul(baseFee, big.NewInt(2)) //nolint:gomnd
+	gasFeeCap.Add(gasFeeCap, gasTipCap)
+	if gasFeeCap.Cmp(minFeeCap) < 0 {
+		gasFeeCap.Set(minFeeCap)
+	}
+	if gasFeeCap.Cmp(maxFeeCap) > 0 {
+		gasFeeCap.Set(maxFeeCap)
+	}
+	if gasTipCap.Cmp(gasFeeCap) > 0 {
+		gasTipCap = new(big.Int).Set(gasFeeCap)
+	}
+	return gasFeeCap, gasTipCap
+}
 
-	return auth, nil
+// gweiToWei converts an amount in gwei to wei
+func gweiToWei(gwei int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(gwei), big.NewInt(params.GWei))
 }
 
 func (t *TxManager) shouldSendRollupForgeBatch(batchInfo *BatchInfo) error {
@@ -239,6 +510,75 @@ func addPerc(v *big.Int, p int64) *big.Int {
 	return r.Add(v, r)
 }
 
+// bumpGasFees increases the fees of an ethereum transaction so that it can
+// replace a pending one.  Both the maxFeePerGas and maxPriorityFeePerGas of a
+// dynamic fee transaction must be increased.
+func bumpGasFees(auth *bind.TransactOpts) {
+	if auth.GasFeeCap != nil {
+		auth.GasFeeCap = addPerc(auth.GasFeeCap, 10) //nolint:gomnd
+		auth.GasTipCap = addPerc(auth.GasTipCap, 10) //nolint:gomnd
+		return
+	}
+	auth.GasPrice = addPerc(auth.GasPrice, 10) //nolint:gomnd
+}
+
+// sendForgeBatchTx sends the forge call of the batch with auth.  The restored
+// batches don't have the ForgeBatchArgs, so the calldata of their last eth tx
+// is signed again with the nonce and fees of auth.  The tx is stored in the
+// L2DB once signed, before it's sent, and it's not sent if it can't be stored.
+func (t *TxManager) sendForgeBatchTx(ctx context.Context, batchInfo *BatchInfo,
+	auth *bind.TransactOpts) (*types.Transaction, error) {
+	signer := auth.Signer
+	writeAheadAuth := *auth
+	writeAheadAuth.Signer = func(from ethCommon.Address,
+		tx *types.Transaction) (*types.Transaction, error) {
+		signedTx, err := signer(from, tx)
+		if err != nil {
+			return nil, tracerr.Wrap(err)
+		}
+		if err := t.storeSignedEthTx(batchInfo, signedTx); err != nil {
+			return nil, tracerr.Wrap(fmt.Errorf("l2DB.StoreTxManagerBatch: %w", err))
+		}
+		return signedTx, nil
+	}
+	auth = &writeAheadAuth
+	if batchInfo.ForgeBatchArgs != nil {
+		ethTx, err := t.ethClient.RollupForgeBatch(batchInfo.ForgeBatchArgs, auth)
+		return ethTx, tracerr.Wrap(err)
+	}
+	lastEthTx := batchInfo.EthTxs[len(batchInfo.EthTxs)-1]
+	var txData types.TxData
+	if auth.GasFeeCap != nil {
+		txData = &types.DynamicFeeTx{
+			ChainID:   t.chainID,
+			Nonce:     auth.Nonce.Uint64(),
+			GasTipCap: auth.GasTipCap,
+			GasFeeCap: auth.GasFeeCap,
+			Gas:       auth.GasLimit,
+			To:        lastEthTx.To(),
+			Value:     auth.Value,
+			Data:      lastEthTx.Data(),
+		}
+	} else {
+		txData = &types.LegacyTx{
+			Nonce:    auth.Nonce.Uint64(),
+			GasPrice: auth.GasPrice,
+			Gas:      auth.GasLimit,
+			To:       lastEthTx.To(),
+			Value:    auth.Value,
+			Data:     lastEthTx.Data(),
+		}
+	}
+	ethTx, err := auth.Signer(auth.From, types.NewTx(txData))
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	if err := t.ethClient.EthSendTransaction(ctx, ethTx); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	return ethTx, nil
+}
+
 func (t *TxManager) sendRollupForgeBatch(ctx context.Context, batchInfo *BatchInfo,
 	resend bool) error {
 	var ethTx *types.Transaction
@@ -246,7 +586,7 @@ func (t *TxManager) sendRollupForgeBatch(ctx context.Context, batchInfo *BatchIn
 	var auth *bind.TransactOpts
 	if resend {
 		auth = batchInfo.Auth
-		auth.GasPrice = addPerc(auth.GasPrice, 10)
+		bumpGasFees(auth)
 	} else {
 		auth, err = t.NewAuth(ctx, batchInfo)
 		if err != nil {
@@ -257,14 +597,16 @@ func (t *TxManager) sendRollupForgeBatch(ctx context.Context, batchInfo *BatchIn
 	}
 	auth.Context = ctx
 	for attempt := 0; attempt < t.cfg.EthClientAttempts; attempt++ {
-		maxGasPrice := big.NewInt(t.cfg.MaxGasPrice)
-		maxGasPrice.Mul(maxGasPrice, big.NewInt(params.GWei))
-		if auth.GasPrice.Cmp(maxGasPrice) > 0 {
+		maxGasPrice := gweiToWei(t.cfg.MaxGasPrice)
+		if auth.GasFeeCap != nil && auth.GasFeeCap.Cmp(maxGasPrice) > 0 {
+			return tracerr.Wrap(fmt.Errorf("calculated maxFeePerGas (%v) > maxGasPrice (%v)",
+				auth.GasFeeCap, t.cfg.MaxGasPrice))
+		} else if auth.GasPrice != nil && auth.GasPrice.Cmp(maxGasPrice) > 0 {
 			return tracerr.Wrap(fmt.Errorf("calculated gasPrice (%v) > maxGasPrice (%v)",
 				auth.GasPrice, t.cfg.MaxGasPrice))
 		}
 		// RollupForgeBatch() calls ethclient.SendTransaction()
-		ethTx, err = t.ethClient.RollupForgeBatch(batchInfo.ForgeBatchArgs, auth)
+		ethTx, err = t.sendForgeBatchTx(ctx, batchInfo, auth)
 		// We check the errors via strings because we match the
 		// definition of the error from geth, with the string returned
 		// via RPC obtained by the client.
@@ -282,13 +624,24 @@ func (t *TxManager) sendRollupForgeBatch(ctx context.Context, batchInfo *BatchIn
 			attempt--
 		} else if strings.Contains(err.Error(), core.ErrReplaceUnderpriced.Error()) {
 			log.Warnw("TxManager ethClient.RollupForgeBatch incrementing gasPrice",
-				"err", err, "gasPrice", auth.GasPrice, "batchNum", batchInfo.BatchNum)
-			auth.GasPrice = addPerc(auth.GasPrice, 10)
+				"err", err, "gasPrice", auth.GasPrice, "maxFeePerGas", auth.GasFeeCap,
+				"maxPriorityFeePerGas", auth.GasTipCap, "batchNum", batchInfo.BatchNum)
+			bumpGasFees(auth)
 			attempt--
 		} else if strings.Contains(err.Error(), core.ErrUnderpriced.Error()) {
 			log.Warnw("TxManager ethClient.RollupForgeBatch incrementing gasPrice",
-				"err", err, "gasPrice", auth.GasPrice, "batchNum", batchInfo.BatchNum)
-			auth.GasPrice = addPerc(auth.GasPrice, 10)
+				"err", err, "gasPrice", auth.GasPrice, "maxFeePerGas", auth.GasFeeCap,
+				"maxPriorityFeePerGas", auth.GasTipCap, "batchNum", batchInfo.BatchNum)
+			bumpGasFees(auth)
+			attempt--
+		} else if auth.GasFeeCap != nil &&
+			strings.Contains(err.Error(), types.ErrTxTypeNotSupported.Error()) {
+			log.Warnw("TxManager ethClient.RollupForgeBatch using legacy gas price",
+				"err", err, "batchNum", batchInfo.BatchNum)
+			if auth.GasPrice, err = t.legacyGasPrice(ctx); err != nil {
+				return tracerr.Wrap(err)
+			}
+			auth.GasFeeCap, auth.GasTipCap = nil, nil
 			attempt--
 		} else {
 			log.Errorw("TxManager ethClient.RollupForgeBatch",
@@ -321,6 +674,13 @@ func (t *TxManager) sendRollupForgeBatch(ctx context.Context, batchInfo *BatchIn
 	batchInfo.Debug.StartToSendDelay = batchInfo.Debug.SendTimestamp.Sub(
 		batchInfo.Debug.StartTimestamp).Seconds()
 	t.cfg.debugBatchStore(batchInfo)
+	// The tx has been sent, so the errors from here on are only logged and
+	// the batch is still queued to check its receipt.  The signed tx was
+	// stored before sending it, so it's restored after a restart anyway.
+	if err := t.storeBatchInfo(batchInfo); err != nil {
+		log.Errorw("TxManager: l2DB.StoreTxManagerBatch", "err", err,
+			"batch", batchInfo.BatchNum)
+	}
 
 	if !resend {
 		if batchInfo.L1Batch {
@@ -329,7 +689,8 @@ func (t *TxManager) sendRollupForgeBatch(ctx context.Context, batchInfo *BatchIn
 	}
 	if err := t.l2DB.DoneForging(common.TxIDsFromL2Txs(batchInfo.L2Txs),
 		batchInfo.BatchNum); err != nil {
-		return tracerr.Wrap(err)
+		log.Errorw("TxManager: l2DB.DoneForging", "err", err,
+			"batch", batchInfo.BatchNum)
 	}
 	return nil
 }
@@ -398,8 +759,16 @@ func (t *TxManager) handleReceipt(ctx context.Context, batchInfo *BatchInfo) (*i
 			return nil, tracerr.Wrap(fmt.Errorf(
 				"ethereum transaction receipt status is failed: %w", err))
 		} else if receipt.Status == types.ReceiptStatusSuccessful {
-			batchInfo.Debug.Status = StatusMined
 			batchInfo.Debug.MineBlockNum = receipt.BlockNumber.Int64()
+			if batchInfo.Debug.Status != StatusMined {
+				batchInfo.Debug.Status = StatusMined
+				// The stored status is only informative, the
+				// receipt is checked again after a restart
+				if err := t.storeBatchInfo(batchInfo); err != nil {
+					log.Warnw("TxManager: l2DB.StoreTxManagerBatch", "err", err,
+						"batch", batchInfo.BatchNum)
+				}
+			}
 			batchInfo.Debug.StartToMineBlocksDelay = batchInfo.Debug.MineBlockNum -
 				batchInfo.Debug.StartBlockNum
 			if batchInfo.Debug.StartToMineDelay == 0 {
@@ -500,7 +869,23 @@ func (t *TxManager) Run(ctx context.Context) {
 	log.Infow("TxManager: received initial statsVars",
 		"block", t.stats.Eth.LastBlock.Num, "batch", t.stats.Eth.LastBatchNum)
 
+	// The restored batches are checked before sending the batches of the
+	// pipeline
+	if failedBatchNum, err := t.checkRestoredBatchInfos(ctx); ctx.Err() != nil {
+		log.Info("TxManager done")
+		return
+	} else if err != nil {
+		log.Errorw("TxManager: checkRestoredBatchInfos", "err", err)
+		t.coord.SendMsg(ctx, MsgStopPipeline{
+			Reason:         fmt.Sprintf("forgeBatch restored: %v", err),
+			FailedBatchNum: failedBatchNum,
+		})
+	}
+
 	timer := time.NewTimer(longWaitDuration)
+	if t.queue.Len() > 0 {
+		timer.Reset(t.cfg.TxManagerCheckInterval)
+	}
 	for {
 		select {
 		case <-ctx.Done():
@@ -536,8 +921,9 @@ func (t *TxManager) Run(ctx context.Context) {
 				// If we reach here it's because our ethNode has
 				// been unable to send the transaction to
 				// ethereum.  This could be due to the ethNode
-				// failure, or an invalid transaction (that
-				// can't be mined)
+				// failure, an invalid transaction (that
+				// can't be mined), or the L2DB failing to
+				// store the signed transaction
 				log.Warnw("TxManager: forgeBatch send failed", "err", err,
 					"batch", batchInfo.BatchNum)
 				t.coord.SendMsg(ctx, MsgStopPipeline{
@@ -620,7 +1006,7 @@ func (t *TxManager) Run(ctx context.Context) {
 				}
 				log.Debugw("TxManager: forgeBatch tx confirmed",
 					"txs", txsHashes, "batch", batchInfo.BatchNum)
-				t.queue.Remove(queuePosition)
+				t.removeFromQueue(queuePosition)
 			}
 		}
 	}
@@ -653,14 +1039,15 @@ func (t *TxManager) removeBadBatchInfos(ctx context.Context) error {
 			if t.minPipelineNum <= batchInfo.PipelineNum {
 				t.minPipelineNum = batchInfo.PipelineNum + 1
 			}
-			t.queue.Remove(next)
+			t.removeFromQueue(next)
 			continue
 		}
 		// If tx is pending but is from a cancelled pipeline, remove it
-		// from the queue
+		// from the queue.  The restored batches (PipelineNum 0) don't
+		// belong to any pipeline, so they are kept and resent.
 		if confirm == nil {
-			if batchInfo.PipelineNum < t.minPipelineNum {
-				t.queue.Remove(next)
+			if batchInfo.PipelineNum != 0 && batchInfo.PipelineNum < t.minPipelineNum {
+				t.removeFromQueue(next)
 				continue
 			}
 		}
@@ -672,10 +1059,61 @@ func (t *TxManager) removeBadBatchInfos(ctx context.Context) error {
 	}
 	if !t.cfg.EthNoReuseNonce {
 		t.accNextNonce = accNonce
+		// The nonces of the pending restored batches are not reused
+		for i := 0; i < t.queue.Len(); i++ {
+			batchInfo := t.queue.At(i)
+			nonce := batchInfo.EthTxs[len(batchInfo.EthTxs)-1].Nonce()
+			if batchInfo.PipelineNum == 0 && nonce+1 > t.accNextNonce {
+				t.accNextNonce = nonce + 1
+			}
+		}
 	}
 	return nil
 }
 
+// checkRestoredBatchInfos checks the receipts of the batches restored from the
+// L2DB.  The confirmed ones are removed from the queue, and the failed ones
+// are removed and returned as an error, together with the lowest failed
+// BatchNum, from which the pipeline must forge again.
+func (t *TxManager) checkRestoredBatchInfos(ctx context.Context) (common.BatchNum, error) {
+	var failedBatchNums []common.BatchNum
+	next := 0
+	for {
+		batchInfo := t.queue.At(next)
+		if batchInfo == nil {
+			break
+		}
+		if err := t.checkEthTransactionReceipt(ctx, batchInfo); err != nil {
+			return 0, tracerr.Wrap(err)
+		}
+		confirm, err := t.handleReceipt(ctx, batchInfo)
+		if ctx.Err() != nil {
+			return 0, tracerr.Wrap(common.ErrDone)
+		} else if err != nil {
+			log.Warnw("TxManager: restored forgeBatch tx failed", "err", err,
+				"batch", batchInfo.BatchNum)
+			failedBatchNums = append(failedBatchNums, batchInfo.BatchNum)
+			t.removeFromQueue(next)
+			continue
+		}
+		if confirm != nil && *confirm >= t.cfg.ConfirmBlocks {
+			log.Debugw("TxManager: restored forgeBatch tx confirmed",
+				"batch", batchInfo.BatchNum)
+			t.removeFromQueue(next)
+			continue
+		}
+		next++
+	}
+	if len(failedBatchNums) > 0 {
+		sort.Slice(failedBatchNums, func(i, j int) bool {
+			return failedBatchNums[i] < failedBatchNums[j]
+		})
+		return failedBatchNums[0], tracerr.Wrap(fmt.Errorf("restored batches failed: %v",
+			failedBatchNums))
+	}
+	return 0, nil
+}
+
 func (t *TxManager) canForgeAt(blockNum int64) bool {
 	return canForge(&t.consts.Auction, &t.vars.Auction,
 		&t.stats.Sync.Auction.CurrentSlot, &t.stats.Sync.Auction.NextSlot,
@@ -1,10 +1,22 @@
 package coordinator
 
 import (
+	"context"
+	"fmt"
 	"math/big"
 	"testing"
 
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/chainbing/node/common"
+	"github.com/chainbing/node/eth"
+	"github.com/chainbing/node/test"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAddPerc(t *testing.T) {
@@ -13,3 +25,406 @@ func TestAddPerc(t *testing.T) {
 	assert.Equal(t, "12", addPerc(big.NewInt(10), 20).String())
 	assert.Equal(t, "1500", addPerc(big.NewInt(1000), 50).String())
 }
+
+func TestDynamicFees(t *testing.T) {
+	feeCap, tipCap := dynamicFees(big.NewInt(100), big.NewInt(10), 0,
+		big.NewInt(0), big.NewInt(1000))
+	assert.Equal(t, "210", feeCap.String())
+	assert.Equal(t, "10", tipCap.String())
+	// The increase applies to the priority fee
+	feeCap, tipCap = dynamicFees(big.NewInt(100), big.NewInt(10), 50,
+		big.NewInt(0), big.NewInt(1000))
+	assert.Equal(t, "215", feeCap.String())
+	assert.Equal(t, "15", tipCap.String())
+	// The fee cap is bounded, and the priority fee can't exceed it
+	feeCap, tipCap = dynamicFees(big.NewInt(100), big.NewInt(10), 0,
+		big.NewInt(300), big.NewInt(1000))
+	assert.Equal(t, "300", feeCap.String())
+	assert.Equal(t, "10", tipCap.String())
+	feeCap, tipCap = dynamicFees(big.NewInt(100), big.NewInt(500), 0,
+		big.NewInt(0), big.NewInt(400))
+	assert.Equal(t, "400", feeCap.String())
+	assert.Equal(t, "400", tipCap.String())
+}
+
+func TestFeeHistory(t *testing.T) {
+	feeHistory := &ethereum.FeeHistory{
+		Reward: [][]*big.Int{
+			{big.NewInt(3)}, {big.NewInt(1)}, {big.NewInt(2)}, {nil},
+		},
+		BaseFee: []*big.Int{big.NewInt(10), big.NewInt(11), big.NewInt(12)},
+	}
+	assert.Equal(t, "12", nextBaseFee(feeHistory).String())
+	assert.Equal(t, "2", medianReward(feeHistory).String())
+
+	// Before London the base fees are 0 and there are no rewards
+	feeHistory = &ethereum.FeeHistory{
+		Reward:  [][]*big.Int{{big.NewInt(0)}},
+		BaseFee: []*big.Int{big.NewInt(0), big.NewInt(0)},
+	}
+	assert.Nil(t, nextBaseFee(feeHistory))
+	assert.Nil(t, medianReward(feeHistory))
+}
+
+func TestBumpGasFees(t *testing.T) {
+	auth := &bind.TransactOpts{GasPrice: big.NewInt(100)}
+	bumpGasFees(auth)
+	assert.Equal(t, "110", auth.GasPrice.String())
+
+	auth = &bind.TransactOpts{GasFeeCap: big.NewInt(200), GasTipCap: big.NewInt(10)}
+	bumpGasFees(auth)
+	assert.Nil(t, auth.GasPrice)
+	assert.Equal(t, "220", auth.GasFeeCap.String())
+	assert.Equal(t, "11", auth.GasTipCap.String())
+}
+
+func TestTxManagerGasFees(t *testing.T) {
+	ctx := context.Background()
+	var timer timer
+	ethClient := test.NewClient(true, &timer, &forger, test.NewClientSetupExample())
+	txManager := &TxManager{
+		cfg:       Config{MinGasPrice: 1, MaxGasPrice: 100},
+		ethClient: ethClient,
+	}
+	gwei := func(n int64) string { return gweiToWei(n).String() }
+
+	// Without London the legacy gas price is used
+	gasPrice, gasFeeCap, gasTipCap, err := txManager.gasFees(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, gwei(1), gasPrice.String())
+	assert.Nil(t, gasFeeCap)
+	assert.Nil(t, gasTipCap)
+	gasPrice, err = txManager.effectiveGasPrice(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, gwei(1), gasPrice.String())
+
+	// With London the fees are taken from the fee history
+	ethClient.CtlSetGasFees(gweiToWei(10), gweiToWei(2))
+	gasPrice, gasFeeCap, gasTipCap, err = txManager.gasFees(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, gasPrice)
+	assert.Equal(t, gwei(22), gasFeeCap.String())
+	assert.Equal(t, gwei(2), gasTipCap.String())
+	// The expected price is the base fee plus the priority fee
+	gasPrice, err = txManager.effectiveGasPrice(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, gwei(12), gasPrice.String())
+
+	// The max fee is capped by MaxGasPrice
+	ethClient.CtlSetGasFees(gweiToWei(60), gweiToWei(2))
+	_, gasFeeCap, gasTipCap, err = txManager.gasFees(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, gwei(100), gasFeeCap.String())
+	assert.Equal(t, gwei(2), gasTipCap.String())
+	gasPrice, err = txManager.effectiveGasPrice(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, gwei(62), gasPrice.String())
+}
+
+func TestTxManagerSendRollupForgeBatch(t *testing.T) {
+	modules := newTestModules(t)
+	defer closeTestModules(t, modules)
+	ctx := context.Background()
+	var timer timer
+	ethClientSetup := test.NewClientSetupExample()
+	ethClientSetup.ChainID = big.NewInt(int64(chainID))
+	ethClientSetup.AuctionVariables.BootCoordinator = forger
+	ethClient := test.NewClient(true, &timer, &forger, ethClientSetup)
+	txManager := &TxManager{
+		cfg:       Config{MinGasPrice: 1, MaxGasPrice: 100, EthClientAttempts: 1},
+		ethClient: ethClient,
+		l2DB:      modules.l2DB,
+		account:   accounts.Account{Address: forger},
+		chainID:   ethClientSetup.ChainID,
+	}
+	gwei := func(n int64) string { return gweiToWei(n).String() }
+	newBatchInfo := func(batchNum common.BatchNum) *BatchInfo {
+		return &BatchInfo{
+			BatchNum: batchNum,
+			ForgeBatchArgs: &eth.RollupForgeBatchArgs{
+				NewStRoot:   big.NewInt(0),
+				NewExitRoot: big.NewInt(0),
+			},
+		}
+	}
+
+	// With London the batch is sent in a dynamic fee transaction
+	ethClient.CtlSetGasFees(gweiToWei(10), gweiToWei(2))
+	batchInfo := newBatchInfo(1)
+	require.NoError(t, txManager.sendRollupForgeBatch(ctx, batchInfo, false))
+	auth := ethClient.CtlLastForgeBatchAuth()
+	require.NotNil(t, auth)
+	assert.Nil(t, auth.GasPrice)
+	assert.Equal(t, gwei(22), auth.GasFeeCap.String())
+	assert.Equal(t, gwei(2), auth.GasTipCap.String())
+	assert.Equal(t, uint64(0), auth.Nonce.Uint64())
+	assert.Equal(t, uint64(1), txManager.accNextNonce)
+	assert.Equal(t, 1, len(batchInfo.EthTxs))
+
+	// The resend reuses the nonce and bumps both fees by 10%
+	require.NoError(t, txManager.sendRollupForgeBatch(ctx, batchInfo, true))
+	auth = ethClient.CtlLastForgeBatchAuth()
+	assert.Nil(t, auth.GasPrice)
+	assert.Equal(t, "24200000000", auth.GasFeeCap.String())
+	assert.Equal(t, "2200000000", auth.GasTipCap.String())
+	assert.Equal(t, uint64(0), auth.Nonce.Uint64())
+	assert.Equal(t, uint64(1), txManager.accNextNonce)
+	assert.Equal(t, 2, len(batchInfo.EthTxs))
+	assert.Equal(t, 1, batchInfo.Debug.ResendNum)
+
+	// If the node doesn't accept dynamic fee transactions, the batch is
+	// resent with the legacy gas price
+	ethClient.CtlSetGasFees(nil, nil)
+	require.NoError(t, txManager.sendRollupForgeBatch(ctx, batchInfo, true))
+	auth = ethClient.CtlLastForgeBatchAuth()
+	assert.Equal(t, gwei(1), auth.GasPrice.String())
+	assert.Nil(t, auth.GasFeeCap)
+	assert.Nil(t, auth.GasTipCap)
+	assert.Equal(t, uint64(0), auth.Nonce.Uint64())
+	assert.Equal(t, 3, len(batchInfo.EthTxs))
+
+	// Without London the next batch is sent in a legacy transaction with
+	// the next nonce
+	batchInfo = newBatchInfo(2)
+	require.NoError(t, txManager.sendRollupForgeBatch(ctx, batchInfo, false))
+	auth = ethClient.CtlLastForgeBatchAuth()
+	assert.Equal(t, gwei(1), auth.GasPrice.String())
+	assert.Nil(t, auth.GasFeeCap)
+	assert.Nil(t, auth.GasTipCap)
+	assert.Equal(t, uint64(1), auth.Nonce.Uint64())
+	assert.Equal(t, uint64(2), txManager.accNextNonce)
+}
+
+// newTestEthTxFn returns a function that creates signed eth txs with a nonce
+func newTestEthTxFn(t *testing.T) func(nonce uint64) *types.Transaction {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	to := ethCommon.BigToAddress(big.NewInt(1))
+	return func(nonce uint64) *types.Transaction {
+		tx, err := types.SignNewTx(key, types.HomesteadSigner{}, &types.LegacyTx{
+			Nonce:    nonce,
+			GasPrice: big.NewInt(1000),
+			Gas:      1000000,
+			To:       &to,
+			Value:    big.NewInt(0),
+		})
+		require.NoError(t, err)
+		return tx
+	}
+}
+
+func TestRestoreQueue(t *testing.T) {
+	modules := newTestModules(t)
+	defer closeTestModules(t, modules)
+	txManager := &TxManager{l2DB: modules.l2DB, queue: NewQueue()}
+
+	newTx := newTestEthTxFn(t)
+	// Only the sent batches are stored
+	require.NoError(t, txManager.storeBatchInfo(&BatchInfo{BatchNum: 3}))
+	batchInfos := []*BatchInfo{
+		{BatchNum: 4, EthTxs: []*types.Transaction{newTx(5)}},
+		{BatchNum: 5, L1Batch: true, EthTxs: []*types.Transaction{newTx(6), newTx(6)}},
+	}
+	batchInfos[0].Debug.Status = StatusMined
+	batchInfos[1].Debug.Status = StatusSent
+	batchInfos[1].Debug.ResendNum = 1
+	for _, batchInfo := range batchInfos {
+		require.NoError(t, txManager.storeBatchInfo(batchInfo))
+		txManager.queue.Push(batchInfo)
+	}
+	assert.Equal(t, common.BatchNum(5), txManager.lastRestoredBatchNum())
+
+	queue, accNextNonce, err := restoreQueue(modules.l2DB, 3)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), accNextNonce)
+	require.Equal(t, 2, queue.Len())
+	for i, batchInfo := range batchInfos {
+		restored := queue.At(i)
+		assert.Equal(t, batchInfo.BatchNum, restored.BatchNum)
+		assert.Equal(t, batchInfo.L1Batch, restored.L1Batch)
+		assert.Equal(t, batchInfo.Debug.Status, restored.Debug.Status)
+		assert.Equal(t, batchInfo.Debug.ResendNum, restored.Debug.ResendNum)
+		require.Equal(t, len(batchInfo.EthTxs), len(restored.EthTxs))
+		assert.Equal(t, batchInfo.EthTxs[0].Hash(), restored.EthTxs[0].Hash())
+		// The restored batches don't belong to any pipeline
+		assert.Nil(t, restored.ForgeBatchArgs)
+		assert.Equal(t, 0, restored.PipelineNum)
+	}
+
+	// The nonce of the account is used if the restored txs have been mined
+	// and the removed batches are not restored
+	txManager.removeFromQueue(0)
+	queue, accNextNonce, err = restoreQueue(modules.l2DB, 10)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), accNextNonce)
+	require.Equal(t, 1, queue.Len())
+	assert.Equal(t, common.BatchNum(5), queue.At(0).BatchNum)
+}
+
+func TestTxManagerResendRestoredBatch(t *testing.T) {
+	modules := newTestModules(t)
+	defer closeTestModules(t, modules)
+	ctx := context.Background()
+	var timer timer
+	ethClient := test.NewClient(true, &timer, &forger, test.NewClientSetupExample())
+	ethClient.CtlSetGasFees(gweiToWei(10), gweiToWei(2))
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	txChainID := big.NewInt(1337)
+	keyedAuth, err := bind.NewKeyedTransactorWithChainID(key, txChainID)
+	require.NoError(t, err)
+	txManager := &TxManager{
+		cfg:       Config{MinGasPrice: 1, MaxGasPrice: 100, EthClientAttempts: 1},
+		ethClient: ethClient,
+		l2DB:      modules.l2DB,
+		account:   accounts.Account{Address: keyedAuth.From},
+		chainID:   txChainID,
+		queue:     NewQueue(),
+	}
+
+	// Store a batch sent before a restart
+	to := ethCommon.BigToAddress(big.NewInt(1))
+	ethTx, err := types.SignNewTx(key, types.LatestSignerForChainID(txChainID),
+		&types.DynamicFeeTx{
+			ChainID:   txChainID,
+			Nonce:     3,
+			GasTipCap: gweiToWei(2),
+			GasFeeCap: gweiToWei(20),
+			Gas:       1000000,
+			To:        &to,
+			Value:     big.NewInt(0),
+			Data:      []byte{1, 2, 3},
+		})
+	require.NoError(t, err)
+	require.NoError(t, txManager.storeBatchInfo(&BatchInfo{BatchNum: 4,
+		EthTxs: []*types.Transaction{ethTx}}))
+
+	// The auth of the restored batch keeps the nonce and fees of its tx
+	queue, accNextNonce, err := restoreQueue(modules.l2DB, 0)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), accNextNonce)
+	require.Equal(t, 1, queue.Len())
+	txManager.queue = queue
+	txManager.accNextNonce = accNextNonce
+	batchInfo := queue.At(0)
+	batchInfo.Auth, err = txManager.restoredAuth(batchInfo.EthTxs[0])
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), batchInfo.Auth.Nonce.Uint64())
+	assert.Equal(t, uint64(1000000), batchInfo.Auth.GasLimit)
+	assert.Equal(t, gweiToWei(20), batchInfo.Auth.GasFeeCap)
+	assert.Equal(t, gweiToWei(2), batchInfo.Auth.GasTipCap)
+	assert.Nil(t, batchInfo.Auth.GasPrice)
+	// The test client doesn't have a keystore
+	batchInfo.Auth.Signer = keyedAuth.Signer
+
+	// The restored batch is resent with the same nonce and calldata, and
+	// bumped fees
+	require.NoError(t, txManager.sendRollupForgeBatch(ctx, batchInfo, true))
+	sentTx := ethClient.CtlLastSentTx()
+	require.NotNil(t, sentTx)
+	assert.Equal(t, uint8(types.DynamicFeeTxType), sentTx.Type())
+	assert.Equal(t, uint64(3), sentTx.Nonce())
+	assert.Equal(t, ethTx.Data(), sentTx.Data())
+	assert.Equal(t, ethTx.To(), sentTx.To())
+	assert.Equal(t, "22000000000", sentTx.GasFeeCap().String())
+	assert.Equal(t, "2200000000", sentTx.GasTipCap().String())
+	assert.Equal(t, uint64(4), txManager.accNextNonce)
+	assert.Equal(t, 1, batchInfo.Debug.ResendNum)
+	queue, _, err = restoreQueue(modules.l2DB, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, queue.Len())
+	assert.Equal(t, sentTx.Hash(), queue.At(0).EthTxs[1].Hash())
+
+	// Without London the restored batch is resent in a legacy transaction
+	ethClient.CtlSetGasFees(nil, nil)
+	require.NoError(t, txManager.sendRollupForgeBatch(ctx, batchInfo, true))
+	sentTx = ethClient.CtlLastSentTx()
+	assert.Equal(t, uint8(types.LegacyTxType), sentTx.Type())
+	assert.Equal(t, uint64(3), sentTx.Nonce())
+	assert.Equal(t, ethTx.Data(), sentTx.Data())
+
+	// The pending restored batch is not removed with the cancelled
+	// pipelines, and its nonce is not reused
+	txManager.minPipelineNum = 2
+	require.NoError(t, txManager.removeBadBatchInfos(ctx))
+	require.Equal(t, 1, txManager.queue.Len())
+	assert.Equal(t, uint64(4), txManager.accNextNonce)
+}
+
+func TestTxManagerStoreFailure(t *testing.T) {
+	modules := newTestModules(t)
+	defer closeTestModules(t, modules)
+	ctx := context.Background()
+	var timer timer
+	ethClientSetup := test.NewClientSetupExample()
+	ethClientSetup.ChainID = big.NewInt(int64(chainID))
+	ethClientSetup.AuctionVariables.BootCoordinator = forger
+	ethClient := test.NewClient(true, &timer, &forger, ethClientSetup)
+	ethClient.CtlSetGasFees(gweiToWei(10), gweiToWei(2))
+	txManager := &TxManager{
+		cfg:       Config{MinGasPrice: 1, MaxGasPrice: 100, EthClientAttempts: 1},
+		ethClient: ethClient,
+		l2DB:      modules.l2DB,
+		account:   accounts.Account{Address: forger},
+		chainID:   ethClientSetup.ChainID,
+	}
+	// The L2DB fails to store the batches while the table is renamed
+	renameTable := func(from, to string) {
+		_, err := modules.l2DB.DB().Exec(fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", from, to))
+		require.NoError(t, err)
+	}
+	renameTable("tx_manager_batch", "tx_manager_batch_renamed")
+
+	// The batch whose tx has been sent is not failed by the L2DB
+	batchInfo := &BatchInfo{
+		BatchNum: 1,
+		ForgeBatchArgs: &eth.RollupForgeBatchArgs{
+			NewStRoot:   big.NewInt(0),
+			NewExitRoot: big.NewInt(0),
+		},
+	}
+	require.NoError(t, txManager.sendRollupForgeBatch(ctx, batchInfo, false))
+	assert.Equal(t, 1, len(batchInfo.EthTxs))
+	assert.Equal(t, StatusSent, batchInfo.Debug.Status)
+	assert.Equal(t, uint64(1), txManager.accNextNonce)
+
+	// The tx that can't be stored once signed is not sent
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	auth, err := bind.NewKeyedTransactorWithChainID(key, ethClientSetup.ChainID)
+	require.NoError(t, err)
+	auth.Nonce = big.NewInt(1)
+	auth.GasLimit = 1000000
+	auth.GasFeeCap = gweiToWei(20)
+	auth.GasTipCap = gweiToWei(2)
+	to := ethCommon.BigToAddress(big.NewInt(1))
+	ethTx, err := types.SignNewTx(key, types.LatestSignerForChainID(ethClientSetup.ChainID),
+		&types.DynamicFeeTx{
+			ChainID:   ethClientSetup.ChainID,
+			Nonce:     1,
+			GasTipCap: auth.GasTipCap,
+			GasFeeCap: auth.GasFeeCap,
+			Gas:       auth.GasLimit,
+			To:        &to,
+			Value:     big.NewInt(0),
+			Data:      []byte{1, 2, 3},
+		})
+	require.NoError(t, err)
+	restored := &BatchInfo{BatchNum: 2, EthTxs: []*types.Transaction{ethTx}, Auth: auth}
+	lastSentTx := ethClient.CtlLastSentTx()
+	require.Error(t, txManager.sendRollupForgeBatch(ctx, restored, true))
+	assert.Equal(t, lastSentTx, ethClient.CtlLastSentTx())
+	assert.Equal(t, 1, len(restored.EthTxs))
+
+	// Once the L2DB works, the tx is sent and stored as sent
+	renameTable("tx_manager_batch_renamed", "tx_manager_batch")
+	require.NoError(t, txManager.sendRollupForgeBatch(ctx, restored, true))
+	sentTx := ethClient.CtlLastSentTx()
+	require.Equal(t, 2, len(restored.EthTxs))
+	assert.Equal(t, sentTx.Hash(), restored.EthTxs[1].Hash())
+	queue, _, err := restoreQueue(modules.l2DB, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, queue.Len())
+	assert.Equal(t, StatusSent, queue.At(0).Debug.Status)
+	assert.Equal(t, sentTx.Hash(), queue.At(0).EthTxs[1].Hash())
+}
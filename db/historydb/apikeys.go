@@ -0,0 +1,142 @@
+package historydb
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"time"
+
+	"github.com/chainbing/tracerr"
+	"github.com/russross/meddler"
+)
+
+// APIKey is a key that identifies the clients of the API, which get their
+// own rate limits and daily tx submission quota
+type APIKey struct {
+	ItemID uint64 `meddler:"item_id,pk"`
+	// KeyHash is the SHA-256 hash of the key, the key itself is not stored
+	KeyHash          []byte `meddler:"key_hash"`
+	Name             string `meddler:"name"`
+	CoordinatorScope bool   `meddler:"coordinator_scope"`
+	ExplorerScope    bool   `meddler:"explorer_scope"`
+	// RateLimit is the number of requests per second allowed
+	RateLimit float64 `meddler:"rate_limit"`
+	// Burst is the number of requests allowed at once
+	Burst int `meddler:"burst"`
+	// DailyTxQuota is the number of tx submissions allowed per day (UTC),
+	// unlimited if nil
+	DailyTxQuota *int      `meddler:"daily_tx_quota"`
+	Revoked      bool      `meddler:"revoked"`
+	Timestamp    time.Time `meddler:"timestamp,utctime"`
+}
+
+// APIKeyHash returns the hash of an API key as it's stored in the DB
+func APIKeyHash(key string) []byte {
+	hash := sha256.Sum256([]byte(key))
+	return hash[:]
+}
+
+// AddAPIKey inserts an API key into the DB
+func (hdb *HistoryDB) AddAPIKey(apiKey *APIKey) error {
+	return tracerr.Wrap(hdb.dbWrite.QueryRow(
+		`INSERT INTO api_key (
+			key_hash, name, coordinator_scope, explorer_scope,
+			rate_limit, burst, daily_tx_quota
+		) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING item_id, timestamp;`,
+		apiKey.KeyHash, apiKey.Name, apiKey.CoordinatorScope, apiKey.ExplorerScope,
+		apiKey.RateLimit, apiKey.Burst, apiKey.DailyTxQuota,
+	).Scan(&apiKey.ItemID, &apiKey.Timestamp))
+}
+
+// RevokeAPIKey revokes an API key, sql.ErrNoRows is returned if it doesn't
+// exist
+func (hdb *HistoryDB) RevokeAPIKey(itemID uint64) error {
+	res, err := hdb.dbWrite.Exec(
+		`UPDATE api_key SET revoked = true WHERE item_id = $1;`, itemID,
+	)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return tracerr.Wrap(err)
+	} else if n == 0 {
+		return tracerr.Wrap(sql.ErrNoRows)
+	}
+	return nil
+}
+
+// GetAPIKeyAPI returns the API key with the given hash
+func (hdb *HistoryDB) GetAPIKeyAPI(keyHash []byte) (*APIKey, error) {
+	cancel, err := hdb.apiConnCon.Acquire()
+	defer cancel()
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	defer hdb.apiConnCon.Release()
+	apiKey := &APIKey{}
+	err = meddler.QueryRow(
+		hdb.dbRead, apiKey, `SELECT * FROM api_key WHERE key_hash = $1;`, keyHash,
+	)
+	return apiKey, tracerr.Wrap(err)
+}
+
+// GetAPIKeyTxCountAPI returns the number of txs submitted with the API key in
+// the given day
+func (hdb *HistoryDB) GetAPIKeyTxCountAPI(itemID uint64, day time.Time) (int, error) {
+	cancel, err := hdb.apiConnCon.Acquire()
+	defer cancel()
+	if err != nil {
+		return 0, tracerr.Wrap(err)
+	}
+	defer hdb.apiConnCon.Release()
+	var txCount int
+	err = hdb.dbRead.QueryRow(
+		`SELECT COALESCE(MAX(tx_count), 0) FROM api_key_tx_count
+		WHERE api_key_id = $1 AND day = $2::DATE;`,
+		itemID, day.UTC().Format("2006-01-02"),
+	).Scan(&txCount)
+	return txCount, tracerr.Wrap(err)
+}
+
+// ReserveAPIKeyTxQuotaAPI counts numTxs txs submitted with the API key in the
+// given day, unless they exceed the quota.  The check and the count are done
+// in a single statement, so that concurrent submissions can't exceed the
+// quota.  Returns false if the txs exceed the quota.
+func (hdb *HistoryDB) ReserveAPIKeyTxQuotaAPI(itemID uint64, day time.Time, numTxs, quota int) (bool, error) {
+	cancel, err := hdb.apiConnCon.Acquire()
+	defer cancel()
+	if err != nil {
+		return false, tracerr.Wrap(err)
+	}
+	defer hdb.apiConnCon.Release()
+	var txCount int
+	err = hdb.dbWrite.QueryRow(
+		`INSERT INTO api_key_tx_count (api_key_id, day, tx_count)
+		SELECT $1, $2::DATE, $3 WHERE $3 <= $4
+		ON CONFLICT (api_key_id, day) DO UPDATE
+		SET tx_count = api_key_tx_count.tx_count + EXCLUDED.tx_count
+		WHERE api_key_tx_count.tx_count + EXCLUDED.tx_count <= $4
+		RETURNING tx_count;`,
+		itemID, day.UTC().Format("2006-01-02"), numTxs, quota,
+	).Scan(&txCount)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, tracerr.Wrap(err)
+}
+
+// RefundAPIKeyTxQuotaAPI discounts numTxs txs reserved with
+// ReserveAPIKeyTxQuotaAPI that have not been accepted
+func (hdb *HistoryDB) RefundAPIKeyTxQuotaAPI(itemID uint64, day time.Time, numTxs int) error {
+	cancel, err := hdb.apiConnCon.Acquire()
+	defer cancel()
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer hdb.apiConnCon.Release()
+	_, err = hdb.dbWrite.Exec(
+		`UPDATE api_key_tx_count SET tx_count = GREATEST(tx_count - $3, 0)
+		WHERE api_key_id = $1 AND day = $2::DATE;`,
+		itemID, day.UTC().Format("2006-01-02"), numTxs,
+	)
+	return tracerr.Wrap(err)
+}
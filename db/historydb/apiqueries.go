@@ -28,6 +28,17 @@ func (hdb *HistoryDB) GetLastBlockAPI() (*common.Block, error) {
 	return hdb.GetLastBlock()
 }
 
+// GetLastBatchNumAPI returns the BatchNum of the latest forged batch
+func (hdb *HistoryDB) GetLastBatchNumAPI() (common.BatchNum, error) {
+	cancel, err := hdb.apiConnCon.Acquire()
+	defer cancel()
+	if err != nil {
+		return 0, tracerr.Wrap(err)
+	}
+	defer hdb.apiConnCon.Release()
+	return hdb.GetLastBatchNum()
+}
+
 // GetBatchAPI return the batch with the given batchNum
 func (hdb *HistoryDB) GetBatchAPI(batchNum common.BatchNum) (*BatchAPI, error) {
 	cancel, err := hdb.apiConnCon.Acquire()
@@ -62,16 +73,34 @@ func (hdb *HistoryDB) getBatchAPI(d meddler.DB, batchNum common.BatchNum) (*Batc
 	return batch, nil
 }
 
+// GetBatchForgedTxsAPI returns the txs forged in a batch
+func (hdb *HistoryDB) GetBatchForgedTxsAPI(batchNum common.BatchNum) (*BatchForgedTxs, error) {
+	cancel, err := hdb.apiConnCon.Acquire()
+	defer cancel()
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	defer hdb.apiConnCon.Release()
+	return hdb.GetBatchForgedTxs(batchNum)
+}
+
 // GetBatchesAPIRequest is an API request struct for getting batches
 type GetBatchesAPIRequest struct {
 	MinBatchNum *uint
 	MaxBatchNum *uint
 	SlotNum     *uint
 	ForgerAddr  *ethCommon.Address
+	// FromTimestamp is inclusive and ToTimestamp exclusive
+	FromTimestamp *time.Time
+	ToTimestamp   *time.Time
+	// FromBlock and ToBlock are inclusive
+	FromBlock *int64
+	ToBlock   *int64
 
 	FromItem *uint
 	Limit    *uint
 	Order    string
+	SortBy   string
 }
 
 // GetBatchesAPI return the batches applying the given filters
@@ -84,6 +113,13 @@ func (hdb *HistoryDB) GetBatchesAPI(
 		return nil, 0, tracerr.Wrap(err)
 	}
 	defer hdb.apiConnCon.Release()
+	sortBy, err := sortColumn(batchesSortColumns, request.SortBy)
+	if err != nil {
+		return nil, 0, tracerr.Wrap(err)
+	}
+	if err := hdb.checkFromItem("batch", sortBy, request.FromItem); err != nil {
+		return nil, 0, tracerr.Wrap(err)
+	}
 	var query string
 	var args []interface{}
 	queryStr := `SELECT batch.item_id, batch.batch_num, batch.eth_block_num,
@@ -139,6 +175,14 @@ func (hdb *HistoryDB) GetBatchesAPI(
 		args = append(args, request.ForgerAddr)
 		nextIsAnd = true
 	}
+	// time and block filters
+	rangeFilters, rangeArgs := blockRangeFilters("batch", request.FromTimestamp,
+		request.ToTimestamp, request.FromBlock, request.ToBlock, nextIsAnd)
+	if rangeFilters != "" {
+		queryStr += rangeFilters
+		args = append(args, rangeArgs...)
+		nextIsAnd = true
+	}
 	// pagination
 	if request.FromItem != nil {
 		if nextIsAnd {
@@ -146,19 +190,10 @@ func (hdb *HistoryDB) GetBatchesAPI(
 		} else {
 			queryStr += "WHERE "
 		}
-		if request.Order == db.OrderAsc {
-			queryStr += "batch.item_id >= ? "
-		} else {
-			queryStr += "batch.item_id <= ? "
-		}
+		queryStr += fromItemCondition("batch", sortBy, request.Order)
 		args = append(args, request.FromItem)
 	}
-	queryStr += "ORDER BY batch.item_id "
-	if request.Order == db.OrderAsc {
-		queryStr += " ASC "
-	} else {
-		queryStr += " DESC "
-	}
+	queryStr += orderByClause("batch", sortBy, request.Order)
 	queryStr += fmt.Sprintf("LIMIT %d;", *request.Limit)
 	query = hdb.dbRead.Rebind(queryStr)
 	// log.Debug(query)
@@ -273,10 +308,17 @@ func (hdb *HistoryDB) getBestBidsAPI(
 type GetBidsAPIRequest struct {
 	SlotNum    *int64
 	BidderAddr *ethCommon.Address
+	// FromTimestamp is inclusive and ToTimestamp exclusive
+	FromTimestamp *time.Time
+	ToTimestamp   *time.Time
+	// FromBlock and ToBlock are inclusive
+	FromBlock *int64
+	ToBlock   *int64
 
 	FromItem *uint
 	Limit    *uint
 	Order    string
+	SortBy   string
 }
 
 // GetBidsAPI return the bids applying the given filters
@@ -287,6 +329,13 @@ func (hdb *HistoryDB) GetBidsAPI(request GetBidsAPIRequest) ([]BidAPI, uint64, e
 		return nil, 0, tracerr.Wrap(err)
 	}
 	defer hdb.apiConnCon.Release()
+	sortBy, err := sortColumn(bidsSortColumns, request.SortBy)
+	if err != nil {
+		return nil, 0, tracerr.Wrap(err)
+	}
+	if err := hdb.checkFromItem("bid", sortBy, request.FromItem); err != nil {
+		return nil, 0, tracerr.Wrap(err)
+	}
 	var query string
 	var args []interface{}
 	// JOIN each bid with the latest update of each coordinator
@@ -322,26 +371,25 @@ func (hdb *HistoryDB) GetBidsAPI(request GetBidsAPIRequest) ([]BidAPI, uint64, e
 		args = append(args, request.BidderAddr)
 		nextIsAnd = true
 	}
+	// time and block filters
+	rangeFilters, rangeArgs := blockRangeFilters("bid", request.FromTimestamp,
+		request.ToTimestamp, request.FromBlock, request.ToBlock, nextIsAnd)
+	if rangeFilters != "" {
+		queryStr += rangeFilters
+		args = append(args, rangeArgs...)
+		nextIsAnd = true
+	}
 	if request.FromItem != nil {
 		if nextIsAnd {
 			queryStr += "AND "
 		} else {
 			queryStr += "WHERE "
 		}
-		if request.Order == db.OrderAsc {
-			queryStr += "bid.item_id >= ? "
-		} else {
-			queryStr += "bid.item_id <= ? "
-		}
+		queryStr += fromItemCondition("bid", sortBy, request.Order)
 		args = append(args, request.FromItem)
 	}
 	// pagination
-	queryStr += "ORDER BY bid.item_id "
-	if request.Order == db.OrderAsc {
-		queryStr += "ASC "
-	} else {
-		queryStr += "DESC "
-	}
+	queryStr += orderByClause("bid", sortBy, request.Order)
 	queryStr += fmt.Sprintf("LIMIT %d;", *request.Limit)
 	query, argsQ, err := sqlx.In(queryStr, args...)
 	if err != nil {
@@ -369,6 +417,46 @@ func (hdb *HistoryDB) GetTokenAPI(tokenID common.TokenID) (*TokenWithUSD, error)
 	return hdb.GetToken(tokenID)
 }
 
+// GetTokenPricesAPIRequest is an API request struct for getting the price
+// history of a token
+type GetTokenPricesAPIRequest struct {
+	TokenID common.TokenID
+	// FromTimestamp is inclusive and ToTimestamp exclusive
+	FromTimestamp time.Time
+	ToTimestamp   time.Time
+	// Interval is the size of the periods in which the prices are grouped,
+	// returning the last price of each period
+	Interval time.Duration
+}
+
+// GetTokenPricesAPI returns the price history of a token, with a price for
+// every interval that had price updates
+func (hdb *HistoryDB) GetTokenPricesAPI(request GetTokenPricesAPIRequest) ([]TokenPriceAPI, error) {
+	if request.Interval < time.Second {
+		return nil, tracerr.Wrap(fmt.Errorf("invalid interval: %v", request.Interval))
+	}
+	cancel, err := hdb.apiConnCon.Acquire()
+	defer cancel()
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	defer hdb.apiConnCon.Release()
+	interval := int64(request.Interval / time.Second)
+	prices := []*TokenPriceAPI{}
+	if err := meddler.QueryAll(
+		hdb.dbRead, &prices,
+		`SELECT DISTINCT ON (token_prices.period) token_prices.period AS timestamp, token_prices.usd FROM (
+			SELECT to_timestamp(FLOOR(EXTRACT(EPOCH FROM timestamp) / $1) * $1) AT TIME ZONE 'utc' AS period,
+			usd, timestamp FROM token_price_history
+			WHERE token_id = $2 AND timestamp >= $3 AND timestamp < $4
+		) token_prices ORDER BY token_prices.period ASC, token_prices.timestamp DESC;`,
+		interval, request.TokenID, request.FromTimestamp.UTC(), request.ToTimestamp.UTC(),
+	); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	return db.SlicePtrsToSlice(prices).([]TokenPriceAPI), nil
+}
+
 // GetTokensAPIRequest is an API request struct for getting tokens
 type GetTokensAPIRequest struct {
 	Ids       []common.TokenID
@@ -555,10 +643,17 @@ type GetTxsAPIRequest struct {
 	BatchNum          *uint
 	TxType            *common.TxType
 	IncludePendingL1s *bool
+	// FromTimestamp is inclusive and ToTimestamp exclusive
+	FromTimestamp *time.Time
+	ToTimestamp   *time.Time
+	// FromBlock and ToBlock are inclusive
+	FromBlock *int64
+	ToBlock   *int64
 
 	FromItem *uint
 	Limit    *uint
 	Order    string
+	SortBy   string
 }
 
 // GetTxsAPI returns a list of txs from the DB using the HistoryTx struct
@@ -578,6 +673,13 @@ func (hdb *HistoryDB) GetTxsAPI(
 	if request.EthAddr != nil && request.Bjj != nil {
 		return nil, 0, tracerr.Wrap(errors.New("ethAddr and bjj are incompatible"))
 	}
+	sortBy, err := sortColumn(txsSortColumns, request.SortBy)
+	if err != nil {
+		return nil, 0, tracerr.Wrap(err)
+	}
+	if err := hdb.checkFromItem("tx", sortBy, request.FromItem); err != nil {
+		return nil, 0, tracerr.Wrap(err)
+	}
 	var query string
 	var args []interface{}
 	queryStr := `SELECT tx.item_id, tx.is_l1, tx.id, tx.type, tx.position,
@@ -591,7 +693,32 @@ func (hdb *HistoryDB) GetTxsAPI(
 	token.usd_update, block.timestamp, count(*) OVER() AS total_items
 	FROM tx INNER JOIN token ON tx.token_id = token.token_id
 	INNER JOIN block ON tx.eth_block_num = block.eth_block_num `
-	// Apply filters
+	filters, filtersArgs := txsAPIFilters(request, sortBy)
+	queryStr += filters
+	args = append(args, filtersArgs...)
+
+	// pagination
+	queryStr += orderByClause("tx", sortBy, request.Order)
+	queryStr += fmt.Sprintf("LIMIT %d;", *request.Limit)
+	query = hdb.dbRead.Rebind(queryStr)
+	// log.Debug(query)
+	txsPtrs := []*TxAPI{}
+	if err := meddler.QueryAll(hdb.dbRead, &txsPtrs, query, args...); err != nil {
+		return nil, 0, tracerr.Wrap(err)
+	}
+	txs := db.SlicePtrsToSlice(txsPtrs).([]TxAPI)
+	if len(txs) == 0 {
+		return txs, 0, nil
+	}
+	return txs, txs[0].TotalItems - uint64(len(txs)), nil
+}
+
+// txsAPIFilters returns the WHERE clause of the queries of txs that applies
+// the filters of the request, and its arguments.  The pagination starts at
+// FromItem in the order given by the sortBy expression.
+func txsAPIFilters(request GetTxsAPIRequest, sortBy string) (string, []interface{}) {
+	queryStr := ""
+	var args []interface{}
 	nextIsAnd := false
 	// ethAddr filter
 	if request.EthAddr != nil {
@@ -704,14 +831,18 @@ func (hdb *HistoryDB) GetTxsAPI(
 		} else {
 			queryStr += "WHERE "
 		}
-		if request.Order == db.OrderAsc {
-			queryStr += "tx.item_id >= ? "
-		} else {
-			queryStr += "tx.item_id <= ? "
-		}
+		queryStr += fromItemCondition("tx", sortBy, request.Order)
 		args = append(args, request.FromItem)
 		nextIsAnd = true
 	}
+	// time and block filters
+	rangeFilters, rangeArgs := blockRangeFilters("tx", request.FromTimestamp,
+		request.ToTimestamp, request.FromBlock, request.ToBlock, nextIsAnd)
+	if rangeFilters != "" {
+		queryStr += rangeFilters
+		args = append(args, rangeArgs...)
+		nextIsAnd = true
+	}
 
 	// Include pending L1 txs? (default false)
 	if request.IncludePendingL1s == nil || (request.IncludePendingL1s != nil && !*request.IncludePendingL1s) {
@@ -722,19 +853,103 @@ func (hdb *HistoryDB) GetTxsAPI(
 		}
 		queryStr += "tx.batch_num IS NOT NULL "
 	}
+	return queryStr, args
+}
 
-	// pagination
-	queryStr += "ORDER BY tx.item_id "
-	if request.Order == db.OrderAsc {
-		queryStr += " ASC "
+// blockRangeFilters returns the conditions that filter the items of the table
+// by the timestamp of their block, which must be joined as block, and by
+// their block number, and its arguments.  The conditions start with AND when
+// nextIsAnd is true, and with WHERE otherwise.
+func blockRangeFilters(table string, fromTimestamp, toTimestamp *time.Time,
+	fromBlock, toBlock *int64, nextIsAnd bool) (string, []interface{}) {
+	queryStr := ""
+	var args []interface{}
+	if fromTimestamp != nil {
+		if nextIsAnd {
+			queryStr += "AND "
+		} else {
+			queryStr += "WHERE "
+		}
+		queryStr += "block.timestamp >= ? "
+		args = append(args, fromTimestamp)
+		nextIsAnd = true
+	}
+	if toTimestamp != nil {
+		if nextIsAnd {
+			queryStr += "AND "
+		} else {
+			queryStr += "WHERE "
+		}
+		queryStr += "block.timestamp < ? "
+		args = append(args, toTimestamp)
+		nextIsAnd = true
+	}
+	if fromBlock != nil {
+		if nextIsAnd {
+			queryStr += "AND "
+		} else {
+			queryStr += "WHERE "
+		}
+		queryStr += fmt.Sprintf("%s.eth_block_num >= ? ", table)
+		args = append(args, fromBlock)
+		nextIsAnd = true
+	}
+	if toBlock != nil {
+		if nextIsAnd {
+			queryStr += "AND "
+		} else {
+			queryStr += "WHERE "
+		}
+		queryStr += fmt.Sprintf("%s.eth_block_num <= ? ", table)
+		args = append(args, toBlock)
+	}
+	return queryStr, args
+}
+
+// GetPendingDepositsAPI returns the first limit L1 user txs with a deposit
+// that are still in the open or frozen queues, and that deposit to an account
+// of ethAddr or bjj, either created by the tx or an existing one, along with
+// the number of deposits that didn't fit in the limit
+func (hdb *HistoryDB) GetPendingDepositsAPI(ethAddr *ethCommon.Address,
+	bjj *babyjub.PublicKeyComp, limit uint) ([]TxAPI, uint64, error) {
+	if (ethAddr == nil) == (bjj == nil) {
+		return nil, 0, tracerr.Wrap(errors.New("either ethAddr or bjj must be set"))
+	}
+	cancel, err := hdb.apiConnCon.Acquire()
+	defer cancel()
+	if err != nil {
+		return nil, 0, tracerr.Wrap(err)
+	}
+	defer hdb.apiConnCon.Release()
+	queryStr := `SELECT tx.item_id, tx.is_l1, tx.id, tx.type, tx.position,
+	cb_idx(tx.effective_from_idx, token.symbol) AS from_idx, tx.from_eth_addr, tx.from_bjj,
+	cb_idx(tx.to_idx, token.symbol) AS to_idx, tx.to_eth_addr, tx.to_bjj,
+	tx.amount, tx.amount_success, tx.token_id, tx.amount_usd,
+	tx.batch_num, tx.eth_block_num, tx.to_forge_l1_txs_num, tx.user_origin,
+	tx.deposit_amount, tx.deposit_amount_usd, tx.deposit_amount_success, tx.fee, tx.fee_usd, tx.nonce,
+	token.token_id, token.item_id AS token_item_id, token.eth_block_num AS token_block,
+	token.eth_addr, token.name, token.symbol, token.decimals, token.usd,
+	token.usd_update, block.timestamp, count(*) OVER() AS total_items
+	FROM tx INNER JOIN token ON tx.token_id = token.token_id
+	INNER JOIN block ON tx.eth_block_num = block.eth_block_num
+	WHERE tx.is_l1 AND tx.user_origin AND tx.batch_num IS NULL AND tx.deposit_amount > 0 `
+	// The sender of a deposit only owns the destination account when the
+	// deposit creates it (from_idx is 0), otherwise the deposit may go to an
+	// account of somebody else
+	var args []interface{}
+	if ethAddr != nil {
+		queryStr += `AND ((tx.from_eth_addr = ? AND COALESCE(tx.from_idx, 0) = 0)
+		OR tx.from_idx IN (SELECT idx FROM account WHERE eth_addr = ?)) `
+		args = append(args, ethAddr, ethAddr)
 	} else {
-		queryStr += " DESC "
+		queryStr += `AND ((tx.from_bjj = ? AND COALESCE(tx.from_idx, 0) = 0)
+		OR tx.from_idx IN (SELECT idx FROM account WHERE bjj = ?)) `
+		args = append(args, bjj, bjj)
 	}
-	queryStr += fmt.Sprintf("LIMIT %d;", *request.Limit)
-	query = hdb.dbRead.Rebind(queryStr)
-	// log.Debug(query)
+	queryStr += "ORDER BY tx.item_id ASC "
+	queryStr += fmt.Sprintf("LIMIT %d;", limit)
 	txsPtrs := []*TxAPI{}
-	if err := meddler.QueryAll(hdb.dbRead, &txsPtrs, query, args...); err != nil {
+	if err := meddler.QueryAll(hdb.dbRead, &txsPtrs, hdb.dbRead.Rebind(queryStr), args...); err != nil {
 		return nil, 0, tracerr.Wrap(err)
 	}
 	txs := db.SlicePtrsToSlice(txsPtrs).([]TxAPI)
@@ -744,6 +959,48 @@ func (hdb *HistoryDB) GetTxsAPI(
 	return txs, txs[0].TotalItems - uint64(len(txs)), nil
 }
 
+// GetL1QueuesAPI returns the L1 user txs that are not forged yet, grouped by
+// the queue in which they will be forged and sorted by position
+func (hdb *HistoryDB) GetL1QueuesAPI() (*L1QueuesAPI, error) {
+	cancel, err := hdb.apiConnCon.Acquire()
+	defer cancel()
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	defer hdb.apiConnCon.Release()
+	queues := &L1QueuesAPI{}
+	if queues.LastL1TxsNum, err = hdb.GetLastL1TxsNum(); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	lastL1BatchBlock, err := hdb.GetLastL1BatchBlockNum()
+	if err == nil {
+		queues.LastL1BatchBlock = &lastL1BatchBlock
+	} else if tracerr.Unwrap(err) != sql.ErrNoRows {
+		return nil, tracerr.Wrap(err)
+	}
+	txsPtrs := []*TxAPI{}
+	if err := meddler.QueryAll(
+		hdb.dbRead, &txsPtrs, // only L1 user txs can have batch_num set to null
+		`SELECT tx.item_id, tx.is_l1, tx.id, tx.type, tx.position,
+		cb_idx(tx.effective_from_idx, token.symbol) AS from_idx, tx.from_eth_addr, tx.from_bjj,
+		cb_idx(tx.to_idx, token.symbol) AS to_idx, tx.to_eth_addr, tx.to_bjj,
+		tx.amount, tx.amount_success, tx.token_id, tx.amount_usd,
+		tx.batch_num, tx.eth_block_num, tx.to_forge_l1_txs_num, tx.user_origin,
+		tx.deposit_amount, tx.deposit_amount_usd, tx.deposit_amount_success, tx.fee, tx.fee_usd, tx.nonce,
+		token.token_id, token.item_id AS token_item_id, token.eth_block_num AS token_block,
+		token.eth_addr, token.name, token.symbol, token.decimals, token.usd,
+		token.usd_update, block.timestamp, count(*) OVER() AS total_items
+		FROM tx INNER JOIN token ON tx.token_id = token.token_id
+		INNER JOIN block ON tx.eth_block_num = block.eth_block_num
+		WHERE tx.batch_num IS NULL
+		ORDER BY tx.to_forge_l1_txs_num ASC, tx.position ASC;`,
+	); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	queues.Txs = db.SlicePtrsToSlice(txsPtrs).([]TxAPI)
+	return queues, nil
+}
+
 // GetExitAPI returns a exit from the DB
 func (hdb *HistoryDB) GetExitAPI(batchNum *uint, idx *common.Idx) (*ExitAPI, error) {
 	cancel, err := hdb.apiConnCon.Acquire()
@@ -777,10 +1034,18 @@ type GetExitsAPIRequest struct {
 	Idx                  *common.Idx
 	BatchNum             *uint
 	OnlyPendingWithdraws *bool
+	// FromTimestamp is inclusive and ToTimestamp exclusive
+	FromTimestamp *time.Time
+	ToTimestamp   *time.Time
+	// FromBlock and ToBlock are inclusive, and refer to the block of the
+	// batch of the exit
+	FromBlock *int64
+	ToBlock   *int64
 
 	FromItem *uint
 	Limit    *uint
 	Order    string
+	SortBy   string
 }
 
 // GetExitsAPI returns a list of exits from the DB and pagination info
@@ -796,6 +1061,13 @@ func (hdb *HistoryDB) GetExitsAPI(
 		return nil, 0, tracerr.Wrap(err)
 	}
 	defer hdb.apiConnCon.Release()
+	sortBy, err := sortColumn(exitsSortColumns, request.SortBy)
+	if err != nil {
+		return nil, 0, tracerr.Wrap(err)
+	}
+	if err := hdb.checkFromItem("exit_tree", sortBy, request.FromItem); err != nil {
+		return nil, 0, tracerr.Wrap(err)
+	}
 	var query string
 	var args []interface{}
 	queryStr := `SELECT exit_tree.item_id, exit_tree.batch_num,
@@ -808,6 +1080,13 @@ func (hdb *HistoryDB) GetExitsAPI(
 	token.decimals, token.usd, token.usd_update, COUNT(*) OVER() AS total_items
 	FROM exit_tree INNER JOIN account ON exit_tree.account_idx = account.idx
 	INNER JOIN token ON account.token_id = token.token_id `
+	// The block of the exits is the one of their batch, which is only
+	// joined when filtering by time or block
+	if request.FromTimestamp != nil || request.ToTimestamp != nil ||
+		request.FromBlock != nil || request.ToBlock != nil {
+		queryStr += `INNER JOIN batch ON exit_tree.batch_num = batch.batch_num
+		INNER JOIN block ON batch.eth_block_num = block.eth_block_num `
+	}
 	// Apply filters
 	nextIsAnd := false
 	// ethAddr filter
@@ -865,27 +1144,26 @@ func (hdb *HistoryDB) GetExitsAPI(
 			nextIsAnd = true
 		}
 	}
+	// time and block filters
+	rangeFilters, rangeArgs := blockRangeFilters("batch", request.FromTimestamp,
+		request.ToTimestamp, request.FromBlock, request.ToBlock, nextIsAnd)
+	if rangeFilters != "" {
+		queryStr += rangeFilters
+		args = append(args, rangeArgs...)
+		nextIsAnd = true
+	}
 	if request.FromItem != nil {
 		if nextIsAnd {
 			queryStr += "AND "
 		} else {
 			queryStr += "WHERE "
 		}
-		if request.Order == db.OrderAsc {
-			queryStr += "exit_tree.item_id >= ? "
-		} else {
-			queryStr += "exit_tree.item_id <= ? "
-		}
+		queryStr += fromItemCondition("exit_tree", sortBy, request.Order)
 		args = append(args, request.FromItem)
 		// nextIsAnd = true
 	}
 	// pagination
-	queryStr += "ORDER BY exit_tree.item_id "
-	if request.Order == db.OrderAsc {
-		queryStr += " ASC "
-	} else {
-		queryStr += " DESC "
-	}
+	queryStr += orderByClause("exit_tree", sortBy, request.Order)
 	queryStr += fmt.Sprintf("LIMIT %d;", *request.Limit)
 	query = hdb.dbRead.Rebind(queryStr)
 	// log.Debug(query)
@@ -1123,6 +1401,114 @@ func (hdb *HistoryDB) GetAccountsAPI(
 		accounts[0].TotalItems - uint64(len(accounts)), nil
 }
 
+// GetAccountHistoryAPIRequest is an API request struct for getting the
+// balance history of an account
+type GetAccountHistoryAPIRequest struct {
+	Idx           common.Idx
+	MinBatchNum   *common.BatchNum
+	MaxBatchNum   *common.BatchNum
+	FromTimestamp *time.Time
+	ToTimestamp   *time.Time
+	// Daily returns only the last update of each day, with the txs of
+	// all the updates of the day
+	Daily bool
+
+	FromItem *uint
+	Limit    *uint
+	Order    string
+}
+
+// GetAccountHistoryAPI returns the updates of the balance and nonce of an
+// account, and pagination info
+func (hdb *HistoryDB) GetAccountHistoryAPI(
+	request GetAccountHistoryAPIRequest,
+) ([]AccountUpdateAPI, uint64, error) {
+	cancel, err := hdb.apiConnCon.Acquire()
+	defer cancel()
+	if err != nil {
+		return nil, 0, tracerr.Wrap(err)
+	}
+	defer hdb.apiConnCon.Release()
+	// The txs of an update are the ones of the account forged between
+	// first_batch_num and batch_num, which are the same batch unless the
+	// updates are grouped by day
+	queryStr := `SELECT au.item_id, au.idx, au.batch_num, au.eth_block_num, au.timestamp,
+	au.nonce, au.balance, (
+		SELECT COALESCE(json_agg('0x' || encode(tx.id, 'hex') ORDER BY tx.item_id), '[]')
+		FROM tx WHERE tx.batch_num BETWEEN au.first_batch_num AND au.batch_num AND
+		(tx.effective_from_idx = ? OR tx.to_idx = ?)
+	) AS tx_ids, COUNT(*) OVER() AS total_items FROM (SELECT `
+	args := []interface{}{request.Idx, request.Idx}
+	if request.Daily {
+		queryStr += `DISTINCT ON (date_trunc('day', block.timestamp)) `
+	}
+	queryStr += `account_update.item_id, cb_idx(account_update.idx, token.symbol) AS idx,
+	account_update.batch_num, account_update.eth_block_num, block.timestamp,
+	account_update.nonce, account_update.balance, `
+	if request.Daily {
+		queryStr += `MIN(account_update.batch_num) OVER (
+			PARTITION BY date_trunc('day', block.timestamp)
+		) AS first_batch_num `
+	} else {
+		queryStr += "account_update.batch_num AS first_batch_num "
+	}
+	queryStr += `FROM account_update INNER JOIN account ON account_update.idx = account.idx
+	INNER JOIN token ON account.token_id = token.token_id
+	INNER JOIN block ON account_update.eth_block_num = block.eth_block_num
+	WHERE account_update.idx = ? `
+	args = append(args, request.Idx)
+	// Apply filters
+	if request.MinBatchNum != nil {
+		queryStr += "AND account_update.batch_num >= ? "
+		args = append(args, request.MinBatchNum)
+	}
+	if request.MaxBatchNum != nil {
+		queryStr += "AND account_update.batch_num <= ? "
+		args = append(args, request.MaxBatchNum)
+	}
+	if request.FromTimestamp != nil {
+		queryStr += "AND block.timestamp >= ? "
+		args = append(args, request.FromTimestamp)
+	}
+	if request.ToTimestamp != nil {
+		queryStr += "AND block.timestamp < ? "
+		args = append(args, request.ToTimestamp)
+	}
+	if request.Daily {
+		queryStr += `ORDER BY date_trunc('day', block.timestamp), account_update.item_id DESC`
+	}
+	queryStr += ") AS au "
+	if request.FromItem != nil {
+		if request.Order == db.OrderAsc {
+			queryStr += "WHERE au.item_id >= ? "
+		} else {
+			queryStr += "WHERE au.item_id <= ? "
+		}
+		args = append(args, request.FromItem)
+	}
+	// pagination
+	queryStr += "ORDER BY au.item_id "
+	if request.Order == db.OrderAsc {
+		queryStr += "ASC "
+	} else {
+		queryStr += "DESC "
+	}
+	if request.Limit != nil {
+		queryStr += fmt.Sprintf("LIMIT %d;", *request.Limit)
+	}
+	query := hdb.dbRead.Rebind(queryStr)
+
+	updates := []*AccountUpdateAPI{}
+	if err := meddler.QueryAll(hdb.dbRead, &updates, query, args...); err != nil {
+		return nil, 0, tracerr.Wrap(err)
+	}
+	if len(updates) == 0 {
+		return []AccountUpdateAPI{}, 0, nil
+	}
+	return db.SlicePtrsToSlice(updates).([]AccountUpdateAPI),
+		updates[0].TotalItems - uint64(len(updates)), nil
+}
+
 // GetCommonAccountAPI returns the account associated to an account idx
 func (hdb *HistoryDB) GetCommonAccountAPI(idx common.Idx) (*common.Account, error) {
 	cancel, err := hdb.apiConnCon.Acquire()
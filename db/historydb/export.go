@@ -0,0 +1,294 @@
+package historydb
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chainbing/node/common"
+	"github.com/chainbing/node/db"
+	"github.com/chainbing/tracerr"
+	"github.com/russross/meddler"
+)
+
+// TxsExportFormat is the format of an export of txs
+type TxsExportFormat string
+
+const (
+	// TxsExportFormatCSV exports the txs as CSV with a header row
+	TxsExportFormatCSV TxsExportFormat = "csv"
+	// TxsExportFormatJSONL exports the txs as one JSON object per line
+	TxsExportFormatJSONL TxsExportFormat = "jsonl"
+)
+
+// TxExport is the representation of a tx used in the exports for
+// accounting.  The amounts are normalized using the decimals of the token.
+type TxExport struct {
+	ItemID               uint64           `json:"itemId"`
+	TxID                 common.TxID      `json:"id"`
+	Type                 common.TxType    `json:"type"`
+	L1orL2               string           `json:"L1orL2"`
+	BatchNum             *common.BatchNum `json:"batchNum"`
+	EthBlockNum          int64            `json:"ethereumBlockNum"`
+	Timestamp            time.Time        `json:"timestamp"`
+	FromIdx              string           `json:"fromAccountIndex"`
+	FromEthAddr          string           `json:"fromCbEthereumAddress"`
+	FromBJJ              string           `json:"fromBJJ"`
+	ToIdx                string           `json:"toAccountIndex"`
+	ToEthAddr            string           `json:"toCbEthereumAddress"`
+	ToBJJ                string           `json:"toBJJ"`
+	TokenID              common.TokenID   `json:"tokenId"`
+	TokenSymbol          string           `json:"tokenSymbol"`
+	Amount               string           `json:"amount"`
+	AmountSuccess        *bool            `json:"amountSuccess"`
+	AmountUSD            *float64         `json:"amountUSD"`
+	DepositAmount        string           `json:"depositAmount"`
+	DepositAmountSuccess *bool            `json:"depositAmountSuccess"`
+	DepositAmountUSD     *float64         `json:"depositAmountUSD"`
+	Fee                  string           `json:"fee"`
+	FeeUSD               *float64         `json:"feeUSD"`
+	Nonce                *common.Nonce    `json:"nonce"`
+}
+
+// txExportCSVHeader is the header of the CSV exports, in the same order as
+// the values returned by TxExport.csvRecord
+var txExportCSVHeader = []string{
+	"itemId", "id", "type", "L1orL2", "batchNum", "ethereumBlockNum", "timestamp",
+	"fromAccountIndex", "fromCbEthereumAddress", "fromBJJ",
+	"toAccountIndex", "toCbEthereumAddress", "toBJJ",
+	"tokenId", "tokenSymbol", "amount", "amountSuccess", "amountUSD",
+	"depositAmount", "depositAmountSuccess", "depositAmountUSD",
+	"fee", "feeUSD", "nonce",
+}
+
+// newTxExport converts a TxAPI to its export representation
+func newTxExport(tx *TxAPI) (*TxExport, error) {
+	export := &TxExport{
+		ItemID:      tx.ItemID,
+		TxID:        tx.TxID,
+		Type:        tx.Type,
+		L1orL2:      "L2",
+		BatchNum:    tx.BatchNum,
+		EthBlockNum: tx.EthBlockNum,
+		Timestamp:   tx.Timestamp,
+		ToIdx:       string(tx.ToIdx),
+		TokenID:     tx.TokenID,
+		TokenSymbol: tx.TokenSymbol,
+		AmountUSD:   tx.HistoricUSD,
+	}
+	amount, ok := new(big.Int).SetString(string(tx.Amount), 10)
+	if !ok {
+		return nil, tracerr.Wrap(fmt.Errorf("invalid amount: %v", tx.Amount))
+	}
+	export.Amount = normalizeAmount(amount, tx.TokenDecimals)
+	if tx.FromIdx != nil {
+		export.FromIdx = string(*tx.FromIdx)
+	}
+	if tx.FromEthAddr != nil {
+		export.FromEthAddr = string(*tx.FromEthAddr)
+	}
+	if tx.FromBJJ != nil {
+		export.FromBJJ = string(*tx.FromBJJ)
+	}
+	if tx.ToEthAddr != nil {
+		export.ToEthAddr = string(*tx.ToEthAddr)
+	}
+	if tx.ToBJJ != nil {
+		export.ToBJJ = string(*tx.ToBJJ)
+	}
+	if tx.IsL1 {
+		export.L1orL2 = "L1"
+		// Unforged L1 txs haven't been processed yet
+		amountSuccess := tx.AmountSuccess && tx.BatchNum != nil
+		depositAmountSuccess := tx.DepositAmountSuccess && tx.BatchNum != nil
+		export.AmountSuccess = &amountSuccess
+		export.DepositAmountSuccess = &depositAmountSuccess
+		if tx.DepositAmount != nil {
+			depositAmount, ok := new(big.Int).SetString(string(*tx.DepositAmount), 10)
+			if !ok {
+				return nil, tracerr.Wrap(fmt.Errorf("invalid deposit amount: %v", *tx.DepositAmount))
+			}
+			export.DepositAmount = normalizeAmount(depositAmount, tx.TokenDecimals)
+		}
+		export.DepositAmountUSD = tx.HistoricDepositAmountUSD
+	} else {
+		if tx.Fee != nil {
+			fee, err := common.CalcFeeAmount(amount, *tx.Fee)
+			if err != nil {
+				return nil, tracerr.Wrap(err)
+			}
+			export.Fee = normalizeAmount(fee, tx.TokenDecimals)
+		}
+		export.FeeUSD = tx.HistoricFeeUSD
+		export.Nonce = tx.Nonce
+	}
+	return export, nil
+}
+
+// csvRecord returns the values of the tx in the CSV exports
+func (e *TxExport) csvRecord() []string {
+	optUint := func(v interface{}) string {
+		switch v := v.(type) {
+		case *common.BatchNum:
+			if v != nil {
+				return strconv.FormatInt(int64(*v), 10)
+			}
+		case *common.Nonce:
+			if v != nil {
+				return strconv.FormatUint(uint64(*v), 10)
+			}
+		}
+		return ""
+	}
+	optBool := func(v *bool) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatBool(*v)
+	}
+	optFloat := func(v *float64) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*v, 'f', -1, 64)
+	}
+	return []string{
+		strconv.FormatUint(e.ItemID, 10), e.TxID.String(), string(e.Type), e.L1orL2,
+		optUint(e.BatchNum), strconv.FormatInt(e.EthBlockNum, 10),
+		e.Timestamp.UTC().Format(time.RFC3339),
+		e.FromIdx, e.FromEthAddr, e.FromBJJ,
+		e.ToIdx, e.ToEthAddr, e.ToBJJ,
+		strconv.FormatUint(uint64(e.TokenID), 10), e.TokenSymbol,
+		e.Amount, optBool(e.AmountSuccess), optFloat(e.AmountUSD),
+		e.DepositAmount, optBool(e.DepositAmountSuccess), optFloat(e.DepositAmountUSD),
+		e.Fee, optFloat(e.FeeUSD), optUint(e.Nonce),
+	}
+}
+
+// normalizeAmount returns the amount as a decimal number of tokens, without
+// losing precision
+func normalizeAmount(amount *big.Int, decimals uint64) string {
+	if decimals == 0 {
+		return amount.String()
+	}
+	abs := new(big.Int).Abs(amount)
+	unit := new(big.Int).Exp(big.NewInt(10), new(big.Int).SetUint64(decimals), nil) //nolint:gomnd
+	integer, fraction := new(big.Int).QuoRem(abs, unit, new(big.Int))
+	sign := ""
+	if amount.Sign() < 0 {
+		sign = "-"
+	}
+	if fraction.Sign() == 0 {
+		return sign + integer.String()
+	}
+	fractionStr := fmt.Sprintf("%0*s", decimals, fraction.String())
+	return sign + integer.String() + "." + strings.TrimRight(fractionStr, "0")
+}
+
+// txsExportPageSize is the number of txs of an export read from the DB at
+// once.  It must be at least 2, as each page repeats the last tx of the
+// previous one.
+var txsExportPageSize uint = 1000
+
+// ExportTxsAPI writes to w the txs that match the filters of the request
+// (pagination is ignored) in the given format and sort order.  The txs are
+// read from the DB by pages, holding a DB connection only while each page is
+// read, so that exports of any size neither need to be loaded into memory nor
+// keep a connection busy while they are sent to a slow client.
+func (hdb *HistoryDB) ExportTxsAPI(w io.Writer, format TxsExportFormat, request GetTxsAPIRequest) error {
+	if format != TxsExportFormatCSV && format != TxsExportFormatJSONL {
+		return tracerr.Wrap(fmt.Errorf("invalid export format: %v", format))
+	}
+	if request.EthAddr != nil && request.Bjj != nil {
+		return tracerr.Wrap(fmt.Errorf("ethAddr and bjj are incompatible"))
+	}
+	if request.Order != db.OrderDesc {
+		request.Order = db.OrderAsc
+	}
+	sortBy, err := sortColumn(txsSortColumns, request.SortBy)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	request.FromItem = nil
+
+	csvWriter := csv.NewWriter(w)
+	jsonEncoder := json.NewEncoder(w)
+	if format == TxsExportFormatCSV {
+		if err := csvWriter.Write(txExportCSVHeader); err != nil {
+			return tracerr.Wrap(err)
+		}
+	}
+	for {
+		txs, err := hdb.getTxsExportPage(request, sortBy)
+		if err != nil {
+			return tracerr.Wrap(err)
+		}
+		pageLen := uint(len(txs))
+		// The pages start at the last tx of the previous one, which
+		// has already been exported
+		if request.FromItem != nil && len(txs) > 0 && uint(txs[0].ItemID) == *request.FromItem {
+			txs = txs[1:]
+		}
+		for i := range txs {
+			export, err := newTxExport(&txs[i])
+			if err != nil {
+				return tracerr.Wrap(err)
+			}
+			if format == TxsExportFormatCSV {
+				err = csvWriter.Write(export.csvRecord())
+			} else {
+				err = jsonEncoder.Encode(export)
+			}
+			if err != nil {
+				return tracerr.Wrap(err)
+			}
+		}
+		csvWriter.Flush()
+		if err := csvWriter.Error(); err != nil {
+			return tracerr.Wrap(err)
+		}
+		if pageLen < txsExportPageSize || len(txs) == 0 {
+			return nil
+		}
+		lastItemID := uint(txs[len(txs)-1].ItemID)
+		request.FromItem = &lastItemID
+	}
+}
+
+// getTxsExportPage returns the page of txs of an export that starts at the
+// FromItem of the request, in the order given by the sortBy expression
+func (hdb *HistoryDB) getTxsExportPage(request GetTxsAPIRequest, sortBy string) ([]TxAPI, error) {
+	cancel, err := hdb.apiConnCon.Acquire()
+	defer cancel()
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	defer hdb.apiConnCon.Release()
+	queryStr := `SELECT tx.item_id, tx.is_l1, tx.id, tx.type, tx.position,
+	cb_idx(tx.effective_from_idx, token.symbol) AS from_idx, tx.from_eth_addr, tx.from_bjj,
+	cb_idx(tx.to_idx, token.symbol) AS to_idx, tx.to_eth_addr, tx.to_bjj,
+	tx.amount, tx.amount_success, tx.token_id, tx.amount_usd,
+	tx.batch_num, tx.eth_block_num, tx.to_forge_l1_txs_num, tx.user_origin,
+	tx.deposit_amount, tx.deposit_amount_usd, tx.deposit_amount_success, tx.fee, tx.fee_usd, tx.nonce,
+	token.token_id, token.item_id AS token_item_id, token.eth_block_num AS token_block,
+	token.eth_addr, token.name, token.symbol, token.decimals, token.usd,
+	token.usd_update, block.timestamp
+	FROM tx INNER JOIN token ON tx.token_id = token.token_id
+	INNER JOIN block ON tx.eth_block_num = block.eth_block_num `
+	filters, args := txsAPIFilters(request, sortBy)
+	queryStr += filters
+	queryStr += orderByClause("tx", sortBy, request.Order)
+	queryStr += fmt.Sprintf("LIMIT %d;", txsExportPageSize)
+	txsPtrs := []*TxAPI{}
+	if err := meddler.QueryAll(
+		hdb.dbRead, &txsPtrs, hdb.dbRead.Rebind(queryStr), args...,
+	); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	return db.SlicePtrsToSlice(txsPtrs).([]TxAPI), nil
+}
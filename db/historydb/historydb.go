@@ -25,6 +25,7 @@ import (
 	"math"
 	"math/big"
 	"strings"
+	"time"
 
 	ethCommon "github.com/ethereum/go-ethereum/common"
 	"github.com/chainbing/node/common"
@@ -233,6 +234,22 @@ func (hdb *HistoryDB) GetFirstBatchBlockNumBySlot(slotNum int64) (int64, error)
 	return blockNum, tracerr.Wrap(row.Scan(&blockNum))
 }
 
+// GetAvgSlotFeesUSD returns the average of the fees in USD collected in each
+// of the last numSlots slots before currentSlot in which batches were forged,
+// or nil if no batch has been forged.  The currentSlot is not counted because
+// its batches are still being forged.
+func (hdb *HistoryDB) GetAvgSlotFeesUSD(numSlots int, currentSlot int64) (*float64, error) {
+	row := hdb.dbRead.QueryRow(
+		`SELECT AVG(fees_usd) FROM (
+			SELECT SUM(COALESCE(total_fees_usd, 0)) AS fees_usd FROM batch
+			WHERE slot_num < $2
+			GROUP BY slot_num ORDER BY slot_num DESC LIMIT $1
+		) AS slot_fees;`, numSlots, currentSlot,
+	)
+	var avgFeesUSD *float64
+	return avgFeesUSD, tracerr.Wrap(row.Scan(&avgFeesUSD))
+}
+
 // GetLastBatchNum returns the BatchNum of the latest forged batch
 func (hdb *HistoryDB) GetLastBatchNum() (common.BatchNum, error) {
 	row := hdb.dbRead.QueryRow("SELECT batch_num FROM batch ORDER BY batch_num DESC LIMIT 1;")
@@ -307,6 +324,22 @@ func (hdb *HistoryDB) GetAllBids() ([]common.Bid, error) {
 	return db.SlicePtrsToSlice(bids).([]common.Bid), tracerr.Wrap(err)
 }
 
+// GetBidsByBidderSince returns the bids placed by the bidder in the blocks
+// after since, in the order they were placed
+func (hdb *HistoryDB) GetBidsByBidderSince(bidder ethCommon.Address,
+	since time.Time) ([]BidWithTimestamp, error) {
+	var bids []*BidWithTimestamp
+	err := meddler.QueryAll(
+		hdb.dbRead, &bids,
+		`SELECT bid.slot_num, bid.bid_value, block.timestamp FROM bid
+		INNER JOIN block ON bid.eth_block_num = block.eth_block_num
+		WHERE bid.bidder_addr = $1 AND block.timestamp > $2
+		ORDER BY bid.item_id;`,
+		bidder, since,
+	)
+	return db.SlicePtrsToSlice(bids).([]BidWithTimestamp), tracerr.Wrap(err)
+}
+
 // GetBestBidCoordinator returns the forger address of the highest bidder in a slot by slotNum
 func (hdb *HistoryDB) GetBestBidCoordinator(slotNum int64) (*common.BidCoordinator, error) {
 	bidCoord := &common.BidCoordinator{}
@@ -538,6 +571,15 @@ func (hdb *HistoryDB) GetToken(tokenID common.TokenID) (*TokenWithUSD, error) {
 	return token, tracerr.Wrap(err)
 }
 
+// GetTokenByEthAddr returns a token from the DB given its ethereum address
+func (hdb *HistoryDB) GetTokenByEthAddr(ethAddr ethCommon.Address) (*TokenWithUSD, error) {
+	token := &TokenWithUSD{}
+	err := meddler.QueryRow(
+		hdb.dbRead, token, `SELECT * FROM token WHERE eth_addr = $1;`, ethAddr,
+	)
+	return token, tracerr.Wrap(err)
+}
+
 // GetAllTokens returns all tokens from the DB
 func (hdb *HistoryDB) GetAllTokens() ([]TokenWithUSD, error) {
 	var tokens []*TokenWithUSD
@@ -799,6 +841,60 @@ func (hdb *HistoryDB) GetAllL2Txs() ([]common.L2Tx, error) {
 	return db.SlicePtrsToSlice(txs).([]common.L2Tx), tracerr.Wrap(err)
 }
 
+// BatchForgedTxs are the txs forged in a batch, in the order of its data
+// availability
+type BatchForgedTxs struct {
+	L1UserTxs        []common.L1Tx
+	L1CoordinatorTxs []common.L1Tx
+	L2Txs            []common.L2Tx
+}
+
+// GetBatchForgedTxs returns the txs forged in a batch
+func (hdb *HistoryDB) GetBatchForgedTxs(batchNum common.BatchNum) (*BatchForgedTxs, error) {
+	var l1Txs []*common.L1Tx
+	if err := meddler.QueryAll(
+		hdb.dbRead, &l1Txs,
+		`SELECT tx.id, tx.to_forge_l1_txs_num, tx.position, tx.user_origin,
+		tx.from_idx, tx.effective_from_idx, tx.from_eth_addr, tx.from_bjj, tx.to_idx, tx.token_id,
+		tx.amount, (CASE WHEN tx.amount_success THEN tx.amount ELSE 0 END) AS effective_amount,
+		tx.deposit_amount, (CASE WHEN tx.deposit_amount_success THEN tx.deposit_amount ELSE 0 END) AS effective_deposit_amount,
+		tx.eth_block_num, tx.type, tx.batch_num
+		FROM tx WHERE is_l1 = TRUE AND batch_num = $1 ORDER BY item_id;`,
+		batchNum,
+	); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	txs := &BatchForgedTxs{}
+	for _, tx := range l1Txs {
+		if tx.UserOrigin {
+			txs.L1UserTxs = append(txs.L1UserTxs, *tx)
+		} else {
+			txs.L1CoordinatorTxs = append(txs.L1CoordinatorTxs, *tx)
+		}
+	}
+	var l2Txs []*common.L2Tx
+	if err := meddler.QueryAll(
+		hdb.dbRead, &l2Txs,
+		`SELECT tx.id, tx.batch_num, tx.position,
+		tx.from_idx, tx.to_idx, tx.amount, tx.token_id,
+		tx.fee, tx.nonce, tx.type, tx.eth_block_num
+		FROM tx WHERE is_l1 = FALSE AND batch_num = $1 ORDER BY item_id;`,
+		batchNum,
+	); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	txs.L2Txs = db.SlicePtrsToSlice(l2Txs).([]common.L2Tx)
+	return txs, nil
+}
+
+// GetBatchNumByEthTxHash returns the batchNum of the batch forged in the
+// ethereum tx with the given hash
+func (hdb *HistoryDB) GetBatchNumByEthTxHash(ethTxHash ethCommon.Hash) (common.BatchNum, error) {
+	var batchNum common.BatchNum
+	row := hdb.dbRead.QueryRow("SELECT batch_num FROM batch WHERE eth_tx_hash = $1;", ethTxHash)
+	return batchNum, tracerr.Wrap(row.Scan(&batchNum))
+}
+
 // GetUnforgedL1UserTxs gets L1 User Txs to be forged in the L1Batch with toForgeL1TxsNum.
 func (hdb *HistoryDB) GetUnforgedL1UserTxs(toForgeL1TxsNum int64) ([]common.L1Tx, error) {
 	var txs []*common.L1Tx
@@ -2,10 +2,12 @@ package historydb
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"math"
 	"math/big"
 	"os"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -1359,6 +1361,82 @@ func TestGetFirstBatchBlockNumBySlot(t *testing.T) {
 	assert.Equal(t, int64(10), bn2)
 }
 
+func TestGetAvgSlotFeesUSD(t *testing.T) {
+	blocks := setTestBlocks(1, 2)
+	avgFeesUSD, err := historyDB.GetAvgSlotFeesUSD(2, 4)
+	require.NoError(t, err)
+	assert.Nil(t, avgFeesUSD)
+
+	tokens, _ := test.GenTokens(1, blocks)
+	require.NoError(t, historyDB.AddTokens(tokens))
+	require.NoError(t, historyDB.UpdateTokenValue(tokens[0].EthAddr, 2))
+	token, err := historyDB.GetTokenByEthAddr(tokens[0].EthAddr)
+	require.NoError(t, err)
+	assert.Equal(t, tokens[0].TokenID, token.TokenID)
+	assert.Equal(t, 2.0, *token.USD)
+	_, err = historyDB.GetTokenByEthAddr(ethCommon.BigToAddress(big.NewInt(42)))
+	assert.Equal(t, sql.ErrNoRows, tracerr.Unwrap(err))
+
+	// Fees of 40, 20 + 40 and 60 USD collected in the slots 0, 1 and 3
+	fees := []struct {
+		slotNum int64
+		amount  int64
+	}{{0, 20}, {1, 10}, {1, 20}, {3, 30}}
+	for i, fee := range fees {
+		amount := new(big.Int).Mul(big.NewInt(fee.amount),
+			new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(tokens[0].Decimals)), nil))
+		require.NoError(t, historyDB.AddBatch(&common.Batch{
+			BatchNum:      common.BatchNum(i + 1),
+			EthBlockNum:   blocks[0].Num,
+			CollectedFees: map[common.TokenID]*big.Int{tokens[0].TokenID: amount},
+			StateRoot:     big.NewInt(0),
+			ExitRoot:      big.NewInt(0),
+			SlotNum:       fee.slotNum,
+		}))
+	}
+	avgFeesUSD, err = historyDB.GetAvgSlotFeesUSD(2, 4)
+	require.NoError(t, err)
+	assert.InDelta(t, 60, *avgFeesUSD, 1e-9)
+	avgFeesUSD, err = historyDB.GetAvgSlotFeesUSD(3, 4)
+	require.NoError(t, err)
+	assert.InDelta(t, 160.0/3, *avgFeesUSD, 1e-9)
+	// The fees of the current slot are not counted
+	avgFeesUSD, err = historyDB.GetAvgSlotFeesUSD(2, 3)
+	require.NoError(t, err)
+	assert.InDelta(t, 50, *avgFeesUSD, 1e-9)
+}
+
+func TestGetBidsByBidderSince(t *testing.T) {
+	test.WipeDB(historyDB.DB())
+	now := time.Now().UTC().Truncate(time.Second)
+	for i := int64(1); i <= 2; i++ {
+		require.NoError(t, historyDB.AddBlock(&common.Block{
+			Num:       i,
+			Timestamp: now.Add(time.Duration(i-3) * time.Hour),
+			Hash:      ethCommon.BigToHash(big.NewInt(i)),
+		}))
+	}
+	bidder := ethCommon.BigToAddress(big.NewInt(1))
+	otherBidder := ethCommon.BigToAddress(big.NewInt(2))
+	require.NoError(t, historyDB.AddBids([]common.Bid{
+		{SlotNum: 10, BidValue: big.NewInt(100), EthBlockNum: 1, Bidder: bidder},
+		{SlotNum: 11, BidValue: big.NewInt(200), EthBlockNum: 2, Bidder: bidder},
+		{SlotNum: 11, BidValue: big.NewInt(300), EthBlockNum: 2, Bidder: otherBidder},
+		{SlotNum: 12, BidValue: big.NewInt(400), EthBlockNum: 2, Bidder: bidder},
+	}))
+
+	// Only the bids of the bidder in the blocks after since are returned
+	bids, err := historyDB.GetBidsByBidderSince(bidder, now.Add(-90*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, []BidWithTimestamp{
+		{SlotNum: 11, BidValue: big.NewInt(200), Timestamp: now.Add(-time.Hour)},
+		{SlotNum: 12, BidValue: big.NewInt(400), Timestamp: now.Add(-time.Hour)},
+	}, bids)
+	bids, err = historyDB.GetBidsByBidderSince(bidder, now)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(bids))
+}
+
 func TestTxItemID(t *testing.T) {
 	test.WipeDB(historyDB.DB())
 	testUsersLen := 10
@@ -1593,3 +1671,615 @@ func TestNodeInfo(t *testing.T) {
 	dbStateAPI.Network.NextForgers[0].Period.ToTimestamp = stateAPI.Network.NextForgers[0].Period.ToTimestamp
 	assert.Equal(t, stateAPI, dbStateAPI)
 }
+
+func TestAPIKeys(t *testing.T) {
+	test.WipeDB(historyDB.DB())
+	quota := 2
+	apiKey := &APIKey{
+		KeyHash:          APIKeyHash("secret key"),
+		Name:             "partner",
+		CoordinatorScope: true,
+		RateLimit:        10.5,
+		Burst:            20,
+		DailyTxQuota:     &quota,
+	}
+	require.NoError(t, historyDB.AddAPIKey(apiKey))
+	dbAPIKey, err := historyDBWithACC.GetAPIKeyAPI(APIKeyHash("secret key"))
+	require.NoError(t, err)
+	assert.Equal(t, apiKey.ItemID, dbAPIKey.ItemID)
+	assert.Equal(t, apiKey.Timestamp.Unix(), dbAPIKey.Timestamp.Unix())
+	dbAPIKey.Timestamp = apiKey.Timestamp
+	assert.Equal(t, apiKey, dbAPIKey)
+	_, err = historyDBWithACC.GetAPIKeyAPI(APIKeyHash("other key"))
+	assert.Equal(t, sql.ErrNoRows, tracerr.Unwrap(err))
+
+	// Count the txs submitted each day
+	day := time.Date(2021, 4, 17, 23, 0, 0, 0, time.UTC)
+	txCount, err := historyDBWithACC.GetAPIKeyTxCountAPI(apiKey.ItemID, day)
+	require.NoError(t, err)
+	assert.Equal(t, 0, txCount)
+	reserved, err := historyDBWithACC.ReserveAPIKeyTxQuotaAPI(apiKey.ItemID, day, 1, 4)
+	require.NoError(t, err)
+	assert.True(t, reserved)
+	reserved, err = historyDBWithACC.ReserveAPIKeyTxQuotaAPI(apiKey.ItemID, day, 3, 4)
+	require.NoError(t, err)
+	assert.True(t, reserved)
+	txCount, err = historyDBWithACC.GetAPIKeyTxCountAPI(apiKey.ItemID, day)
+	require.NoError(t, err)
+	assert.Equal(t, 4, txCount)
+	// The txs that exceed the quota are not counted
+	reserved, err = historyDBWithACC.ReserveAPIKeyTxQuotaAPI(apiKey.ItemID, day, 1, 4)
+	require.NoError(t, err)
+	assert.False(t, reserved)
+	reserved, err = historyDBWithACC.ReserveAPIKeyTxQuotaAPI(apiKey.ItemID, day.Add(-24*time.Hour), 5, 4)
+	require.NoError(t, err)
+	assert.False(t, reserved)
+	// The refunded txs are discounted
+	require.NoError(t, historyDBWithACC.RefundAPIKeyTxQuotaAPI(apiKey.ItemID, day, 3))
+	txCount, err = historyDBWithACC.GetAPIKeyTxCountAPI(apiKey.ItemID, day)
+	require.NoError(t, err)
+	assert.Equal(t, 1, txCount)
+	// The count is restarted the next day
+	txCount, err = historyDBWithACC.GetAPIKeyTxCountAPI(apiKey.ItemID, day.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, txCount)
+
+	// Revoke the key
+	require.NoError(t, historyDB.RevokeAPIKey(apiKey.ItemID))
+	dbAPIKey, err = historyDBWithACC.GetAPIKeyAPI(APIKeyHash("secret key"))
+	require.NoError(t, err)
+	assert.True(t, dbAPIKey.Revoked)
+	err = historyDB.RevokeAPIKey(apiKey.ItemID + 1)
+	assert.Equal(t, sql.ErrNoRows, tracerr.Unwrap(err))
+}
+
+func TestNormalizeAmount(t *testing.T) {
+	assert.Equal(t, "0", normalizeAmount(big.NewInt(0), 18))
+	assert.Equal(t, "1234", normalizeAmount(big.NewInt(1234), 0))
+	assert.Equal(t, "1.5", normalizeAmount(big.NewInt(1500), 3))
+	assert.Equal(t, "0.001", normalizeAmount(big.NewInt(1), 3))
+	assert.Equal(t, "-12.34", normalizeAmount(big.NewInt(-1234), 2))
+	amount, ok := new(big.Int).SetString("123456789012345678901234567890", 10)
+	require.True(t, ok)
+	assert.Equal(t, "123456789012.34567890123456789", normalizeAmount(amount, 18))
+}
+
+func TestExportTxs(t *testing.T) {
+	test.WipeDB(historyDB.DB())
+	set := `
+		Type: Blockchain
+
+		AddToken(1)
+		CreateAccountDeposit(1) A: 100
+		CreateAccountDeposit(1) B: 100
+		> batchL1
+		> batchL1
+		> block
+
+		Transfer(1) A-B : 10 (126)
+		Transfer(1) B-A : 20 (126)
+		> batch
+		> block
+	`
+	tc := til.NewContext(uint16(0), common.RollupConstMaxL1UserTx)
+	tilCfgExtra := til.ConfigExtra{
+		BootCoordAddr: ethCommon.HexToAddress("0xE39fEc6224708f0772D2A74fd3f9055A90E0A9f2"),
+		CoordUser:     "A",
+	}
+	blocks, err := tc.GenerateBlocks(set)
+	require.NoError(t, err)
+	require.NoError(t, tc.FillBlocksExtra(blocks, &tilCfgExtra))
+	for i := range blocks {
+		require.NoError(t, historyDB.AddBlockSCData(&blocks[i]))
+	}
+
+	// CSV, reading the txs in several pages
+	txsExportPageSize = 3
+	defer func() { txsExportPageSize = 1000 }()
+	var csvExport strings.Builder
+	require.NoError(t, historyDBWithACC.ExportTxsAPI(&csvExport, TxsExportFormatCSV, GetTxsAPIRequest{}))
+	lines := strings.Split(strings.TrimSpace(csvExport.String()), "\n")
+	require.Equal(t, 5, len(lines))
+	assert.Equal(t, strings.Join(txExportCSVHeader, ","), lines[0])
+	assert.Contains(t, lines[1], ",L1,")
+	assert.Contains(t, lines[4], ",L2,")
+	// The pages don't repeat nor skip txs
+	for i := 2; i < len(lines); i++ {
+		prevItemID, err := strconv.Atoi(strings.Split(lines[i-1], ",")[0])
+		require.NoError(t, err)
+		itemID, err := strconv.Atoi(strings.Split(lines[i], ",")[0])
+		require.NoError(t, err)
+		assert.Less(t, prevItemID, itemID)
+	}
+
+	// JSONL, filtering by tx type
+	txType := common.TxTypeTransfer
+	var jsonlExport strings.Builder
+	require.NoError(t, historyDBWithACC.ExportTxsAPI(&jsonlExport, TxsExportFormatJSONL, GetTxsAPIRequest{
+		TxType: &txType,
+		Order:  dbUtils.OrderDesc,
+	}))
+	lines = strings.Split(strings.TrimSpace(jsonlExport.String()), "\n")
+	require.Equal(t, 2, len(lines))
+	var txs [2]TxExport
+	for i, line := range lines {
+		require.NoError(t, json.Unmarshal([]byte(line), &txs[i]))
+		assert.Equal(t, common.TxTypeTransfer, txs[i].Type)
+		assert.NotEmpty(t, txs[i].Fee)
+		assert.Nil(t, txs[i].AmountSuccess)
+	}
+	assert.Greater(t, txs[0].ItemID, txs[1].ItemID)
+
+	// Sorted by amount, reading the txs in several pages
+	txsExportPageSize = 2
+	var sortedExport strings.Builder
+	require.NoError(t, historyDBWithACC.ExportTxsAPI(&sortedExport, TxsExportFormatJSONL, GetTxsAPIRequest{
+		Order:  dbUtils.OrderDesc,
+		SortBy: SortByAmount,
+	}))
+	lines = strings.Split(strings.TrimSpace(sortedExport.String()), "\n")
+	require.Equal(t, 4, len(lines))
+	limit := uint(10)
+	sortedTxs, _, err := historyDBWithACC.GetTxsAPI(GetTxsAPIRequest{
+		Limit:  &limit,
+		Order:  dbUtils.OrderDesc,
+		SortBy: SortByAmount,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 4, len(sortedTxs))
+	assert.Equal(t, "20", string(sortedTxs[0].Amount))
+	for i, line := range lines {
+		var tx TxExport
+		require.NoError(t, json.Unmarshal([]byte(line), &tx))
+		assert.Equal(t, sortedTxs[i].ItemID, tx.ItemID)
+	}
+
+	// Timestamp range that doesn't include any tx
+	from := blocks[len(blocks)-1].Block.Timestamp.Add(time.Hour)
+	var emptyExport strings.Builder
+	require.NoError(t, historyDBWithACC.ExportTxsAPI(&emptyExport, TxsExportFormatJSONL, GetTxsAPIRequest{
+		FromTimestamp: &from,
+	}))
+	assert.Empty(t, emptyExport.String())
+
+	// Invalid format
+	assert.Error(t, historyDBWithACC.ExportTxsAPI(&emptyExport, "xml", GetTxsAPIRequest{}))
+}
+
+func TestGetAccountHistory(t *testing.T) {
+	test.WipeDB(historyDB.DB())
+	set := `
+		Type: Blockchain
+
+		AddToken(1)
+		CreateAccountDeposit(1) A: 100
+		CreateAccountDeposit(1) B: 100
+		> batchL1
+		> batchL1
+		> block
+
+		Transfer(1) A-B : 10 (126)
+		Transfer(1) B-A : 20 (126)
+		> batch
+		> block
+	`
+	tc := til.NewContext(uint16(0), common.RollupConstMaxL1UserTx)
+	tilCfgExtra := til.ConfigExtra{
+		BootCoordAddr: ethCommon.HexToAddress("0xE39fEc6224708f0772D2A74fd3f9055A90E0A9f2"),
+		CoordUser:     "A",
+	}
+	blocks, err := tc.GenerateBlocks(set)
+	require.NoError(t, err)
+	require.NoError(t, tc.FillBlocksExtra(blocks, &tilCfgExtra))
+	for i := range blocks {
+		require.NoError(t, historyDB.AddBlockSCData(&blocks[i]))
+	}
+	idx := tc.Users["A"].Accounts[common.TokenID(1)].Idx
+	l2Txs := blocks[1].Rollup.Batches[0].L2Txs
+
+	// All the updates, from the newest
+	updates, pendingItems, err := historyDBWithACC.GetAccountHistoryAPI(GetAccountHistoryAPIRequest{
+		Idx:   idx,
+		Order: dbUtils.OrderDesc,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(updates))
+	assert.Equal(t, uint64(0), pendingItems)
+	assert.Greater(t, updates[0].ItemID, updates[1].ItemID)
+	assert.Equal(t, blocks[1].Rollup.Batches[0].Batch.BatchNum, updates[0].BatchNum)
+	assert.Equal(t, []common.TxID{l2Txs[0].TxID, l2Txs[1].TxID}, updates[0].TxIDs)
+	assert.Equal(t, "100", string(*updates[1].Balance))
+
+	// Batch range and pagination
+	limit := uint(1)
+	updates, pendingItems, err = historyDBWithACC.GetAccountHistoryAPI(GetAccountHistoryAPIRequest{
+		Idx:         idx,
+		MaxBatchNum: &blocks[0].Rollup.Batches[1].Batch.BatchNum,
+		Limit:       &limit,
+		Order:       dbUtils.OrderAsc,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(updates))
+	assert.Equal(t, uint64(0), pendingItems)
+	assert.Equal(t, blocks[0].Rollup.Batches[1].Batch.BatchNum, updates[0].BatchNum)
+
+	// Daily resolution, the last update of each day has the txs of the day
+	daily, _, err := historyDBWithACC.GetAccountHistoryAPI(GetAccountHistoryAPIRequest{
+		Idx:   idx,
+		Daily: true,
+		Order: dbUtils.OrderDesc,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, daily)
+	assert.Equal(t, blocks[1].Rollup.Batches[0].Batch.BatchNum, daily[0].BatchNum)
+	assert.Subset(t, daily[0].TxIDs, []common.TxID{l2Txs[0].TxID, l2Txs[1].TxID})
+
+	// Timestamp range that doesn't include any update
+	from := blocks[len(blocks)-1].Block.Timestamp.Add(time.Hour)
+	updates, _, err = historyDBWithACC.GetAccountHistoryAPI(GetAccountHistoryAPIRequest{
+		Idx:           idx,
+		FromTimestamp: &from,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, updates)
+}
+
+func TestGetBatchForgedTxs(t *testing.T) {
+	test.WipeDB(historyDB.DB())
+	set := `
+		Type: Blockchain
+
+		AddToken(1)
+		CreateAccountDeposit(1) A: 100
+		> batchL1
+		> batchL1
+		CreateAccountCoordinator(1) B
+		Transfer(1) A-B : 10 (126)
+		> batch
+		> block
+	`
+	tc := til.NewContext(uint16(0), common.RollupConstMaxL1UserTx)
+	tilCfgExtra := til.ConfigExtra{
+		BootCoordAddr: ethCommon.HexToAddress("0xE39fEc6224708f0772D2A74fd3f9055A90E0A9f2"),
+		CoordUser:     "A",
+	}
+	blocks, err := tc.GenerateBlocks(set)
+	require.NoError(t, err)
+	require.NoError(t, tc.FillBlocksExtra(blocks, &tilCfgExtra))
+	for i := range blocks {
+		require.NoError(t, historyDB.AddBlockSCData(&blocks[i]))
+	}
+	batches := blocks[0].Rollup.Batches
+
+	txs, err := historyDB.GetBatchForgedTxs(batches[1].Batch.BatchNum)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(txs.L1UserTxs))
+	assert.Empty(t, txs.L1CoordinatorTxs)
+	assert.Empty(t, txs.L2Txs)
+
+	txs, err = historyDB.GetBatchForgedTxs(batches[2].Batch.BatchNum)
+	require.NoError(t, err)
+	assert.Empty(t, txs.L1UserTxs)
+	require.Equal(t, 1, len(txs.L1CoordinatorTxs))
+	assert.Equal(t, batches[2].L1CoordinatorTxs[0].TxID, txs.L1CoordinatorTxs[0].TxID)
+	require.Equal(t, 1, len(txs.L2Txs))
+	assert.Equal(t, batches[2].L2Txs[0].TxID, txs.L2Txs[0].TxID)
+}
+
+func TestGetAPISortAndBlockRange(t *testing.T) {
+	test.WipeDB(historyDB.DB())
+	set := `
+		Type: Blockchain
+
+		AddToken(1)
+		CreateAccountDeposit(1) A: 100
+		CreateAccountDeposit(1) B: 100
+		> batchL1
+		> batchL1
+		> block
+
+		Transfer(1) A-B : 10 (126)
+		Transfer(1) B-A : 20 (126)
+		> batch
+		> block
+
+		Transfer(1) A-B : 5 (126)
+		> batch
+		> block
+	`
+	tc := til.NewContext(uint16(0), common.RollupConstMaxL1UserTx)
+	tilCfgExtra := til.ConfigExtra{
+		BootCoordAddr: ethCommon.HexToAddress("0xE39fEc6224708f0772D2A74fd3f9055A90E0A9f2"),
+		CoordUser:     "A",
+	}
+	blocks, err := tc.GenerateBlocks(set)
+	require.NoError(t, err)
+	require.NoError(t, tc.FillBlocksExtra(blocks, &tilCfgExtra))
+	for i := range blocks {
+		require.NoError(t, historyDB.AddBlockSCData(&blocks[i]))
+	}
+
+	// Sort the txs by amount, paginating from the second tx
+	limit := uint(2)
+	txs, pendingItems, err := historyDBWithACC.GetTxsAPI(GetTxsAPIRequest{
+		Limit:  &limit,
+		Order:  dbUtils.OrderDesc,
+		SortBy: SortByAmount,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(txs))
+	assert.Equal(t, uint64(3), pendingItems)
+	assert.Equal(t, "20", string(txs[0].Amount))
+	assert.Equal(t, "10", string(txs[1].Amount))
+	fromItem := uint(txs[1].ItemID)
+	txs, pendingItems, err = historyDBWithACC.GetTxsAPI(GetTxsAPIRequest{
+		FromItem: &fromItem,
+		Limit:    &limit,
+		Order:    dbUtils.OrderDesc,
+		SortBy:   SortByAmount,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(txs))
+	assert.Equal(t, uint64(2), pendingItems)
+	assert.Equal(t, "10", string(txs[0].Amount))
+	assert.Equal(t, "5", string(txs[1].Amount))
+	// The txs without USD value are sorted as 0
+	_, err = historyDB.dbWrite.Exec("UPDATE tx SET fee_usd = amount WHERE NOT is_l1;")
+	require.NoError(t, err)
+	txs, _, err = historyDBWithACC.GetTxsAPI(GetTxsAPIRequest{
+		Limit:  &limit,
+		Order:  dbUtils.OrderAsc,
+		SortBy: SortByFeeUSD,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(txs))
+	assert.True(t, txs[0].IsL1)
+	assert.True(t, txs[1].IsL1)
+
+	// Sort the batches by the USD value of the fees, paginating from the
+	// second batch
+	_, err = historyDB.dbWrite.Exec("UPDATE batch SET total_fees_usd = 10 - batch_num;")
+	require.NoError(t, err)
+	batches, pendingItems, err := historyDBWithACC.GetBatchesAPI(GetBatchesAPIRequest{
+		Limit:  &limit,
+		Order:  dbUtils.OrderDesc,
+		SortBy: SortByTotalFeesUSD,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(batches))
+	assert.Equal(t, uint64(2), pendingItems)
+	assert.Equal(t, common.BatchNum(1), batches[0].BatchNum)
+	assert.Equal(t, common.BatchNum(2), batches[1].BatchNum)
+	fromItem = uint(batches[1].ItemID)
+	batches, pendingItems, err = historyDBWithACC.GetBatchesAPI(GetBatchesAPIRequest{
+		FromItem: &fromItem,
+		Limit:    &limit,
+		Order:    dbUtils.OrderDesc,
+		SortBy:   SortByTotalFeesUSD,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(batches))
+	assert.Equal(t, uint64(1), pendingItems)
+	assert.Equal(t, common.BatchNum(2), batches[0].BatchNum)
+	assert.Equal(t, common.BatchNum(3), batches[1].BatchNum)
+
+	// Unknown fromItem
+	fromItem = uint(batches[1].ItemID) + 100
+	_, _, err = historyDBWithACC.GetBatchesAPI(GetBatchesAPIRequest{
+		FromItem: &fromItem,
+		Limit:    &limit,
+		Order:    dbUtils.OrderDesc,
+		SortBy:   SortByTotalFeesUSD,
+	})
+	assert.Equal(t, ErrUnknownFromItem, tracerr.Unwrap(err))
+
+	// Invalid sort key
+	_, _, err = historyDBWithACC.GetBatchesAPI(GetBatchesAPIRequest{
+		Limit:  &limit,
+		SortBy: SortByBidValue,
+	})
+	assert.Error(t, err)
+	_, _, err = historyDBWithACC.GetTxsAPI(GetTxsAPIRequest{
+		Limit:  &limit,
+		SortBy: SortByBidValue,
+	})
+	assert.Error(t, err)
+
+	// Block range
+	blockNum := blocks[1].Block.Num
+	limit = 10
+	txs, _, err = historyDBWithACC.GetTxsAPI(GetTxsAPIRequest{
+		FromBlock: &blockNum,
+		ToBlock:   &blockNum,
+		Limit:     &limit,
+		Order:     dbUtils.OrderAsc,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(txs))
+	for _, tx := range txs {
+		assert.Equal(t, blockNum, tx.EthBlockNum)
+	}
+	lastBlockNum := blocks[2].Block.Num
+	batches, _, err = historyDBWithACC.GetBatchesAPI(GetBatchesAPIRequest{
+		FromBlock: &lastBlockNum,
+		Limit:     &limit,
+		Order:     dbUtils.OrderAsc,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(batches))
+	assert.Equal(t, lastBlockNum, batches[0].EthBlockNum)
+}
+
+func TestGetPendingDepositsAPI(t *testing.T) {
+	test.WipeDB(historyDB.DB())
+	set := `
+		Type: Blockchain
+
+		AddToken(1)
+		CreateAccountDeposit(1) A: 100
+		CreateAccountDeposit(1) C: 100
+		> batchL1
+		> batchL1
+		> block
+
+		Deposit(1) A: 50
+		Deposit(1) A: 10
+		CreateAccountDeposit(1) B: 30
+		Deposit(1) C: 20
+		> block
+	`
+	tc := til.NewContext(uint16(0), common.RollupConstMaxL1UserTx)
+	tilCfgExtra := til.ConfigExtra{
+		BootCoordAddr: ethCommon.HexToAddress("0xE39fEc6224708f0772D2A74fd3f9055A90E0A9f2"),
+		CoordUser:     "A",
+	}
+	blocks, err := tc.GenerateBlocks(set)
+	require.NoError(t, err)
+	require.NoError(t, tc.FillBlocksExtra(blocks, &tilCfgExtra))
+	// A sends the deposit to the existing account of C
+	addrA := tc.Users["A"].Addr
+	blocks[1].Rollup.L1UserTxs[3].FromEthAddr = addrA
+	for i := range blocks {
+		require.NoError(t, historyDB.AddBlockSCData(&blocks[i]))
+	}
+
+	// The deposits to the existing account of A are pending, but not the
+	// deposit that A sends to the account of C
+	deposits, pendingItems, err := historyDBWithACC.GetPendingDepositsAPI(&addrA, nil, 10)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(deposits))
+	assert.Equal(t, uint64(0), pendingItems)
+	assert.Equal(t, blocks[1].Rollup.L1UserTxs[0].TxID, deposits[0].TxID)
+	assert.Equal(t, blocks[1].Rollup.L1UserTxs[1].TxID, deposits[1].TxID)
+	assert.Nil(t, deposits[0].BatchNum)
+
+	// The deposits that don't fit in the limit are reported
+	deposits, pendingItems, err = historyDBWithACC.GetPendingDepositsAPI(&addrA, nil, 1)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(deposits))
+	assert.Equal(t, uint64(1), pendingItems)
+	assert.Equal(t, blocks[1].Rollup.L1UserTxs[0].TxID, deposits[0].TxID)
+
+	// The deposit that A sends to the account of C is pending for C
+	addrC := tc.Users["C"].Addr
+	deposits, _, err = historyDBWithACC.GetPendingDepositsAPI(&addrC, nil, 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(deposits))
+	assert.Equal(t, blocks[1].Rollup.L1UserTxs[3].TxID, deposits[0].TxID)
+
+	// The deposit that creates the account of B is pending
+	bjjB := tc.Users["B"].BJJ.Public().Compress()
+	deposits, _, err = historyDBWithACC.GetPendingDepositsAPI(nil, &bjjB, 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(deposits))
+	assert.Equal(t, blocks[1].Rollup.L1UserTxs[2].TxID, deposits[0].TxID)
+
+	_, _, err = historyDBWithACC.GetPendingDepositsAPI(nil, nil, 10)
+	assert.Error(t, err)
+}
+
+func TestGetL1QueuesAPI(t *testing.T) {
+	test.WipeDB(historyDB.DB())
+	set := `
+		Type: Blockchain
+
+		AddToken(1)
+		CreateAccountDeposit(1) A: 100
+		> batchL1
+		> batchL1
+		Deposit(1) A: 50
+		> batchL1
+		CreateAccountDeposit(1) B: 30
+		Deposit(1) B: 10
+		> block
+	`
+	tc := til.NewContext(uint16(0), common.RollupConstMaxL1UserTx)
+	tilCfgExtra := til.ConfigExtra{
+		BootCoordAddr: ethCommon.HexToAddress("0xE39fEc6224708f0772D2A74fd3f9055A90E0A9f2"),
+		CoordUser:     "A",
+	}
+	blocks, err := tc.GenerateBlocks(set)
+	require.NoError(t, err)
+	require.NoError(t, tc.FillBlocksExtra(blocks, &tilCfgExtra))
+	for i := range blocks {
+		require.NoError(t, historyDB.AddBlockSCData(&blocks[i]))
+	}
+
+	queues, err := historyDBWithACC.GetL1QueuesAPI()
+	require.NoError(t, err)
+	require.NotNil(t, queues.LastL1TxsNum)
+	require.NotNil(t, queues.LastL1BatchBlock)
+	assert.Equal(t, blocks[0].Block.Num, *queues.LastL1BatchBlock)
+	// The deposit of A is in the frozen queue, and the txs of B are in the
+	// open one, sorted by position
+	require.Equal(t, 3, len(queues.Txs))
+	frozenL1TxsNum := *queues.LastL1TxsNum + 1
+	assert.Equal(t, frozenL1TxsNum, *queues.Txs[0].ToForgeL1TxsNum)
+	for i, tx := range queues.Txs[1:] {
+		assert.Equal(t, frozenL1TxsNum+1, *tx.ToForgeL1TxsNum)
+		assert.Equal(t, i, tx.Position)
+		assert.Nil(t, tx.BatchNum)
+	}
+}
+
+func TestGetTokenPricesAPI(t *testing.T) {
+	test.WipeDB(historyDB.DB())
+	blocks := setTestBlocks(1, 2)
+	tokens, _ := test.GenTokens(1, blocks)
+	require.NoError(t, historyDB.AddTokens(tokens))
+	token := tokens[0]
+	// Every change of the price of at least 1% is added to the history
+	for _, value := range []float64{1.5, 1.5, 1.505, 2, 3} {
+		require.NoError(t, historyDB.UpdateTokenValue(token.EthAddr, value))
+	}
+	now := time.Now()
+	request := GetTokenPricesAPIRequest{
+		TokenID:       token.TokenID,
+		FromTimestamp: now.Add(-time.Hour),
+		ToTimestamp:   now.Add(time.Hour),
+		Interval:      time.Second,
+	}
+	prices, err := historyDBWithACC.GetTokenPricesAPI(request)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(prices), 1)
+	assert.Equal(t, 3.0, prices[len(prices)-1].USD)
+	// The prices of the same interval are grouped in the last one
+	request.Interval = 2 * time.Hour
+	prices, err = historyDBWithACC.GetTokenPricesAPI(request)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(prices), 1)
+	require.LessOrEqual(t, len(prices), 2)
+	assert.Equal(t, 3.0, prices[len(prices)-1].USD)
+	// Out of the time range
+	request.FromTimestamp = now.Add(time.Hour)
+	request.ToTimestamp = now.Add(2 * time.Hour)
+	prices, err = historyDBWithACC.GetTokenPricesAPI(request)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(prices))
+
+	// Each period has the last price by timestamp, regardless of the order
+	// in which the prices were added
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, price := range []struct {
+		usd     float64
+		minutes time.Duration
+	}{{2, 50}, {1, 10}, {4, 100}, {3, 70}} {
+		_, err := historyDB.dbWrite.Exec(
+			"INSERT INTO token_price_history (token_id, usd, timestamp) VALUES ($1, $2, $3);",
+			token.TokenID, price.usd, from.Add(price.minutes*time.Minute),
+		)
+		require.NoError(t, err)
+	}
+	prices, err = historyDBWithACC.GetTokenPricesAPI(GetTokenPricesAPIRequest{
+		TokenID:       token.TokenID,
+		FromTimestamp: from,
+		ToTimestamp:   from.Add(2 * time.Hour),
+		Interval:      time.Hour,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(prices))
+	assert.Equal(t, from, prices[0].Timestamp.UTC())
+	assert.Equal(t, 2.0, prices[0].USD)
+	assert.Equal(t, from.Add(time.Hour), prices[1].Timestamp.UTC())
+	assert.Equal(t, 4.0, prices[1].USD)
+}
@@ -0,0 +1,109 @@
+package historydb
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/chainbing/node/db"
+	"github.com/chainbing/tracerr"
+)
+
+const (
+	// SortByItemID sorts the items in the order they were inserted, which
+	// is the default
+	SortByItemID = "itemId"
+	// SortByAmount sorts the txs by amount
+	SortByAmount = "amount"
+	// SortByAmountUSD sorts the txs by the USD value of the amount
+	SortByAmountUSD = "amountUSD"
+	// SortByFeeUSD sorts the txs by the USD value of the fee
+	SortByFeeUSD = "feeUSD"
+	// SortByTotalFeesUSD sorts the batches by the USD value of the
+	// collected fees
+	SortByTotalFeesUSD = "totalFeesUSD"
+	// SortByBalance sorts the exits by balance
+	SortByBalance = "balance"
+	// SortByBidValue sorts the bids by value
+	SortByBidValue = "bidValue"
+)
+
+// The sort keys supported by each list, with the expression used to sort.
+// The expressions match the indexes of the migration 0017, and the items
+// without USD value are sorted as 0.
+var (
+	txsSortColumns = map[string]string{
+		SortByAmount:    "tx.amount",
+		SortByAmountUSD: "COALESCE(tx.amount_usd, 0)",
+		SortByFeeUSD:    "COALESCE(tx.fee_usd, 0)",
+	}
+	batchesSortColumns = map[string]string{
+		SortByTotalFeesUSD: "COALESCE(batch.total_fees_usd, 0)",
+	}
+	exitsSortColumns = map[string]string{
+		SortByBalance: "exit_tree.balance",
+	}
+	bidsSortColumns = map[string]string{
+		SortByBidValue: "bid.bid_value",
+	}
+)
+
+// ErrUnknownFromItem is returned when a list is sorted by value and its
+// fromItem doesn't exist, as the page can't be located without its value
+var ErrUnknownFromItem = errors.New("fromItem not found")
+
+// sortColumn returns the expression used to sort by sortBy, or "" when the
+// items are sorted by item_id
+func sortColumn(sortColumns map[string]string, sortBy string) (string, error) {
+	if sortBy == "" || sortBy == SortByItemID {
+		return "", nil
+	}
+	column, ok := sortColumns[sortBy]
+	if !ok {
+		return "", tracerr.Wrap(fmt.Errorf("invalid sort key: %v", sortBy))
+	}
+	return column, nil
+}
+
+// fromItemCondition returns the condition that selects the item fromItem and
+// the ones after it when sorting the table by column.  The item_id breaks the
+// ties, so that the pagination is stable when sorting by value.
+func fromItemCondition(table, column, order string) string {
+	op := "<="
+	if order == db.OrderAsc {
+		op = ">="
+	}
+	if column == "" {
+		return fmt.Sprintf("%s.item_id %s ? ", table, op)
+	}
+	return fmt.Sprintf("(%s, %s.item_id) %s (SELECT %s, %s.item_id FROM %s WHERE %s.item_id = ?) ",
+		column, table, op, column, table, table, table)
+}
+
+// checkFromItem returns ErrUnknownFromItem if the table is sorted by column
+// and the item fromItem doesn't exist
+func (hdb *HistoryDB) checkFromItem(table, column string, fromItem *uint) error {
+	if column == "" || fromItem == nil {
+		return nil
+	}
+	var exists bool
+	if err := hdb.dbRead.QueryRow(
+		fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM %s WHERE item_id = $1);", table), *fromItem,
+	).Scan(&exists); err != nil {
+		return tracerr.Wrap(err)
+	} else if !exists {
+		return tracerr.Wrap(ErrUnknownFromItem)
+	}
+	return nil
+}
+
+// orderByClause returns the ORDER BY clause that sorts the table by column
+func orderByClause(table, column, order string) string {
+	dir := db.OrderDesc
+	if order == db.OrderAsc {
+		dir = db.OrderAsc
+	}
+	if column == "" {
+		return fmt.Sprintf("ORDER BY %s.item_id %s ", table, dir)
+	}
+	return fmt.Sprintf("ORDER BY %s %s, %s.item_id %s ", column, dir, table, dir)
+}
@@ -256,6 +256,23 @@ type TokenWithUSD struct {
 	LastItem    uint64            `json:"-" meddler:"last_item"`
 }
 
+// TokenPriceAPI is the USD price of a token at a point in time
+type TokenPriceAPI struct {
+	Timestamp time.Time `json:"timestamp" meddler:"timestamp,utctime"`
+	USD       float64   `json:"USD" meddler:"usd"`
+}
+
+// L1QueuesAPI are the L1 user txs that are not forged yet, sorted by queue and
+// position, with the last forged L1 batch, from which the queues advance
+type L1QueuesAPI struct {
+	// LastL1TxsNum is the last forged queue, nil if none has been forged
+	LastL1TxsNum *int64
+	// LastL1BatchBlock is the block of the last L1 batch, nil if there is
+	// none
+	LastL1BatchBlock *int64
+	Txs              []TxAPI
+}
+
 // ExitAPI is a representation of a exit with additional information
 // required by the API, and extracted by joining token table
 type ExitAPI struct {
@@ -358,6 +375,20 @@ type AccountAPI struct {
 	TokenUSDUpdate   *time.Time          `meddler:"usd_update"`
 }
 
+// AccountUpdateAPI is a change of the balance or nonce of an account, with
+// the txs of the batch that caused it
+type AccountUpdateAPI struct {
+	ItemID      uint64              `json:"itemId" meddler:"item_id"`
+	Idx         apitypes.CbIdx      `json:"accountIndex" meddler:"idx"`
+	BatchNum    common.BatchNum     `json:"batchNum" meddler:"batch_num"`
+	EthBlockNum int64               `json:"ethereumBlockNum" meddler:"eth_block_num"`
+	Timestamp   time.Time           `json:"timestamp" meddler:"timestamp,utctime"`
+	Nonce       common.Nonce        `json:"nonce" meddler:"nonce"`
+	Balance     *apitypes.BigIntStr `json:"balance" meddler:"balance"`
+	TxIDs       []common.TxID       `json:"transactionIds" meddler:"tx_ids,json"`
+	TotalItems  uint64              `json:"-" meddler:"total_items"`
+}
+
 // MarshalJSON is used to neast some of the fields of AccountAPI
 // without the need of auxiliar structs
 func (account AccountAPI) MarshalJSON() ([]byte, error) {
@@ -434,6 +465,14 @@ type BidAPI struct {
 	LastItem    uint64             `json:"-" meddler:"last_item"`
 }
 
+// BidWithTimestamp is a bid with the timestamp of the block in which it was
+// placed
+type BidWithTimestamp struct {
+	SlotNum   int64     `meddler:"slot_num"`
+	BidValue  *big.Int  `meddler:"bid_value,bigint"`
+	Timestamp time.Time `meddler:"timestamp,utctime"`
+}
+
 // MinBidInfo gives information of the minum bid for specific slot(s)
 type MinBidInfo struct {
 	DefaultSlotSetBid        [6]*big.Int `json:"defaultSlotSetBid" meddler:"default_slot_set_bid,json" validate:"required"`
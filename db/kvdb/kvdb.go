@@ -27,6 +27,9 @@ const (
 	// PathLast defines the subpath of the last Batch in the subpath
 	// of the StateDB
 	PathLast = "last"
+	// PathCheckpointRead defines the prefix of the temporary subpaths
+	// where a Batch Checkpoint is copied in order to be read
+	PathCheckpointRead = "read"
 	// DefaultKeep is the default value for the Keep parameter
 	DefaultKeep = 128
 )
@@ -555,6 +558,31 @@ func (k *KVDB) MakeCheckpointFromTo(fromBatchNum common.BatchNum, dest string) e
 	return PebbleMakeCheckpoint(source, dest)
 }
 
+// CheckpointRead is a thread-safe method to query the KVDB at the checkpoint
+// of the given batchNum.  The checkpoint is copied to a temporary path that
+// is removed once fn returns, so that it can be read without interfering with
+// the deletion of old checkpoints.
+func (k *KVDB) CheckpointRead(batchNum common.BatchNum, fn func(db *pebble.Storage) error) error {
+	dest, err := ioutil.TempDir(k.cfg.Path, fmt.Sprintf("%s%d-", PathCheckpointRead, batchNum))
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dest); err != nil {
+			log.Errorw("remove checkpoint read copy failed", "path", dest, "err", err)
+		}
+	}()
+	if err := k.MakeCheckpointFromTo(batchNum, dest); err != nil {
+		return tracerr.Wrap(err)
+	}
+	sto, err := pebble.NewPebbleStorage(dest, false)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer sto.Close()
+	return fn(sto)
+}
+
 // PebbleMakeCheckpoint is a hepler function to make a pebble checkpoint from
 // source to dest.
 func PebbleMakeCheckpoint(source, dest string) error {
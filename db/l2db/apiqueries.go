@@ -1,6 +1,8 @@
 package l2db
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"math/big"
 
@@ -16,6 +18,18 @@ import (
 
 var (
 	errPoolFull = fmt.Errorf("the pool is at full capacity. More transactions are not accepted currently")
+	// ErrReplaceAtomicTx is returned when trying to replace a tx that
+	// belongs to an atomic group
+	ErrReplaceAtomicTx = errors.New("txs that belong to an atomic group can't be replaced")
+	// ErrReplaceFeeTooLow is returned when the fee of the replacement tx is
+	// not high enough to replace the pending tx
+	ErrReplaceFeeTooLow = errors.New("the fee of the replacement tx is too low")
+	// ErrCancelNotPending is returned when trying to cancel a tx that is
+	// not pending anymore
+	ErrCancelNotPending = errors.New("only pending txs can be cancelled")
+	// ErrMaxWebhooks is returned when registering a webhook with an API
+	// key that has already registered the maximum number of webhooks
+	ErrMaxWebhooks = errors.New("the API key has reached the maximum number of webhooks")
 )
 
 // AddAccountCreationAuthAPI inserts an account creation authorization into the DB
@@ -53,7 +67,17 @@ func (l2db *L2DB) AddTxAPI(tx *common.PoolL2Tx) error {
 		return tracerr.Wrap(err)
 	}
 	defer l2db.apiConnCon.Release()
-	// Check fee is in range
+	if err := l2db.checkFeeUSD(tx); err != nil {
+		return tracerr.Wrap(err)
+	}
+	// Add tx if pool is not full
+	return tracerr.Wrap(
+		l2db.addTxs(l2db.dbWrite, []common.PoolL2Tx{*tx}, true),
+	)
+}
+
+// checkFeeUSD checks that the fee in USD of the tx is in the accepted range
+func (l2db *L2DB) checkFeeUSD(tx *common.PoolL2Tx) error {
 	row := l2db.dbRead.QueryRow(`SELECT
 		($1::NUMERIC * COALESCE(token.usd, 0) * fee_percentage($2::NUMERIC)) /
 			(10.0 ^ token.decimals::NUMERIC)
@@ -71,10 +95,148 @@ func (l2db *L2DB) AddTxAPI(tx *common.PoolL2Tx) error {
 		return tracerr.Wrap(fmt.Errorf("tx.feeUSD (%v) > maxFeeUSD (%v)",
 			feeUSD, l2db.maxFeeUSD))
 	}
-	// Add tx if pool is not full
-	return tracerr.Wrap(
-		l2db.addTxs([]common.PoolL2Tx{*tx}, true),
-	)
+	return nil
+}
+
+// ReplaceTxAPI replaces the pending tx that has the same FromIdx and Nonce as
+// the given tx by it, and returns the TxID of the replaced tx.  The replacement
+// is only accepted if the replaced tx doesn't belong to an atomic group, and
+// if the fee of the new tx is greater than the fee of the replaced tx by at
+// least minFeeBumpPerc.  The TxID of the replaced tx is stored with the new tx.
+func (l2db *L2DB) ReplaceTxAPI(tx *common.PoolL2Tx) (replacedTxID common.TxID, err error) {
+	cancel, err := l2db.apiConnCon.Acquire()
+	defer cancel()
+	if err != nil {
+		return common.TxID{}, tracerr.Wrap(err)
+	}
+	defer l2db.apiConnCon.Release()
+	if err := l2db.checkFeeUSD(tx); err != nil {
+		return common.TxID{}, tracerr.Wrap(err)
+	}
+
+	txn, err := l2db.dbWrite.Beginx()
+	if err != nil {
+		return common.TxID{}, tracerr.Wrap(err)
+	}
+	defer func() {
+		if err != nil {
+			db.Rollback(txn)
+		}
+	}()
+	// Get the tx to be replaced, locking it until the end of the
+	// transaction so that it can't start forging in the meantime
+	type replacedTx struct {
+		TxID          common.TxID           `meddler:"tx_id"`
+		Amount        *big.Int              `meddler:"amount,bigint"`
+		Fee           common.FeeSelector    `meddler:"fee"`
+		AtomicGroupID *common.AtomicGroupID `meddler:"atomic_group_id"`
+	}
+	oldTx := new(replacedTx)
+	if err = meddler.QueryRow(
+		txn, oldTx,
+		`SELECT tx_id, amount, fee, atomic_group_id FROM tx_pool
+		WHERE from_idx = $1 AND nonce = $2 AND state = $3 AND NOT external_delete
+		FOR UPDATE;`,
+		tx.FromIdx, tx.Nonce, common.PoolL2TxStatePending,
+	); err != nil {
+		return common.TxID{}, tracerr.Wrap(err)
+	}
+	if oldTx.AtomicGroupID != nil {
+		err = ErrReplaceAtomicTx
+		return common.TxID{}, tracerr.Wrap(err)
+	}
+	// Check that the fee is increased by at least minFeeBumpPerc
+	oldFee, err := common.CalcFeeAmount(oldTx.Amount, oldTx.Fee)
+	if err != nil {
+		return common.TxID{}, tracerr.Wrap(err)
+	}
+	newFee, err := common.CalcFeeAmount(tx.Amount, tx.Fee)
+	if err != nil {
+		return common.TxID{}, tracerr.Wrap(err)
+	}
+	minFee := new(big.Float).Mul(new(big.Float).SetInt(oldFee),
+		big.NewFloat(1+l2db.minFeeBumpPerc/100)) //nolint:gomnd
+	if newFee.Cmp(oldFee) <= 0 || new(big.Float).SetInt(newFee).Cmp(minFee) < 0 {
+		err = fmt.Errorf("%w: fee (%s) must be greater than %s (pending tx fee "+
+			"(%s) + %v%%)", ErrReplaceFeeTooLow, newFee, minFee.Text('f', 0), oldFee,
+			l2db.minFeeBumpPerc)
+		return common.TxID{}, tracerr.Wrap(err)
+	}
+	// Swap the txs
+	if _, err = txn.Exec("DELETE FROM tx_pool WHERE tx_id = $1;", oldTx.TxID); err != nil {
+		return common.TxID{}, tracerr.Wrap(err)
+	}
+	if err = l2db.addTxs(txn, []common.PoolL2Tx{*tx}, false); err != nil {
+		return common.TxID{}, tracerr.Wrap(err)
+	}
+	if _, err = txn.Exec("UPDATE tx_pool SET replaced_tx_id = $1 WHERE tx_id = $2;",
+		oldTx.TxID, tx.TxID); err != nil {
+		return common.TxID{}, tracerr.Wrap(err)
+	}
+	if err = txn.Commit(); err != nil {
+		return common.TxID{}, tracerr.Wrap(err)
+	}
+	return oldTx.TxID, nil
+}
+
+// CancelTxAPI sets the state of a pending tx to cancelled, so that it's never
+// selected to be forged.  If the tx belongs to an atomic group, the whole
+// group is cancelled.  It returns the TxIDs of the cancelled txs.
+func (l2db *L2DB) CancelTxAPI(txID common.TxID) (cancelledTxIDs []common.TxID, err error) {
+	cancel, err := l2db.apiConnCon.Acquire()
+	defer cancel()
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	defer l2db.apiConnCon.Release()
+
+	txn, err := l2db.dbWrite.Beginx()
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	defer func() {
+		if err != nil {
+			db.Rollback(txn)
+		}
+	}()
+	// Get the tx to be cancelled, locking it until the end of the
+	// transaction so that it can't start forging in the meantime
+	type cancelledTx struct {
+		State         common.PoolL2TxState  `meddler:"state"`
+		AtomicGroupID *common.AtomicGroupID `meddler:"atomic_group_id"`
+	}
+	tx := new(cancelledTx)
+	if err = meddler.QueryRow(
+		txn, tx,
+		`SELECT state, atomic_group_id FROM tx_pool
+		WHERE tx_id = $1 AND NOT external_delete FOR UPDATE;`,
+		txID,
+	); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	if tx.State != common.PoolL2TxStatePending {
+		err = ErrCancelNotPending
+		return nil, tracerr.Wrap(err)
+	}
+	if tx.AtomicGroupID != nil {
+		err = txn.Select(&cancelledTxIDs,
+			`UPDATE tx_pool SET state = $1 WHERE atomic_group_id = $2 AND state = $3
+			RETURNING tx_id;`,
+			common.PoolL2TxStateCancelled, tx.AtomicGroupID, common.PoolL2TxStatePending,
+		)
+	} else {
+		err = txn.Select(&cancelledTxIDs,
+			"UPDATE tx_pool SET state = $1 WHERE tx_id = $2 RETURNING tx_id;",
+			common.PoolL2TxStateCancelled, txID,
+		)
+	}
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	if err = txn.Commit(); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	return cancelledTxIDs, nil
 }
 
 // UpdateTxAPI Update PoolL2Tx regular transactions in the pool.
@@ -158,14 +320,14 @@ func (l2db *L2DB) AddAtomicTxsAPI(txs []common.PoolL2Tx) error {
 	}
 
 	// Insert txs if the pool is not full
-	return tracerr.Wrap(l2db.addTxs(txs, true))
+	return tracerr.Wrap(l2db.addTxs(l2db.dbWrite, txs, true))
 }
 
 // selectPoolTxAPI select part of queries to get PoolL2TxRead
 const selectPoolTxAPI = `SELECT tx_pool.item_id, tx_pool.tx_id, cb_idx(tx_pool.from_idx, token.symbol) AS from_idx, tx_pool.effective_from_eth_addr,
 tx_pool.effective_from_bjj, cb_idx(tx_pool.to_idx, token.symbol) AS to_idx, tx_pool.effective_to_eth_addr,
 tx_pool.effective_to_bjj, tx_pool.token_id, tx_pool.amount, tx_pool.fee, tx_pool.nonce,
-tx_pool.state, tx_pool.info, tx_pool.error_code, tx_pool.error_type, tx_pool.signature, tx_pool.timestamp, tx_pool.batch_num, cb_idx(tx_pool.rq_from_idx, token.symbol) AS rq_from_idx,
+tx_pool.state, tx_pool.info, tx_pool.error_code, tx_pool.error_type, tx_pool.error_details, tx_pool.signature, tx_pool.timestamp, tx_pool.batch_num, cb_idx(tx_pool.rq_from_idx, token.symbol) AS rq_from_idx,
 cb_idx(tx_pool.rq_to_idx, token.symbol) AS rq_to_idx, tx_pool.rq_to_eth_addr, tx_pool.rq_to_bjj, tx_pool.rq_token_id, tx_pool.rq_amount,
 tx_pool.rq_fee, tx_pool.rq_nonce, tx_pool.tx_type, tx_pool.max_num_batch,
 token.item_id AS token_item_id, token.eth_block_num, token.eth_addr, token.name, token.symbol, token.decimals, token.usd, token.usd_update
@@ -175,7 +337,7 @@ FROM tx_pool INNER JOIN token ON tx_pool.token_id = token.token_id `
 const selectPoolTxsAPI = `SELECT tx_pool.item_id, tx_pool.tx_id, cb_idx(tx_pool.from_idx, token.symbol) AS from_idx, tx_pool.effective_from_eth_addr,
 tx_pool.effective_from_bjj, cb_idx(tx_pool.to_idx, token.symbol) AS to_idx, tx_pool.effective_to_eth_addr,
 tx_pool.effective_to_bjj, tx_pool.token_id, tx_pool.amount, tx_pool.fee, tx_pool.nonce,
-tx_pool.state, tx_pool.info, tx_pool.error_code, tx_pool.error_type, tx_pool.signature, tx_pool.timestamp, tx_pool.batch_num, cb_idx(tx_pool.rq_from_idx, token.symbol) AS rq_from_idx,
+tx_pool.state, tx_pool.info, tx_pool.error_code, tx_pool.error_type, tx_pool.error_details, tx_pool.signature, tx_pool.timestamp, tx_pool.batch_num, cb_idx(tx_pool.rq_from_idx, token.symbol) AS rq_from_idx,
 cb_idx(tx_pool.rq_to_idx, token.symbol) AS rq_to_idx, tx_pool.rq_to_eth_addr, tx_pool.rq_to_bjj, tx_pool.rq_token_id, tx_pool.rq_amount,
 tx_pool.rq_fee, tx_pool.rq_nonce, tx_pool.tx_type, tx_pool.max_num_batch,
 token.item_id AS token_item_id, token.eth_block_num, token.eth_addr, token.name, token.symbol, token.decimals, token.usd, token.usd_update,
@@ -199,6 +361,27 @@ func (l2db *L2DB) GetTxAPI(txID common.TxID) (apitypes.TxL2, error) {
 	return tx.ToAPI(), err
 }
 
+// GetTxStateChangesAPI returns the changes of the state of a pool tx, from the
+// oldest
+func (l2db *L2DB) GetTxStateChangesAPI(txID common.TxID) ([]PoolTxStateChangeAPI, error) {
+	cancel, err := l2db.apiConnCon.Acquire()
+	defer cancel()
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	defer l2db.apiConnCon.Release()
+	changes := []*PoolTxStateChangeAPI{}
+	if err := meddler.QueryAll(
+		l2db.dbRead, &changes,
+		`SELECT state, info, batch_num, timestamp FROM tx_pool_state_change
+		WHERE tx_id = $1 ORDER BY item_id;`,
+		txID,
+	); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	return db.SlicePtrsToSlice(changes).([]PoolTxStateChangeAPI), nil
+}
+
 // GetPoolTxsAPIRequest is an API request struct for getting txs from the pool
 type GetPoolTxsAPIRequest struct {
 	EthAddr     *ethCommon.Address
@@ -417,3 +600,146 @@ func (l2db *L2DB) GetPoolTxsByAtomicGroupIDAPI(atomicGroupID common.AtomicGroupI
 	}
 	return retTxs, nil
 }
+
+// AddWebhookAPI registers a webhook, setting its ItemID and Timestamp.
+// ErrMaxWebhooks is returned if the API key of the webhook has already
+// registered maxWebhooks webhooks.
+func (l2db *L2DB) AddWebhookAPI(webhook *Webhook, maxWebhooks int) (err error) {
+	cancel, err := l2db.apiConnCon.Acquire()
+	defer cancel()
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer l2db.apiConnCon.Release()
+
+	txn, err := l2db.dbWrite.Beginx()
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer func() {
+		if err != nil {
+			db.Rollback(txn)
+		}
+	}()
+	// Lock the API key until the end of the transaction, so that
+	// concurrent registrations can't exceed the limit
+	if _, err = txn.Exec("SELECT item_id FROM api_key WHERE item_id = $1 FOR UPDATE;",
+		webhook.APIKeyID); err != nil {
+		return tracerr.Wrap(err)
+	}
+	var numWebhooks int
+	if err = txn.QueryRow("SELECT COUNT(*) FROM webhook WHERE api_key_id = $1;",
+		webhook.APIKeyID).Scan(&numWebhooks); err != nil {
+		return tracerr.Wrap(err)
+	}
+	if numWebhooks >= maxWebhooks {
+		err = ErrMaxWebhooks
+		return tracerr.Wrap(err)
+	}
+	if err = txn.QueryRow(
+		`INSERT INTO webhook (url, secret, eth_addr, bjj, idx, token_id, api_key_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING item_id, timestamp;`,
+		webhook.URL, webhook.Secret, webhook.EthAddr, webhook.BJJ, webhook.Idx, webhook.TokenID,
+		webhook.APIKeyID,
+	).Scan(&webhook.ItemID, &webhook.Timestamp); err != nil {
+		return tracerr.Wrap(err)
+	}
+	return tracerr.Wrap(txn.Commit())
+}
+
+// DeleteWebhookAPI removes a webhook and its deliveries.  The secret of the
+// webhook is required, sql.ErrNoRows is returned if it doesn't match.
+func (l2db *L2DB) DeleteWebhookAPI(itemID uint64, secret string) error {
+	cancel, err := l2db.apiConnCon.Acquire()
+	defer cancel()
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer l2db.apiConnCon.Release()
+	res, err := l2db.dbWrite.Exec(
+		"DELETE FROM webhook WHERE item_id = $1 AND secret = $2;",
+		itemID, secret,
+	)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return tracerr.Wrap(err)
+	} else if n == 0 {
+		return tracerr.Wrap(sql.ErrNoRows)
+	}
+	return nil
+}
+
+// GetWebhookDeliveriesAPIRequest is an API request struct for getting the
+// delivery log of a webhook
+type GetWebhookDeliveriesAPIRequest struct {
+	WebhookID uint64
+	Secret    string
+	State     *WebhookDeliveryState
+
+	FromItem *uint
+	Limit    *uint
+	Order    string
+}
+
+// GetWebhookDeliveriesAPI returns the deliveries of a webhook.  The secret of
+// the webhook is required, sql.ErrNoRows is returned if it doesn't match.
+func (l2db *L2DB) GetWebhookDeliveriesAPI(
+	request GetWebhookDeliveriesAPIRequest,
+) ([]WebhookDeliveryAPI, uint64, error) {
+	cancel, err := l2db.apiConnCon.Acquire()
+	defer cancel()
+	if err != nil {
+		return nil, 0, tracerr.Wrap(err)
+	}
+	defer l2db.apiConnCon.Release()
+	var webhookID uint64
+	if err := l2db.dbRead.QueryRow(
+		"SELECT item_id FROM webhook WHERE item_id = $1 AND secret = $2;",
+		request.WebhookID, request.Secret,
+	).Scan(&webhookID); err != nil {
+		return nil, 0, tracerr.Wrap(err)
+	}
+	queryStr := `SELECT webhook_delivery.item_id, webhook_delivery.event_type,
+	webhook_delivery.payload, webhook_delivery.state, webhook_delivery.attempts,
+	webhook_delivery.next_attempt, webhook_delivery.status_code, webhook_delivery.info,
+	webhook_delivery.timestamp, count(*) OVER() AS total_items
+	FROM webhook_delivery WHERE webhook_delivery.webhook_id = ? `
+	args := []interface{}{webhookID}
+	// state filter
+	if request.State != nil {
+		queryStr += "AND webhook_delivery.state = ? "
+		args = append(args, request.State)
+	}
+	if request.FromItem != nil {
+		if request.Order == db.OrderAsc {
+			queryStr += "AND webhook_delivery.item_id >= ? "
+		} else {
+			queryStr += "AND webhook_delivery.item_id <= ? "
+		}
+		args = append(args, request.FromItem)
+	}
+	// pagination
+	queryStr += "ORDER BY webhook_delivery.item_id "
+	if request.Order == db.OrderAsc {
+		queryStr += "ASC "
+	} else {
+		queryStr += "DESC "
+	}
+	queryStr += fmt.Sprintf("LIMIT %d;", *request.Limit)
+
+	query := l2db.dbRead.Rebind(queryStr)
+	deliveriesPtrs := []*WebhookDeliveryAPI{}
+	if err = meddler.QueryAll(
+		l2db.dbRead, &deliveriesPtrs,
+		query,
+		args...); err != nil {
+		return nil, 0, tracerr.Wrap(err)
+	}
+	deliveries := db.SlicePtrsToSlice(deliveriesPtrs).([]WebhookDeliveryAPI)
+	if len(deliveries) == 0 {
+		return deliveries, 0, nil
+	}
+	return deliveries, deliveries[0].TotalItems - uint64(len(deliveries)), nil
+}
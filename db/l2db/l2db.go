@@ -18,6 +18,7 @@ In some cases, some of the structs defined in this file also include custom Mars
 package l2db
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
@@ -48,30 +49,36 @@ type L2DB struct {
 	maxTxs       uint32 // limit of txs that are accepted in the pool
 	minFeeUSD    float64
 	maxFeeUSD    float64
-	apiConnCon   *db.APIConnectionController
+	// minFeeBumpPerc is the minimum increase of the fee, in percentage,
+	// required to replace a pending tx
+	minFeeBumpPerc float64
+	apiConnCon     *db.APIConnectionController
 }
 
 // NewL2DB creates a L2DB.
 // To create it, it's needed db connection, safety period expressed in batches,
-// maxTxs that the DB should have and TTL (time to live) for pending txs.
+// maxTxs that the DB should have, TTL (time to live) for pending txs and the
+// minimum fee increase (in percentage) required to replace a pending tx.
 func NewL2DB(
 	dbRead, dbWrite *sqlx.DB,
 	safetyPeriod common.BatchNum,
 	maxTxs uint32,
 	minFeeUSD float64,
 	maxFeeUSD float64,
+	minFeeBumpPerc float64,
 	TTL time.Duration,
 	apiConnCon *db.APIConnectionController,
 ) *L2DB {
 	return &L2DB{
-		dbRead:       dbRead,
-		dbWrite:      dbWrite,
-		safetyPeriod: safetyPeriod,
-		ttl:          TTL,
-		maxTxs:       maxTxs,
-		minFeeUSD:    minFeeUSD,
-		maxFeeUSD:    maxFeeUSD,
-		apiConnCon:   apiConnCon,
+		dbRead:         dbRead,
+		dbWrite:        dbWrite,
+		safetyPeriod:   safetyPeriod,
+		ttl:            TTL,
+		maxTxs:         maxTxs,
+		minFeeUSD:      minFeeUSD,
+		maxFeeUSD:      maxFeeUSD,
+		minFeeBumpPerc: minFeeBumpPerc,
+		apiConnCon:     apiConnCon,
 	}
 }
 
@@ -123,30 +130,33 @@ func (l2db *L2DB) UpdateTxsInfo(txs []common.PoolL2Tx, batchNum common.BatchNum)
 		return nil
 	}
 	type txUpdate struct {
-		ID        common.TxID `db:"id"`
-		Info      string      `db:"info"`
-		ErrorCode int         `db:"error_code"`
-		ErrorType string      `db:"error_type"`
+		ID           common.TxID                    `db:"id"`
+		Info         string                         `db:"info"`
+		ErrorCode    int                            `db:"error_code"`
+		ErrorType    string                         `db:"error_type"`
+		ErrorDetails *common.TxSelectorErrorDetails `db:"error_details"`
 	}
 	txUpdates := make([]txUpdate, len(txs))
 	batchN := strconv.FormatInt(int64(batchNum), 10)
 	for i := range txs {
 		txUpdates[i] = txUpdate{
-			ID:        txs[i].TxID,
-			Info:      "BatchNum: " + batchN + ". " + txs[i].Info,
-			ErrorCode: txs[i].ErrorCode,
-			ErrorType: txs[i].ErrorType,
+			ID:           txs[i].TxID,
+			Info:         "BatchNum: " + batchN + ". " + txs[i].Info,
+			ErrorCode:    txs[i].ErrorCode,
+			ErrorType:    txs[i].ErrorType,
+			ErrorDetails: txs[i].ErrorDetails,
 		}
 	}
 	const query string = `
 		UPDATE tx_pool SET
 			info = tx_update.info,
 			error_code = tx_update.error_code,
-			error_type = tx_update.error_type
+			error_type = tx_update.error_type,
+			error_details = tx_update.error_details
 		FROM (VALUES
-			(NULL::::BYTEA, NULL::::VARCHAR, NULL::::NUMERIC, NULL::::VARCHAR),
-			(:id, :info, :error_code, :error_type)
-		) as tx_update (id, info, error_code, error_type)
+			(NULL::::BYTEA, NULL::::VARCHAR, NULL::::NUMERIC, NULL::::VARCHAR, NULL::::JSONB),
+			(:id, :info, :error_code, :error_type, :error_details)
+		) as tx_update (id, info, error_code, error_type, error_details)
 		WHERE tx_pool.tx_id = tx_update.id;
 	`
 	if len(txUpdates) > 0 {
@@ -162,13 +172,13 @@ func (l2db *L2DB) UpdateTxsInfo(txs []common.PoolL2Tx, batchNum common.BatchNum)
 func (l2db *L2DB) AddTxTest(tx *common.PoolL2Tx) error {
 	// Add tx without checking if pool is full
 	return tracerr.Wrap(
-		l2db.addTxs([]common.PoolL2Tx{*tx}, false),
+		l2db.addTxs(l2db.dbWrite, []common.PoolL2Tx{*tx}, false),
 	)
 }
 
 // Insert PoolL2Tx transactions into the pool. If checkPoolIsFull is set to true the insert will
 // fail if the pool is fool and errPoolFull will be returned
-func (l2db *L2DB) addTxs(txs []common.PoolL2Tx, checkPoolIsFull bool) error {
+func (l2db *L2DB) addTxs(d meddler.DB, txs []common.PoolL2Tx, checkPoolIsFull bool) error {
 	// Set the columns that will be affected by the insert on the table
 	const queryInsertPart = `INSERT INTO tx_pool (
 		tx_id, from_idx, to_idx, to_eth_addr, to_bjj, token_id,
@@ -280,7 +290,7 @@ func (l2db *L2DB) addTxs(txs []common.PoolL2Tx, checkPoolIsFull bool) error {
 	// Replace "?, ?, ... ?" ==> "$1, $2, ..., $(len(queryVars))"
 	query = l2db.dbRead.Rebind(query)
 	// Execute query
-	res, err := l2db.dbWrite.Exec(query, queryVars...)
+	res, err := d.Exec(query, queryVars...)
 	if err == nil && checkPoolIsFull {
 		if rowsAffected, err := res.RowsAffected(); err != nil || rowsAffected == 0 {
 			// If the query didn't affect any row, and there is no error in the query
@@ -481,14 +491,14 @@ func (l2db *L2DB) Reorg(lastValidBatch common.BatchNum) error {
 }
 
 // Purge deletes transactions that have been forged or marked as invalid for longer than the safety period
-// it also deletes pending txs that have been in the L2DB for longer than the ttl if maxTxs has been exceeded
+// it also deletes pending and cancelled txs that have been in the L2DB for longer than the ttl if maxTxs has been exceeded
 func (l2db *L2DB) Purge(currentBatchNum common.BatchNum) (err error) {
 	now := time.Now().UTC().Unix()
 	_, err = l2db.dbWrite.Exec(
 		`DELETE FROM tx_pool WHERE (
 			batch_num < $1 AND (state = $2 OR state = $3)
 		) OR (
-			state = $4 AND timestamp < $5
+			(state = $4 OR state = $5) AND timestamp < $6
 		) OR (
 			max_num_batch < $1
 		);`,
@@ -496,6 +506,7 @@ func (l2db *L2DB) Purge(currentBatchNum common.BatchNum) (err error) {
 		common.PoolL2TxStateForged,
 		common.PoolL2TxStateInvalid,
 		common.PoolL2TxStatePending,
+		common.PoolL2TxStateCancelled,
 		time.Unix(now-int64(l2db.ttl.Seconds()), 0),
 	)
 	return tracerr.Wrap(err)
@@ -511,3 +522,97 @@ func (l2db *L2DB) PurgeByExternalDelete() error {
 	)
 	return tracerr.Wrap(err)
 }
+
+// GetPendingWebhookDeliveries returns up to limit pending webhook deliveries
+// whose next attempt is due.  The next attempt of the returned deliveries is
+// postponed by lease, so that they are not returned again while they are
+// being delivered.
+func (l2db *L2DB) GetPendingWebhookDeliveries(limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	now := time.Now().UTC()
+	var deliveries []*WebhookDelivery
+	err := meddler.QueryAll(
+		l2db.dbWrite, &deliveries,
+		`UPDATE webhook_delivery SET next_attempt = $1
+		FROM webhook
+		WHERE webhook_delivery.webhook_id = webhook.item_id AND webhook_delivery.item_id IN (
+			SELECT item_id FROM webhook_delivery
+			WHERE state = $2 AND next_attempt <= $3
+			ORDER BY next_attempt ASC LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		) RETURNING webhook_delivery.item_id, webhook_delivery.webhook_id, webhook.url,
+		webhook.secret, webhook_delivery.event_type, webhook_delivery.payload,
+		webhook_delivery.attempts, webhook_delivery.timestamp;`,
+		now.Add(lease), WebhookDeliveryStatePending, now, limit,
+	)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	return db.SlicePtrsToSlice(deliveries).([]WebhookDelivery), nil
+}
+
+// UpdateWebhookDelivery stores the result of a delivery attempt, increasing
+// the number of attempts.  statusCode is nil if the request failed before
+// receiving a response.
+func (l2db *L2DB) UpdateWebhookDelivery(itemID uint64, state WebhookDeliveryState,
+	statusCode *int, info string, nextAttempt time.Time) error {
+	_, err := l2db.dbWrite.Exec(
+		`UPDATE webhook_delivery SET state = $1, status_code = $2, info = NULLIF($3, ''),
+		next_attempt = $4, attempts = attempts + 1
+		WHERE item_id = $5;`,
+		state, statusCode, info, nextAttempt.UTC(), itemID,
+	)
+	return tracerr.Wrap(err)
+}
+
+// PurgeWebhookDeliveries deletes the deliveries that are done or failed and
+// were created before the given time
+func (l2db *L2DB) PurgeWebhookDeliveries(before time.Time) error {
+	_, err := l2db.dbWrite.Exec(
+		`DELETE FROM webhook_delivery WHERE state <> $1 AND timestamp < $2;`,
+		WebhookDeliveryStatePending, before.UTC(),
+	)
+	return tracerr.Wrap(err)
+}
+
+// StoreTxManagerBatch stores a batch sent by the TxManager, replacing the
+// previous version of the same batch
+func (l2db *L2DB) StoreTxManagerBatch(batch *TxManagerBatch) error {
+	ethTxs, err := json.Marshal(batch.EthTxs)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	_, err = l2db.dbWrite.Exec(
+		`INSERT INTO tx_manager_batch (batch_num, l1_batch, nonce, status, resend_num,
+		eth_txs, send_timestamp, send_block_num)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (batch_num) DO UPDATE SET l1_batch = $2, nonce = $3, status = $4,
+		resend_num = $5, eth_txs = $6, send_timestamp = $7, send_block_num = $8;`,
+		batch.BatchNum, batch.L1Batch, batch.Nonce, batch.Status, batch.ResendNum,
+		ethTxs, batch.SendTimestamp.UTC(), batch.SendBlockNum,
+	)
+	return tracerr.Wrap(err)
+}
+
+// GetTxManagerBatches returns the batches sent by the TxManager that haven't
+// been confirmed, sorted by BatchNum
+func (l2db *L2DB) GetTxManagerBatches() ([]TxManagerBatch, error) {
+	var batches []*TxManagerBatch
+	err := meddler.QueryAll(
+		l2db.dbRead, &batches,
+		`SELECT * FROM tx_manager_batch ORDER BY batch_num;`,
+	)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	return db.SlicePtrsToSlice(batches).([]TxManagerBatch), nil
+}
+
+// RemoveTxManagerBatch removes a batch sent by the TxManager once it's
+// confirmed or discarded
+func (l2db *L2DB) RemoveTxManagerBatch(batchNum common.BatchNum) error {
+	_, err := l2db.dbWrite.Exec(
+		`DELETE FROM tx_manager_batch WHERE batch_num = $1;`,
+		batchNum,
+	)
+	return tracerr.Wrap(err)
+}
@@ -2,6 +2,7 @@ package l2db
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"math/big"
 	"os"
@@ -9,6 +10,8 @@ import (
 	"time"
 
 	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/chainbing/node/common"
 	dbUtils "github.com/chainbing/node/db"
 	"github.com/chainbing/node/db/historydb"
@@ -37,9 +40,9 @@ func TestMain(m *testing.M) {
 	if err != nil {
 		panic(err)
 	}
-	l2DB = NewL2DB(db, db, 10, 1000, 0.0, 1000.0, 24*time.Hour, nil)
+	l2DB = NewL2DB(db, db, 10, 1000, 0.0, 1000.0, 10.0, 24*time.Hour, nil)
 	apiConnCon := dbUtils.NewAPIConnectionController(1, time.Second)
-	l2DBWithACC = NewL2DB(db, db, 10, 1000, 0.0, 1000.0, 24*time.Hour, apiConnCon)
+	l2DBWithACC = NewL2DB(db, db, 10, 1000, 0.0, 1000.0, 10.0, 24*time.Hour, apiConnCon)
 	test.WipeDB(l2DB.DB())
 	historyDB = historydb.NewHistoryDB(db, db, nil)
 	// Run tests
@@ -231,6 +234,189 @@ func TestAddTxAPI(t *testing.T) {
 	l2DBWithACC.minFeeUSD = oldMinFeeUSD
 }
 
+func TestReplaceTxAPI(t *testing.T) {
+	err := prepareHistoryDB(historyDB)
+	if err != nil {
+		log.Error("Error prepare historyDB", err)
+	}
+	poolL2Txs, err := generatePoolL2Txs()
+	require.NoError(t, err)
+	// add a regular tx and an atomic tx
+	pendingTx := poolL2Txs[0]
+	require.NoError(t, l2DBWithACC.AddTxAPI(&pendingTx))
+	atomicTx := poolL2Txs[1]
+	atomicTx.RqFromIdx = atomicTx.FromIdx
+	atomicTx.RqOffset = 1
+	atomicTx.AtomicGroupID[0] = 1
+	require.NoError(t, l2DBWithACC.AddTxAPI(&atomicTx))
+
+	// the fee must be increased at least by minFeeBumpPerc
+	tx := pendingTx
+	tx.Amount = new(big.Int).Add(tx.Amount, big.NewInt(1))
+	require.NoError(t, tx.SetID())
+	_, err = l2DBWithACC.ReplaceTxAPI(&tx)
+	require.Error(t, err)
+	assert.True(t, errors.Is(tracerr.Unwrap(err), ErrReplaceFeeTooLow))
+
+	// txs that belong to an atomic group can't be replaced
+	tx = atomicTx
+	tx.Fee = 192 // 100%
+	require.NoError(t, tx.SetID())
+	_, err = l2DBWithACC.ReplaceTxAPI(&tx)
+	assert.Equal(t, ErrReplaceAtomicTx, tracerr.Unwrap(err))
+
+	// there is no pending tx to replace
+	tx = poolL2Txs[2]
+	tx.Fee = 192 // 100%
+	require.NoError(t, tx.SetID())
+	_, err = l2DBWithACC.ReplaceTxAPI(&tx)
+	assert.Equal(t, sql.ErrNoRows, tracerr.Unwrap(err))
+
+	// valid replacement
+	tx = pendingTx
+	tx.Fee = 192 // 100%
+	require.NoError(t, tx.SetID())
+	replacedTxID, err := l2DBWithACC.ReplaceTxAPI(&tx)
+	require.NoError(t, err)
+	assert.Equal(t, pendingTx.TxID, replacedTxID)
+	_, err = l2DB.GetTx(pendingTx.TxID)
+	assert.Equal(t, sql.ErrNoRows, tracerr.Unwrap(err))
+	fetchedTx, err := l2DB.GetTx(tx.TxID)
+	require.NoError(t, err)
+	assertTx(t, &tx, fetchedTx)
+	var storedReplacedTxID common.TxID
+	require.NoError(t, l2DB.dbRead.QueryRow(
+		"SELECT replaced_tx_id FROM tx_pool WHERE tx_id = $1;", tx.TxID,
+	).Scan(&storedReplacedTxID))
+	assert.Equal(t, pendingTx.TxID, storedReplacedTxID)
+}
+
+func TestCancelTxAPI(t *testing.T) {
+	err := prepareHistoryDB(historyDB)
+	if err != nil {
+		log.Error("Error prepare historyDB", err)
+	}
+	poolL2Txs, err := generatePoolL2Txs()
+	require.NoError(t, err)
+	// add a regular tx and an atomic group of two txs
+	pendingTx := poolL2Txs[0]
+	require.NoError(t, l2DBWithACC.AddTxAPI(&pendingTx))
+	atomicTxs := []common.PoolL2Tx{poolL2Txs[1], poolL2Txs[2]}
+	for i := range atomicTxs {
+		atomicTxs[i].RqFromIdx = atomicTxs[(i+1)%2].FromIdx
+		atomicTxs[i].RqOffset = 1
+		atomicTxs[i].AtomicGroupID[0] = 1
+		require.NoError(t, l2DBWithACC.AddTxAPI(&atomicTxs[i]))
+	}
+
+	// cancel the regular tx
+	cancelledTxIDs, err := l2DBWithACC.CancelTxAPI(pendingTx.TxID)
+	require.NoError(t, err)
+	assert.Equal(t, []common.TxID{pendingTx.TxID}, cancelledTxIDs)
+	fetchedTx, err := l2DB.GetTx(pendingTx.TxID)
+	require.NoError(t, err)
+	assert.Equal(t, common.PoolL2TxStateCancelled, fetchedTx.State)
+	// a tx can't be cancelled twice
+	_, err = l2DBWithACC.CancelTxAPI(pendingTx.TxID)
+	assert.Equal(t, ErrCancelNotPending, tracerr.Unwrap(err))
+
+	// cancelling a tx of an atomic group cancels the whole group
+	cancelledTxIDs, err = l2DBWithACC.CancelTxAPI(atomicTxs[0].TxID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []common.TxID{atomicTxs[0].TxID, atomicTxs[1].TxID}, cancelledTxIDs)
+
+	// cancelled txs are never selected
+	pendingTxs, err := l2DB.GetPendingTxs()
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(pendingTxs))
+
+	// the tx doesn't exist
+	_, err = l2DBWithACC.CancelTxAPI(poolL2Txs[3].TxID)
+	assert.Equal(t, sql.ErrNoRows, tracerr.Unwrap(err))
+}
+
+func TestWebhooks(t *testing.T) {
+	err := prepareHistoryDB(historyDB)
+	if err != nil {
+		log.Error("Error prepare historyDB", err)
+	}
+	poolL2Txs, err := generatePoolL2Txs()
+	require.NoError(t, err)
+	apiKey := historydb.APIKey{
+		KeyHash:          historydb.APIKeyHash("webhooks"),
+		Name:             "webhooks",
+		CoordinatorScope: true,
+		RateLimit:        1,
+		Burst:            1,
+	}
+	require.NoError(t, historyDB.AddAPIKey(&apiKey))
+	// register a webhook for the txs of the sender of the first tx
+	webhook := Webhook{
+		URL:      "http://localhost:1234/hook",
+		Secret:   "secret",
+		Idx:      &poolL2Txs[0].FromIdx,
+		APIKeyID: apiKey.ItemID,
+	}
+	require.NoError(t, l2DBWithACC.AddWebhookAPI(&webhook, 1))
+	assert.NotZero(t, webhook.ItemID)
+	// the API key can't register more webhooks than the limit
+	otherWebhook := webhook
+	err = l2DBWithACC.AddWebhookAPI(&otherWebhook, 1)
+	assert.True(t, errors.Is(tracerr.Unwrap(err), ErrMaxWebhooks))
+	require.NoError(t, l2DBWithACC.AddTxAPI(&poolL2Txs[0]))
+
+	// the new tx is delivered
+	deliveries, err := l2DB.GetPendingWebhookDeliveries(10, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(deliveries))
+	assert.Equal(t, webhook.ItemID, deliveries[0].WebhookID)
+	assert.Equal(t, webhook.URL, deliveries[0].URL)
+	assert.Equal(t, "poolTx", deliveries[0].EventType)
+	assert.Contains(t, string(deliveries[0].Payload), poolL2Txs[0].TxID.String())
+	// the delivery is not returned again while it's being delivered
+	pendingDeliveries, err := l2DB.GetPendingWebhookDeliveries(10, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(pendingDeliveries))
+	statusCode := 200
+	require.NoError(t, l2DB.UpdateWebhookDelivery(deliveries[0].ItemID,
+		WebhookDeliveryStateDone, &statusCode, "", time.Now()))
+
+	// the delivery log requires the secret of the webhook
+	limit := uint(10)
+	request := GetWebhookDeliveriesAPIRequest{
+		WebhookID: webhook.ItemID,
+		Secret:    "wrong",
+		Limit:     &limit,
+	}
+	_, _, err = l2DBWithACC.GetWebhookDeliveriesAPI(request)
+	assert.Equal(t, sql.ErrNoRows, tracerr.Unwrap(err))
+	request.Secret = webhook.Secret
+	deliveryLog, pendingItems, err := l2DBWithACC.GetWebhookDeliveriesAPI(request)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), pendingItems)
+	require.Equal(t, 1, len(deliveryLog))
+	assert.Equal(t, WebhookDeliveryStateDone, deliveryLog[0].State)
+	assert.Equal(t, 1, deliveryLog[0].Attempts)
+	assert.Equal(t, statusCode, *deliveryLog[0].StatusCode)
+	assert.Equal(t, poolL2Txs[0].TxID.String(), deliveryLog[0].Payload["txId"])
+
+	// finished deliveries are purged
+	require.NoError(t, l2DB.PurgeWebhookDeliveries(time.Now().Add(time.Minute)))
+	deliveryLog, _, err = l2DBWithACC.GetWebhookDeliveriesAPI(request)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(deliveryLog))
+
+	// the webhook can only be deleted with its secret
+	err = l2DBWithACC.DeleteWebhookAPI(webhook.ItemID, "wrong")
+	assert.Equal(t, sql.ErrNoRows, tracerr.Unwrap(err))
+	require.NoError(t, l2DBWithACC.DeleteWebhookAPI(webhook.ItemID, webhook.Secret))
+	// txs are not delivered to deleted webhooks
+	require.NoError(t, l2DBWithACC.AddTxAPI(&poolL2Txs[1]))
+	deliveries, err = l2DB.GetPendingWebhookDeliveries(10, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(deliveries))
+}
+
 func TestUpdateTxsInfo(t *testing.T) {
 	err := prepareHistoryDB(historyDB)
 	if err != nil {
@@ -245,6 +431,14 @@ func TestUpdateTxsInfo(t *testing.T) {
 		// once added, change the Info parameter
 		poolL2Txs[i].Info = "test"
 	}
+	// set the structured details of the error of a tx
+	expectedNonce, providedNonce := common.Nonce(2), common.Nonce(3)
+	poolL2Txs[0].ErrorCode = 12
+	poolL2Txs[0].ErrorType = "ErrNoCurrentNonce"
+	poolL2Txs[0].ErrorDetails = &common.TxSelectorErrorDetails{
+		ExpectedNonce: &expectedNonce,
+		ProvidedNonce: &providedNonce,
+	}
 	// update the txs
 	var batchNum common.BatchNum
 	err = l2DB.UpdateTxsInfo(poolL2Txs, batchNum)
@@ -255,6 +449,14 @@ func TestUpdateTxsInfo(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, "BatchNum: 0. test", fetchedTx.Info)
 	}
+	apiTx, err := l2DBWithACC.GetTxAPI(poolL2Txs[0].TxID)
+	require.NoError(t, err)
+	require.NotNil(t, apiTx.ErrorCode)
+	assert.Equal(t, 12, *apiTx.ErrorCode)
+	assert.Equal(t, poolL2Txs[0].ErrorDetails, apiTx.ErrorDetails)
+	apiTx, err = l2DBWithACC.GetTxAPI(poolL2Txs[1].TxID)
+	require.NoError(t, err)
+	assert.Nil(t, apiTx.ErrorDetails)
 }
 
 func assertTx(t *testing.T, expected, actual *common.PoolL2Tx) {
@@ -394,6 +596,49 @@ func TestStartForging(t *testing.T) {
 	}
 }
 
+func TestGetTxStateChangesAPI(t *testing.T) {
+	var fakeBatchNum common.BatchNum = 33
+	err := prepareHistoryDB(historyDB)
+	if err != nil {
+		log.Error("Error prepare historyDB", err)
+	}
+	poolL2Txs, err := generatePoolL2Txs()
+	require.NoError(t, err)
+	tx := poolL2Txs[0]
+	require.NoError(t, l2DB.AddTxTest(&tx))
+	require.NoError(t, l2DB.StartForging([]common.TxID{tx.TxID}, fakeBatchNum))
+	require.NoError(t, l2DB.DoneForging([]common.TxID{tx.TxID}, fakeBatchNum))
+
+	changes, err := l2DBWithACC.GetTxStateChangesAPI(tx.TxID)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(changes))
+	assert.Equal(t, common.PoolL2TxStatePending, changes[0].State)
+	assert.Nil(t, changes[0].BatchNum)
+	assert.Equal(t, common.PoolL2TxStateForging, changes[1].State)
+	assert.Equal(t, common.PoolL2TxStateForged, changes[2].State)
+	assert.Equal(t, &fakeBatchNum, changes[2].BatchNum)
+
+	// Unknown txs have no state changes
+	changes, err = l2DBWithACC.GetTxStateChangesAPI(poolL2Txs[1].TxID)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	// The info of the pending txs updated at every batch is only recorded
+	// when the error changes
+	pendingTx := poolL2Txs[1]
+	require.NoError(t, l2DB.AddTxTest(&pendingTx))
+	pendingTx.Info = "not selected"
+	pendingTx.ErrorCode = 12
+	for batchNum := fakeBatchNum; batchNum < fakeBatchNum+3; batchNum++ {
+		require.NoError(t, l2DB.UpdateTxsInfo([]common.PoolL2Tx{pendingTx}, batchNum))
+	}
+	changes, err = l2DBWithACC.GetTxStateChangesAPI(pendingTx.TxID)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(changes))
+	assert.Equal(t, common.PoolL2TxStatePending, changes[1].State)
+	assert.Equal(t, "BatchNum: 33. not selected", *changes[1].Info)
+}
+
 func TestDoneForging(t *testing.T) {
 	// Generate txs
 	var fakeBatchNum common.BatchNum = 33
@@ -907,3 +1152,78 @@ func TestPurgeByExternalDelete(t *testing.T) {
 		require.Equal(t, sql.ErrNoRows, tracerr.Unwrap(err))
 	}
 }
+
+func TestTxManagerBatches(t *testing.T) {
+	_, err := l2DB.dbWrite.Exec("DELETE FROM tx_manager_batch;")
+	require.NoError(t, err)
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	chainID := big.NewInt(1337)
+	to := ethCommon.BigToAddress(big.NewInt(1))
+	newTx := func(nonce uint64, gasFeeCap int64) *types.Transaction {
+		tx, err := types.SignNewTx(key, types.NewLondonSigner(chainID), &types.DynamicFeeTx{
+			ChainID:   chainID,
+			Nonce:     nonce,
+			GasTipCap: big.NewInt(1),
+			GasFeeCap: big.NewInt(gasFeeCap),
+			Gas:       1000000,
+			To:        &to,
+			Value:     big.NewInt(0),
+			Data:      []byte{0x01, 0x02},
+		})
+		require.NoError(t, err)
+		return tx
+	}
+	batches := []TxManagerBatch{
+		{
+			BatchNum:      4,
+			L1Batch:       true,
+			Nonce:         7,
+			Status:        "sent",
+			EthTxs:        []*types.Transaction{newTx(7, 100)},
+			SendTimestamp: time.Unix(1600000000, 0).UTC(),
+			SendBlockNum:  100,
+		},
+		{
+			BatchNum:      3,
+			Nonce:         6,
+			Status:        "mined",
+			EthTxs:        []*types.Transaction{newTx(6, 100)},
+			SendTimestamp: time.Unix(1600000000, 0).UTC(),
+			SendBlockNum:  99,
+		},
+	}
+	for i := range batches {
+		require.NoError(t, l2DB.StoreTxManagerBatch(&batches[i]))
+	}
+	// A resent batch replaces the stored one
+	batches[0].EthTxs = append(batches[0].EthTxs, newTx(7, 110))
+	batches[0].ResendNum = 1
+	batches[0].SendTimestamp = time.Unix(1600000060, 0).UTC()
+	batches[0].SendBlockNum = 104
+	require.NoError(t, l2DB.StoreTxManagerBatch(&batches[0]))
+
+	dbBatches, err := l2DB.GetTxManagerBatches()
+	require.NoError(t, err)
+	require.Equal(t, 2, len(dbBatches))
+	for i, expected := range []TxManagerBatch{batches[1], batches[0]} {
+		assert.Equal(t, expected.BatchNum, dbBatches[i].BatchNum)
+		assert.Equal(t, expected.L1Batch, dbBatches[i].L1Batch)
+		assert.Equal(t, expected.Nonce, dbBatches[i].Nonce)
+		assert.Equal(t, expected.Status, dbBatches[i].Status)
+		assert.Equal(t, expected.ResendNum, dbBatches[i].ResendNum)
+		assert.Equal(t, expected.SendTimestamp.Unix(), dbBatches[i].SendTimestamp.Unix())
+		assert.Equal(t, expected.SendBlockNum, dbBatches[i].SendBlockNum)
+		require.Equal(t, len(expected.EthTxs), len(dbBatches[i].EthTxs))
+		for j, tx := range expected.EthTxs {
+			assert.Equal(t, tx.Hash(), dbBatches[i].EthTxs[j].Hash())
+		}
+	}
+
+	// Confirmed batches are removed
+	require.NoError(t, l2DB.RemoveTxManagerBatch(3))
+	dbBatches, err = l2DB.GetTxManagerBatches()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(dbBatches))
+	assert.Equal(t, common.BatchNum(4), dbBatches[0].BatchNum)
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/chainbing/node/common"
 	"github.com/chainbing/node/common/apitypes"
 	"github.com/iden3/go-iden3-crypto/babyjub"
@@ -27,6 +28,7 @@ type poolTxAPIView struct {
 	Info                 *string               `meddler:"info"`
 	ErrorCode            *int                  `meddler:"error_code"`
 	ErrorType            *string               `meddler:"error_type"`
+	ErrorDetails         *common.TxSelectorErrorDetails `meddler:"error_details"`
 	Signature            babyjub.SignatureComp `meddler:"signature"`
 	RqFromIdx            *apitypes.CbIdx      `meddler:"rq_from_idx"`
 	RqToIdx              *apitypes.CbIdx      `meddler:"rq_to_idx"`
@@ -80,6 +82,7 @@ func (tx *poolTxAPIView) ToAPI() apitypes.TxL2 {
 		Info:                 tx.Info,
 		ErrorCode:            tx.ErrorCode,
 		ErrorType:            tx.ErrorType,
+		ErrorDetails:         tx.ErrorDetails,
 		Signature:            tx.Signature,
 		Timestamp:            tx.Timestamp,
 		RqFromIdx:            tx.RqFromIdx,
@@ -103,3 +106,81 @@ func (tx *poolTxAPIView) ToAPI() apitypes.TxL2 {
 
 	return pooll2apilocal
 }
+
+// WebhookDeliveryState is the state of a webhook delivery
+type WebhookDeliveryState string
+
+const (
+	// WebhookDeliveryStatePending is used for deliveries that haven't
+	// been delivered yet and will be attempted again
+	WebhookDeliveryStatePending WebhookDeliveryState = "pend"
+	// WebhookDeliveryStateDone is used for deliveries that have been
+	// accepted by the webhook URL
+	WebhookDeliveryStateDone WebhookDeliveryState = "done"
+	// WebhookDeliveryStateFailed is used for deliveries that have reached
+	// the maximum number of attempts without being accepted
+	WebhookDeliveryStateFailed WebhookDeliveryState = "fail"
+)
+
+// Webhook is a URL registered to receive the events of the txs and exits
+// that match all its filters
+type Webhook struct {
+	ItemID    uint64                 `meddler:"item_id"`
+	URL       string                 `meddler:"url"`
+	Secret    string                 `meddler:"secret"`
+	EthAddr   *ethCommon.Address     `meddler:"eth_addr"`
+	BJJ       *babyjub.PublicKeyComp `meddler:"bjj"`
+	Idx       *common.Idx            `meddler:"idx"`
+	TokenID   *common.TokenID        `meddler:"token_id"`
+	APIKeyID  uint64                 `meddler:"api_key_id"`
+	Timestamp time.Time              `meddler:"timestamp,utctime"`
+}
+
+// WebhookDelivery is an event queued to be delivered to a webhook
+type WebhookDelivery struct {
+	ItemID    uint64    `meddler:"item_id"`
+	WebhookID uint64    `meddler:"webhook_id"`
+	URL       string    `meddler:"url"`
+	Secret    string    `meddler:"secret"`
+	EventType string    `meddler:"event_type"`
+	Payload   []byte    `meddler:"payload"`
+	Attempts  int       `meddler:"attempts"`
+	Timestamp time.Time `meddler:"timestamp,utctime"`
+}
+
+// WebhookDeliveryAPI is the representation of a webhook delivery used by
+// the delivery log of the API
+type WebhookDeliveryAPI struct {
+	ItemID      uint64                 `json:"itemId" meddler:"item_id"`
+	EventType   string                 `json:"type" meddler:"event_type"`
+	Payload     map[string]interface{} `json:"payload" meddler:"payload,json"`
+	State       WebhookDeliveryState   `json:"state" meddler:"state"`
+	Attempts    int                    `json:"attempts" meddler:"attempts"`
+	NextAttempt time.Time              `json:"nextAttempt" meddler:"next_attempt,utctime"`
+	StatusCode  *int                   `json:"statusCode" meddler:"status_code"`
+	Info        *string                `json:"info" meddler:"info"`
+	Timestamp   time.Time              `json:"timestamp" meddler:"timestamp,utctime"`
+	TotalItems  uint64                 `json:"-" meddler:"total_items"`
+}
+
+// TxManagerBatch is a batch whose forge call has been sent to ethereum by the
+// TxManager of the coordinator.  It's kept until the forge call is confirmed,
+// so that the sent eth txs can be checked after a restart.
+type TxManagerBatch struct {
+	BatchNum      common.BatchNum      `meddler:"batch_num"`
+	L1Batch       bool                 `meddler:"l1_batch"`
+	Nonce         uint64               `meddler:"nonce"`
+	Status        string               `meddler:"status"`
+	ResendNum     int                  `meddler:"resend_num"`
+	EthTxs        []*types.Transaction `meddler:"eth_txs,json"`
+	SendTimestamp time.Time            `meddler:"send_timestamp,utctime"`
+	SendBlockNum  int64                `meddler:"send_block_num"`
+}
+
+// PoolTxStateChangeAPI is a change of the state or the info of a pool tx
+type PoolTxStateChangeAPI struct {
+	State     common.PoolL2TxState `json:"state" meddler:"state"`
+	Info      *string              `json:"info" meddler:"info"`
+	BatchNum  *common.BatchNum     `json:"batchNum" meddler:"batch_num"`
+	Timestamp time.Time            `json:"timestamp" meddler:"timestamp,utctime"`
+}
@@ -0,0 +1,100 @@
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE FUNCTION notify_block()
+    RETURNS TRIGGER
+AS
+$BODY$
+BEGIN
+    PERFORM pg_notify('chainbing_events', json_build_object(
+        'type', 'block',
+        'ethBlockNum', NEW.eth_block_num
+    )::text);
+    RETURN NULL;
+END;
+$BODY$
+LANGUAGE plpgsql;
+-- +migrate StatementEnd
+CREATE TRIGGER trigger_notify_block AFTER INSERT ON block
+FOR EACH ROW EXECUTE PROCEDURE notify_block();
+
+-- +migrate StatementBegin
+CREATE FUNCTION notify_batch()
+    RETURNS TRIGGER
+AS
+$BODY$
+BEGIN
+    PERFORM pg_notify('chainbing_events', json_build_object(
+        'type', 'batch',
+        'batchNum', NEW.batch_num,
+        'ethBlockNum', NEW.eth_block_num
+    )::text);
+    RETURN NULL;
+END;
+$BODY$
+LANGUAGE plpgsql;
+-- +migrate StatementEnd
+CREATE TRIGGER trigger_notify_batch AFTER INSERT ON batch
+FOR EACH ROW EXECUTE PROCEDURE notify_batch();
+
+-- +migrate StatementBegin
+CREATE FUNCTION notify_bid()
+    RETURNS TRIGGER
+AS
+$BODY$
+BEGIN
+    PERFORM pg_notify('chainbing_events', json_build_object(
+        'type', 'bid',
+        'itemId', NEW.item_id,
+        'slotNum', NEW.slot_num,
+        'bidValue', NEW.bid_value::text,
+        'ethBlockNum', NEW.eth_block_num,
+        'bidderAddr', '0x' || encode(NEW.bidder_addr, 'hex')
+    )::text);
+    RETURN NULL;
+END;
+$BODY$
+LANGUAGE plpgsql;
+-- +migrate StatementEnd
+CREATE TRIGGER trigger_notify_bid AFTER INSERT ON bid
+FOR EACH ROW EXECUTE PROCEDURE notify_bid();
+
+-- +migrate StatementBegin
+CREATE FUNCTION notify_pool_tx()
+    RETURNS TRIGGER
+AS
+$BODY$
+BEGIN
+    -- Only notify new txs and changes of state
+    IF TG_OP = 'UPDATE' AND OLD.state = NEW.state THEN
+        RETURN NULL;
+    END IF;
+    -- The accounts of the tx are sent so that the API can filter the
+    -- subscribers without querying the tx
+    PERFORM pg_notify('chainbing_events', json_build_object(
+        'type', 'poolTx',
+        'txId', '0x' || encode(NEW.tx_id, 'hex'),
+        'state', NEW.state,
+        'fromIdx', NEW.from_idx,
+        'toIdx', NEW.to_idx,
+        'fromEthAddr', '0x' || encode(NEW.effective_from_eth_addr, 'hex'),
+        'fromBjj', '0x' || encode(NEW.effective_from_bjj, 'hex'),
+        'toEthAddr', '0x' || encode(NEW.effective_to_eth_addr, 'hex'),
+        'toBjj', '0x' || encode(NEW.effective_to_bjj, 'hex')
+    )::text);
+    RETURN NULL;
+END;
+$BODY$
+LANGUAGE plpgsql;
+-- +migrate StatementEnd
+CREATE TRIGGER trigger_notify_pool_tx AFTER INSERT OR UPDATE OF state ON tx_pool
+FOR EACH ROW EXECUTE PROCEDURE notify_pool_tx();
+
+-- +migrate Down
+DROP TRIGGER IF EXISTS trigger_notify_pool_tx ON tx_pool;
+DROP FUNCTION IF EXISTS notify_pool_tx();
+DROP TRIGGER IF EXISTS trigger_notify_bid ON bid;
+DROP FUNCTION IF EXISTS notify_bid();
+DROP TRIGGER IF EXISTS trigger_notify_batch ON batch;
+DROP FUNCTION IF EXISTS notify_batch();
+DROP TRIGGER IF EXISTS trigger_notify_block ON block;
+DROP FUNCTION IF EXISTS notify_block();
@@ -0,0 +1,49 @@
+package migrations_test
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+// This migration adds the triggers that notify new events on the `chainbing_events` channel
+
+type migrationTest0010 struct{}
+
+const queryCountNotifyTriggers = `SELECT COUNT(*) FROM pg_trigger WHERE tgname IN (
+	'trigger_notify_block', 'trigger_notify_batch', 'trigger_notify_bid', 'trigger_notify_pool_tx'
+);`
+
+func (m migrationTest0010) InsertData(db *sqlx.DB) error {
+	return nil
+}
+
+func (m migrationTest0010) RunAssertsAfterMigrationUp(t *testing.T, db *sqlx.DB) {
+	// check that the triggers have been created
+	row := db.QueryRow(queryCountNotifyTriggers)
+	var result int
+	assert.NoError(t, row.Scan(&result))
+	assert.Equal(t, 4, result)
+	// check that inserting a block (which fires the trigger) still works
+	_, err := db.Exec(`INSERT INTO block (
+		eth_block_num,"timestamp",hash
+	) VALUES (
+		4417296,'2021-03-10 16:44:06.000',decode('C4D46677F3B2511D96389521C2BDFFE91127DE214423FF14899A6177631D2105','hex')
+	);`)
+	assert.NoError(t, err)
+	_, err = db.Exec(`DELETE FROM block WHERE eth_block_num = 4417296;`)
+	assert.NoError(t, err)
+}
+
+func (m migrationTest0010) RunAssertsAfterMigrationDown(t *testing.T, db *sqlx.DB) {
+	// check that the triggers don't exist anymore
+	row := db.QueryRow(queryCountNotifyTriggers)
+	var result int
+	assert.NoError(t, row.Scan(&result))
+	assert.Equal(t, 0, result)
+}
+
+func TestMigration0010(t *testing.T) {
+	runMigrationTest(t, 10, migrationTest0010{})
+}
@@ -0,0 +1,15 @@
+-- +migrate Up
+ALTER TABLE tx_pool ADD COLUMN replaced_tx_id BYTEA DEFAULT NULL;
+
+-- Only recalculate the effective recipient when the recipient is updated, so
+-- that updating other columns of a pool tx doesn't overwrite it
+DROP TRIGGER IF EXISTS trigger_update_pool_tx ON tx_pool;
+CREATE TRIGGER trigger_update_pool_tx BEFORE UPDATE OF to_idx, to_eth_addr, to_bjj ON tx_pool
+FOR EACH ROW EXECUTE PROCEDURE update_pool_tx();
+
+-- +migrate Down
+DROP TRIGGER IF EXISTS trigger_update_pool_tx ON tx_pool;
+CREATE TRIGGER trigger_update_pool_tx BEFORE UPDATE ON tx_pool
+FOR EACH ROW EXECUTE PROCEDURE update_pool_tx();
+
+ALTER TABLE tx_pool DROP COLUMN replaced_tx_id;
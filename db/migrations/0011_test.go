@@ -0,0 +1,113 @@
+package migrations_test
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+// This migration adds the column `replaced_tx_id` on `tx_pool`, and limits the
+// `trigger_update_pool_tx` trigger to the updates of the recipient columns
+
+type migrationTest0011 struct{}
+
+func (m migrationTest0011) InsertData(db *sqlx.DB) error {
+	// insert block to respect the FKey of token
+	const queryInsertBlock = `INSERT INTO block (
+		eth_block_num,"timestamp",hash
+	) VALUES (
+		4417296,'2021-03-10 16:44:06.000',decode('C4D46677F3B2511D96389521C2BDFFE91127DE214423FF14899A6177631D2105','hex')
+	);`
+	// insert token to respect the FKey of tx_pool
+	const queryInsertToken = `INSERT INTO "token" (
+		token_id,eth_block_num,eth_addr,"name",symbol,decimals,usd,usd_update
+	) VALUES (
+		2,4417296,decode('1B36A4DED4DF40248C0E0E52CEA5EDC9A298B721','hex'),'Dai Stablecoin','DAI',18,1.01,'2021-04-17 20:21:16.870'
+	);`
+	// insert batch to respect the FKey of account
+	const queryInsertBatch = `INSERT INTO batch (
+		batch_num, eth_block_num, forger_addr, fees_collected, fee_idxs_coordinator, state_root,
+		num_accounts, last_idx, exit_root, forge_l1_txs_num, slot_num, total_fees_usd
+	) VALUES (
+		6758,
+		4417296,
+		decode('459264CC7D2BF350AFDDA828C273E81367729C1F', 'hex'),
+		decode('7B2230223A34383337383531313632323134343030307D0A', 'hex'),
+		decode('5B3236335D0A', 'hex'),
+		12898140512818699175738765060248919016800434587665040485377676113605873428098,
+		256,
+		1044,
+		0,
+		NULL,
+		717,
+		115.047487133272
+	);`
+	// insert sender and receiver accounts to set the effective addresses through trigger
+	const queryInsertAccounts = `INSERT INTO account (
+		idx,token_id,batch_num,bjj,eth_addr
+	) VALUES (
+		789,2,6758,decode('FDDACE21457376B0952CCD19CE66B854FDD7C6E45905B0A0A75747C87D41719A','hex'),decode('A631BE6995643E6085330A31B9E1AF48DD5D6B7F','hex')
+	), (
+		790,2,6758,decode('1224456678907543564567567567567657567567000000000000000000000000','hex'),decode('1224456678907543564567567567567657567567','hex')
+	);`
+	// insert a transfer to an idx, which doesn't set to_eth_addr nor to_bjj
+	const queryInsertTxPool = `INSERT INTO tx_pool (
+		tx_id, from_idx, to_idx, token_id, amount, amount_f, fee, nonce, state, signature, tx_type
+	) VALUES (
+		decode('023A0D72BEB1095C28A7130D896F484CC9D465C1C95F1617C0A7B2094E3E1F11FF', 'hex'),
+		789,
+		790,
+		2,
+		5,
+		5,
+		227,
+		3,
+		'pend',
+		decode('9C6A159C57D7FC58E3E5D3510FBC64EAC9C0D56A1B3144D94D6BBA4C23B9402CEE57D0CFF4A3BE135CBD2393AB8FD2A1840A62281B1721801DBF708D27F1DF00', 'hex'),
+		'Transfer'
+	);`
+	_, err := db.Exec(queryInsertBlock +
+		queryInsertToken +
+		queryInsertBatch +
+		queryInsertAccounts +
+		queryInsertTxPool,
+	)
+	return err
+}
+
+func (m migrationTest0011) RunAssertsAfterMigrationUp(t *testing.T, db *sqlx.DB) {
+	// set the replaced tx id
+	_, err := db.Exec(`UPDATE tx_pool SET
+		replaced_tx_id = decode('02C674951A81881B7BC50DB3B9E5EFAE97E1E3A4A8D4D1C5A6F8C1D0E8C4B3A201', 'hex')
+		WHERE tx_id = decode('023A0D72BEB1095C28A7130D896F484CC9D465C1C95F1617C0A7B2094E3E1F11FF', 'hex');`)
+	assert.NoError(t, err)
+	// check that the effective receiver has not been overwritten by the update
+	const queryGetTxPool = `SELECT COUNT(*) FROM tx_pool WHERE
+		tx_id = decode('023A0D72BEB1095C28A7130D896F484CC9D465C1C95F1617C0A7B2094E3E1F11FF', 'hex') AND
+		replaced_tx_id = decode('02C674951A81881B7BC50DB3B9E5EFAE97E1E3A4A8D4D1C5A6F8C1D0E8C4B3A201', 'hex') AND
+		effective_to_eth_addr = decode('1224456678907543564567567567567657567567', 'hex') AND
+		effective_to_bjj = decode('1224456678907543564567567567567657567567000000000000000000000000', 'hex');`
+	row := db.QueryRow(queryGetTxPool)
+	var result int
+	assert.NoError(t, row.Scan(&result))
+	assert.Equal(t, 1, result)
+}
+
+func (m migrationTest0011) RunAssertsAfterMigrationDown(t *testing.T, db *sqlx.DB) {
+	// check that the tx_pool inserted in previous step is persisted
+	const queryGetTxPool = `SELECT COUNT(*) FROM tx_pool WHERE
+		tx_id = decode('023A0D72BEB1095C28A7130D896F484CC9D465C1C95F1617C0A7B2094E3E1F11FF', 'hex');`
+	row := db.QueryRow(queryGetTxPool)
+	var result int
+	assert.NoError(t, row.Scan(&result))
+	assert.Equal(t, 1, result)
+	// check that replaced_tx_id colum doesn't exist anymore
+	const queryCheckReplacedTxID = `SELECT COUNT(*) FROM tx_pool WHERE replaced_tx_id IS NULL;`
+	row = db.QueryRow(queryCheckReplacedTxID)
+	assert.Equal(t, `pq: column "replaced_tx_id" does not exist`, row.Scan(&result).Error())
+}
+
+func TestMigration0011(t *testing.T) {
+	runMigrationTest(t, 11, migrationTest0011{})
+}
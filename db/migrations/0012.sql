@@ -0,0 +1,158 @@
+-- +migrate Up
+CREATE TABLE webhook (
+    item_id SERIAL PRIMARY KEY,
+    url VARCHAR NOT NULL,
+    secret VARCHAR NOT NULL,
+    -- Filters, a webhook receives the events of the txs that involve all
+    -- the filters that are set, either as sender or as receiver
+    eth_addr BYTEA,
+    bjj BYTEA,
+    idx BIGINT,
+    token_id INT,
+    timestamp TIMESTAMP WITHOUT TIME ZONE DEFAULT timezone('utc', now())
+);
+
+CREATE TABLE webhook_delivery (
+    item_id SERIAL PRIMARY KEY,
+    webhook_id INT NOT NULL REFERENCES webhook (item_id) ON DELETE CASCADE,
+    event_type VARCHAR(40) NOT NULL,
+    payload JSONB NOT NULL,
+    state CHAR(4) NOT NULL DEFAULT 'pend', -- pend, done or fail
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT timezone('utc', now()),
+    status_code INT,
+    info VARCHAR,
+    timestamp TIMESTAMP WITHOUT TIME ZONE DEFAULT timezone('utc', now())
+);
+
+CREATE INDEX webhook_delivery_webhook_id ON webhook_delivery (webhook_id);
+CREATE INDEX webhook_delivery_next_attempt ON webhook_delivery (next_attempt) WHERE state = 'pend';
+
+-- enqueue_webhook_deliveries adds a delivery of the event to every webhook
+-- that matches it.  Arguments: event type, payload, token_id, from_idx,
+-- from_eth_addr, from_bjj, to_idx, to_eth_addr, to_bjj
+-- +migrate StatementBegin
+CREATE FUNCTION enqueue_webhook_deliveries(VARCHAR, JSONB, INT, BIGINT, BYTEA, BYTEA, BIGINT, BYTEA, BYTEA)
+    RETURNS VOID
+AS
+$BODY$
+BEGIN
+    INSERT INTO webhook_delivery (webhook_id, event_type, payload)
+    SELECT webhook.item_id, $1, $2 FROM webhook WHERE
+        (webhook.token_id IS NULL OR webhook.token_id = $3) AND
+        (webhook.idx IS NULL OR webhook.idx = $4 OR webhook.idx = $7) AND
+        (webhook.eth_addr IS NULL OR webhook.eth_addr = $5 OR webhook.eth_addr = $8) AND
+        (webhook.bjj IS NULL OR webhook.bjj = $6 OR webhook.bjj = $9);
+END;
+$BODY$
+LANGUAGE plpgsql;
+-- +migrate StatementEnd
+
+-- +migrate StatementBegin
+CREATE FUNCTION webhook_pool_tx()
+    RETURNS TRIGGER
+AS
+$BODY$
+BEGIN
+    -- Only deliver new txs and changes of state
+    IF TG_OP = 'UPDATE' AND OLD.state = NEW.state THEN
+        RETURN NULL;
+    END IF;
+    PERFORM enqueue_webhook_deliveries('poolTx', json_build_object(
+        'txId', '0x' || encode(NEW.tx_id, 'hex'),
+        'state', NEW.state,
+        'batchNum', NEW.batch_num,
+        'tokenId', NEW.token_id,
+        'fromIdx', NEW.from_idx,
+        'toIdx', NEW.to_idx,
+        'amount', NEW.amount::text,
+        'nonce', NEW.nonce
+    )::jsonb, NEW.token_id,
+    NEW.from_idx, NEW.effective_from_eth_addr, NEW.effective_from_bjj,
+    NEW.to_idx, NEW.effective_to_eth_addr, NEW.effective_to_bjj);
+    RETURN NULL;
+END;
+$BODY$
+LANGUAGE plpgsql;
+-- +migrate StatementEnd
+CREATE TRIGGER trigger_webhook_pool_tx AFTER INSERT OR UPDATE OF state ON tx_pool
+FOR EACH ROW EXECUTE PROCEDURE webhook_pool_tx();
+
+-- The account created by an L1 user tx is only known once its
+-- effective_from_idx is set, which is done after the tx is forged, so the
+-- forged txs that create an account are delivered at that point
+-- +migrate StatementBegin
+CREATE FUNCTION webhook_l1_user_tx()
+    RETURNS TRIGGER
+AS
+$BODY$
+BEGIN
+    -- Only deliver L1 user txs when they get forged
+    IF NEW.user_origin IS NOT TRUE OR NEW.batch_num IS NULL THEN
+        RETURN NULL;
+    END IF;
+    IF COALESCE(NEW.from_idx, 0) = 0 THEN
+        IF NEW.effective_from_idx IS NULL OR
+            (OLD.batch_num IS NOT NULL AND OLD.effective_from_idx IS NOT NULL) THEN
+            RETURN NULL;
+        END IF;
+    ELSIF OLD.batch_num IS NOT NULL THEN
+        RETURN NULL;
+    END IF;
+    PERFORM enqueue_webhook_deliveries('l1UserTxForged', json_build_object(
+        'txId', '0x' || encode(NEW.id, 'hex'),
+        'type', NEW.type,
+        'batchNum', NEW.batch_num,
+        'tokenId', NEW.token_id,
+        'fromIdx', COALESCE(NEW.effective_from_idx, NEW.from_idx),
+        'toIdx', NEW.to_idx,
+        'amount', NEW.amount::text,
+        'depositAmount', NEW.deposit_amount::text
+    )::jsonb, NEW.token_id,
+    COALESCE(NEW.effective_from_idx, NEW.from_idx), NEW.from_eth_addr, NEW.from_bjj,
+    NEW.to_idx, NULL, NULL);
+    RETURN NULL;
+END;
+$BODY$
+LANGUAGE plpgsql;
+-- +migrate StatementEnd
+CREATE TRIGGER trigger_webhook_l1_user_tx AFTER UPDATE OF batch_num, effective_from_idx ON tx
+FOR EACH ROW EXECUTE PROCEDURE webhook_l1_user_tx();
+
+-- Exits are inserted once the batch is synchronized, which is when the exit
+-- root is available in the smart contract and the exit can be withdrawn
+-- +migrate StatementBegin
+CREATE FUNCTION webhook_exit()
+    RETURNS TRIGGER
+AS
+$BODY$
+DECLARE
+    exit_account account%ROWTYPE;
+BEGIN
+    SELECT * INTO exit_account FROM account WHERE idx = NEW.account_idx;
+    PERFORM enqueue_webhook_deliveries('exitWithdrawable', json_build_object(
+        'batchNum', NEW.batch_num,
+        'tokenId', exit_account.token_id,
+        'accountIdx', NEW.account_idx,
+        'balance', NEW.balance::text
+    )::jsonb, exit_account.token_id,
+    NEW.account_idx, exit_account.eth_addr, exit_account.bjj,
+    NULL, NULL, NULL);
+    RETURN NULL;
+END;
+$BODY$
+LANGUAGE plpgsql;
+-- +migrate StatementEnd
+CREATE TRIGGER trigger_webhook_exit AFTER INSERT ON exit_tree
+FOR EACH ROW EXECUTE PROCEDURE webhook_exit();
+
+-- +migrate Down
+DROP TRIGGER IF EXISTS trigger_webhook_exit ON exit_tree;
+DROP FUNCTION IF EXISTS webhook_exit();
+DROP TRIGGER IF EXISTS trigger_webhook_l1_user_tx ON tx;
+DROP FUNCTION IF EXISTS webhook_l1_user_tx();
+DROP TRIGGER IF EXISTS trigger_webhook_pool_tx ON tx_pool;
+DROP FUNCTION IF EXISTS webhook_pool_tx();
+DROP FUNCTION IF EXISTS enqueue_webhook_deliveries(VARCHAR, JSONB, INT, BIGINT, BYTEA, BYTEA, BIGINT, BYTEA, BYTEA);
+DROP TABLE IF EXISTS webhook_delivery;
+DROP TABLE IF EXISTS webhook;
@@ -0,0 +1,152 @@
+package migrations_test
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+// This migration adds the `webhook` and `webhook_delivery` tables, and the
+// triggers that queue the deliveries of pool tx, L1 user tx and exit events
+
+type migrationTest0012 struct{}
+
+func (m migrationTest0012) InsertData(db *sqlx.DB) error {
+	// insert block to respect the FKey of token
+	const queryInsertBlock = `INSERT INTO block (
+		eth_block_num,"timestamp",hash
+	) VALUES (
+		4417296,'2021-03-10 16:44:06.000',decode('C4D46677F3B2511D96389521C2BDFFE91127DE214423FF14899A6177631D2105','hex')
+	);`
+	// insert token to respect the FKey of tx_pool
+	const queryInsertToken = `INSERT INTO "token" (
+		token_id,eth_block_num,eth_addr,"name",symbol,decimals,usd,usd_update
+	) VALUES (
+		2,4417296,decode('1B36A4DED4DF40248C0E0E52CEA5EDC9A298B721','hex'),'Dai Stablecoin','DAI',18,1.01,'2021-04-17 20:21:16.870'
+	);`
+	// insert batch to respect the FKey of account
+	const queryInsertBatch = `INSERT INTO batch (
+		batch_num, eth_block_num, forger_addr, fees_collected, fee_idxs_coordinator, state_root,
+		num_accounts, last_idx, exit_root, forge_l1_txs_num, slot_num, total_fees_usd
+	) VALUES (
+		6758,
+		4417296,
+		decode('459264CC7D2BF350AFDDA828C273E81367729C1F', 'hex'),
+		decode('7B2230223A34383337383531313632323134343030307D0A', 'hex'),
+		decode('5B3236335D0A', 'hex'),
+		12898140512818699175738765060248919016800434587665040485377676113605873428098,
+		256,
+		1044,
+		0,
+		1,
+		717,
+		115.047487133272
+	);`
+	// insert the accounts that will be involved in the events
+	const queryInsertAccounts = `INSERT INTO account (
+		idx,token_id,batch_num,bjj,eth_addr
+	) VALUES (
+		789,2,6758,decode('FDDACE21457376B0952CCD19CE66B854FDD7C6E45905B0A0A75747C87D41719A','hex'),decode('A631BE6995643E6085330A31B9E1AF48DD5D6B7F','hex')
+	), (
+		790,2,6758,decode('1224456678907543564567567567567657567567000000000000000000000000','hex'),decode('1224456678907543564567567567567657567567','hex')
+	);`
+	// insert a queued L1 user tx that creates the account 789, and a
+	// deposit to the account 789
+	const queryInsertTxs = `INSERT INTO tx (
+		is_l1, id, type, position, from_idx, from_eth_addr, from_bjj, to_idx, amount, amount_f,
+		token_id, eth_block_num, to_forge_l1_txs_num, user_origin, deposit_amount, deposit_amount_f
+	) VALUES (
+		true,
+		decode('00A6AD14AB2C0CA4B2AB5E2B6D5B32D3B3ECF6BBC24D1D6F1FB1F1E8A0F0F0F0F0', 'hex'),
+		'CreateAccountDeposit', 0, 0,
+		decode('A631BE6995643E6085330A31B9E1AF48DD5D6B7F','hex'),
+		decode('FDDACE21457376B0952CCD19CE66B854FDD7C6E45905B0A0A75747C87D41719A','hex'),
+		0, 0, 0, 2, 4417296, 1, true, 10, 10
+	), (
+		true,
+		decode('00B6AD14AB2C0CA4B2AB5E2B6D5B32D3B3ECF6BBC24D1D6F1FB1F1E8A0F0F0F0F0', 'hex'),
+		'Deposit', 1, 789,
+		decode('A631BE6995643E6085330A31B9E1AF48DD5D6B7F','hex'),
+		decode('FDDACE21457376B0952CCD19CE66B854FDD7C6E45905B0A0A75747C87D41719A','hex'),
+		0, 0, 0, 2, 4417296, 1, true, 10, 10
+	);`
+	_, err := db.Exec(queryInsertBlock +
+		queryInsertToken +
+		queryInsertBatch +
+		queryInsertAccounts +
+		queryInsertTxs,
+	)
+	return err
+}
+
+func (m migrationTest0012) RunAssertsAfterMigrationUp(t *testing.T, db *sqlx.DB) {
+	// register a webhook for the txs of the account 789
+	_, err := db.Exec(`INSERT INTO webhook (url, secret, idx) VALUES ('http://localhost/hook', 'secret', 789);`)
+	assert.NoError(t, err)
+	// insert a transfer from 789 and change its state, and a transfer that doesn't involve 789
+	_, err = db.Exec(`INSERT INTO tx_pool (
+		tx_id, from_idx, to_idx, token_id, amount, amount_f, fee, nonce, state, signature, tx_type
+	) VALUES (
+		decode('023A0D72BEB1095C28A7130D896F484CC9D465C1C95F1617C0A7B2094E3E1F11FF', 'hex'),
+		789, 790, 2, 5, 5, 227, 3, 'pend',
+		decode('9C6A159C57D7FC58E3E5D3510FBC64EAC9C0D56A1B3144D94D6BBA4C23B9402CEE57D0CFF4A3BE135CBD2393AB8FD2A1840A62281B1721801DBF708D27F1DF00', 'hex'),
+		'Transfer'
+	), (
+		decode('02C674951A81881B7BC50DB3B9E5EFAE97E1E3A4A8D4D1C5A6F8C1D0E8C4B3A201', 'hex'),
+		790, 790, 2, 5, 5, 227, 4, 'pend',
+		decode('9C6A159C57D7FC58E3E5D3510FBC64EAC9C0D56A1B3144D94D6BBA4C23B9402CEE57D0CFF4A3BE135CBD2393AB8FD2A1840A62281B1721801DBF708D27F1DF00', 'hex'),
+		'Transfer'
+	);
+	UPDATE tx_pool SET state = 'fing' WHERE from_idx = 789;
+	UPDATE tx_pool SET info = 'not a state change' WHERE from_idx = 789;`)
+	assert.NoError(t, err)
+	// check that only the deliveries of the new tx and the state change have been queued
+	row := db.QueryRow(`SELECT COUNT(*) FROM webhook_delivery WHERE
+		event_type = 'poolTx' AND state = 'pend' AND attempts = 0 AND
+		payload->>'txId' = '0x023a0d72beb1095c28a7130d896f484cc9d465c1c95f1617c0a7b2094e3e1f11ff';`)
+	var result int
+	assert.NoError(t, row.Scan(&result))
+	assert.Equal(t, 2, result)
+	row = db.QueryRow(`SELECT COUNT(*) FROM webhook_delivery;`)
+	assert.NoError(t, row.Scan(&result))
+	assert.Equal(t, 2, result)
+
+	countL1Deliveries := func(txType string) int {
+		row := db.QueryRow(`SELECT COUNT(*) FROM webhook_delivery WHERE
+			event_type = 'l1UserTxForged' AND payload->>'type' = $1 AND
+			payload->>'fromIdx' = '789';`, txType)
+		var result int
+		assert.NoError(t, row.Scan(&result))
+		return result
+	}
+	// the L1 user txs are forged, the deposit is delivered when it gets
+	// forged
+	_, err = db.Exec(`UPDATE tx SET batch_num = 6758 WHERE to_forge_l1_txs_num = 1;`)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, countL1Deliveries("CreateAccountDeposit"))
+	assert.Equal(t, 1, countL1Deliveries("Deposit"))
+	// the tx that creates the account is delivered once the account is
+	// set, and only once
+	_, err = db.Exec(`UPDATE tx SET effective_from_idx = 789 WHERE to_forge_l1_txs_num = 1;
+	UPDATE tx SET effective_from_idx = 789 WHERE to_forge_l1_txs_num = 1;`)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, countL1Deliveries("CreateAccountDeposit"))
+	assert.Equal(t, 1, countL1Deliveries("Deposit"))
+}
+
+func (m migrationTest0012) RunAssertsAfterMigrationDown(t *testing.T, db *sqlx.DB) {
+	// check that the tables don't exist anymore
+	var result int
+	row := db.QueryRow(`SELECT COUNT(*) FROM webhook;`)
+	assert.Equal(t, `pq: relation "webhook" does not exist`, row.Scan(&result).Error())
+	row = db.QueryRow(`SELECT COUNT(*) FROM webhook_delivery;`)
+	assert.Equal(t, `pq: relation "webhook_delivery" does not exist`, row.Scan(&result).Error())
+	// check that pool txs can still be updated without the triggers
+	_, err := db.Exec(`UPDATE tx_pool SET state = 'fged' WHERE from_idx = 789;`)
+	assert.NoError(t, err)
+}
+
+func TestMigration0012(t *testing.T) {
+	runMigrationTest(t, 12, migrationTest0012{})
+}
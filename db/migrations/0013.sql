@@ -0,0 +1,31 @@
+-- +migrate Up
+CREATE TABLE api_key (
+    item_id SERIAL PRIMARY KEY,
+    -- Only the SHA-256 hash of the key is stored
+    key_hash BYTEA UNIQUE NOT NULL,
+    name VARCHAR NOT NULL,
+    coordinator_scope BOOLEAN NOT NULL DEFAULT false,
+    explorer_scope BOOLEAN NOT NULL DEFAULT false,
+    rate_limit NUMERIC NOT NULL, -- Requests per second
+    burst INT NOT NULL,
+    daily_tx_quota INT, -- Unlimited if NULL
+    revoked BOOLEAN NOT NULL DEFAULT false,
+    timestamp TIMESTAMP WITHOUT TIME ZONE DEFAULT timezone('utc', now())
+);
+
+CREATE TABLE api_key_tx_count (
+    api_key_id INT NOT NULL REFERENCES api_key (item_id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    tx_count INT NOT NULL,
+    PRIMARY KEY (api_key_id, day)
+);
+
+-- The webhooks are registered with an API key, which limits the number of
+-- webhooks of each client
+ALTER TABLE webhook ADD COLUMN api_key_id INT REFERENCES api_key (item_id) ON DELETE CASCADE;
+CREATE INDEX webhook_api_key_id ON webhook (api_key_id);
+
+-- +migrate Down
+ALTER TABLE webhook DROP COLUMN IF EXISTS api_key_id;
+DROP TABLE IF EXISTS api_key_tx_count;
+DROP TABLE IF EXISTS api_key;
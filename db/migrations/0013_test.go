@@ -0,0 +1,70 @@
+package migrations_test
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+// This migration adds the `api_key` and `api_key_tx_count` tables, and the
+// API key that registered each webhook
+
+type migrationTest0013 struct{}
+
+func (m migrationTest0013) InsertData(db *sqlx.DB) error {
+	_, err := db.Exec(`INSERT INTO webhook (url, secret, idx) VALUES ('https://example.com/hook', 'secret', 789);`)
+	return err
+}
+
+func (m migrationTest0013) RunAssertsAfterMigrationUp(t *testing.T, db *sqlx.DB) {
+	// insert a key and count its txs
+	_, err := db.Exec(`INSERT INTO api_key (
+		key_hash, name, coordinator_scope, rate_limit, burst, daily_tx_quota
+	) VALUES (
+		decode('2C26B46B68FFC68FF99B453C1D30413413422D706483BFA0F98A5E886266E7AE', 'hex'), 'partner', true, 10.5, 20, 1000
+	);
+	INSERT INTO api_key_tx_count (api_key_id, day, tx_count)
+	SELECT item_id, '2021-04-17', 1 FROM api_key;`)
+	assert.NoError(t, err)
+	row := db.QueryRow(`SELECT COUNT(*) FROM api_key WHERE
+		name = 'partner' AND coordinator_scope AND NOT explorer_scope AND NOT revoked AND
+		rate_limit = 10.5 AND burst = 20 AND daily_tx_quota = 1000;`)
+	var result int
+	assert.NoError(t, row.Scan(&result))
+	assert.Equal(t, 1, result)
+	// the hash of the keys is unique
+	_, err = db.Exec(`INSERT INTO api_key (
+		key_hash, name, rate_limit, burst
+	) VALUES (
+		decode('2C26B46B68FFC68FF99B453C1D30413413422D706483BFA0F98A5E886266E7AE', 'hex'), 'other', 1, 1
+	);`)
+	assert.Error(t, err)
+	// the existing webhooks have no API key, and are removed with the API
+	// key that registered them
+	row = db.QueryRow(`SELECT COUNT(*) FROM webhook WHERE api_key_id IS NULL;`)
+	assert.NoError(t, row.Scan(&result))
+	assert.Equal(t, 1, result)
+	_, err = db.Exec(`UPDATE webhook SET api_key_id = (SELECT item_id FROM api_key);
+	DELETE FROM api_key;`)
+	assert.NoError(t, err)
+	row = db.QueryRow(`SELECT COUNT(*) FROM webhook;`)
+	assert.NoError(t, row.Scan(&result))
+	assert.Equal(t, 0, result)
+}
+
+func (m migrationTest0013) RunAssertsAfterMigrationDown(t *testing.T, db *sqlx.DB) {
+	// check that the tables don't exist anymore
+	var result int
+	row := db.QueryRow(`SELECT COUNT(*) FROM api_key;`)
+	assert.Equal(t, `pq: relation "api_key" does not exist`, row.Scan(&result).Error())
+	row = db.QueryRow(`SELECT COUNT(*) FROM api_key_tx_count;`)
+	assert.Equal(t, `pq: relation "api_key_tx_count" does not exist`, row.Scan(&result).Error())
+	// check that the webhooks don't have an API key anymore
+	row = db.QueryRow(`SELECT COUNT(api_key_id) FROM webhook;`)
+	assert.Equal(t, `pq: column "api_key_id" does not exist`, row.Scan(&result).Error())
+}
+
+func TestMigration0013(t *testing.T) {
+	runMigrationTest(t, 13, migrationTest0013{})
+}
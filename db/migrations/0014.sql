@@ -0,0 +1,6 @@
+-- +migrate Up
+-- The balance history of an account is read from its updates
+CREATE INDEX account_update_idx_batch_num ON account_update (idx, batch_num);
+
+-- +migrate Down
+DROP INDEX IF EXISTS account_update_idx_batch_num;
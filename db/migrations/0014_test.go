@@ -0,0 +1,37 @@
+package migrations_test
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+// This migration adds the `account_update_idx_batch_num` index on
+// `account_update`
+
+type migrationTest0014 struct{}
+
+func (m migrationTest0014) InsertData(db *sqlx.DB) error {
+	return nil
+}
+
+func (m migrationTest0014) RunAssertsAfterMigrationUp(t *testing.T, db *sqlx.DB) {
+	row := db.QueryRow(`SELECT COUNT(*) FROM pg_indexes WHERE
+		tablename = 'account_update' AND indexname = 'account_update_idx_batch_num';`)
+	var result int
+	assert.NoError(t, row.Scan(&result))
+	assert.Equal(t, 1, result)
+}
+
+func (m migrationTest0014) RunAssertsAfterMigrationDown(t *testing.T, db *sqlx.DB) {
+	row := db.QueryRow(`SELECT COUNT(*) FROM pg_indexes WHERE
+		tablename = 'account_update' AND indexname = 'account_update_idx_batch_num';`)
+	var result int
+	assert.NoError(t, row.Scan(&result))
+	assert.Equal(t, 0, result)
+}
+
+func TestMigration0014(t *testing.T) {
+	runMigrationTest(t, 14, migrationTest0014{})
+}
@@ -0,0 +1,43 @@
+-- +migrate Up
+-- The state changes of the pool txs are kept while the tx is in the pool,
+-- so that its lifecycle can be followed.  They are purged with the tx.
+CREATE TABLE tx_pool_state_change (
+    item_id SERIAL PRIMARY KEY,
+    tx_id BYTEA NOT NULL REFERENCES tx_pool (tx_id) ON DELETE CASCADE,
+    state CHAR(4) NOT NULL,
+    info VARCHAR,
+    batch_num BIGINT,
+    timestamp TIMESTAMP WITHOUT TIME ZONE DEFAULT timezone('utc', now())
+);
+
+CREATE INDEX tx_pool_state_change_tx_id ON tx_pool_state_change (tx_id);
+
+INSERT INTO tx_pool_state_change (tx_id, state, info, batch_num, timestamp)
+SELECT tx_id, state, info, batch_num, timestamp FROM tx_pool;
+
+-- +migrate StatementBegin
+CREATE FUNCTION add_pool_tx_state_change()
+    RETURNS TRIGGER
+AS
+$BODY$
+BEGIN
+    -- Only changes of state or error are recorded.  The info of the pending
+    -- txs is rewritten at every batch, so its changes alone are not recorded.
+    IF TG_OP = 'UPDATE' AND OLD.state = NEW.state AND
+        OLD.error_code IS NOT DISTINCT FROM NEW.error_code THEN
+        RETURN NULL;
+    END IF;
+    INSERT INTO tx_pool_state_change (tx_id, state, info, batch_num)
+    VALUES (NEW.tx_id, NEW.state, NEW.info, NEW.batch_num);
+    RETURN NULL;
+END;
+$BODY$
+LANGUAGE plpgsql;
+-- +migrate StatementEnd
+CREATE TRIGGER trigger_add_pool_tx_state_change AFTER INSERT OR UPDATE OF state, error_code ON tx_pool
+FOR EACH ROW EXECUTE PROCEDURE add_pool_tx_state_change();
+
+-- +migrate Down
+DROP TRIGGER IF EXISTS trigger_add_pool_tx_state_change ON tx_pool;
+DROP FUNCTION IF EXISTS add_pool_tx_state_change();
+DROP TABLE IF EXISTS tx_pool_state_change;
@@ -0,0 +1,9 @@
+-- +migrate Up
+-- error_details holds the structured details of the error_code and
+-- error_type of the txs that were not selected
+ALTER TABLE tx_pool
+ADD COLUMN error_details JSONB;
+
+-- +migrate Down
+ALTER TABLE tx_pool
+DROP COLUMN error_details;
@@ -0,0 +1,27 @@
+-- +migrate Up
+-- The explorer lists can be filtered by time (joined through block) and by
+-- block, and sorted by value
+CREATE INDEX block_timestamp ON block (timestamp);
+CREATE INDEX tx_eth_block_num ON tx (eth_block_num);
+CREATE INDEX tx_amount ON tx (amount, item_id);
+CREATE INDEX tx_amount_usd ON tx ((COALESCE(amount_usd, 0)), item_id);
+CREATE INDEX tx_fee_usd ON tx ((COALESCE(fee_usd, 0)), item_id);
+CREATE INDEX batch_eth_block_num ON batch (eth_block_num);
+CREATE INDEX batch_total_fees_usd ON batch ((COALESCE(total_fees_usd, 0)), item_id);
+CREATE INDEX exit_tree_batch_num ON exit_tree (batch_num);
+CREATE INDEX exit_tree_balance ON exit_tree (balance, item_id);
+CREATE INDEX bid_eth_block_num ON bid (eth_block_num);
+CREATE INDEX bid_bid_value ON bid (bid_value, item_id);
+
+-- +migrate Down
+DROP INDEX IF EXISTS block_timestamp;
+DROP INDEX IF EXISTS tx_eth_block_num;
+DROP INDEX IF EXISTS tx_amount;
+DROP INDEX IF EXISTS tx_amount_usd;
+DROP INDEX IF EXISTS tx_fee_usd;
+DROP INDEX IF EXISTS batch_eth_block_num;
+DROP INDEX IF EXISTS batch_total_fees_usd;
+DROP INDEX IF EXISTS exit_tree_batch_num;
+DROP INDEX IF EXISTS exit_tree_balance;
+DROP INDEX IF EXISTS bid_eth_block_num;
+DROP INDEX IF EXISTS bid_bid_value;
@@ -0,0 +1,40 @@
+package migrations_test
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+// This migration adds the indexes used to filter the explorer lists by time
+// and block, and to sort them by value
+
+type migrationTest0017 struct{}
+
+const migration0017IndexesQuery = `SELECT COUNT(*) FROM pg_indexes WHERE indexname IN (
+	'block_timestamp', 'tx_eth_block_num', 'tx_amount', 'tx_amount_usd', 'tx_fee_usd',
+	'batch_eth_block_num', 'batch_total_fees_usd', 'exit_tree_batch_num',
+	'exit_tree_balance', 'bid_eth_block_num', 'bid_bid_value');`
+
+func (m migrationTest0017) InsertData(db *sqlx.DB) error {
+	return nil
+}
+
+func (m migrationTest0017) RunAssertsAfterMigrationUp(t *testing.T, db *sqlx.DB) {
+	row := db.QueryRow(migration0017IndexesQuery)
+	var result int
+	assert.NoError(t, row.Scan(&result))
+	assert.Equal(t, 11, result)
+}
+
+func (m migrationTest0017) RunAssertsAfterMigrationDown(t *testing.T, db *sqlx.DB) {
+	row := db.QueryRow(migration0017IndexesQuery)
+	var result int
+	assert.NoError(t, row.Scan(&result))
+	assert.Equal(t, 0, result)
+}
+
+func TestMigration0017(t *testing.T) {
+	runMigrationTest(t, 17, migrationTest0017{})
+}
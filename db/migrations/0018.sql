@@ -0,0 +1,198 @@
+-- +migrate Up
+-- The USD price of the tokens is recorded when it changes, so that the txs can
+-- be valued at the price of the time of their block.  Since the prices are
+-- updated every few seconds, a change is only recorded if the last recorded
+-- price of the token is at least 10 minutes old or differs by at least 1%,
+-- which keeps the history at around 150 rows per token and day
+CREATE TABLE token_price_history (
+    item_id SERIAL PRIMARY KEY,
+    token_id INT NOT NULL REFERENCES token (token_id) ON DELETE CASCADE,
+    usd NUMERIC NOT NULL, -- value of a normalized token (1 token = 10^decimals units)
+    timestamp TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+CREATE INDEX token_price_history_token_id_timestamp ON token_price_history (token_id, timestamp);
+
+INSERT INTO token_price_history (token_id, usd, timestamp)
+    SELECT token_id, usd, COALESCE(usd_update, timezone('utc', now())) FROM token WHERE usd IS NOT NULL;
+
+-- +migrate StatementBegin
+CREATE FUNCTION add_token_price_history()
+    RETURNS TRIGGER
+AS
+$BODY$
+DECLARE
+    _timestamp TIMESTAMP;
+    _last_usd NUMERIC;
+    _last_timestamp TIMESTAMP;
+BEGIN
+    IF NEW."usd" IS NULL OR (tg_op = 'UPDATE' AND NEW."usd" IS NOT DISTINCT FROM OLD."usd") THEN
+        RETURN NULL;
+    END IF;
+    _timestamp = COALESCE(NEW."usd_update", timezone('utc', now()));
+    SELECT INTO _last_usd, _last_timestamp usd, timestamp FROM token_price_history
+        WHERE token_id = NEW."token_id" ORDER BY timestamp DESC LIMIT 1;
+    IF _last_timestamp IS NULL OR _timestamp - _last_timestamp >= interval '10 minutes' OR
+        _last_usd = 0 OR ABS(NEW."usd" - _last_usd) >= _last_usd * 0.01 THEN
+        INSERT INTO token_price_history (token_id, usd, timestamp)
+            VALUES (NEW."token_id", NEW."usd", _timestamp);
+    END IF;
+    RETURN NULL;
+END;
+$BODY$
+LANGUAGE plpgsql;
+-- +migrate StatementEnd
+CREATE TRIGGER trigger_token_price_history AFTER UPDATE OR INSERT ON token
+FOR EACH ROW EXECUTE PROCEDURE add_token_price_history();
+
+-- token_usd_at returns the price of the token closest to the timestamp, or
+-- NULL if there's no price for the token
+-- +migrate StatementBegin
+CREATE FUNCTION token_usd_at(_token_id INT, _timestamp TIMESTAMP)
+    RETURNS NUMERIC
+AS
+$BODY$
+DECLARE
+    _before_usd NUMERIC;
+    _before_timestamp TIMESTAMP;
+    _after_usd NUMERIC;
+    _after_timestamp TIMESTAMP;
+BEGIN
+    SELECT INTO _before_usd, _before_timestamp usd, timestamp FROM token_price_history
+        WHERE token_id = _token_id AND timestamp <= _timestamp ORDER BY timestamp DESC LIMIT 1;
+    SELECT INTO _after_usd, _after_timestamp usd, timestamp FROM token_price_history
+        WHERE token_id = _token_id AND timestamp > _timestamp ORDER BY timestamp ASC LIMIT 1;
+    IF _after_timestamp IS NULL OR
+        (_before_timestamp IS NOT NULL AND _timestamp - _before_timestamp <= _after_timestamp - _timestamp) THEN
+        RETURN _before_usd;
+    END IF;
+    RETURN _after_usd;
+END;
+$BODY$
+LANGUAGE plpgsql;
+-- +migrate StatementEnd
+
+-- +migrate StatementBegin
+CREATE OR REPLACE FUNCTION set_tx()
+    RETURNS TRIGGER
+AS
+$BODY$
+DECLARE
+	_value NUMERIC;
+	_usd_update TIMESTAMP;
+    _tx_timestamp TIMESTAMP;
+BEGIN
+    IF NEW.is_l1  THEN
+        -- Validate L1 Tx
+        IF NEW.user_origin IS NULL OR
+        NEW.from_eth_addr IS NULL OR
+        NEW.from_bjj IS NULL OR
+        NEW.deposit_amount IS NULL OR
+        NEW.deposit_amount_f IS NULL OR
+        (NOT NEW.user_origin AND NEW.batch_num IS NULL)  THEN -- If is Coordinator L1, must include batch_num
+            RAISE EXCEPTION 'Invalid L1 tx: %', NEW;
+        END IF;
+    ELSE
+        -- Validate L2 Tx
+        IF NEW.batch_num IS NULL OR NEW.nonce IS NULL THEN
+            RAISE EXCEPTION 'Invalid L2 tx: %', NEW;
+        END IF;
+        -- Set fee if it's null
+        IF NEW.fee IS NULL THEN
+            NEW.fee = (SELECT 0);
+        END IF;
+        -- Set token_id
+        NEW."token_id" = (SELECT token_id FROM account WHERE idx = NEW."from_idx");
+        -- Set from_{eth_addr,bjj}
+        SELECT INTO NEW."from_eth_addr", NEW."from_bjj" eth_addr, bjj FROM account WHERE idx = NEW.from_idx;
+    END IF;
+    -- Set USD related, using the price closest to the block of the tx
+    SELECT INTO _value, _usd_update, _tx_timestamp
+        COALESCE(token_usd_at(token.token_id, block.timestamp), usd) / POWER(10, decimals), usd_update, block.timestamp
+        FROM token INNER JOIN block ON block.eth_block_num = NEW.eth_block_num WHERE token_id = NEW.token_id;
+    IF _usd_update - interval '24 hours' < _usd_update AND _usd_update + interval '24 hours' > _usd_update THEN
+        IF _value > 0.0 THEN
+            IF NEW."amount_f" > 0.0 THEN
+                NEW."amount_usd" = (SELECT _value * NEW."amount_f");
+                IF NOT NEW."is_l1" AND NEW."fee" > 0 THEN
+                    NEW."fee_usd" = (SELECT NEW."amount_usd" * fee_percentage(NEW.fee::NUMERIC));
+                END IF;
+            END IF;
+            IF NEW."is_l1" AND NEW."deposit_amount_f" > 0.0 THEN
+                NEW."deposit_amount_usd" = (SELECT _value * NEW.deposit_amount_f);
+            END IF;
+        END IF;
+    END IF;
+    -- Set to_{eth_addr,bjj}
+    IF NEW."to_idx" > 255 THEN
+        SELECT INTO NEW."to_eth_addr", NEW."to_bjj" eth_addr, bjj FROM account WHERE idx = NEW."to_idx";
+    END IF;
+    RETURN NEW;
+END;
+$BODY$
+LANGUAGE plpgsql;
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+CREATE OR REPLACE FUNCTION set_tx()
+    RETURNS TRIGGER
+AS
+$BODY$
+DECLARE
+	_value NUMERIC;
+	_usd_update TIMESTAMP;
+    _tx_timestamp TIMESTAMP;
+BEGIN
+    IF NEW.is_l1  THEN
+        -- Validate L1 Tx
+        IF NEW.user_origin IS NULL OR
+        NEW.from_eth_addr IS NULL OR
+        NEW.from_bjj IS NULL OR
+        NEW.deposit_amount IS NULL OR
+        NEW.deposit_amount_f IS NULL OR
+        (NOT NEW.user_origin AND NEW.batch_num IS NULL)  THEN -- If is Coordinator L1, must include batch_num
+            RAISE EXCEPTION 'Invalid L1 tx: %', NEW;
+        END IF;
+    ELSE
+        -- Validate L2 Tx
+        IF NEW.batch_num IS NULL OR NEW.nonce IS NULL THEN
+            RAISE EXCEPTION 'Invalid L2 tx: %', NEW;
+        END IF;
+        -- Set fee if it's null
+        IF NEW.fee IS NULL THEN
+            NEW.fee = (SELECT 0);
+        END IF;
+        -- Set token_id
+        NEW."token_id" = (SELECT token_id FROM account WHERE idx = NEW."from_idx");
+        -- Set from_{eth_addr,bjj}
+        SELECT INTO NEW."from_eth_addr", NEW."from_bjj" eth_addr, bjj FROM account WHERE idx = NEW.from_idx;
+    END IF;
+    -- Set USD related
+    SELECT INTO _value, _usd_update, _tx_timestamp
+        usd / POWER(10, decimals), usd_update, timestamp FROM token INNER JOIN block on token.eth_block_num = block.eth_block_num WHERE token_id = NEW.token_id;
+    IF _usd_update - interval '24 hours' < _usd_update AND _usd_update + interval '24 hours' > _usd_update THEN
+        IF _value > 0.0 THEN
+            IF NEW."amount_f" > 0.0 THEN
+                NEW."amount_usd" = (SELECT _value * NEW."amount_f");
+                IF NOT NEW."is_l1" AND NEW."fee" > 0 THEN
+                    NEW."fee_usd" = (SELECT NEW."amount_usd" * fee_percentage(NEW.fee::NUMERIC));
+                END IF;
+            END IF;
+            IF NEW."is_l1" AND NEW."deposit_amount_f" > 0.0 THEN
+                NEW."deposit_amount_usd" = (SELECT _value * NEW.deposit_amount_f);
+            END IF;
+        END IF;
+    END IF;
+    -- Set to_{eth_addr,bjj}
+    IF NEW."to_idx" > 255 THEN
+        SELECT INTO NEW."to_eth_addr", NEW."to_bjj" eth_addr, bjj FROM account WHERE idx = NEW."to_idx";
+    END IF;
+    RETURN NEW;
+END;
+$BODY$
+LANGUAGE plpgsql;
+-- +migrate StatementEnd
+DROP FUNCTION IF EXISTS token_usd_at;
+DROP TRIGGER IF EXISTS trigger_token_price_history ON token;
+DROP FUNCTION IF EXISTS add_token_price_history;
+DROP TABLE IF EXISTS token_price_history;
@@ -0,0 +1,86 @@
+package migrations_test
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+// This migration adds the `token_price_history` table, filled by the
+// `trigger_token_price_history` trigger on `token` with the price changes
+// that cross a time or percentage threshold, and the `token_usd_at`
+// function used by `set_tx` to value the txs at the price of their block
+
+type migrationTest0018 struct{}
+
+func (m migrationTest0018) InsertData(db *sqlx.DB) error {
+	// insert block to respect the FKey of token
+	const queryInsertBlock = `INSERT INTO block (
+		eth_block_num,"timestamp",hash
+	) VALUES (
+		4417296,'2021-03-10 16:44:06.000',decode('C4D46677F3B2511D96389521C2BDFFE91127DE214423FF14899A6177631D2105','hex')
+	);`
+	// insert a token with a price, which is the first price of its history
+	const queryInsertToken = `INSERT INTO "token" (
+		token_id,eth_block_num,eth_addr,"name",symbol,decimals,usd,usd_update
+	) VALUES (
+		2,4417296,decode('1B36A4DED4DF40248C0E0E52CEA5EDC9A298B721','hex'),'Dai Stablecoin','DAI',18,1.01,'2021-04-17 20:21:16.870'
+	);`
+	_, err := db.Exec(queryInsertBlock + queryInsertToken)
+	return err
+}
+
+func (m migrationTest0018) RunAssertsAfterMigrationUp(t *testing.T, db *sqlx.DB) {
+	// the current price is added to the history
+	row := db.QueryRow(`SELECT COUNT(*) FROM token_price_history WHERE token_id = 2;`)
+	var result int
+	assert.NoError(t, row.Scan(&result))
+	assert.Equal(t, 1, result)
+	// changes of the price are added with the time of the update, other
+	// updates are not
+	_, err := db.Exec(`UPDATE token SET usd = 1.02 WHERE token_id = 2;
+	UPDATE token SET name = 'Dai' WHERE token_id = 2;`)
+	assert.NoError(t, err)
+	row = db.QueryRow(`SELECT COUNT(*) FROM token_price_history WHERE token_id = 2;`)
+	assert.NoError(t, row.Scan(&result))
+	assert.Equal(t, 2, result)
+	// changes of less than 1% within 10 minutes of the last recorded price
+	// are not added
+	_, err = db.Exec(`UPDATE token SET usd = 1.025 WHERE token_id = 2;`)
+	assert.NoError(t, err)
+	row = db.QueryRow(`SELECT COUNT(*) FROM token_price_history WHERE token_id = 2;`)
+	assert.NoError(t, row.Scan(&result))
+	assert.Equal(t, 2, result)
+	_, err = db.Exec(`UPDATE token SET usd = 1.04 WHERE token_id = 2;`)
+	assert.NoError(t, err)
+	row = db.QueryRow(`SELECT COUNT(*) FROM token_price_history WHERE token_id = 2;`)
+	assert.NoError(t, row.Scan(&result))
+	assert.Equal(t, 3, result)
+	// the closest price is used
+	var usd float64
+	row = db.QueryRow(`SELECT token_usd_at(2, '2021-04-17 00:00:00');`)
+	assert.NoError(t, row.Scan(&usd))
+	assert.Equal(t, 1.01, usd)
+	row = db.QueryRow(`SELECT token_usd_at(2, '2021-04-18 10:00:00');`)
+	assert.NoError(t, row.Scan(&usd))
+	assert.Equal(t, 1.01, usd)
+	row = db.QueryRow(`SELECT token_usd_at(2, timezone('utc', now())::TIMESTAMP);`)
+	assert.NoError(t, row.Scan(&usd))
+	assert.Equal(t, 1.04, usd)
+	var noPrice *float64
+	row = db.QueryRow(`SELECT token_usd_at(3, '2021-04-18 10:00:00');`)
+	assert.NoError(t, row.Scan(&noPrice))
+	assert.Nil(t, noPrice)
+}
+
+func (m migrationTest0018) RunAssertsAfterMigrationDown(t *testing.T, db *sqlx.DB) {
+	// check that the table doesn't exist anymore
+	var result int
+	row := db.QueryRow(`SELECT COUNT(*) FROM token_price_history;`)
+	assert.Equal(t, `pq: relation "token_price_history" does not exist`, row.Scan(&result).Error())
+}
+
+func TestMigration0018(t *testing.T) {
+	runMigrationTest(t, 18, migrationTest0018{})
+}
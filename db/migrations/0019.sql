@@ -0,0 +1,16 @@
+-- +migrate Up
+-- The forge calls sent by the TxManager of the coordinator are stored until
+-- they are confirmed, so that they are still checked after a restart
+CREATE TABLE tx_manager_batch (
+    batch_num BIGINT PRIMARY KEY,
+    l1_batch BOOLEAN NOT NULL,
+    nonce BIGINT NOT NULL, -- nonce of the last eth tx
+    status VARCHAR(10) NOT NULL,
+    resend_num INT NOT NULL DEFAULT 0,
+    eth_txs BYTEA NOT NULL, -- json array of the sent eth txs
+    send_timestamp TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    send_block_num BIGINT NOT NULL
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS tx_manager_batch;
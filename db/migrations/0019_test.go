@@ -0,0 +1,42 @@
+package migrations_test
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+// This migration adds the `tx_manager_batch` table, where the TxManager stores
+// the forge calls sent to ethereum until they are confirmed
+
+type migrationTest0019 struct{}
+
+func (m migrationTest0019) InsertData(db *sqlx.DB) error {
+	return nil
+}
+
+func (m migrationTest0019) RunAssertsAfterMigrationUp(t *testing.T, db *sqlx.DB) {
+	// the sent forge calls can be stored
+	_, err := db.Exec(`INSERT INTO tx_manager_batch (
+		batch_num, l1_batch, nonce, status, eth_txs, send_timestamp, send_block_num
+	) VALUES (
+		6758, true, 12, 'sent', '[]', '2021-04-17 20:21:16.870', 4417296
+	);`)
+	assert.NoError(t, err)
+	row := db.QueryRow(`SELECT resend_num FROM tx_manager_batch WHERE batch_num = 6758;`)
+	var resendNum int
+	assert.NoError(t, row.Scan(&resendNum))
+	assert.Equal(t, 0, resendNum)
+}
+
+func (m migrationTest0019) RunAssertsAfterMigrationDown(t *testing.T, db *sqlx.DB) {
+	// check that the table doesn't exist anymore
+	var result int
+	row := db.QueryRow(`SELECT COUNT(*) FROM tx_manager_batch;`)
+	assert.Equal(t, `pq: relation "tx_manager_batch" does not exist`, row.Scan(&result).Error())
+}
+
+func TestMigration0019(t *testing.T) {
+	runMigrationTest(t, 19, migrationTest0019{})
+}
@@ -481,6 +481,39 @@ func (s *StateDB) MTGetProof(idx common.Idx) (*merkletree.CircomVerifierProof, e
 	return p, nil
 }
 
+// CheckpointMTGetProof returns the Account and its CircomVerifierProof for a
+// given Idx at the checkpoint of the given batchNum.  The proof is generated
+// against the root of the Merkle Tree at that checkpoint.
+func (s *StateDB) CheckpointMTGetProof(batchNum common.BatchNum, idx common.Idx) (
+	*common.Account, *merkletree.CircomVerifierProof, error) {
+	if s.MT == nil {
+		return nil, nil, tracerr.Wrap(ErrStateDBWithoutMT)
+	}
+	var account *common.Account
+	var proof *merkletree.CircomVerifierProof
+	if err := s.db.CheckpointRead(batchNum, func(sto *pebble.Storage) error {
+		mt, err := merkletree.NewMerkleTree(sto.WithPrefix(PrefixKeyMT), s.cfg.NLevels)
+		if err != nil {
+			return tracerr.Wrap(err)
+		}
+		account, err = GetAccountInTreeDB(sto, idx)
+		if err != nil {
+			return tracerr.Wrap(err)
+		}
+		proof, err = mt.GenerateSCVerifierProof(idx.BigInt(), mt.Root())
+		return tracerr.Wrap(err)
+	}); err != nil {
+		return nil, nil, tracerr.Wrap(err)
+	}
+	return account, proof, nil
+}
+
+// CheckpointExists returns true if the checkpoint of the given batchNum
+// exists
+func (s *StateDB) CheckpointExists(batchNum common.BatchNum) (bool, error) {
+	return s.db.CheckpointExists(batchNum)
+}
+
 // Close the StateDB
 func (s *StateDB) Close() {
 	s.db.Close()
@@ -304,6 +304,60 @@ func TestStateDBWithMT(t *testing.T) {
 	sdb.Close()
 }
 
+func TestCheckpointMTGetProof(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tmpdb")
+	require.NoError(t, err)
+	deleteme = append(deleteme, dir)
+
+	sdb, err := NewStateDB(Config{Path: dir, Keep: 128, Type: TypeSynchronizer, NLevels: 32})
+	require.NoError(t, err)
+
+	// create the accounts and checkpoint them at batch 1
+	var accounts []*common.Account
+	for i := 0; i < 4; i++ {
+		accounts = append(accounts, newAccount(t, i))
+		_, err = sdb.CreateAccount(accounts[i].Idx, accounts[i])
+		require.NoError(t, err)
+	}
+	err = sdb.MakeCheckpoint()
+	require.NoError(t, err)
+	rootBatch1 := sdb.MT.Root()
+	proofBatch1, err := sdb.MTGetProof(accounts[0].Idx)
+	require.NoError(t, err)
+
+	// update an account and checkpoint it at batch 2
+	updatedAccount := *accounts[0]
+	updatedAccount.Balance = big.NewInt(2000)
+	_, err = sdb.UpdateAccount(updatedAccount.Idx, &updatedAccount)
+	require.NoError(t, err)
+	err = sdb.MakeCheckpoint()
+	require.NoError(t, err)
+	assert.NotEqual(t, rootBatch1, sdb.MT.Root())
+
+	// the proof at batch 1 is the one of the old state
+	account, proof, err := sdb.CheckpointMTGetProof(common.BatchNum(1), accounts[0].Idx)
+	require.NoError(t, err)
+	assert.Equal(t, accounts[0], account)
+	assert.Equal(t, rootBatch1, proof.Root)
+	assert.Equal(t, proofBatch1, proof)
+
+	// the proof at batch 2 is the one of the current state
+	account, proof, err = sdb.CheckpointMTGetProof(common.BatchNum(2), accounts[0].Idx)
+	require.NoError(t, err)
+	assert.Equal(t, &updatedAccount, account)
+	assert.Equal(t, sdb.MT.Root(), proof.Root)
+
+	// the temporary copies are removed and not listed as checkpoints
+	list, err := sdb.db.ListCheckpoints()
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, list)
+
+	_, _, err = sdb.CheckpointMTGetProof(common.BatchNum(3), accounts[0].Idx)
+	assert.NotNil(t, err)
+
+	sdb.Close()
+}
+
 // TestCheckpoints performs almost the same test than kvdb/kvdb_test.go
 // TestCheckpoints, but over the StateDB
 func TestCheckpoints(t *testing.T) {
@@ -22,9 +22,7 @@ import (
 	"github.com/chainbing/node/log"
 	"github.com/chainbing/tracerr"
 	"github.com/jmoiron/sqlx"
-
-	//nolint:errcheck // driver for postgres DB
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	migrate "github.com/rubenv/sql-migrate"
 	"github.com/russross/meddler"
 	"golang.org/x/sync/semaphore"
@@ -35,6 +33,10 @@ const (
 	OrderAsc = "ASC"
 	// OrderDesc indicates descending order when using pagination
 	OrderDesc = "DESC"
+	// EventsChannel is the PostgreSQL notification channel in which the
+	// triggers defined in the migrations publish the new events (batches,
+	// bids, blocks and pool tx state changes)
+	EventsChannel = "chainbing_events"
 )
 
 var migrations *migrate.PackrMigrationSource
@@ -84,7 +86,15 @@ func ConnectSQLDB(port int, host, user, password, name string) (*sqlx.DB, error)
 	initMeddler()
 	meddler.Default = meddler.PostgreSQL
 	// Stablish connection
-	psqlconn := fmt.Sprintf(
+	db, err := sqlx.Connect("postgres", psqlConnString(port, host, user, password, name))
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	return db, nil
+}
+
+func psqlConnString(port int, host, user, password, name string) string {
+	return fmt.Sprintf(
 		"host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
 		host,
 		port,
@@ -92,11 +102,28 @@ func ConnectSQLDB(port int, host, user, password, name string) (*sqlx.DB, error)
 		password,
 		name,
 	)
-	db, err := sqlx.Connect("postgres", psqlconn)
-	if err != nil {
+}
+
+// NewSQLListener opens a dedicated connection to the SQL DB that listens to
+// the notifications published in the EventsChannel.  The listener reconnects
+// automatically, waiting between minReconnect and maxReconnect between
+// attempts.  It must only be opened when API.Stream is enabled, since the
+// events are only consumed by the /stream endpoint.
+func NewSQLListener(port int, host, user, password, name string,
+	minReconnect, maxReconnect time.Duration) (*pq.Listener, error) {
+	listener := pq.NewListener(psqlConnString(port, host, user, password, name),
+		minReconnect, maxReconnect, func(ev pq.ListenerEventType, err error) {
+			if err != nil {
+				log.Warnw("SQL listener event", "event", ev, "err", err)
+			}
+		})
+	if err := listener.Listen(EventsChannel); err != nil {
+		if closeErr := listener.Close(); closeErr != nil {
+			log.Errorw("SQL listener close", "err", closeErr)
+		}
 		return nil, tracerr.Wrap(err)
 	}
-	return db, nil
+	return listener, nil
 }
 
 // InitSQLDB runs migrations and registers meddlers
@@ -0,0 +1,117 @@
+package eth
+
+import (
+	"fmt"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/chainbing/node/common"
+	"github.com/chainbing/node/common/apitypes"
+	"github.com/chainbing/tracerr"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+)
+
+// DataAvailabilityL1CoordinatorTx is a L1 coordinator tx published in the
+// calldata of a forgeBatch call
+type DataAvailabilityL1CoordinatorTx struct {
+	TokenID     common.TokenID        `json:"tokenId"`
+	FromBJJ     babyjub.PublicKeyComp `json:"fromBJJ"`
+	FromEthAddr ethCommon.Address     `json:"fromEthereumAddress"`
+}
+
+// DataAvailabilityL2Tx is a L2 tx published in the data availability section
+// of the calldata of a forgeBatch call
+type DataAvailabilityL2Tx struct {
+	FromIdx common.Idx         `json:"fromAccountIndex"`
+	ToIdx   common.Idx         `json:"toAccountIndex"`
+	Amount  apitypes.BigIntStr `json:"amount"`
+	Fee     common.FeeSelector `json:"fee"`
+}
+
+// ForgeBatchDataAvailability is the data of a batch published on chain in the
+// calldata of its forgeBatch call
+type ForgeBatchDataAvailability struct {
+	NewLastIdx       int64                             `json:"newLastIdx"`
+	NewStateRoot     apitypes.BigIntStr                `json:"newStateRoot"`
+	NewExitRoot      apitypes.BigIntStr                `json:"newExitRoot"`
+	VerifierIdx      uint8                             `json:"verifierIdx"`
+	NLevels          int64                             `json:"nLevels"`
+	L1Batch          bool                              `json:"l1Batch"`
+	L1UserTxsLen     int                               `json:"l1UserTxsLen"`
+	L1CoordinatorTxs []DataAvailabilityL1CoordinatorTx `json:"l1CoordinatorTxs"`
+	L2Txs            []DataAvailabilityL2Tx            `json:"l2Txs"`
+}
+
+// NewForgeBatchDataAvailability builds the data availability of a batch from
+// the arguments of its forgeBatch call, as decoded by
+// RollupClient.RollupForgeBatchArgs, which skips the l1UserTxsLen L1 user txs
+// at the beginning of the L1 and L2 txs data.  The NLevels of the verifier
+// used in the batch is taken from consts.
+func NewForgeBatchDataAvailability(args *RollupForgeBatchArgs, l1UserTxsLen int,
+	consts *common.RollupConstants) (*ForgeBatchDataAvailability, error) {
+	if int(args.VerifierIdx) >= len(consts.Verifiers) {
+		return nil, tracerr.Wrap(fmt.Errorf("unknown verifierIdx %v", args.VerifierIdx))
+	}
+	da := &ForgeBatchDataAvailability{
+		NewLastIdx:       args.NewLastIdx,
+		NewStateRoot:     *apitypes.NewBigIntStr(args.NewStRoot),
+		NewExitRoot:      *apitypes.NewBigIntStr(args.NewExitRoot),
+		VerifierIdx:      args.VerifierIdx,
+		NLevels:          consts.Verifiers[args.VerifierIdx].NLevels,
+		L1Batch:          args.L1Batch,
+		L1UserTxsLen:     l1UserTxsLen,
+		L1CoordinatorTxs: make([]DataAvailabilityL1CoordinatorTx, 0, len(args.L1CoordinatorTxs)),
+		L2Txs:            make([]DataAvailabilityL2Tx, 0, len(args.L2TxsData)),
+	}
+	for _, l1Tx := range args.L1CoordinatorTxs {
+		da.L1CoordinatorTxs = append(da.L1CoordinatorTxs, DataAvailabilityL1CoordinatorTx{
+			TokenID:     l1Tx.TokenID,
+			FromBJJ:     l1Tx.FromBJJ,
+			FromEthAddr: l1Tx.FromEthAddr,
+		})
+	}
+	for _, l2Tx := range args.L2TxsData {
+		da.L2Txs = append(da.L2Txs, DataAvailabilityL2Tx{
+			FromIdx: l2Tx.FromIdx,
+			ToIdx:   l2Tx.ToIdx,
+			Amount:  *apitypes.NewBigIntStr(l2Tx.Amount),
+			Fee:     l2Tx.Fee,
+		})
+	}
+	return da, nil
+}
+
+// Mismatches returns a description of every difference between the data
+// availability and the txs that are believed to be forged in the batch
+func (da *ForgeBatchDataAvailability) Mismatches(l1CoordinatorTxs []common.L1Tx,
+	l2Txs []common.L2Tx) []string {
+	mismatches := []string{}
+	if len(l1CoordinatorTxs) != len(da.L1CoordinatorTxs) {
+		mismatches = append(mismatches, fmt.Sprintf("expected %v L1 coordinator txs, got %v on chain",
+			len(l1CoordinatorTxs), len(da.L1CoordinatorTxs)))
+	}
+	for i := 0; i < len(l1CoordinatorTxs) && i < len(da.L1CoordinatorTxs); i++ {
+		expected, onChain := l1CoordinatorTxs[i], da.L1CoordinatorTxs[i]
+		if expected.TokenID != onChain.TokenID || expected.FromBJJ != onChain.FromBJJ ||
+			expected.FromEthAddr != onChain.FromEthAddr {
+			mismatches = append(mismatches, fmt.Sprintf("L1 coordinator tx %v (%v): expected "+
+				"tokenId %v, bjj %v and ethAddr %v, got %v, %v and %v on chain", i, expected.TxID,
+				expected.TokenID, expected.FromBJJ, expected.FromEthAddr.Hex(),
+				onChain.TokenID, onChain.FromBJJ, onChain.FromEthAddr.Hex()))
+		}
+	}
+	if len(l2Txs) != len(da.L2Txs) {
+		mismatches = append(mismatches, fmt.Sprintf("expected %v L2 txs, got %v on chain",
+			len(l2Txs), len(da.L2Txs)))
+	}
+	for i := 0; i < len(l2Txs) && i < len(da.L2Txs); i++ {
+		expected, onChain := l2Txs[i], da.L2Txs[i]
+		if expected.FromIdx != onChain.FromIdx || expected.ToIdx != onChain.ToIdx ||
+			expected.Amount.String() != string(onChain.Amount) || expected.Fee != onChain.Fee {
+			mismatches = append(mismatches, fmt.Sprintf("L2 tx %v (%v): expected "+
+				"%v -> %v, amount %v and fee %v, got %v -> %v, %v and %v on chain", i, expected.TxID,
+				expected.FromIdx, expected.ToIdx, expected.Amount, expected.Fee,
+				onChain.FromIdx, onChain.ToIdx, onChain.Amount, onChain.Fee))
+		}
+	}
+	return mismatches
+}
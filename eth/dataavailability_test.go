@@ -0,0 +1,58 @@
+package eth
+
+import (
+	"math/big"
+	"testing"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/chainbing/node/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewForgeBatchDataAvailability(t *testing.T) {
+	const nLevels = 32
+	consts := &common.RollupConstants{
+		Verifiers: []common.RollupVerifierStruct{{MaxTx: 376, NLevels: nLevels}},
+	}
+	l1CoordinatorTxs := []common.L1Tx{
+		{TokenID: 1, FromEthAddr: ethCommon.HexToAddress("0x1234")},
+	}
+	l2Txs := []common.L2Tx{
+		{FromIdx: 256, ToIdx: 257, Amount: big.NewInt(1000), Fee: 126},
+		{FromIdx: 257, ToIdx: 1, Amount: big.NewInt(20), Fee: 0},
+	}
+	args := &RollupForgeBatchArgs{
+		NewLastIdx:       300,
+		NewStRoot:        big.NewInt(1),
+		NewExitRoot:      big.NewInt(2),
+		L1CoordinatorTxs: l1CoordinatorTxs,
+		L2TxsData:        l2Txs,
+		VerifierIdx:      0,
+		L1Batch:          true,
+	}
+
+	da, err := NewForgeBatchDataAvailability(args, 1, consts)
+	require.NoError(t, err)
+	assert.Equal(t, int64(300), da.NewLastIdx)
+	assert.Equal(t, "1", string(da.NewStateRoot))
+	assert.Equal(t, int64(nLevels), da.NLevels)
+	assert.True(t, da.L1Batch)
+	assert.Equal(t, 1, da.L1UserTxsLen)
+	require.Equal(t, 1, len(da.L1CoordinatorTxs))
+	assert.Equal(t, l1CoordinatorTxs[0].FromEthAddr, da.L1CoordinatorTxs[0].FromEthAddr)
+	require.Equal(t, 2, len(da.L2Txs))
+	assert.Equal(t, common.Idx(257), da.L2Txs[1].FromIdx)
+	assert.Equal(t, "1000", string(da.L2Txs[0].Amount))
+	assert.Empty(t, da.Mismatches(l1CoordinatorTxs, l2Txs))
+
+	// A different fee, a different token and a missing tx are reported
+	l2Txs[0].Fee = 127
+	l1CoordinatorTxs[0].TokenID = 2
+	assert.Equal(t, 3, len(da.Mismatches(l1CoordinatorTxs, l2Txs[:1])))
+
+	// An unknown verifier is an error
+	args.VerifierIdx = 1
+	_, err = NewForgeBatchDataAvailability(args, 1, consts)
+	assert.Error(t, err)
+}
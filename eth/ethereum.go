@@ -40,8 +40,12 @@ type EthereumInterface interface {
 	EthPendingNonceAt(ctx context.Context, account ethCommon.Address) (uint64, error)
 	EthNonceAt(ctx context.Context, account ethCommon.Address, blockNumber *big.Int) (uint64, error)
 	EthSuggestGasPrice(ctx context.Context) (*big.Int, error)
+	EthSuggestGasTipCap(ctx context.Context) (*big.Int, error)
+	EthFeeHistory(ctx context.Context, blockCount uint64,
+		rewardPercentiles []float64) (*ethereum.FeeHistory, error)
 	EthKeyStore() *ethKeystore.KeyStore
 	EthCall(ctx context.Context, tx *types.Transaction, blockNum *big.Int) ([]byte, error)
+	EthSendTransaction(ctx context.Context, tx *types.Transaction) error
 }
 
 var (
@@ -136,18 +140,43 @@ func (c *EthereumClient) EthSuggestGasPrice(ctx context.Context) (gasPrice *big.
 		err = fmt.Errorf("[EthSuggestGasPrice]. Error getting head: %s", err.Error())
 		return
 	}
+	baseFee := head.BaseFee
+	if baseFee == nil {
+		// Before London there's no base fee, so the legacy gas price
+		// is used
+		gasPrice, err = c.client.SuggestGasPrice(ctx)
+		if err != nil {
+			err = fmt.Errorf("[EthSuggestGasPrice]. Error getting gas price: %s", err.Error())
+		}
+		return
+	}
 	var tip *big.Int
 	tip, err = c.client.SuggestGasTipCap(ctx)
 	if err != nil {
 		err = fmt.Errorf("[EthSuggestGasPrice]. Error getting tip: %s", err.Error())
 		return
 	}
-	baseFee := head.BaseFee
 	gasPrice = new(big.Int).Add(baseFee, tip)
 	log.Debugw("Suggested Gas Price:", "tip", tip, "baseFee", baseFee, "gasPrice", gasPrice)
 	return
 }
 
+// EthSuggestGasTipCap retrieves the currently suggested priority fee
+// (maxPriorityFeePerGas) to allow a timely execution of a transaction.
+func (c *EthereumClient) EthSuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	gasTipCap, err := c.client.SuggestGasTipCap(ctx)
+	return gasTipCap, tracerr.Wrap(err)
+}
+
+// EthFeeHistory returns the base fee of the last blockCount blocks and of the
+// next one, and the priority fees paid in those blocks at rewardPercentiles.
+// Before London the base fees are 0.
+func (c *EthereumClient) EthFeeHistory(ctx context.Context, blockCount uint64,
+	rewardPercentiles []float64) (*ethereum.FeeHistory, error) {
+	feeHistory, err := c.client.FeeHistory(ctx, blockCount, nil, rewardPercentiles)
+	return feeHistory, tracerr.Wrap(err)
+}
+
 // EthKeyStore returns the keystore in the EthereumClient
 func (c *EthereumClient) EthKeyStore() *ethKeystore.KeyStore {
 	return c.ks
@@ -349,3 +378,8 @@ func (c *EthereumClient) EthCall(ctx context.Context, tx *types.Transaction,
 	result, err := c.client.CallContract(ctx, msg, blockNum)
 	return result, tracerr.Wrap(err)
 }
+
+// EthSendTransaction sends a signed transaction to the ethereum node
+func (c *EthereumClient) EthSendTransaction(ctx context.Context, tx *types.Transaction) error {
+	return tracerr.Wrap(c.client.SendTransaction(ctx, tx))
+}
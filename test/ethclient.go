@@ -121,10 +121,11 @@ func (a *AuctionBlock) getMinBidBySlot(slot int64) (*big.Int, error) {
 	slotSet := a.getSlotSet(slot)
 	// fmt.Println("slot:", slot, "slotSet:", slotSet)
 	var prevBid *big.Int
+	// The slot state isn't stored so that the min bid can be read from the
+	// current block
 	slotState, ok := a.State.Slots[slot]
 	if !ok {
 		slotState = eth.NewSlotState()
-		a.State.Slots[slot] = slotState
 	}
 	// If the bidAmount for a slot is 0 it means that it has not yet been
 	// bid, so the midBid will be the minimum bid for the slot time plus
@@ -390,6 +391,15 @@ type Client struct {
 	forgeBatchArgsPending map[ethCommon.Hash]*batch
 	forgeBatchArgs        map[ethCommon.Hash]*batch
 
+	// baseFee is the base fee of the blocks, nil if London is not active
+	baseFee *big.Int
+	// gasTipCap is the priority fee paid in the blocks
+	gasTipCap *big.Int
+	// lastForgeBatchAuth are the options of the last forgeBatch call
+	lastForgeBatchAuth *bind.TransactOpts
+	// lastSentTx is the last transaction sent with EthSendTransaction
+	lastSentTx *types.Transaction
+
 	startBlock int64
 }
 
@@ -536,6 +546,31 @@ func (c *Client) CtlSetAddr(addr ethCommon.Address) {
 	c.addr = &addr
 }
 
+// CtlSetGasFees sets the base fee and the priority fee of the blocks.  A nil
+// baseFee simulates a chain without London, which doesn't accept dynamic fee
+// transactions.
+func (c *Client) CtlSetGasFees(baseFee, gasTipCap *big.Int) {
+	c.rw.Lock()
+	defer c.rw.Unlock()
+	c.baseFee = baseFee
+	c.gasTipCap = gasTipCap
+}
+
+// CtlLastForgeBatchAuth returns the transaction options of the last
+// successful forgeBatch call
+func (c *Client) CtlLastForgeBatchAuth() *bind.TransactOpts {
+	c.rw.RLock()
+	defer c.rw.RUnlock()
+	return c.lastForgeBatchAuth
+}
+
+// CtlLastSentTx returns the last transaction sent with EthSendTransaction
+func (c *Client) CtlLastSentTx() *types.Transaction {
+	c.rw.RLock()
+	defer c.rw.RUnlock()
+	return c.lastSentTx
+}
+
 // CtlMineBlock moves one block forward
 func (c *Client) CtlMineBlock() {
 	c.rw.Lock()
@@ -624,8 +659,63 @@ func (c *Client) EthNonceAt(ctx context.Context, account ethCommon.Address,
 // EthSuggestGasPrice retrieves the currently suggested gas price to allow a
 // timely execution of a transaction.
 func (c *Client) EthSuggestGasPrice(ctx context.Context) (*big.Int, error) {
-	// NOTE: For now Client doesn't simulate gasPrice
-	return big.NewInt(0), nil
+	c.rw.RLock()
+	defer c.rw.RUnlock()
+	if c.baseFee == nil {
+		// NOTE: For now Client doesn't simulate legacy gasPrice
+		return big.NewInt(0), nil
+	}
+	return new(big.Int).Add(c.baseFee, c.suggestedGasTipCap()), nil
+}
+
+// EthSuggestGasTipCap retrieves the currently suggested priority fee to allow
+// a timely execution of a transaction.
+func (c *Client) EthSuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	c.rw.RLock()
+	defer c.rw.RUnlock()
+	if c.baseFee == nil {
+		return nil, tracerr.Wrap(fmt.Errorf("method eth_maxPriorityFeePerGas not supported"))
+	}
+	return c.suggestedGasTipCap(), nil
+}
+
+func (c *Client) suggestedGasTipCap() *big.Int {
+	if c.gasTipCap == nil {
+		return big.NewInt(0)
+	}
+	return new(big.Int).Set(c.gasTipCap)
+}
+
+// EthFeeHistory returns the base fee of the last blockCount blocks and of the
+// next one, and the priority fees paid in those blocks at rewardPercentiles.
+// All the blocks have the fees set with CtlSetGasFees, and before London the
+// base fees are 0.
+func (c *Client) EthFeeHistory(ctx context.Context, blockCount uint64,
+	rewardPercentiles []float64) (*ethereum.FeeHistory, error) {
+	c.rw.RLock()
+	defer c.rw.RUnlock()
+	if blockCount > uint64(c.blockNum+1) {
+		blockCount = uint64(c.blockNum + 1)
+	}
+	baseFee := big.NewInt(0)
+	if c.baseFee != nil {
+		baseFee = c.baseFee
+	}
+	feeHistory := &ethereum.FeeHistory{
+		OldestBlock: big.NewInt(c.blockNum + 1 - int64(blockCount)),
+	}
+	for i := uint64(0); i < blockCount; i++ {
+		reward := make([]*big.Int, len(rewardPercentiles))
+		for j := range reward {
+			reward[j] = c.suggestedGasTipCap()
+		}
+		feeHistory.Reward = append(feeHistory.Reward, reward)
+		feeHistory.BaseFee = append(feeHistory.BaseFee, new(big.Int).Set(baseFee))
+		feeHistory.GasUsedRatio = append(feeHistory.GasUsedRatio, 0.5) //nolint:gomnd
+	}
+	// Base fee of the next block
+	feeHistory.BaseFee = append(feeHistory.BaseFee, new(big.Int).Set(baseFee))
+	return feeHistory, nil
 }
 
 // EthKeyStore returns the keystore in the Client
@@ -640,6 +730,19 @@ func (c *Client) EthCall(ctx context.Context, tx *types.Transaction,
 	return nil, tracerr.Wrap(common.ErrTODO)
 }
 
+// EthSendTransaction adds a signed transaction to the next block.  The
+// transaction is not applied to the smart contracts.
+func (c *Client) EthSendTransaction(ctx context.Context, tx *types.Transaction) error {
+	c.rw.Lock()
+	defer c.rw.Unlock()
+	if tx.Type() == types.DynamicFeeTxType && c.baseFee == nil {
+		return tracerr.Wrap(types.ErrTxTypeNotSupported)
+	}
+	c.nextBlock().Rollup.addTransaction(tx)
+	c.lastSentTx = tx
+	return nil
+}
+
 // EthLastBlock returns the last blockNum
 func (c *Client) EthLastBlock() (int64, error) {
 	c.rw.RLock()
@@ -954,6 +1057,18 @@ func (c *Client) RollupForgeBatch(args *eth.RollupForgeBatchArgs,
 	if c.addr == nil {
 		return nil, tracerr.Wrap(eth.ErrAccountNil)
 	}
+	if auth != nil && auth.GasFeeCap != nil {
+		if c.baseFee == nil {
+			return nil, tracerr.Wrap(types.ErrTxTypeNotSupported)
+		}
+		if auth.GasFeeCap.Cmp(c.baseFee) < 0 {
+			return nil, tracerr.Wrap(fmt.Errorf("max fee per gas less than block base fee: "+
+				"maxFeePerGas: %v baseFee: %v", auth.GasFeeCap, c.baseFee))
+		}
+		if auth.GasTipCap == nil || auth.GasTipCap.Cmp(auth.GasFeeCap) > 0 {
+			return nil, tracerr.Wrap(fmt.Errorf("max priority fee per gas higher than max fee per gas"))
+		}
+	}
 
 	a := c.nextBlock().Auction
 	ok, err := a.canForge(*c.addr, a.Eth.BlockNum)
@@ -977,7 +1092,11 @@ func (c *Client) RollupForgeBatch(args *eth.RollupForgeBatchArgs,
 	// TODO: Add method to move the tx to another block, reapply it there, and possibly go from
 	// successful to failed.
 
-	return c.addBatch(args)
+	if tx, err = c.addBatch(args); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	c.lastForgeBatchAuth = auth
+	return tx, nil
 }
 
 // CtlAddBatch adds forged batch to the Rollup, without checking any ZKProof
@@ -1218,8 +1337,9 @@ func (c *Client) AuctionGetOpenAuctionSlots() (uint16, error) {
 	c.rw.RLock()
 	defer c.rw.RUnlock()
 
-	log.Error("TODO")
-	return 0, tracerr.Wrap(errTODO)
+	currentBlock := c.currentBlock()
+	a := currentBlock.Auction
+	return a.Vars.OpenAuctionSlots, nil
 }
 
 // AuctionSetClosedAuctionSlots is the interface to call the smart contract function
@@ -1242,8 +1362,9 @@ func (c *Client) AuctionGetClosedAuctionSlots() (uint16, error) {
 	c.rw.RLock()
 	defer c.rw.RUnlock()
 
-	log.Error("TODO")
-	return 0, tracerr.Wrap(errTODO)
+	currentBlock := c.currentBlock()
+	a := currentBlock.Auction
+	return a.Vars.ClosedAuctionSlots, nil
 }
 
 // AuctionSetOutbidding is the interface to call the smart contract function
@@ -1265,8 +1386,9 @@ func (c *Client) AuctionGetOutbidding() (uint16, error) {
 	c.rw.RLock()
 	defer c.rw.RUnlock()
 
-	log.Error("TODO")
-	return 0, tracerr.Wrap(errTODO)
+	currentBlock := c.currentBlock()
+	a := currentBlock.Auction
+	return a.Vars.Outbidding, nil
 }
 
 // AuctionSetAllocationRatio is the interface to call the smart contract function
@@ -1432,8 +1554,9 @@ func (c *Client) AuctionGetCurrentSlotNumber() (int64, error) {
 	c.rw.RLock()
 	defer c.rw.RUnlock()
 
-	log.Error("TODO")
-	return 0, tracerr.Wrap(errTODO)
+	currentBlock := c.currentBlock()
+	a := currentBlock.Auction
+	return a.getCurrentSlotNumber(), nil
 }
 
 // AuctionGetMinBidBySlot is the interface to call the smart contract function
@@ -1441,8 +1564,9 @@ func (c *Client) AuctionGetMinBidBySlot(slot int64) (*big.Int, error) {
 	c.rw.RLock()
 	defer c.rw.RUnlock()
 
-	log.Error("TODO")
-	return nil, tracerr.Wrap(errTODO)
+	currentBlock := c.currentBlock()
+	a := currentBlock.Auction
+	return a.getMinBidBySlot(slot)
 }
 
 // AuctionGetDefaultSlotSetBid is the interface to call the smart contract function
@@ -64,8 +64,11 @@ package txselector
 
 import (
 	"fmt"
+	"io/ioutil"
 	"math/big"
+	"os"
 	"sort"
+	"sync"
 
 	ethCommon "github.com/ethereum/go-ethereum/common"
 	"github.com/chainbing/node/api"
@@ -94,6 +97,9 @@ type CoordAccount struct {
 type TxSelector struct {
 	l2db            *l2db.L2DB
 	localAccountsDB *statedb.LocalStateDB
+	// rw protects the checkpoints of localAccountsDB, which are reset
+	// and made by the selections and copied by the simulations
+	rw sync.Mutex
 
 	coordAccount *CoordAccount
 }
@@ -128,6 +134,8 @@ func (txsel *TxSelector) LocalAccountsDB() *statedb.LocalStateDB {
 // Reset tells the TxSelector to get it's internal AccountsDB
 // from the required `batchNum`
 func (txsel *TxSelector) Reset(batchNum common.BatchNum, fromSynchronizer bool) error {
+	txsel.rw.Lock()
+	defer txsel.rw.Unlock()
 	return tracerr.Wrap(txsel.localAccountsDB.Reset(batchNum, fromSynchronizer))
 }
 
@@ -222,6 +230,8 @@ type failedAtomicGroup struct {
 func (txsel *TxSelector) getL1L2TxSelection(selectionConfig txprocessor.Config,
 	l1UserTxs, l1UserFutureTxs []common.L1Tx) ([]common.Idx, [][]byte, []common.L1Tx,
 	[]common.L1Tx, []common.PoolL2Tx, []common.PoolL2Tx, error) {
+	txsel.rw.Lock()
+	defer txsel.rw.Unlock()
 	// WIP.0: the TxSelector is not optimized and will need a redesign. The
 	// current version is implemented in order to have a functional
 	// implementation that can be used ASAP.
@@ -419,6 +429,85 @@ START_SELECTION:
 	return coordIdxs, accAuths, l1UserTxs, l1CoordinatorTxs, selectedTxs, discardedTxs, nil
 }
 
+// SimulateL2Tx runs the given PoolL2Tx through the same checks done when
+// selecting the txs of the next batch, over a throwaway copy of the current
+// state of the TxSelector LocalStateDB.  Neither the L2DB nor the state of the
+// TxSelector are modified.  It returns nil if the tx would be selected, or the
+// TxSelectorError with the reason why it would not.  The tx is simulated on
+// its own, so the rest of pending txs of the pool and the L1UserFutureTxs are
+// not taken into account.  The TxSelector is only locked while its current
+// checkpoint is copied, so the simulation doesn't block the selections.
+func (txsel *TxSelector) SimulateL2Tx(selectionConfig txprocessor.Config,
+	tx common.PoolL2Tx) (*common.TxSelectorError, error) {
+	dbPath, err := ioutil.TempDir("", "txselSimulate")
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dbPath); err != nil {
+			log.Errorw("TxSelector.SimulateL2Tx: os.RemoveAll", "path", dbPath, "err", err)
+		}
+	}()
+	localAccountsDB, err := txsel.copyLocalAccountsDB(dbPath)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	defer localAccountsDB.Close()
+
+	simTxsel := &TxSelector{
+		l2db:            txsel.l2db,
+		localAccountsDB: localAccountsDB,
+		coordAccount:    txsel.coordAccount,
+	}
+	tp := txprocessor.NewTxProcessor(localAccountsDB.StateDB, selectionConfig)
+	tp.AccumulatedFees = make(map[common.Idx]*big.Int)
+	_, _, selectedTxs, nonSelectedTxs, unforjableTxs, failedAG, err := simTxsel.processL2Txs(
+		tp, selectionConfig, 0, 0, nil, []common.PoolL2Tx{tx})
+	if failedAG.id != common.EmptyAtomicGroupID {
+		return &failedAG.reason, nil
+	}
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	if len(selectedTxs) == 1 {
+		return nil, nil
+	}
+	discardedTxs := append(nonSelectedTxs, unforjableTxs...)
+	if len(discardedTxs) != 1 {
+		return nil, tracerr.Wrap(fmt.Errorf("unexpected simulation result: %d selected txs, "+
+			"%d discarded txs", len(selectedTxs), len(discardedTxs)))
+	}
+	return &common.TxSelectorError{
+		Message: discardedTxs[0].Info,
+		Code:    discardedTxs[0].ErrorCode,
+		Type:    discardedTxs[0].ErrorType,
+		Details: discardedTxs[0].ErrorDetails,
+	}, nil
+}
+
+// copyLocalAccountsDB returns a LocalStateDB in dbPath with a copy of the
+// current checkpoint of the TxSelector LocalStateDB
+func (txsel *TxSelector) copyLocalAccountsDB(dbPath string) (*statedb.LocalStateDB, error) {
+	txsel.rw.Lock()
+	defer txsel.rw.Unlock()
+	localAccountsDB, err := statedb.NewLocalStateDB(
+		statedb.Config{
+			Path:    dbPath,
+			Keep:    kvdb.DefaultKeep,
+			Type:    statedb.TypeTxSelector,
+			NLevels: 0,
+		},
+		txsel.localAccountsDB.StateDB) // copy from the TxSelector LocalStateDB
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	if err := localAccountsDB.Reset(txsel.localAccountsDB.CurrentBatch(), true); err != nil {
+		localAccountsDB.Close()
+		return nil, tracerr.Wrap(err)
+	}
+	return localAccountsDB, nil
+}
+
 func (txsel *TxSelector) processL2Txs(
 	tp *txprocessor.TxProcessor,
 	selectionConfig txprocessor.Config,
@@ -478,6 +567,7 @@ func (txsel *TxSelector) processL2Txs(
 				l2Txs[i].Info = obj.Message
 				l2Txs[i].ErrorCode = obj.Code
 				l2Txs[i].ErrorType = obj.Type
+				l2Txs[i].ErrorDetails = obj.Details
 				nonSelectedL2Txs = append(nonSelectedL2Txs, l2Txs[j])
 			}
 			break
@@ -485,10 +575,15 @@ func (txsel *TxSelector) processL2Txs(
 
 		// Reject tx if the batch that is being selected is greater than MaxNumBatch
 		if l2Txs[i].MaxNumBatch != 0 && nextBatchNum > l2Txs[i].MaxNumBatch {
+			maxNumBatch, batchNum := l2Txs[i].MaxNumBatch, common.BatchNum(nextBatchNum)
 			obj := common.TxSelectorError{
 				Message: ErrUnsupportedMaxNumBatch,
 				Code:    ErrUnsupportedMaxNumBatchCode,
 				Type:    ErrUnsupportedMaxNumBatchType,
+				Details: &common.TxSelectorErrorDetails{
+					MaxNumBatch: &maxNumBatch,
+					BatchNum:    &batchNum,
+				},
 			}
 			// If tx is atomic, restart process without txs from the atomic group
 			if l2Txs[i].AtomicGroupID != common.EmptyAtomicGroupID {
@@ -505,6 +600,7 @@ func (txsel *TxSelector) processL2Txs(
 			l2Txs[i].Info = obj.Message
 			l2Txs[i].ErrorCode = obj.Code
 			l2Txs[i].ErrorType = obj.Type
+			l2Txs[i].ErrorDetails = obj.Details
 			// Tx won't be forjable since the current batch num won't go backwards
 			unforjableL2Txs = append(unforjableL2Txs, l2Txs[i])
 			continue
@@ -532,6 +628,7 @@ func (txsel *TxSelector) processL2Txs(
 			l2Txs[i].Info = obj.Message
 			l2Txs[i].ErrorCode = obj.Code
 			l2Txs[i].ErrorType = obj.Type
+			l2Txs[i].ErrorDetails = obj.Details
 			// Although tecnicaly forjable, it won't never get forged with current code
 			unforjableL2Txs = append(unforjableL2Txs, l2Txs[i])
 			continue
@@ -552,6 +649,10 @@ func (txsel *TxSelector) processL2Txs(
 					balance.String(), feeAndAmount.String()),
 				Code: ErrSenderNotEnoughBalanceCode,
 				Type: ErrSenderNotEnoughBalanceType,
+				Details: &common.TxSelectorErrorDetails{
+					RequiredBalance:  stringPtr(feeAndAmount.String()),
+					AvailableBalance: stringPtr(balance.String()),
+				},
 			}
 			// If tx is atomic, restart process without txs from the atomic group
 			if l2Txs[i].AtomicGroupID != common.EmptyAtomicGroupID {
@@ -571,16 +672,22 @@ func (txsel *TxSelector) processL2Txs(
 			l2Txs[i].Info = obj.Message
 			l2Txs[i].ErrorCode = obj.Code
 			l2Txs[i].ErrorType = obj.Type
+			l2Txs[i].ErrorDetails = obj.Details
 			nonSelectedL2Txs = append(nonSelectedL2Txs, l2Txs[i])
 			continue
 		}
 
 		// Check if Nonce is correct
 		if l2Txs[i].Nonce != accSender.Nonce {
+			expectedNonce, providedNonce := accSender.Nonce, l2Txs[i].Nonce
 			obj := common.TxSelectorError{
 				Message: fmt.Sprintf(ErrNoCurrentNonce+"Tx.Nonce: %d, Account.Nonce: %d", l2Txs[i].Nonce, accSender.Nonce),
 				Code:    ErrNoCurrentNonceCode,
 				Type:    ErrNoCurrentNonceType,
+				Details: &common.TxSelectorErrorDetails{
+					ExpectedNonce: &expectedNonce,
+					ProvidedNonce: &providedNonce,
+				},
 			}
 			// If tx is atomic, restart process without txs from the atomic group
 			if l2Txs[i].AtomicGroupID != common.EmptyAtomicGroupID {
@@ -600,6 +707,7 @@ func (txsel *TxSelector) processL2Txs(
 			l2Txs[i].Info = obj.Message
 			l2Txs[i].ErrorCode = obj.Code
 			l2Txs[i].ErrorType = obj.Type
+			l2Txs[i].ErrorDetails = obj.Details
 			nonSelectedL2Txs = append(nonSelectedL2Txs, l2Txs[i])
 			continue
 		}
@@ -644,6 +752,7 @@ func (txsel *TxSelector) processL2Txs(
 				l2Txs[i].Info = obj.Message
 				l2Txs[i].ErrorCode = obj.Code
 				l2Txs[i].ErrorType = obj.Type
+				l2Txs[i].ErrorDetails = obj.Details
 				nonSelectedL2Txs = append(nonSelectedL2Txs, l2Txs[i])
 				continue
 			}
@@ -682,6 +791,9 @@ func (txsel *TxSelector) processL2Txs(
 					Message: fmt.Sprintf(ErrTxDiscartedInProcessTxToEthAddrBJJ+" due to %s", err.Error()),
 					Code:    ErrTxDiscartedInProcessTxToEthAddrBJJCode,
 					Type:    ErrTxDiscartedInProcessTxToEthAddrBJJType,
+					Details: &common.TxSelectorErrorDetails{
+						Cause: stringPtr(err.Error()),
+					},
 				}
 				log.Debugw("txsel.processTxToEthAddrBJJ", "err", err)
 				// If tx is atomic, restart process without txs from the atomic group
@@ -701,6 +813,7 @@ func (txsel *TxSelector) processL2Txs(
 				l2Txs[i].Info = obj.Message
 				l2Txs[i].ErrorCode = obj.Code
 				l2Txs[i].ErrorType = obj.Type
+				l2Txs[i].ErrorDetails = obj.Details
 				nonSelectedL2Txs = append(nonSelectedL2Txs, l2Txs[i])
 				continue
 			}
@@ -737,6 +850,7 @@ func (txsel *TxSelector) processL2Txs(
 						Message: l2Txs[i].Info,
 						Code:    l2Txs[i].ErrorCode,
 						Type:    l2Txs[i].ErrorType,
+						Details: l2Txs[i].ErrorDetails,
 					}
 					failedAG = failedAtomicGroup{
 						id:         l2Txs[i].AtomicGroupID,
@@ -754,10 +868,14 @@ func (txsel *TxSelector) processL2Txs(
 		} else if l2Txs[i].ToIdx >= common.IdxUserThreshold {
 			_, err := txsel.localAccountsDB.GetAccount(l2Txs[i].ToIdx)
 			if err != nil {
+				toIdx := l2Txs[i].ToIdx
 				obj := common.TxSelectorError{
 					Message: fmt.Sprintf(ErrToIdxNotFound+"ToIdx: %d", l2Txs[i].ToIdx),
 					Code:    ErrToIdxNotFoundCode,
 					Type:    ErrToIdxNotFoundType,
+					Details: &common.TxSelectorErrorDetails{
+						ToIdx: &toIdx,
+					},
 				}
 				// tx not valid
 				log.Debugw("invalid L2Tx: ToIdx not found in StateDB",
@@ -779,6 +897,7 @@ func (txsel *TxSelector) processL2Txs(
 				l2Txs[i].Info = obj.Message
 				l2Txs[i].ErrorCode = obj.Code
 				l2Txs[i].ErrorType = obj.Type
+				l2Txs[i].ErrorDetails = obj.Details
 				nonSelectedL2Txs = append(nonSelectedL2Txs, l2Txs[i])
 				continue
 			}
@@ -810,6 +929,9 @@ func (txsel *TxSelector) processL2Txs(
 				Message: fmt.Sprintf(ErrTxDiscartedInProcessL2Tx+" due to %s", err.Error()),
 				Code:    ErrTxDiscartedInProcessL2TxCode,
 				Type:    ErrTxDiscartedInProcessL2TxType,
+				Details: &common.TxSelectorErrorDetails{
+					Cause: stringPtr(err.Error()),
+				},
 			}
 			log.Debugw("txselector.getL1L2TxSelection at ProcessL2Tx", "err", err)
 			// If tx is atomic, restart process without txs from the atomic group
@@ -829,6 +951,7 @@ func (txsel *TxSelector) processL2Txs(
 			l2Txs[i].Info = obj.Message
 			l2Txs[i].ErrorCode = obj.Code
 			l2Txs[i].ErrorType = obj.Type
+			l2Txs[i].ErrorDetails = obj.Details
 			nonSelectedL2Txs = append(nonSelectedL2Txs, l2Txs[i])
 			continue
 		}
@@ -1113,6 +1236,7 @@ func filterFailedAtomicGroups(
 				txs[i].Info = obj.Message
 				txs[i].ErrorCode = obj.Code
 				txs[i].ErrorType = obj.Type
+				txs[i].ErrorDetails = obj.Details
 				filteredTxs = append(filteredTxs, txs[i])
 				txFailed = true
 				break
@@ -1150,13 +1274,17 @@ func filterInvalidAtomicGroups(
 		}
 	}
 	// Validate atomic groups
-	for _, atomicGroup := range atomicGroups {
+	for atomicGroupID, atomicGroup := range atomicGroups {
 		if !isAtomicGroupValid(atomicGroup) {
+			atomicGroupID := atomicGroupID
 			// Set Info message and add txs of the atomic group to filteredTxs
 			for i := 0; i < len(atomicGroup.Txs); i++ {
 				atomicGroup.Txs[i].Info = ErrInvalidAtomicGroup
 				atomicGroup.Txs[i].ErrorType = ErrInvalidAtomicGroupType
 				atomicGroup.Txs[i].ErrorCode = ErrInvalidAtomicGroupCode
+				atomicGroup.Txs[i].ErrorDetails = &common.TxSelectorErrorDetails{
+					AtomicGroupID: &atomicGroupID,
+				}
 				filteredTxs = append(filteredTxs, atomicGroup.Txs[i])
 			}
 		} else {
@@ -1242,16 +1370,28 @@ func setInfoForFailedAtomicTx(
 	failMessage common.TxSelectorError,
 ) common.TxSelectorError {
 	if isOriginOfFailure {
+		// Keep the details of the failure, identifying the group
+		details := common.TxSelectorErrorDetails{}
+		if failMessage.Details != nil {
+			details = *failMessage.Details
+		}
+		details.AtomicGroupID = &failedAtomicGroupID
+		details.FailedTxID = &failedTxID
 		obj := common.TxSelectorError{
 			Message: fmt.Sprintf("unselectable atomic group"+" %s, tx %s failed due to: %s",
 				failedAtomicGroupID,
 				failedTxID,
 				failMessage.Message,
 			),
-			Code: failMessage.Code,
-			Type: failMessage.Type,
+			Code:    failMessage.Code,
+			Type:    failMessage.Type,
+			Details: &details,
 		}
 		return obj
 	}
 	return common.TxSelectorError{}
 }
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -42,7 +42,7 @@ func initTest(t *testing.T, chainID uint16, chainbingContractAddr ethCommon.Addr
 	coordUser *til.User) (*TxSelector, *historydb.HistoryDB, *statedb.StateDB) {
 	db, err := dbUtils.InitTestSQLDB()
 	require.NoError(t, err)
-	l2DB := l2db.NewL2DB(db, db, 10, 100, 0.0, 1000.0, 24*time.Hour, nil)
+	l2DB := l2db.NewL2DB(db, db, 10, 100, 0.0, 1000.0, 0.0, 24*time.Hour, nil)
 
 	historyDB := historydb.NewHistoryDB(db, db, nil)
 
@@ -749,6 +749,93 @@ func TestPoolL2TxInvalidNonces(t *testing.T) {
 	txsel.LocalAccountsDB().Close()
 }
 
+func TestSimulateL2Tx(t *testing.T) {
+	set := `
+		Type: Blockchain
+
+		CreateAccountDeposit(0) Coord: 0
+		CreateAccountDeposit(0) A: 100000
+		CreateAccountDeposit(0) B: 10000
+
+		> batchL1 // Batch1: freeze L1User{3}
+		> batchL1 // Batch2: forge L1User{3}
+		> block
+	`
+
+	chainID := uint16(0)
+	tc := til.NewContext(chainID, common.RollupConstMaxL1UserTx)
+	blocks, err := tc.GenerateBlocks(set)
+	assert.NoError(t, err)
+
+	chainbingContractAddr := ethCommon.HexToAddress("0xc344E203a046Da13b0B4467EB7B3629D0C99F6E6")
+	txsel, _, stateDB := initTest(t, chainID, chainbingContractAddr, tc.Users["Coord"])
+
+	tc.RestartNonces()
+
+	tpc := txprocessor.Config{
+		NLevels:  16,
+		MaxFeeTx: 10,
+		MaxTx:    20,
+		MaxL1Tx:  10,
+		ChainID:  chainID,
+	}
+	// batch1 to freeze L1UserTxs
+	_, _, _, _, _, _, err = txsel.GetL1L2TxSelection(tpc, []common.L1Tx{}, nil)
+	require.NoError(t, err)
+	// batch2 to create the accounts
+	l1UserTxs := til.L1TxsToCommonL1Txs(tc.Queues[*blocks[0].Rollup.Batches[1].Batch.ForgeL1TxsNum])
+	_, _, _, _, _, _, err = txsel.GetL1L2TxSelection(tpc, l1UserTxs, nil)
+	require.NoError(t, err)
+
+	batchPoolL2 := `
+	Type: PoolL2
+	PoolTransfer(0) B-A: 10 (126)
+	`
+	poolL2Txs, err := tc.GeneratePoolL2Txs(batchPoolL2)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(poolL2Txs))
+
+	// valid tx
+	reason, err := txsel.SimulateL2Tx(tpc, poolL2Txs[0])
+	require.NoError(t, err)
+	assert.Nil(t, reason)
+
+	// not enough balance
+	tx := poolL2Txs[0]
+	tx.Amount = big.NewInt(1000000)
+	reason, err = txsel.SimulateL2Tx(tpc, tx)
+	require.NoError(t, err)
+	require.NotNil(t, reason)
+	assert.Equal(t, ErrSenderNotEnoughBalanceCode, reason.Code)
+	assert.Equal(t, ErrSenderNotEnoughBalanceType, reason.Type)
+
+	// invalid nonce
+	tx = poolL2Txs[0]
+	tx.Nonce = 1
+	reason, err = txsel.SimulateL2Tx(tpc, tx)
+	require.NoError(t, err)
+	require.NotNil(t, reason)
+	assert.Equal(t, ErrNoCurrentNonceCode, reason.Code)
+
+	// non existing receiver
+	tx = poolL2Txs[0]
+	tx.ToIdx = common.Idx(1000)
+	reason, err = txsel.SimulateL2Tx(tpc, tx)
+	require.NoError(t, err)
+	require.NotNil(t, reason)
+	assert.Equal(t, ErrToIdxNotFoundCode, reason.Code)
+
+	// the simulations don't alter neither the state nor the pool
+	checkBalance(t, tc, txsel, "A", 0, "100000")
+	checkBalance(t, tc, txsel, "B", 0, "10000")
+	pendingTxs, err := txsel.l2db.GetPendingTxs()
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(pendingTxs))
+
+	stateDB.Close()
+	txsel.LocalAccountsDB().Close()
+}
+
 func TestProcessL2Selection(t *testing.T) {
 	set := `
 		Type: Blockchain
@@ -0,0 +1,119 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/chainbing/tracerr"
+)
+
+// ErrForbiddenAddress is returned when the host of a webhook URL resolves to
+// an address that is not public
+var ErrForbiddenAddress = errors.New("the webhook URL must resolve to public addresses only")
+
+// forbiddenNets are the networks that the webhooks can't point to, so that
+// the node can't be used to reach its own host, its internal network or the
+// metadata service of the cloud provider
+var forbiddenNets = mustParseCIDRs(
+	"0.0.0.0/8",      // "This" network
+	"10.0.0.0/8",     // Private
+	"100.64.0.0/10",  // Carrier-grade NAT
+	"127.0.0.0/8",    // Loopback
+	"169.254.0.0/16", // Link-local, including the metadata services
+	"172.16.0.0/12",  // Private
+	"192.0.0.0/24",   // IETF protocol assignments
+	"192.168.0.0/16", // Private
+	"198.18.0.0/15",  // Benchmarking
+	"224.0.0.0/4",    // Multicast
+	"240.0.0.0/4",    // Reserved and broadcast
+	"::/128",         // Unspecified
+	"::1/128",        // Loopback
+	"64:ff9b::/96",   // IPv4/IPv6 translation
+	"fc00::/7",       // Unique local
+	"fe80::/10",      // Link-local
+	"ff00::/8",       // Multicast
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets[i] = ipNet
+	}
+	return nets
+}
+
+// isForbiddenIP returns true if the ip belongs to a forbidden network
+func isForbiddenIP(ip net.IP) bool {
+	for _, ipNet := range forbiddenNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckURL resolves the host of a webhook URL and returns ErrForbiddenAddress
+// if any of its addresses is not public.  The addresses are checked again at
+// each delivery, since the host may resolve to other addresses later.
+func CheckURL(ctx context.Context, rawURL string) error {
+	hookURL, err := url.Parse(rawURL)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	host := hookURL.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if isForbiddenIP(ip) {
+			return tracerr.Wrap(ErrForbiddenAddress)
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	for _, addr := range addrs {
+		if isForbiddenIP(addr.IP) {
+			return tracerr.Wrap(ErrForbiddenAddress)
+		}
+	}
+	return nil
+}
+
+// dialControl rejects the connections to forbidden addresses.  It's called
+// with the resolved address, so it also covers the hosts that resolve to
+// other addresses after the webhook is registered.  The errors are not
+// wrapped, so that they can be unwrapped from the errors of the client.
+func dialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || isForbiddenIP(ip) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// newTransport returns the transport used for the deliveries, which only
+// connects to public addresses and ignores the proxy of the environment
+func newTransport(timeout time.Duration) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: dialControl,
+	}
+	return &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: timeout,
+		MaxIdleConns:        100,              //nolint:gomnd
+		IdleConnTimeout:     90 * time.Second, //nolint:gomnd
+	}
+}
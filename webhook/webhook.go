@@ -0,0 +1,224 @@
+/*
+Package webhook delivers the events queued for the registered webhooks.
+
+The webhooks are registered through the coordinator API, and the events (pool
+txs that change their state, forged L1 user txs and withdrawable exits) are
+queued in the SQL DB by triggers, so that no event is lost while the node is
+stopped.  The Dispatcher sends each queued event as an HTTP POST request with a
+JSON body signed with the secret of the webhook, retrying with exponential
+backoff the deliveries that are not accepted.
+
+The signature is sent in the X-Chainbing-Signature header as
+"sha256=<hex encoded HMAC-SHA256 of the body using the webhook secret>".
+*/
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/chainbing/node/db/l2db"
+	"github.com/chainbing/node/log"
+	"github.com/chainbing/tracerr"
+)
+
+const (
+	// SignatureHeader is the header that contains the signature of the
+	// body of the delivery
+	SignatureHeader = "X-Chainbing-Signature"
+	// EventHeader is the header that contains the type of the event
+	EventHeader = "X-Chainbing-Event"
+	// DeliveryHeader is the header that contains the id of the delivery,
+	// which is kept between retries
+	DeliveryHeader = "X-Chainbing-Delivery"
+	// maxInfoLen is the maximum length of the response body or error
+	// stored with a failed attempt
+	maxInfoLen = 256
+)
+
+// Config is the configuration of the Dispatcher
+type Config struct {
+	// Interval is the waiting time between checks of pending deliveries
+	Interval time.Duration
+	// Timeout is the maximum duration of each delivery request
+	Timeout time.Duration
+	// BatchSize is the maximum number of deliveries attempted at each check
+	BatchSize int
+	// MaxAttempts is the number of attempts after which a delivery is
+	// marked as failed
+	MaxAttempts int
+	// MinBackoff is the waiting time after the first failed attempt.  It's
+	// doubled after each failed attempt.
+	MinBackoff time.Duration
+	// MaxBackoff is the maximum waiting time between attempts
+	MaxBackoff time.Duration
+	// KeepDeliveries is the time that the done and failed deliveries are
+	// kept for the delivery log
+	KeepDeliveries time.Duration
+}
+
+// Dispatcher delivers the events queued in the L2DB to the webhooks
+type Dispatcher struct {
+	cfg    Config
+	l2DB   *l2db.L2DB
+	client *http.Client
+}
+
+// NewDispatcher creates a new Dispatcher
+func NewDispatcher(cfg Config, l2DB *l2db.L2DB) *Dispatcher {
+	return &Dispatcher{
+		cfg:  cfg,
+		l2DB: l2DB,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// Only public addresses are reached, even if the host
+			// of the webhook resolves to other addresses later
+			Transport: newTransport(cfg.Timeout),
+			// Redirects are not followed, the registered URL
+			// must accept the delivery
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Run delivers the pending events until the context is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	lastPurge := time.Time{}
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("Webhook Dispatcher done")
+			return
+		case <-time.After(d.cfg.Interval):
+		}
+		if err := d.dispatch(ctx); ctx.Err() != nil {
+			continue
+		} else if err != nil {
+			log.Errorw("Webhook Dispatcher.dispatch", "err", err)
+		}
+		if time.Since(lastPurge) > d.cfg.KeepDeliveries/10 { //nolint:gomnd
+			if err := d.l2DB.PurgeWebhookDeliveries(time.Now().Add(-d.cfg.KeepDeliveries)); err != nil {
+				log.Errorw("Webhook Dispatcher PurgeWebhookDeliveries", "err", err)
+			}
+			lastPurge = time.Now()
+		}
+	}
+}
+
+// dispatch attempts the pending deliveries until there are no more
+// deliveries ready to be attempted
+func (d *Dispatcher) dispatch(ctx context.Context) error {
+	for {
+		// The deliveries are leased for the time it takes to attempt
+		// them all, so that they aren't attempted twice
+		lease := time.Duration(d.cfg.BatchSize+1) * d.cfg.Timeout
+		deliveries, err := d.l2DB.GetPendingWebhookDeliveries(d.cfg.BatchSize, lease)
+		if err != nil {
+			return tracerr.Wrap(err)
+		}
+		if len(deliveries) == 0 {
+			return nil
+		}
+		for i := range deliveries {
+			if ctx.Err() != nil {
+				return nil
+			}
+			delivery := &deliveries[i]
+			statusCode, err := d.deliver(ctx, delivery)
+			state := l2db.WebhookDeliveryStateDone
+			nextAttempt := time.Now()
+			info := ""
+			if err != nil {
+				info = err.Error()
+				if len(info) > maxInfoLen {
+					info = info[:maxInfoLen]
+				}
+				if delivery.Attempts+1 >= d.cfg.MaxAttempts {
+					state = l2db.WebhookDeliveryStateFailed
+					log.Warnw("Webhook delivery failed", "webhook", delivery.WebhookID,
+						"delivery", delivery.ItemID, "err", err)
+				} else {
+					state = l2db.WebhookDeliveryStatePending
+					nextAttempt = nextAttempt.Add(d.backoff(delivery.Attempts))
+				}
+			}
+			if err := d.l2DB.UpdateWebhookDelivery(delivery.ItemID, state,
+				statusCode, info, nextAttempt); err != nil {
+				return tracerr.Wrap(err)
+			}
+		}
+	}
+}
+
+// backoff returns the waiting time before the next attempt of a delivery
+// that has failed attempts+1 times
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	backoff := d.cfg.MinBackoff
+	for i := 0; i < attempts && backoff < d.cfg.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > d.cfg.MaxBackoff {
+		backoff = d.cfg.MaxBackoff
+	}
+	return backoff
+}
+
+// event is the body of a delivery
+type event struct {
+	ID        uint64          `json:"id"`
+	Type      string          `json:"type"`
+	Timestamp time.Time       `json:"timestamp"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// deliver sends the delivery to the webhook URL.  An error is returned if
+// the delivery is not accepted with a 2xx status code.
+func (d *Dispatcher) deliver(ctx context.Context, delivery *l2db.WebhookDelivery) (*int, error) {
+	body, err := json.Marshal(event{
+		ID:        delivery.ItemID,
+		Type:      delivery.EventType,
+		Timestamp: delivery.Timestamp,
+		Data:      delivery.Payload,
+	})
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, fmt.Sprint(delivery.ItemID))
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, body))
+	res, err := d.client.Do(req)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	defer res.Body.Close() //nolint:errcheck
+	statusCode := res.StatusCode
+	if statusCode < 200 || statusCode >= 300 {
+		resBody, _ := ioutil.ReadAll(io.LimitReader(res.Body, maxInfoLen))
+		return &statusCode, tracerr.Wrap(fmt.Errorf("status code %d: %s", statusCode, resBody))
+	}
+	return &statusCode, nil
+}
+
+// Sign returns the value of the signature header of a delivery body, which
+// is the HMAC-SHA256 of the body using the webhook secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body) //nolint:errcheck
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,108 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chainbing/node/db/l2db"
+	"github.com/chainbing/tracerr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliver(t *testing.T) {
+	var received *http.Request
+	var receivedBody []byte
+	statusCode := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		receivedBody = body
+		w.WriteHeader(statusCode)
+	}))
+	defer server.Close()
+
+	d := NewDispatcher(Config{Timeout: time.Second}, nil)
+	// the test server listens on the loopback, which is forbidden
+	d.client.Transport = http.DefaultTransport
+	delivery := l2db.WebhookDelivery{
+		ItemID:    42,
+		WebhookID: 1,
+		URL:       server.URL,
+		Secret:    "secret",
+		EventType: "poolTx",
+		Payload:   []byte(`{"txId": "0x02", "state": "fged"}`),
+		Timestamp: time.Now(),
+	}
+	resStatusCode, err := d.deliver(context.Background(), &delivery)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, *resStatusCode)
+	assert.Equal(t, http.MethodPost, received.Method)
+	assert.Equal(t, "poolTx", received.Header.Get(EventHeader))
+	assert.Equal(t, "42", received.Header.Get(DeliveryHeader))
+	// the body is signed with the secret of the webhook
+	assert.Equal(t, Sign("secret", receivedBody), received.Header.Get(SignatureHeader))
+	assert.NotEqual(t, Sign("other", receivedBody), received.Header.Get(SignatureHeader))
+	var e event
+	require.NoError(t, json.Unmarshal(receivedBody, &e))
+	assert.Equal(t, uint64(42), e.ID)
+	assert.Equal(t, "poolTx", e.Type)
+	assert.JSONEq(t, string(delivery.Payload), string(e.Data))
+
+	// deliveries not accepted with a 2xx status code fail
+	statusCode = http.StatusInternalServerError
+	resStatusCode, err = d.deliver(context.Background(), &delivery)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, *resStatusCode)
+}
+
+func TestForbiddenAddress(t *testing.T) {
+	ctx := context.Background()
+	for _, hookURL := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://10.1.2.3/hook",
+		"http://192.168.1.1/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]/hook",
+		"http://[fd00::1]/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+	} {
+		err := CheckURL(ctx, hookURL)
+		assert.True(t, errors.Is(tracerr.Unwrap(err), ErrForbiddenAddress), hookURL)
+	}
+	assert.NoError(t, CheckURL(ctx, "https://8.8.8.8/hook"))
+
+	// the deliveries to forbidden addresses are rejected when connecting
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	d := NewDispatcher(Config{Timeout: time.Second}, nil)
+	delivery := l2db.WebhookDelivery{
+		ItemID:    1,
+		URL:       server.URL,
+		Secret:    "secret",
+		EventType: "poolTx",
+		Payload:   []byte(`{}`),
+	}
+	statusCode, err := d.deliver(ctx, &delivery)
+	assert.Nil(t, statusCode)
+	assert.True(t, errors.Is(tracerr.Unwrap(err), ErrForbiddenAddress))
+}
+
+func TestBackoff(t *testing.T) {
+	d := NewDispatcher(Config{MinBackoff: time.Second, MaxBackoff: 10 * time.Second}, nil)
+	assert.Equal(t, time.Second, d.backoff(0))
+	assert.Equal(t, 2*time.Second, d.backoff(1))
+	assert.Equal(t, 8*time.Second, d.backoff(3))
+	assert.Equal(t, 10*time.Second, d.backoff(4))
+	assert.Equal(t, 10*time.Second, d.backoff(100))
+}